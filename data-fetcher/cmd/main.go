@@ -1,7 +1,16 @@
 package main
 
-import "cur/internal/app"
+import (
+	"cur/internal/app"
+	"os"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		os.Exit(app.RunBacktest(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(app.RunConfig(os.Args[2:]))
+	}
 	app.StartApplication()
 }
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/service/alert"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultAlertEventsLimit = 100
+
+type alertRuleDto struct {
+	Id              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Pair            string     `json:"pair"`
+	Bar             string     `json:"bar,omitempty"`
+	Kind            string     `json:"kind"`
+	Direction       string     `json:"direction"`
+	Level           string     `json:"level,omitempty"`
+	Percent         string     `json:"percent,omitempty"`
+	Window          string     `json:"window,omitempty"`
+	Multiplier      string     `json:"multiplier,omitempty"`
+	Lookback        int        `json:"lookback,omitempty"`
+	Indicator       string     `json:"indicator,omitempty"`
+	Cooldown        string     `json:"cooldown,omitempty"`
+	Channels        []string   `json:"channels"`
+	Enabled         bool       `json:"enabled"`
+	LastTriggeredAt *time.Time `json:"lastTriggeredAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// alertRuleRequest body of a new rule, decimals are strings and durations are Go durations, e.g. "1h30m"
+type alertRuleRequest struct {
+	Name       string   `json:"name"`
+	Pair       string   `json:"pair"`
+	Bar        string   `json:"bar"`
+	Kind       string   `json:"kind"`
+	Direction  string   `json:"direction"`
+	Level      string   `json:"level"`
+	Percent    string   `json:"percent"`
+	Window     string   `json:"window"`
+	Multiplier string   `json:"multiplier"`
+	Lookback   int      `json:"lookback"`
+	Indicator  string   `json:"indicator"`
+	Cooldown   string   `json:"cooldown"`
+	Channels   []string `json:"channels"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+type alertEventDto struct {
+	Id          int64     `json:"id"`
+	RuleId      int64     `json:"ruleId"`
+	Pair        string    `json:"pair"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Message     string    `json:"message"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// EnableAlerts serves management of alert rules and history of triggered alerts,
+// changed is called after rules are created or deleted
+func (s *Server) EnableAlerts(repository store.AlertStore, changed func()) {
+	if changed == nil {
+		changed = func() {}
+	}
+
+	s.Handle("GET /v1/alerts/rules", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAlertRules(w, r, repository)
+	}))
+	s.Handle("POST /v1/alerts/rules", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleCreateAlertRule(w, r, repository, changed)
+	}))
+	s.Handle("GET /v1/alerts/rules/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAlertRule(w, r, repository)
+	}))
+	s.Handle("DELETE /v1/alerts/rules/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleDeleteAlertRule(w, r, repository, changed)
+	}))
+	s.Handle("GET /v1/alerts/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAlertEvents(w, r, repository)
+	}))
+}
+
+// handleAlertRules GET /v1/alerts/rules
+func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request, repository store.AlertStore) {
+	rules, err := repository.FetchAlertRules()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]alertRuleDto, 0, len(rules))
+	for _, rule := range rules {
+		data = append(data, toAlertRuleDto(rule))
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleAlertRule GET /v1/alerts/rules/{id}
+func (s *Server) handleAlertRule(w http.ResponseWriter, r *http.Request, repository store.AlertStore) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid rule id")
+		return
+	}
+
+	rule, err := repository.FetchAlertRule(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "rule not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toAlertRuleDto(rule))
+}
+
+// handleCreateAlertRule POST /v1/alerts/rules
+func (s *Server) handleCreateAlertRule(w http.ResponseWriter, r *http.Request, repository store.AlertStore, changed func()) {
+	var req alertRuleRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	rule, err := req.toRule()
+	if err == nil {
+		err = alert.ValidateRule(rule)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	rule, err = repository.InsertAlertRule(rule)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	changed()
+
+	writeJson(w, http.StatusCreated, toAlertRuleDto(rule))
+}
+
+// handleDeleteAlertRule DELETE /v1/alerts/rules/{id}
+func (s *Server) handleDeleteAlertRule(w http.ResponseWriter, r *http.Request, repository store.AlertStore, changed func()) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid rule id")
+		return
+	}
+
+	err = repository.DeleteAlertRule(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "rule not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	changed()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAlertEvents GET /v1/alerts/events?rule=&limit=, newest first
+func (s *Server) handleAlertEvents(w http.ResponseWriter, r *http.Request, repository store.AlertStore) {
+	q := r.URL.Query()
+
+	var ruleId int64
+	if value := q.Get("rule"); value != "" {
+		var err error
+		ruleId, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || ruleId <= 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid rule id")
+			return
+		}
+	}
+
+	limit, err := parseLimit(q, DefaultAlertEventsLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	events, err := repository.FetchAlertEvents(ruleId, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]alertEventDto, 0, len(events))
+	for _, e := range events {
+		data = append(data, alertEventDto{
+			Id:          e.Id,
+			RuleId:      e.RuleId,
+			Pair:        e.Pair,
+			Kind:        string(e.Kind),
+			Value:       price.Price{Price: e.Value}.String(),
+			Message:     e.Message,
+			TriggeredAt: e.TriggeredAt.UTC(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+func (req alertRuleRequest) toRule() (model.AlertRule, error) {
+	rule := model.AlertRule{
+		Name:      req.Name,
+		Pair:      req.Pair,
+		Bar:       req.Bar,
+		Kind:      model.AlertKind(req.Kind),
+		Direction: model.AlertDirection(req.Direction),
+		Lookback:  req.Lookback,
+		Indicator: req.Indicator,
+		Channels:  req.Channels,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if rule.Direction == "" {
+		rule.Direction = model.DirectionAny
+	}
+
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *int64
+	}{
+		{"level", req.Level, &rule.Level},
+		{"percent", req.Percent, &rule.Percent},
+		{"multiplier", req.Multiplier, &rule.Multiplier},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value, err := price.ParsePrice(field.value)
+		if err != nil {
+			return rule, fmt.Errorf("%s must be a decimal number", field.name)
+		}
+		*field.target = value
+	}
+
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"window", req.Window, &rule.Window},
+		{"cooldown", req.Cooldown, &rule.Cooldown},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value, err := time.ParseDuration(field.value)
+		if err != nil {
+			return rule, fmt.Errorf("%s must be a duration, e.g. 1h30m", field.name)
+		}
+		*field.target = value
+	}
+
+	return rule, nil
+}
+
+func toAlertRuleDto(rule model.AlertRule) alertRuleDto {
+	dto := alertRuleDto{
+		Id:        rule.Id,
+		Name:      rule.Name,
+		Pair:      rule.Pair,
+		Bar:       rule.Bar,
+		Kind:      string(rule.Kind),
+		Direction: string(rule.Direction),
+		Lookback:  rule.Lookback,
+		Indicator: rule.Indicator,
+		Channels:  rule.Channels,
+		Enabled:   rule.Enabled,
+		CreatedAt: rule.CreatedAt.UTC(),
+	}
+	if dto.Channels == nil {
+		dto.Channels = []string{}
+	}
+	if rule.Level != 0 {
+		dto.Level = price.Price{Price: rule.Level}.String()
+	}
+	if rule.Percent != 0 {
+		dto.Percent = price.Price{Price: rule.Percent}.String()
+	}
+	if rule.Multiplier != 0 {
+		dto.Multiplier = price.Price{Price: rule.Multiplier}.String()
+	}
+	if rule.Window != 0 {
+		dto.Window = rule.Window.String()
+	}
+	if rule.Cooldown != 0 {
+		dto.Cooldown = rule.Cooldown.String()
+	}
+	if !rule.LastTriggeredAt.IsZero() {
+		lastTriggered := rule.LastTriggeredAt.UTC()
+		dto.LastTriggeredAt = &lastTriggered
+	}
+	return dto
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlertsEnv(t *testing.T) (*testEnv, *int) {
+	storage := memory.NewStore()
+	changes := 0
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableAlerts(storage.Alert(), func() { changes++ })
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+	return env, &changes
+}
+
+func (env *testEnv) send(t *testing.T, method, path, body string) *http.Response {
+	req, err := http.NewRequest(method, env.server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestServer_AlertRules(t *testing.T) {
+	env, changes := newAlertsEnv(t)
+
+	resp := env.send(t, http.MethodPost, "/v1/alerts/rules", `{
+		"name": "BTC moves 5%", "pair": "BTC-USDT", "kind": "percent_change",
+		"percent": "5", "window": "1h", "cooldown": "30m", "channels": ["webhook", "telegram"]
+	}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, 1, *changes)
+
+	var rules struct {
+		Data []alertRuleDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/alerts/rules", &rules))
+	require.Len(t, rules.Data, 1)
+	rule := rules.Data[0]
+	assert.Equal(t, "percent_change", rule.Kind)
+	assert.Equal(t, "any", rule.Direction)
+	assert.Equal(t, "5", rule.Percent)
+	assert.Equal(t, "1h0m0s", rule.Window)
+	assert.Equal(t, "30m0s", rule.Cooldown)
+	assert.Equal(t, []string{"webhook", "telegram"}, rule.Channels)
+	assert.True(t, rule.Enabled)
+	assert.Nil(t, rule.LastTriggeredAt)
+
+	stored, err := env.storage.Alert().FetchAlertRule(rule.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5*100_000_000), stored.Percent)
+	assert.Equal(t, time.Hour, stored.Window)
+
+	triggered := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	_, err = env.storage.Alert().InsertAlertEvent(model.AlertEvent{
+		RuleId: rule.Id, Pair: "BTC-USDT", Kind: model.AlertPercentChange, Value: 512_000_000, Message: "moved", TriggeredAt: triggered,
+	})
+	require.NoError(t, err)
+
+	var single alertRuleDto
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/alerts/rules/1", &single))
+	require.NotNil(t, single.LastTriggeredAt)
+	assert.Equal(t, triggered, *single.LastTriggeredAt)
+
+	var events struct {
+		Data []alertEventDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/alerts/events?rule=1", &events))
+	require.Len(t, events.Data, 1)
+	assert.Equal(t, "5.12", events.Data[0].Value)
+
+	assert.Equal(t, http.StatusNoContent, env.send(t, http.MethodDelete, "/v1/alerts/rules/1", "").StatusCode)
+	assert.Equal(t, 2, *changes)
+	assert.Equal(t, http.StatusNotFound, env.send(t, http.MethodDelete, "/v1/alerts/rules/1", "").StatusCode)
+	var body errorBody
+	assert.Equal(t, http.StatusNotFound, env.get(t, "/v1/alerts/rules/1", &body))
+}
+
+func TestServer_AlertRulesErrors(t *testing.T) {
+	env, changes := newAlertsEnv(t)
+
+	for _, body := range []string{
+		`not json`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "threshold", "level": "100000", "unknown": 1}`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "threshold", "level": "a lot"}`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "threshold"}`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "percent_change", "percent": "5", "window": "an hour"}`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "indicator", "bar": "1H", "indicator": "magic_5", "level": "70"}`,
+		`{"name": "x", "pair": "BTC-USDT", "kind": "threshold", "level": "1", "channels": ["pigeon"]}`,
+	} {
+		resp := env.send(t, http.MethodPost, "/v1/alerts/rules", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+	assert.Zero(t, *changes)
+
+	var body errorBody
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/alerts/rules/abc", &body))
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/alerts/events?rule=-1", &body))
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const DefaultVolatilityLimit = 100
+
+type volatilityDto struct {
+	Pair      string    `json:"pair"`
+	Bar       string    `json:"bar"`
+	Window    int       `json:"window"`
+	Estimator string    `json:"estimator"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     string    `json:"value"`
+}
+
+// correlationMatrixDto rows and columns follow pairs, a missing coefficient is null
+type correlationMatrixDto struct {
+	Bar       string      `json:"bar"`
+	Window    int         `json:"window"`
+	Method    string      `json:"method"`
+	Timestamp time.Time   `json:"timestamp"`
+	Pairs     []string    `json:"pairs"`
+	Matrix    [][]*string `json:"matrix"`
+}
+
+// EnableAnalytics serves stored volatilities and correlation matrices of computed windows, the first window is the default one
+func (s *Server) EnableAnalytics(repository store.AnalyticsStore, windows []int) {
+	s.Handle("GET /v1/analytics/volatility", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleVolatility(w, r, repository, windows)
+	}))
+	s.Handle("GET /v1/analytics/correlation", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleCorrelation(w, r, repository, windows)
+	}))
+}
+
+// handleVolatility GET /v1/analytics/volatility?pair=&bar=&window=&estimator=&from=&to=&limit=, newest first
+func (s *Server) handleVolatility(w http.ResponseWriter, r *http.Request, repository store.AnalyticsStore, windows []int) {
+	q := r.URL.Query()
+	query := store.VolatilityQuery{Pair: q.Get("pair"), Bar: q.Get("bar"), Estimator: model.VolatilityEstimator(q.Get("estimator"))}
+	if query.Pair == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "pair is required")
+		return
+	}
+	if query.Bar == "" {
+		query.Bar = s.defaultBar
+	}
+	switch query.Estimator {
+	case "":
+		query.Estimator = model.VolatilityCloseToClose
+	case model.VolatilityCloseToClose, model.VolatilityParkinson, model.VolatilityGarmanKlass:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("unknown estimator %q", query.Estimator))
+		return
+	}
+
+	var err error
+	if query.Window, err = parseWindow(q.Get("window"), windows); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if query.From, err = parseTime(q, "from"); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if query.To, err = parseTime(q, "to"); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from must be earlier than to")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultVolatilityLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	values, err := repository.FetchVolatilities(query, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]volatilityDto, 0, len(values))
+	for _, v := range values {
+		data = append(data, volatilityDto{
+			Pair:      v.Pair,
+			Bar:       v.Bar,
+			Window:    v.Window,
+			Estimator: string(v.Estimator),
+			Timestamp: v.Timestamp.UTC(),
+			Value:     price.Price{Price: v.Value}.String(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleCorrelation GET /v1/analytics/correlation?bar=&window=&method=&at=, the latest matrix at or before at
+func (s *Server) handleCorrelation(w http.ResponseWriter, r *http.Request, repository store.AnalyticsStore, windows []int) {
+	q := r.URL.Query()
+
+	bar := q.Get("bar")
+	if bar == "" {
+		bar = s.defaultBar
+	}
+
+	method := model.CorrelationMethod(q.Get("method"))
+	switch method {
+	case "":
+		method = model.CorrelationPearson
+	case model.CorrelationPearson, model.CorrelationSpearman:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("unknown method %q", method))
+		return
+	}
+
+	window, err := parseWindow(q.Get("window"), windows)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	at, err := parseTime(q, "at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	correlations, err := repository.FetchCorrelationMatrix(bar, window, method, at)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	if len(correlations) == 0 {
+		writeError(w, http.StatusNotFound, CodeNotFound, "correlations not found")
+		return
+	}
+
+	writeJson(w, http.StatusOK, toCorrelationMatrixDto(bar, window, method, correlations))
+}
+
+// parseWindow returns the first window when value is empty, only computed windows are accepted
+func parseWindow(value string, windows []int) (int, error) {
+	if value == "" {
+		return windows[0], nil
+	}
+	window, err := strconv.Atoi(value)
+	if err != nil || !slices.Contains(windows, window) {
+		return 0, fmt.Errorf("window must be one of %v", windows)
+	}
+	return window, nil
+}
+
+func toCorrelationMatrixDto(bar string, window int, method model.CorrelationMethod, correlations []model.Correlation) correlationMatrixDto {
+	index := make(map[string]int)
+	for _, c := range correlations {
+		index[c.PairA] = 0
+		index[c.PairB] = 0
+	}
+	pairs := make([]string, 0, len(index))
+	for pair := range index {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for i, pair := range pairs {
+		index[pair] = i
+	}
+
+	var timestamp time.Time
+	for _, c := range correlations {
+		if c.Timestamp.After(timestamp) {
+			timestamp = c.Timestamp
+		}
+	}
+
+	one := "1"
+	matrix := make([][]*string, len(pairs))
+	for i := range matrix {
+		matrix[i] = make([]*string, len(pairs))
+		matrix[i][i] = &one
+	}
+	for _, c := range correlations {
+		value := price.Price{Price: c.Value}.String()
+		a, b := index[c.PairA], index[c.PairB]
+		matrix[a][b], matrix[b][a] = &value, &value
+	}
+
+	return correlationMatrixDto{
+		Bar:       bar,
+		Window:    window,
+		Method:    string(method),
+		Timestamp: timestamp.UTC(),
+		Pairs:     pairs,
+		Matrix:    matrix,
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Analytics(t *testing.T) {
+	storage := memory.NewStore()
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableAnalytics(storage.Analytics(), []int{24, 168})
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	ts := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.Analytics().InsertVolatilities([]model.Volatility{
+		{Pair: "BTC-USDT", Bar: "1H", Window: 24, Estimator: model.VolatilityCloseToClose, Timestamp: ts.Add(-time.Hour), Value: 40 * 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Window: 24, Estimator: model.VolatilityCloseToClose, Timestamp: ts, Value: 45 * 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Window: 24, Estimator: model.VolatilityParkinson, Timestamp: ts, Value: 38 * 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Window: 168, Estimator: model.VolatilityCloseToClose, Timestamp: ts, Value: 50 * 100_000_000},
+	}))
+	require.NoError(t, storage.Analytics().InsertCorrelations([]model.Correlation{
+		{Bar: "1H", Window: 24, Method: model.CorrelationPearson, Timestamp: ts, PairA: "BTC-USDT", PairB: "ETH-USDT", Value: 85_000_000},
+		{Bar: "1H", Window: 24, Method: model.CorrelationPearson, Timestamp: ts.Add(-time.Hour), PairA: "BTC-USDT", PairB: "SOL-USDT", Value: 70_000_000},
+	}))
+
+	var volatility struct {
+		Data []volatilityDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/analytics/volatility?pair=BTC-USDT", &volatility))
+	require.Len(t, volatility.Data, 2)
+	assert.Equal(t, "45", volatility.Data[0].Value, "newest first")
+	assert.Equal(t, 24, volatility.Data[0].Window, "the first window is the default one")
+
+	require.Equal(t, 200, env.get(t, "/v1/analytics/volatility?pair=BTC-USDT&window=168", &volatility))
+	require.Len(t, volatility.Data, 1)
+	assert.Equal(t, "50", volatility.Data[0].Value)
+
+	require.Equal(t, 200, env.get(t, "/v1/analytics/volatility?pair=BTC-USDT&estimator=parkinson&limit=1", &volatility))
+	require.Len(t, volatility.Data, 1)
+	assert.Equal(t, "38", volatility.Data[0].Value)
+
+	var matrix correlationMatrixDto
+	require.Equal(t, 200, env.get(t, "/v1/analytics/correlation", &matrix))
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT", "SOL-USDT"}, matrix.Pairs)
+	assert.Equal(t, ts, matrix.Timestamp, "the latest timestamp of the pairs")
+	require.Len(t, matrix.Matrix, 3)
+	assert.Equal(t, "1", *matrix.Matrix[0][0])
+	assert.Equal(t, "0.85", *matrix.Matrix[0][1])
+	assert.Equal(t, "0.85", *matrix.Matrix[1][0])
+	assert.Equal(t, "0.7", *matrix.Matrix[2][0])
+	assert.Nil(t, matrix.Matrix[1][2], "ETH and SOL have no common candles")
+
+	var body errorBody
+	assert.Equal(t, 404, env.get(t, "/v1/analytics/correlation?method=spearman", &body))
+	assert.Equal(t, 404, env.get(t, "/v1/analytics/correlation?at=2023-12-31T00:00:00Z", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/analytics/correlation?method=kendall", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/analytics/volatility", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/analytics/volatility?pair=BTC-USDT&window=7", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/analytics/volatility?pair=BTC-USDT&estimator=yang_zhang", &body))
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/anomaly"
+	"cur/internal/store"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultAnomalyLimit = 100
+
+type anomalyDto struct {
+	Id         int64      `json:"id"`
+	Kind       string     `json:"kind"`
+	Source     string     `json:"source"`
+	Pair       string     `json:"pair"`
+	Bar        string     `json:"bar,omitempty"`
+	Timestamp  time.Time  `json:"timestamp"`
+	Reason     string     `json:"reason"`
+	Candle     *candleDto `json:"candle,omitempty"`
+	Trade      *tradeDto  `json:"trade,omitempty"`
+	Status     string     `json:"status"`
+	DetectedAt time.Time  `json:"detectedAt"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+}
+
+// EnableAnomalies serves quarantined rows for review, released rows are stored by the detector
+func (s *Server) EnableAnomalies(detector *anomaly.Detector, repository store.AnomalyStore) {
+	s.Handle("GET /v1/anomalies", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAnomalies(w, r, repository)
+	}))
+	s.Handle("GET /v1/anomalies/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAnomaly(w, r, repository)
+	}))
+	s.Handle("POST /v1/anomalies/{id}/release", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleReviewAnomaly(w, r, detector.Release)
+	}))
+	s.Handle("POST /v1/anomalies/{id}/discard", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleReviewAnomaly(w, r, detector.Discard)
+	}))
+}
+
+// handleAnomalies GET /v1/anomalies?pair=&kind=&status=&limit=, newest first
+func (s *Server) handleAnomalies(w http.ResponseWriter, r *http.Request, repository store.AnomalyStore) {
+	q := r.URL.Query()
+	query := store.AnomalyQuery{Pair: q.Get("pair"), Kind: model.AnomalyKind(q.Get("kind")), Status: model.AnomalyStatus(q.Get("status"))}
+	switch query.Kind {
+	case "", model.AnomalyOutlier, model.AnomalyInvariant, model.AnomalyStale:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "kind must be outlier, invariant or stale")
+		return
+	}
+	switch query.Status {
+	case "", model.AnomalyPending, model.AnomalyReleased, model.AnomalyDiscarded:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "status must be pending, released or discarded")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultAnomalyLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	anomalies, err := repository.FetchAnomalies(query, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]anomalyDto, 0, len(anomalies))
+	for _, a := range anomalies {
+		data = append(data, toAnomalyDto(a))
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleAnomaly GET /v1/anomalies/{id}
+func (s *Server) handleAnomaly(w http.ResponseWriter, r *http.Request, repository store.AnomalyStore) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid anomaly id")
+		return
+	}
+
+	a, err := repository.FetchAnomaly(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "anomaly not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toAnomalyDto(a))
+}
+
+// handleReviewAnomaly POST /v1/anomalies/{id}/release and /discard
+func (s *Server) handleReviewAnomaly(w http.ResponseWriter, r *http.Request, review func(id int64) (model.Anomaly, error)) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid anomaly id")
+		return
+	}
+
+	a, err := review(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "pending anomaly not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toAnomalyDto(a))
+}
+
+func toAnomalyDto(a model.Anomaly) anomalyDto {
+	dto := anomalyDto{
+		Id:         a.Id,
+		Kind:       string(a.Kind),
+		Source:     string(a.Source),
+		Pair:       a.Pair,
+		Bar:        a.Bar,
+		Timestamp:  a.Timestamp.UTC(),
+		Reason:     a.Reason,
+		Status:     string(a.Status),
+		DetectedAt: a.DetectedAt.UTC(),
+	}
+	if a.Candle != nil {
+		candle := toCandleDto(*a.Candle)
+		dto.Candle = &candle
+	}
+	if a.Trade != nil {
+		trade := toTradeDto(*a.Trade)
+		dto.Trade = &trade
+	}
+	if !a.ReviewedAt.IsZero() {
+		reviewedAt := a.ReviewedAt.UTC()
+		dto.ReviewedAt = &reviewedAt
+	}
+	return dto
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/anomaly"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Anomalies(t *testing.T) {
+	storage := memory.NewStore()
+	trades := store.NewTradeRepository(10)
+	detector, err := anomaly.NewDetector(storage.Anomaly(), storage.Candle(), trades, []string{"BTC-USDT"},
+		anomaly.Config{Window: 10, MinSamples: 5, MadScore: 6, ZScore: 6, MinDeviation: 1}, log.New())
+	require.NoError(t, err)
+
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	bad := model.Candle{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Open: 100, High: 90, Low: 95, Close: 100, Volume: 1}
+	trade := model.Trade{Pair: "BTC-USDT", TradeId: "7", Price: 100_000_000, Size: 1, Side: "sell", Timestamp: start}
+	_, err = storage.Anomaly().InsertAnomalies([]model.Anomaly{
+		{Kind: model.AnomalyInvariant, Source: model.AnomalySourceCandle, Pair: "BTC-USDT", Bar: "1H", Timestamp: start,
+			Reason: "high below low", Candle: &bad, DetectedAt: start},
+		{Kind: model.AnomalyOutlier, Source: model.AnomalySourceTrade, Pair: "BTC-USDT", Timestamp: start,
+			Reason: "modified z-score 40", Trade: &trade, DetectedAt: start.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	api := NewServer(storage.Currency(), storage.Candle(), trades, nil, "1H", log.New())
+	api.EnableAnomalies(detector, storage.Anomaly())
+	env := &testEnv{storage: storage, trades: trades, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	var list struct {
+		Data []anomalyDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/anomalies?status=pending", &list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "trade", list.Data[0].Source, "newest first")
+	require.NotNil(t, list.Data[0].Trade)
+	assert.Equal(t, "1", list.Data[0].Trade.Price)
+	assert.Nil(t, list.Data[0].ReviewedAt)
+	require.NotNil(t, list.Data[1].Candle)
+	assert.Equal(t, "1H", list.Data[1].Bar)
+
+	candlePath := "/v1/anomalies/" + strconv.FormatInt(list.Data[1].Id, 10)
+	tradePath := "/v1/anomalies/" + strconv.FormatInt(list.Data[0].Id, 10)
+
+	var one anomalyDto
+	require.Equal(t, 200, env.get(t, candlePath, &one))
+	assert.Equal(t, "high below low", one.Reason)
+
+	resp := env.send(t, "POST", candlePath+"/release", "")
+	require.Equal(t, 200, resp.StatusCode)
+	var released anomalyDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&released))
+	assert.Equal(t, "released", released.Status)
+	assert.NotNil(t, released.ReviewedAt)
+	candles, err := storage.Candle().FetchAll()
+	require.NoError(t, err)
+	assert.Len(t, candles, 1)
+
+	assert.Equal(t, 404, env.send(t, "POST", candlePath+"/discard", "").StatusCode, "reviewed already")
+	assert.Equal(t, 200, env.send(t, "POST", tradePath+"/discard", "").StatusCode)
+	assert.Empty(t, trades.Latest("BTC-USDT", 10))
+
+	var pending struct {
+		Data []anomalyDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/anomalies?status=pending", &pending))
+	assert.Empty(t, pending.Data)
+
+	var body errorBody
+	assert.Equal(t, 400, env.get(t, "/v1/anomalies?kind=weird", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/anomalies?status=open", &body))
+	assert.Equal(t, 404, env.get(t, "/v1/anomalies/999", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/anomalies/x", &body))
+}
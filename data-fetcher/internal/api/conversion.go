@@ -0,0 +1,153 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/store"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type crossRateDto struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Rate      string    `json:"rate"`
+	Amount    string    `json:"amount,omitempty"`
+	Value     string    `json:"value,omitempty"`
+	Path      []string  `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type crossCandlesBody struct {
+	Data []candleDto `json:"data"`
+	Next string      `json:"next,omitempty"`
+	Path []string    `json:"path"`
+}
+
+// EnableConversion serves rates and synthetic candles of any two currencies
+func (s *Server) EnableConversion(repository store.CrossRateStore) {
+	s.Handle("GET /v1/convert", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleConvert(w, r, repository)
+	}))
+	s.Handle("GET /v1/candles/cross", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleCrossCandles(w, r, repository)
+	}))
+}
+
+// handleConvert GET /v1/convert?from=&to=&amount=&at=&bar=, the latest rate by tickers
+// or the rate by closes of bar candles at the time
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request, repository store.CrossRateStore) {
+	q := r.URL.Query()
+	from := strings.ToUpper(q.Get("from"))
+	to := strings.ToUpper(q.Get("to"))
+	if from == "" || to == "" || strings.Contains(from, "-") || strings.Contains(to, "-") {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from and to currencies are required")
+		return
+	}
+
+	var amount int64
+	if value := q.Get("amount"); value != "" {
+		var err error
+		if amount, err = price.ParsePrice(value); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "amount must be a decimal number")
+			return
+		}
+	}
+
+	at, err := parseTime(q, "at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	var rate store.CrossRate
+	if at.IsZero() {
+		rate, err = repository.FetchCrossRate(from, to)
+	} else {
+		bar := q.Get("bar")
+		if bar == "" {
+			bar = s.defaultBar
+		}
+		rate, err = repository.FetchCrossRateAt(from, to, bar, at)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	dto := crossRateDto{
+		From:      rate.From,
+		To:        rate.To,
+		Rate:      price.Price{Price: rate.Rate}.String(),
+		Path:      rate.Path,
+		Timestamp: rate.Timestamp.UTC(),
+	}
+	if amount != 0 {
+		dto.Amount = price.Price{Price: amount}.String()
+		dto.Value = price.Price{Price: price.MulDiv(amount, rate.Rate, price.PriceFactor)}.String()
+	}
+	writeJson(w, http.StatusOK, dto)
+}
+
+// handleCrossCandles GET /v1/candles/cross?pair=&bar=&from=&to=&limit=&cursor=, pair is any two currencies like ETH-BTC
+func (s *Server) handleCrossCandles(w http.ResponseWriter, r *http.Request, repository store.CrossRateStore) {
+	q := r.URL.Query()
+
+	base, quote, ok := strings.Cut(strings.ToUpper(q.Get("pair")), "-")
+	if !ok || base == "" || quote == "" || base == quote {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "pair of two different currencies is required")
+		return
+	}
+
+	bar := q.Get("bar")
+	if bar == "" {
+		bar = s.defaultBar
+	}
+
+	from, err := parseTime(q, "from")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	to, err := parseTime(q, "to")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from must be earlier than to")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultCandlesLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	page, err := repository.FetchCrossCandlePage(store.CandleQuery{Pair: base + "-" + quote, Bar: bar, From: from, To: to}, q.Get("cursor"), limit)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid cursor")
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	candles := make([]candleDto, 0, len(page.Candles))
+	for _, c := range page.Candles {
+		candles = append(candles, toCandleDto(c))
+	}
+
+	writeJson(w, http.StatusOK, crossCandlesBody{Data: candles, Next: page.Next, Path: page.Path})
+}
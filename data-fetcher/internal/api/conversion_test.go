@@ -0,0 +1,75 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConversionEnv(t *testing.T) (*testEnv, *store.TickerRepository) {
+	storage := memory.NewStore()
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []model.Candle{
+		{Pair: "ETH-USDT", Bar: "1H", Timestamp: start, Open: 3_000 * 100_000_000, High: 3_000 * 100_000_000, Low: 3_000 * 100_000_000, Close: 3_000 * 100_000_000, Volume: 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Open: 60_000 * 100_000_000, High: 60_000 * 100_000_000, Low: 60_000 * 100_000_000, Close: 60_000 * 100_000_000, Volume: 100_000_000},
+	}
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	tickers := store.NewTickerRepository()
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableConversion(store.NewCrossRateRepository(storage.Candle(), tickers))
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+	return env, tickers
+}
+
+func TestServer_Convert(t *testing.T) {
+	env, tickers := newConversionEnv(t)
+
+	var rate crossRateDto
+	require.Equal(t, 200, env.get(t, "/v1/convert?from=eth&to=btc&amount=2", &rate))
+	assert.Equal(t, crossRateDto{
+		From: "ETH", To: "BTC", Rate: "0.05", Amount: "2", Value: "0.1", Path: []string{"ETH-USDT", "BTC-USDT"},
+		Timestamp: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, rate)
+
+	tickers.Set(model.Ticker{Pair: "ETH-USDT", Last: 2_400 * 100_000_000, Timestamp: time.Date(2025, 2, 1, 2, 0, 0, 0, time.UTC)})
+	var live crossRateDto
+	require.Equal(t, 200, env.get(t, "/v1/convert?from=ETH&to=BTC", &live))
+	assert.Equal(t, "0.04", live.Rate)
+	assert.Empty(t, live.Value)
+
+	require.Equal(t, 200, env.get(t, "/v1/convert?from=ETH&to=BTC&at=2025-02-01T00:30:00Z", &rate))
+	assert.Equal(t, "0.05", rate.Rate, "historical rates are by candles")
+
+	var body errorBody
+	assert.Equal(t, 404, env.get(t, "/v1/convert?from=ETH&to=EUR", &body))
+	assert.Equal(t, 404, env.get(t, "/v1/convert?from=ETH&to=BTC&at=2025-01-01T00:00:00Z", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/convert?from=ETH", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/convert?from=ETH&to=BTC&amount=x", &body))
+}
+
+func TestServer_CrossCandles(t *testing.T) {
+	env, _ := newConversionEnv(t)
+
+	var body crossCandlesBody
+	require.Equal(t, 200, env.get(t, "/v1/candles/cross?pair=btc-eth", &body))
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT"}, body.Path)
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "BTC-ETH", body.Data[0].Pair)
+	assert.Equal(t, "20", body.Data[0].Close)
+	assert.Equal(t, "1", body.Data[0].Volume)
+
+	var errBody errorBody
+	assert.Equal(t, 400, env.get(t, "/v1/candles/cross?pair=BTC", &errBody))
+	assert.Equal(t, 400, env.get(t, "/v1/candles/cross?pair=BTC-BTC", &errBody))
+	assert.Equal(t, 400, env.get(t, "/v1/candles/cross?pair=BTC-ETH&cursor=!", &errBody))
+	assert.Equal(t, 404, env.get(t, "/v1/candles/cross?pair=BTC-EUR", &errBody))
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/service/divergence"
+	"cur/internal/store"
+	"net/http"
+	"time"
+)
+
+const DefaultDivergenceLimit = 100
+
+type divergenceDto struct {
+	Pair           string    `json:"pair"`
+	Source         string    `json:"source"`
+	OkxPrice       string    `json:"okxPrice"`
+	ReferencePrice string    `json:"referencePrice"`
+	Spread         string    `json:"spread"`
+	NetSpread      string    `json:"netSpread"`
+	ObservedAt     time.Time `json:"observedAt"`
+}
+
+type divergenceEventDto struct {
+	Id             int64     `json:"id"`
+	Pair           string    `json:"pair"`
+	Source         string    `json:"source"`
+	OkxPrice       string    `json:"okxPrice"`
+	ReferencePrice string    `json:"referencePrice"`
+	Spread         string    `json:"spread"`
+	NetSpread      string    `json:"netSpread"`
+	Threshold      string    `json:"threshold"`
+	StartedAt      time.Time `json:"startedAt"`
+	TriggeredAt    time.Time `json:"triggeredAt"`
+}
+
+// EnableDivergence serves spreads between OKX and reference venues, spreads are percents
+func (s *Server) EnableDivergence(monitor *divergence.Monitor, repository store.DivergenceStore) {
+	s.Handle("GET /v1/divergences/latest", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, http.StatusOK, listBody{Data: toDivergenceDtos(monitor.Latest())})
+	}))
+	s.Handle("GET /v1/divergences", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleDivergences(w, r, repository)
+	}))
+	s.Handle("GET /v1/divergences/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleDivergenceEvents(w, r, repository)
+	}))
+}
+
+// handleDivergences GET /v1/divergences?pair=&source=&from=&to=&limit=, newest first
+func (s *Server) handleDivergences(w http.ResponseWriter, r *http.Request, repository store.DivergenceStore) {
+	q := r.URL.Query()
+	query := store.DivergenceQuery{Pair: q.Get("pair"), Source: q.Get("source")}
+
+	var err error
+	if query.From, err = parseTime(q, "from"); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if query.To, err = parseTime(q, "to"); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from must be earlier than to")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultDivergenceLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	divergences, err := repository.FetchDivergences(query, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: toDivergenceDtos(divergences)})
+}
+
+// handleDivergenceEvents GET /v1/divergences/events?pair=&limit=, newest first
+func (s *Server) handleDivergenceEvents(w http.ResponseWriter, r *http.Request, repository store.DivergenceStore) {
+	q := r.URL.Query()
+	limit, err := parseLimit(q, DefaultDivergenceLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	events, err := repository.FetchDivergenceEvents(q.Get("pair"), limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]divergenceEventDto, 0, len(events))
+	for _, e := range events {
+		data = append(data, divergenceEventDto{
+			Id:             e.Id,
+			Pair:           e.Pair,
+			Source:         e.Source,
+			OkxPrice:       price.Price{Price: e.OkxPrice}.String(),
+			ReferencePrice: price.Price{Price: e.ReferencePrice}.String(),
+			Spread:         price.Price{Price: e.Spread}.String(),
+			NetSpread:      price.Price{Price: e.NetSpread}.String(),
+			Threshold:      price.Price{Price: e.Threshold}.String(),
+			StartedAt:      e.StartedAt.UTC(),
+			TriggeredAt:    e.TriggeredAt.UTC(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+func toDivergenceDtos(divergences []model.Divergence) []divergenceDto {
+	data := make([]divergenceDto, 0, len(divergences))
+	for _, d := range divergences {
+		data = append(data, divergenceDto{
+			Pair:           d.Pair,
+			Source:         d.Source,
+			OkxPrice:       price.Price{Price: d.OkxPrice}.String(),
+			ReferencePrice: price.Price{Price: d.ReferencePrice}.String(),
+			Spread:         price.Price{Price: d.Spread}.String(),
+			NetSpread:      price.Price{Price: d.NetSpread}.String(),
+			ObservedAt:     d.ObservedAt.UTC(),
+		})
+	}
+	return data
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/service/divergence"
+	"cur/internal/store/memory"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource map[string]int64
+
+func (s staticSource) Name() string {
+	return "static"
+}
+
+func (s staticSource) Prices(_ context.Context, _ []string) (map[string]int64, error) {
+	return s, nil
+}
+
+func TestServer_Divergences(t *testing.T) {
+	storage := memory.NewStore()
+	monitor, err := divergence.NewMonitor(storage.Divergence(), []divergence.Source{staticSource{"BTC-USDT": 101_000 * 100_000_000}}, nil,
+		divergence.Config{Threshold: 50_000_000, MaxAge: time.Hour}, log.New())
+	require.NoError(t, err)
+
+	monitor.Trade(model.Trade{Pair: "BTC-USDT", Price: 100_000 * 100_000_000, Timestamp: time.Now()})
+	require.NoError(t, monitor.Poll(context.Background()))
+
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableDivergence(monitor, storage.Divergence())
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	var latest struct {
+		Data []divergenceDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/divergences/latest", &latest))
+	require.Len(t, latest.Data, 1)
+	assert.Equal(t, "1", latest.Data[0].Spread)
+	assert.Equal(t, "101000", latest.Data[0].ReferencePrice)
+
+	var history struct {
+		Data []divergenceDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/divergences?pair=BTC-USDT&source=static", &history))
+	assert.Len(t, history.Data, 1)
+	require.Equal(t, 200, env.get(t, "/v1/divergences?pair=ETH-USDT", &history))
+	assert.Empty(t, history.Data)
+
+	var events struct {
+		Data []divergenceEventDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/divergences/events?pair=BTC-USDT", &events))
+	require.Len(t, events.Data, 1, "no window fires at once")
+	assert.Equal(t, "0.5", events.Data[0].Threshold)
+
+	var body errorBody
+	assert.Equal(t, 400, env.get(t, "/v1/divergences?from=yesterday", &body))
+	assert.Equal(t, 400, env.get(t, "/v1/divergences/events?limit=0", &body))
+}
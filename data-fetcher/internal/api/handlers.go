@@ -0,0 +1,189 @@
+package api
+
+import (
+	"cur/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// handleCandles GET /v1/candles?pair=&bar=&from=&to=&limit=&cursor=
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	pair := q.Get("pair")
+	if pair == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "pair is required")
+		return
+	}
+
+	bar := q.Get("bar")
+	if bar == "" {
+		bar = s.defaultBar
+	}
+
+	from, err := parseTime(q, "from")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	to, err := parseTime(q, "to")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from must be earlier than to")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultCandlesLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	page, err := s.candleRepository.FetchPage(store.CandleQuery{Pair: pair, Bar: bar, From: from, To: to}, q.Get("cursor"), limit)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid cursor")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	candles := make([]candleDto, 0, len(page.Candles))
+	for _, c := range page.Candles {
+		candles = append(candles, toCandleDto(c))
+	}
+	if err := s.attachTradeFlows(candles); err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: candles, Next: page.Next})
+}
+
+// handleCurrencies GET /v1/currencies
+func (s *Server) handleCurrencies(w http.ResponseWriter, r *http.Request) {
+	currencies, err := s.currencyRepository.FetchAll()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]currencyDto, 0, len(currencies))
+	for _, c := range currencies {
+		data = append(data, currencyDto{Code: c.Code, Chain: c.Chain, CanDeposit: c.CanDeposit, CanWithdraw: c.CanWithdraw})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handlePairs GET /v1/pairs, configured pairs first then other pairs having candles
+func (s *Server) handlePairs(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.candleRepository.FetchPairStats()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]pairDto, 0, len(s.pairs))
+	index := make(map[string]int)
+	for _, pair := range s.pairs {
+		index[pair] = len(data)
+		data = append(data, pairDto{Pair: pair, Configured: true, Bars: []barDto{}})
+	}
+
+	for _, stat := range stats {
+		i, ok := index[stat.Pair]
+		if !ok {
+			i = len(data)
+			index[stat.Pair] = i
+			data = append(data, pairDto{Pair: stat.Pair, Bars: []barDto{}})
+		}
+		data[i].Bars = append(data[i].Bars, barDto{Bar: stat.Bar, First: stat.First.UTC(), Last: stat.Last.UTC(), Count: stat.Count})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleLatestTrades GET /v1/trades/latest?pair=&limit=, without pair returns the latest trade of each pair
+func (s *Server) handleLatestTrades(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, err := parseLimit(q, DefaultTradesLimit, store.DefaultTradesPerPair)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	data := make([]tradeDto, 0)
+	if pair := q.Get("pair"); pair != "" {
+		for _, t := range s.tradeRepository.Latest(pair, limit) {
+			data = append(data, toTradeDto(t))
+		}
+	} else {
+		for _, pair := range s.tradeRepository.Pairs() {
+			for _, t := range s.tradeRepository.Latest(pair, 1) {
+				data = append(data, toTradeDto(t))
+			}
+		}
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	s.log.Error(err)
+	writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
+}
+
+// parseTime parses RFC3339 time or unix timestamp in milliseconds, zero time if the parameter is empty
+func parseTime(q url.Values, name string) (time.Time, error) {
+	value := q.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be RFC3339 time or unix milliseconds", name)
+	}
+
+	return t, nil
+}
+
+// decodeBody decodes a json body rejecting unknown fields, writes the error response on failure
+func decodeBody(w http.ResponseWriter, r *http.Request, target any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid json body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func parseLimit(q url.Values, defaultLimit, maxLimit int) (int, error) {
+	value := q.Get("limit")
+	if value == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+
+	return limit, nil
+}
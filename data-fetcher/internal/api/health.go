@@ -0,0 +1,60 @@
+package api
+
+import (
+	"cur/internal/health"
+	"net/http"
+	"time"
+)
+
+const (
+	StatusOk       = "ok"
+	StatusFailing  = "failing"
+	StatusDegraded = "degraded"
+)
+
+type healthDto struct {
+	Status    string           `json:"status"`
+	CheckedAt time.Time        `json:"checkedAt"`
+	Checks    []healthCheckDto `json:"checks"`
+}
+
+type healthCheckDto struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// EnableHealth serves probes with results of every check:
+// /healthz answers 200 while the app serves requests, /readyz answers 503 when a check fails
+func (s *Server) EnableHealth(checker *health.Checker) {
+	s.Handle("GET /healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Run(r.Context())
+		status := StatusOk
+		if !report.Healthy() {
+			status = StatusDegraded
+		}
+		writeJson(w, http.StatusOK, toHealthDto(status, report))
+	}))
+	s.Handle("GET /readyz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Run(r.Context())
+		if !report.Healthy() {
+			writeJson(w, http.StatusServiceUnavailable, toHealthDto(StatusFailing, report))
+			return
+		}
+		writeJson(w, http.StatusOK, toHealthDto(StatusOk, report))
+	}))
+}
+
+func toHealthDto(status string, report health.Report) healthDto {
+	checks := make([]healthCheckDto, 0, len(report.Results))
+	for _, result := range report.Results {
+		check := healthCheckDto{Name: result.Name, Status: StatusOk, DurationMs: result.Duration.Milliseconds()}
+		if result.Err != nil {
+			check.Status = StatusFailing
+			check.Error = result.Err.Error()
+		}
+		checks = append(checks, check)
+	}
+	return healthDto{Status: status, CheckedAt: report.CheckedAt.UTC(), Checks: checks}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"cur/internal/health"
+	"cur/internal/store/memory"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Health(t *testing.T) {
+	storage := memory.NewStore()
+	var kafkaErr error
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Add("kafka", func(ctx context.Context) error { return kafkaErr })
+
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableHealth(checker)
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	var body healthDto
+	require.Equal(t, 200, env.get(t, "/readyz", &body))
+	assert.Equal(t, StatusOk, body.Status)
+	require.Len(t, body.Checks, 2)
+	assert.Equal(t, healthCheckDto{Name: "kafka", Status: StatusOk}, body.Checks[1])
+
+	kafkaErr = errors.New("kafka: client has run out of available brokers")
+	body = healthDto{}
+	require.Equal(t, 503, env.get(t, "/readyz", &body))
+	assert.Equal(t, StatusFailing, body.Status)
+	assert.Equal(t, StatusOk, body.Checks[0].Status)
+	assert.Equal(t, StatusFailing, body.Checks[1].Status)
+	assert.Equal(t, kafkaErr.Error(), body.Checks[1].Error)
+
+	body = healthDto{}
+	require.Equal(t, 200, env.get(t, "/healthz", &body), "the app is alive")
+	assert.Equal(t, StatusDegraded, body.Status)
+}
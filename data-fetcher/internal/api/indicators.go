@@ -0,0 +1,90 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/indicators"
+	"cur/internal/store"
+	"errors"
+	"net/http"
+)
+
+type candleIndicatorsDto struct {
+	candleDto
+	Indicators map[string]string `json:"indicators"`
+}
+
+// EnableIndicators serves precomputed indicators aligned with candles, defaults are returned when the request doesn't select any
+func (s *Server) EnableIndicators(repository store.IndicatorStore, defaults []string) {
+	s.Handle("GET /v1/indicators", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleIndicators(w, r, repository, defaults)
+	}))
+}
+
+// handleIndicators GET /v1/indicators?pair=&bar=&indicators=sma_20,rsi_14&from=&to=&limit=&cursor=
+func (s *Server) handleIndicators(w http.ResponseWriter, r *http.Request, repository store.IndicatorStore, defaults []string) {
+	q := r.URL.Query()
+
+	pair := q.Get("pair")
+	if pair == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "pair is required")
+		return
+	}
+
+	bar := q.Get("bar")
+	if bar == "" {
+		bar = s.defaultBar
+	}
+
+	names := splitList(q.Get("indicators"))
+	if len(names) == 0 {
+		names = defaults
+	}
+	for _, name := range names {
+		if _, err := indicators.ParseSpec(name); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+			return
+		}
+	}
+
+	from, err := parseTime(q, "from")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	to, err := parseTime(q, "to")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from must be earlier than to")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultCandlesLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	page, err := repository.FetchIndicatorPage(store.CandleQuery{Pair: pair, Bar: bar, From: from, To: to}, names, q.Get("cursor"), limit)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid cursor")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]candleIndicatorsDto, 0, len(page.Rows))
+	for _, row := range page.Rows {
+		values := make(map[string]string, len(row.Values))
+		for key, value := range row.Values {
+			values[key] = price.Price{Price: value}.String()
+		}
+		data = append(data, candleIndicatorsDto{candleDto: toCandleDto(row.Candle), Indicators: values})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data, Next: page.Next})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type indicatorsBody struct {
+	Data []candleIndicatorsDto `json:"data"`
+	Next string                `json:"next"`
+}
+
+func TestServer_Indicators(t *testing.T) {
+	storage := memory.NewStore()
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableIndicators(storage.Indicator(), []string{"sma_2"})
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.Candle().InsertCandles(&[]model.Candle{
+		storetest.Candle("BTC-USDT", "1H", start, 1),
+		storetest.Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+		storetest.Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 3),
+	}))
+	require.NoError(t, storage.Indicator().InsertIndicatorValues([]model.IndicatorValue{
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Hour), Indicator: "sma_2", Component: "value", Value: 150_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(2 * time.Hour), Indicator: "sma_2", Component: "value", Value: 250_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(2 * time.Hour), Indicator: "macd_1_2_1", Component: "signal", Value: 5_000_000},
+	}))
+
+	var body indicatorsBody
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/indicators?pair=BTC-USDT&limit=2", &body))
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, start, body.Data[0].Timestamp)
+	assert.Equal(t, "0.000001", body.Data[0].Open)
+	assert.Empty(t, body.Data[0].Indicators)
+	assert.Equal(t, map[string]string{"sma_2": "1.5"}, body.Data[1].Indicators)
+	require.NotEmpty(t, body.Next)
+
+	next := body.Next
+	body = indicatorsBody{}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/indicators?pair=BTC-USDT&indicators=sma_2,macd_1_2_1&cursor="+next, &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, map[string]string{"sma_2": "2.5", "macd_1_2_1.signal": "0.05"}, body.Data[0].Indicators)
+	assert.Empty(t, body.Next)
+
+	for _, path := range []string{
+		"/v1/indicators",
+		"/v1/indicators?pair=BTC-USDT&indicators=sma",
+		"/v1/indicators?pair=BTC-USDT&indicators=unknown_5",
+		"/v1/indicators?pair=BTC-USDT&cursor=broken",
+	} {
+		var body errorBody
+		assert.Equal(t, http.StatusBadRequest, env.get(t, path, &body), path)
+		assert.Equal(t, CodeInvalidParameter, body.Error.Code, path)
+	}
+}
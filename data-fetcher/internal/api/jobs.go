@@ -0,0 +1,168 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/scheduler"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const DefaultJobRunsLimit = 50
+
+type jobDto struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	CatchUp  bool       `json:"catchUp"`
+	Paused   bool       `json:"paused"`
+	Running  bool       `json:"running"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+	LastRun  *jobRunDto `json:"lastRun,omitempty"`
+}
+
+type jobRunDto struct {
+	Id         int64      `json:"id"`
+	Job        string     `json:"job"`
+	Trigger    string     `json:"trigger"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Items      int        `json:"items"`
+}
+
+// EnableJobs serves scheduled jobs with their run history, jobs can be triggered, paused and resumed
+func (s *Server) EnableJobs(jobs *scheduler.Scheduler) {
+	s.Handle("GET /v1/jobs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleJobs(w, jobs)
+	}))
+	s.Handle("GET /v1/jobs/{name}/runs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleJobRuns(w, r, jobs)
+	}))
+	s.Handle("POST /v1/jobs/{name}/trigger", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleTriggerJob(w, r, jobs)
+	}))
+	s.Handle("POST /v1/jobs/{name}/pause", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePauseJob(w, r, jobs.Pause, jobs)
+	}))
+	s.Handle("POST /v1/jobs/{name}/resume", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePauseJob(w, r, jobs.Resume, jobs)
+	}))
+}
+
+// handleJobs GET /v1/jobs
+func (s *Server) handleJobs(w http.ResponseWriter, jobs *scheduler.Scheduler) {
+	states, err := jobs.Jobs()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]jobDto, 0, len(states))
+	for _, state := range states {
+		data = append(data, toJobDto(state))
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleJobRuns GET /v1/jobs/{name}/runs?limit=, newest first
+func (s *Server) handleJobRuns(w http.ResponseWriter, r *http.Request, jobs *scheduler.Scheduler) {
+	limit, err := parseLimit(r.URL.Query(), DefaultJobRunsLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	runs, err := jobs.Runs(r.PathValue("name"), limit)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]jobRunDto, 0, len(runs))
+	for _, run := range runs {
+		data = append(data, toJobRunDto(run))
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handleTriggerJob POST /v1/jobs/{name}/trigger, the run is accepted and goes on in background
+func (s *Server) handleTriggerJob(w http.ResponseWriter, r *http.Request, jobs *scheduler.Scheduler) {
+	run, err := jobs.Trigger(r.PathValue("name"))
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+	case errors.Is(err, scheduler.ErrJobRunning):
+		writeError(w, http.StatusConflict, CodeConflict, "job is already running")
+	case errors.Is(err, scheduler.ErrInactive):
+		writeError(w, http.StatusConflict, CodeConflict, "jobs don't run in this instance, it isn't the leader or is starting")
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		writeJson(w, http.StatusAccepted, toJobRunDto(run))
+	}
+}
+
+// handlePauseJob POST /v1/jobs/{name}/pause and /resume
+func (s *Server) handlePauseJob(w http.ResponseWriter, r *http.Request, set func(name string) error, jobs *scheduler.Scheduler) {
+	name := r.PathValue("name")
+	err := set(name)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	state, err := jobs.JobState(name)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toJobDto(state))
+}
+
+func toJobDto(state scheduler.JobState) jobDto {
+	dto := jobDto{
+		Name:     state.Name,
+		Schedule: state.Schedule,
+		CatchUp:  state.CatchUp,
+		Paused:   state.Paused,
+		Running:  state.Running,
+	}
+	if !state.Next.IsZero() {
+		next := state.Next.UTC()
+		dto.NextRun = &next
+	}
+	if state.LastRun.Id != 0 {
+		last := toJobRunDto(state.LastRun)
+		dto.LastRun = &last
+	}
+	return dto
+}
+
+func toJobRunDto(run model.JobRun) jobRunDto {
+	dto := jobRunDto{
+		Id:        run.Id,
+		Job:       run.Job,
+		Trigger:   string(run.Trigger),
+		Status:    string(run.Status),
+		StartedAt: run.StartedAt.UTC(),
+		Error:     run.Error,
+		Items:     run.Items,
+	}
+	if !run.FinishedAt.IsZero() {
+		finishedAt := run.FinishedAt.UTC()
+		dto.FinishedAt = &finishedAt
+	}
+	return dto
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"cur/internal/scheduler"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Jobs(t *testing.T) {
+	storage := memory.NewStore()
+	trades := store.NewTradeRepository(10)
+	release := make(chan struct{})
+	jobs, err := scheduler.NewScheduler(storage.Job(), []scheduler.Job{
+		{Name: "update_candles", Schedule: "0 * * * *", CatchUp: true, Run: func(ctx context.Context) (int, error) {
+			<-release
+			return 5, nil
+		}},
+	}, log.New())
+	require.NoError(t, err)
+
+	api := NewServer(storage.Currency(), storage.Candle(), trades, nil, "1H", log.New())
+	api.EnableJobs(jobs)
+	env := &testEnv{storage: storage, trades: trades, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	assert.Equal(t, 409, env.send(t, "POST", "/v1/jobs/update_candles/trigger", "").StatusCode, "jobs aren't running")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		jobs.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	var triggered jobRunDto
+	require.Eventually(t, func() bool {
+		resp := env.send(t, "POST", "/v1/jobs/update_candles/trigger", "")
+		defer resp.Body.Close()
+		return resp.StatusCode == 202 && json.NewDecoder(resp.Body).Decode(&triggered) == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "manual", triggered.Trigger)
+	assert.Equal(t, "running", triggered.Status)
+	assert.Nil(t, triggered.FinishedAt)
+
+	assert.Equal(t, 409, env.send(t, "POST", "/v1/jobs/update_candles/trigger", "").StatusCode, "already running")
+	assert.Equal(t, 404, env.send(t, "POST", "/v1/jobs/unknown/trigger", "").StatusCode)
+
+	var list struct {
+		Data []jobDto `json:"data"`
+	}
+	require.Equal(t, 200, env.get(t, "/v1/jobs", &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "0 * * * *", list.Data[0].Schedule)
+	assert.True(t, list.Data[0].Running)
+	assert.NotNil(t, list.Data[0].NextRun)
+	require.NotNil(t, list.Data[0].LastRun)
+	assert.Equal(t, triggered.Id, list.Data[0].LastRun.Id)
+
+	close(release)
+	var runs struct {
+		Data []jobRunDto `json:"data"`
+	}
+	require.Eventually(t, func() bool {
+		return env.get(t, "/v1/jobs/update_candles/runs?limit=10", &runs) == 200 && len(runs.Data) == 1 && runs.Data[0].Status == "succeeded"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 5, runs.Data[0].Items)
+	assert.NotNil(t, runs.Data[0].FinishedAt)
+
+	var notFound errorBody
+	assert.Equal(t, 404, env.get(t, "/v1/jobs/unknown/runs", &notFound))
+	var invalid errorBody
+	assert.Equal(t, 400, env.get(t, "/v1/jobs/update_candles/runs?limit=x", &invalid))
+
+	resp := env.send(t, "POST", "/v1/jobs/update_candles/pause", "")
+	require.Equal(t, 200, resp.StatusCode)
+	var paused jobDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&paused))
+	assert.True(t, paused.Paused)
+
+	resp = env.send(t, "POST", "/v1/jobs/update_candles/resume", "")
+	require.Equal(t, 200, resp.StatusCode)
+	var resumed jobDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&resumed))
+	assert.False(t, resumed.Paused)
+
+	assert.Equal(t, 404, env.send(t, "POST", "/v1/jobs/unknown/pause", "").StatusCode)
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/service/paper"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const DefaultPaperLimit = 100
+
+type paperOrderDto struct {
+	Id         int64     `json:"id"`
+	Pair       string    `json:"pair"`
+	Side       string    `json:"side"`
+	Type       string    `json:"type"`
+	Size       string    `json:"size"`
+	LimitPrice string    `json:"limitPrice,omitempty"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// paperOrderRequest body of a new order, decimals are strings
+type paperOrderRequest struct {
+	Pair       string `json:"pair"`
+	Side       string `json:"side"`
+	Type       string `json:"type"`
+	Size       string `json:"size"`
+	LimitPrice string `json:"limitPrice"`
+}
+
+type paperFillDto struct {
+	Id       int64     `json:"id"`
+	OrderId  int64     `json:"orderId"`
+	Pair     string    `json:"pair"`
+	Side     string    `json:"side"`
+	Price    string    `json:"price"`
+	Size     string    `json:"size"`
+	Fee      string    `json:"fee"`
+	TradeId  string    `json:"tradeId"`
+	FilledAt time.Time `json:"filledAt"`
+}
+
+type paperBalanceDto struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+type paperPositionDto struct {
+	Pair        string    `json:"pair"`
+	Size        string    `json:"size"`
+	AvgPrice    string    `json:"avgPrice"`
+	RealizedPnl string    `json:"realizedPnl"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// EnablePaper serves paper trading, orders are placed and cancelled through the engine
+func (s *Server) EnablePaper(engine *paper.Engine, repository store.PaperStore) {
+	s.Handle("GET /v1/paper/orders", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperOrders(w, r, repository)
+	}))
+	s.Handle("POST /v1/paper/orders", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePlacePaperOrder(w, r, engine)
+	}))
+	s.Handle("GET /v1/paper/orders/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperOrder(w, r, repository)
+	}))
+	s.Handle("DELETE /v1/paper/orders/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleCancelPaperOrder(w, r, engine)
+	}))
+	s.Handle("GET /v1/paper/fills", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperFills(w, r, repository)
+	}))
+	s.Handle("GET /v1/paper/balances", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperBalances(w, r, repository)
+	}))
+	s.Handle("POST /v1/paper/balances", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperDeposit(w, r, engine)
+	}))
+	s.Handle("GET /v1/paper/positions", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handlePaperPositions(w, r, repository)
+	}))
+}
+
+// handlePaperOrders GET /v1/paper/orders?status=&limit=, newest first
+func (s *Server) handlePaperOrders(w http.ResponseWriter, r *http.Request, repository store.PaperStore) {
+	q := r.URL.Query()
+	status := model.OrderStatus(q.Get("status"))
+	switch status {
+	case "", model.OrderOpen, model.OrderFilled, model.OrderCancelled, model.OrderRejected:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "status must be open, filled, cancelled or rejected")
+		return
+	}
+
+	limit, err := parseLimit(q, DefaultPaperLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	orders, err := repository.FetchPaperOrders(status, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]paperOrderDto, 0, len(orders))
+	for _, order := range orders {
+		data = append(data, toPaperOrderDto(order))
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handlePaperOrder GET /v1/paper/orders/{id}
+func (s *Server) handlePaperOrder(w http.ResponseWriter, r *http.Request, repository store.PaperStore) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid order id")
+		return
+	}
+
+	order, err := repository.FetchPaperOrder(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "order not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toPaperOrderDto(order))
+}
+
+// handlePlacePaperOrder POST /v1/paper/orders
+func (s *Server) handlePlacePaperOrder(w http.ResponseWriter, r *http.Request, engine *paper.Engine) {
+	var req paperOrderRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	order, err := req.toOrder()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	order, err = engine.PlaceOrder(order)
+	if errors.Is(err, paper.ErrInvalidOrder) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusCreated, toPaperOrderDto(order))
+}
+
+// handleCancelPaperOrder DELETE /v1/paper/orders/{id}
+func (s *Server) handleCancelPaperOrder(w http.ResponseWriter, r *http.Request, engine *paper.Engine) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid order id")
+		return
+	}
+
+	err = engine.CancelOrder(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "open order not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePaperFills GET /v1/paper/fills?limit=, newest first
+func (s *Server) handlePaperFills(w http.ResponseWriter, r *http.Request, repository store.PaperStore) {
+	limit, err := parseLimit(r.URL.Query(), DefaultPaperLimit, MaxCandlesLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	fills, err := repository.FetchPaperFills(limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]paperFillDto, 0, len(fills))
+	for _, f := range fills {
+		data = append(data, paperFillDto{
+			Id:       f.Id,
+			OrderId:  f.OrderId,
+			Pair:     f.Pair,
+			Side:     string(f.Side),
+			Price:    price.Price{Price: f.Price}.String(),
+			Size:     price.Price{Price: f.Size}.String(),
+			Fee:      price.Price{Price: f.Fee}.String(),
+			TradeId:  f.TradeId,
+			FilledAt: f.FilledAt.UTC(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handlePaperBalances GET /v1/paper/balances
+func (s *Server) handlePaperBalances(w http.ResponseWriter, r *http.Request, repository store.PaperStore) {
+	balances, err := repository.FetchPaperBalances()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]paperBalanceDto, 0, len(balances))
+	for _, b := range balances {
+		data = append(data, paperBalanceDto{Currency: b.Currency, Amount: price.Price{Price: b.Amount}.String()})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+// handlePaperDeposit POST /v1/paper/balances adds {"currency", "amount"} to the balance
+func (s *Server) handlePaperDeposit(w http.ResponseWriter, r *http.Request, engine *paper.Engine) {
+	var req paperBalanceDto
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	amount, err := price.ParsePrice(req.Amount)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "amount must be a decimal number")
+		return
+	}
+
+	balance, err := engine.Deposit(req.Currency, amount)
+	if errors.Is(err, paper.ErrInvalidAmount) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, paperBalanceDto{Currency: balance.Currency, Amount: price.Price{Price: balance.Amount}.String()})
+}
+
+// handlePaperPositions GET /v1/paper/positions
+func (s *Server) handlePaperPositions(w http.ResponseWriter, r *http.Request, repository store.PaperStore) {
+	positions, err := repository.FetchPaperPositions()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]paperPositionDto, 0, len(positions))
+	for _, p := range positions {
+		data = append(data, paperPositionDto{
+			Pair:        p.Pair,
+			Size:        price.Price{Price: p.Size}.String(),
+			AvgPrice:    price.Price{Price: p.AvgPrice}.String(),
+			RealizedPnl: price.Price{Price: p.RealizedPnl}.String(),
+			UpdatedAt:   p.UpdatedAt.UTC(),
+		})
+	}
+
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+func (req paperOrderRequest) toOrder() (model.PaperOrder, error) {
+	order := model.PaperOrder{
+		Pair: req.Pair,
+		Side: model.OrderSide(req.Side),
+		Type: model.OrderType(req.Type),
+	}
+	if order.Type == "" {
+		order.Type = model.OrderMarket
+	}
+
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *int64
+	}{
+		{"size", req.Size, &order.Size},
+		{"limitPrice", req.LimitPrice, &order.LimitPrice},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value, err := price.ParsePrice(field.value)
+		if err != nil {
+			return order, fmt.Errorf("%s must be a decimal number", field.name)
+		}
+		*field.target = value
+	}
+
+	return order, nil
+}
+
+func toPaperOrderDto(order model.PaperOrder) paperOrderDto {
+	dto := paperOrderDto{
+		Id:        order.Id,
+		Pair:      order.Pair,
+		Side:      string(order.Side),
+		Type:      string(order.Type),
+		Size:      price.Price{Price: order.Size}.String(),
+		Status:    string(order.Status),
+		Reason:    order.Reason,
+		CreatedAt: order.CreatedAt.UTC(),
+		UpdatedAt: order.UpdatedAt.UTC(),
+	}
+	if order.LimitPrice != 0 {
+		dto.LimitPrice = price.Price{Price: order.LimitPrice}.String()
+	}
+	return dto
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/paper"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPaperEnv(t *testing.T) (*testEnv, *paper.Engine) {
+	storage := memory.NewStore()
+	engine, err := paper.NewEngine(storage.Paper(), nil, 0, log.New())
+	require.NoError(t, err)
+	t.Cleanup(engine.Close)
+
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnablePaper(engine, storage.Paper())
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+	return env, engine
+}
+
+func TestServer_PaperTrading(t *testing.T) {
+	env, engine := newPaperEnv(t)
+
+	resp := env.send(t, http.MethodPost, "/v1/paper/balances", `{"currency": "USDT", "amount": "1000"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var balance paperBalanceDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&balance))
+	assert.Equal(t, paperBalanceDto{Currency: "USDT", Amount: "1000"}, balance)
+
+	resp = env.send(t, http.MethodPost, "/v1/paper/orders", `{"pair": "BTC-USDT", "side": "buy", "type": "limit", "size": "0.01", "limitPrice": "90000"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var order paperOrderDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+	assert.Equal(t, "open", order.Status)
+	assert.Equal(t, "0.01", order.Size)
+	assert.Equal(t, "90000", order.LimitPrice)
+
+	resp = env.send(t, http.MethodPost, "/v1/paper/orders", `{"pair": "BTC-USDT", "side": "sell", "size": "0.01"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var market paperOrderDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&market))
+	assert.Equal(t, "market", market.Type)
+
+	engine.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "7", Price: 89_000 * 100_000_000, Timestamp: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)})
+	engine.Close()
+
+	var orders struct {
+		Data []paperOrderDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/paper/orders?status=filled", &orders))
+	require.Len(t, orders.Data, 2)
+	assert.Equal(t, market.Id, orders.Data[0].Id, "newest first")
+
+	var fills struct {
+		Data []paperFillDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/paper/fills", &fills))
+	require.Len(t, fills.Data, 2)
+	assert.Equal(t, "90000", fills.Data[1].Price)
+	assert.Equal(t, "89000", fills.Data[0].Price)
+	assert.Equal(t, "7", fills.Data[0].TradeId)
+
+	var balances struct {
+		Data []paperBalanceDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/paper/balances", &balances))
+	assert.Equal(t, []paperBalanceDto{{Currency: "BTC", Amount: "0"}, {Currency: "USDT", Amount: "990"}}, balances.Data)
+
+	var positions struct {
+		Data []paperPositionDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/paper/positions", &positions))
+	require.Len(t, positions.Data, 1)
+	assert.Equal(t, "-10", positions.Data[0].RealizedPnl)
+	assert.Equal(t, "0", positions.Data[0].Size)
+}
+
+func TestServer_PaperOrderCancel(t *testing.T) {
+	env, _ := newPaperEnv(t)
+
+	resp := env.send(t, http.MethodPost, "/v1/paper/orders", `{"pair": "ETH-USDT", "side": "buy", "type": "limit", "size": "1", "limitPrice": "2000"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var order paperOrderDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&order))
+
+	path := "/v1/paper/orders/" + strconv.FormatInt(order.Id, 10)
+	assert.Equal(t, http.StatusNoContent, env.send(t, http.MethodDelete, path, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, env.send(t, http.MethodDelete, path, "").StatusCode)
+
+	var cancelled paperOrderDto
+	require.Equal(t, http.StatusOK, env.get(t, path, &cancelled))
+	assert.Equal(t, "cancelled", cancelled.Status)
+
+	var body errorBody
+	assert.Equal(t, http.StatusNotFound, env.get(t, "/v1/paper/orders/42", &body))
+}
+
+func TestServer_PaperValidation(t *testing.T) {
+	env, _ := newPaperEnv(t)
+
+	for _, body := range []string{
+		`{"pair": "BTC-USDT", "side": "buy", "type": "limit", "size": "1"}`,
+		`{"pair": "BTC-USDT", "side": "hold", "size": "1"}`,
+		`{"pair": "BTC-USDT", "side": "buy", "size": "one"}`,
+		`{"pair": "BTC-USDT", "side": "buy", "size": "1", "leverage": 10}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, env.send(t, http.MethodPost, "/v1/paper/orders", body).StatusCode, body)
+	}
+	assert.Equal(t, http.StatusBadRequest, env.send(t, http.MethodPost, "/v1/paper/balances", `{"currency": "USDT", "amount": "-1"}`).StatusCode)
+
+	var body errorBody
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/paper/orders?status=done", &body))
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/service/portfolio"
+	"cur/internal/store"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxCsvSize limits the body of a csv import
+const maxCsvSize = 1 << 20
+
+type holdingDto struct {
+	Id         int64     `json:"id"`
+	Currency   string    `json:"currency"`
+	Amount     string    `json:"amount"`
+	Price      string    `json:"price,omitempty"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// holdingRequest body of a new holding, decimals are strings and the time defaults to now
+type holdingRequest struct {
+	Currency   string    `json:"currency"`
+	Amount     string    `json:"amount"`
+	Price      string    `json:"price"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	Note       string    `json:"note"`
+}
+
+type assetDto struct {
+	Currency string   `json:"currency"`
+	Amount   string   `json:"amount"`
+	Rate     string   `json:"rate"`
+	Value    string   `json:"value"`
+	Cost     string   `json:"cost"`
+	Pnl      string   `json:"pnl"`
+	Path     []string `json:"path"`
+}
+
+type valuationDto struct {
+	Timestamp    time.Time  `json:"timestamp"`
+	BaseCurrency string     `json:"baseCurrency"`
+	Value        string     `json:"value"`
+	Cost         string     `json:"cost"`
+	Pnl          string     `json:"pnl"`
+	Assets       []assetDto `json:"assets,omitempty"`
+	Unpriced     []string   `json:"unpriced,omitempty"`
+}
+
+// EnablePortfolio serves holdings and their valuation in the base currency
+func (s *Server) EnablePortfolio(p *portfolio.Portfolio, repository store.PortfolioStore) {
+	s.Handle("GET /v1/portfolio/holdings", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleHoldings(w, r, repository)
+	}))
+	s.Handle("POST /v1/portfolio/holdings", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleAddHolding(w, r, p)
+	}))
+	s.Handle("POST /v1/portfolio/holdings/import", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleImportHoldings(w, r, p)
+	}))
+	s.Handle("DELETE /v1/portfolio/holdings/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleDeleteHolding(w, r, repository)
+	}))
+	s.Handle("GET /v1/portfolio/valuation", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleValuation(w, r, p)
+	}))
+	s.Handle("GET /v1/portfolio/history", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleValuationHistory(w, r, p)
+	}))
+}
+
+// handleHoldings GET /v1/portfolio/holdings ordered by acquisition time
+func (s *Server) handleHoldings(w http.ResponseWriter, r *http.Request, repository store.PortfolioStore) {
+	holdings, err := repository.FetchHoldings()
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+	writeJson(w, http.StatusOK, listBody{Data: toHoldingDtos(holdings)})
+}
+
+// handleAddHolding POST /v1/portfolio/holdings
+func (s *Server) handleAddHolding(w http.ResponseWriter, r *http.Request, p *portfolio.Portfolio) {
+	var req holdingRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	holding := model.Holding{Currency: req.Currency, AcquiredAt: req.AcquiredAt, Note: req.Note}
+	var err error
+	if holding.Amount, err = price.ParsePrice(req.Amount); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "amount must be a decimal number")
+		return
+	}
+	if req.Price != "" {
+		if holding.Price, err = price.ParsePrice(req.Price); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "price must be a decimal number")
+			return
+		}
+	}
+
+	if inserted, ok := s.addHoldings(w, p, []model.Holding{holding}); ok {
+		writeJson(w, http.StatusCreated, toHoldingDtos(inserted)[0])
+	}
+}
+
+// handleImportHoldings POST /v1/portfolio/holdings/import with a csv body, see portfolio.ParseCsv
+func (s *Server) handleImportHoldings(w http.ResponseWriter, r *http.Request, p *portfolio.Portfolio) {
+	holdings, err := portfolio.ParseCsv(http.MaxBytesReader(w, r.Body, maxCsvSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	if inserted, ok := s.addHoldings(w, p, holdings); ok {
+		writeJson(w, http.StatusCreated, listBody{Data: toHoldingDtos(inserted)})
+	}
+}
+
+// addHoldings stores the holdings, writes the error response on failure
+func (s *Server) addHoldings(w http.ResponseWriter, p *portfolio.Portfolio, holdings []model.Holding) ([]model.Holding, bool) {
+	inserted, err := p.Add(holdings)
+	if errors.Is(err, portfolio.ErrInvalidHolding) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return nil, false
+	}
+	return inserted, true
+}
+
+// handleDeleteHolding DELETE /v1/portfolio/holdings/{id}
+func (s *Server) handleDeleteHolding(w http.ResponseWriter, r *http.Request, repository store.PortfolioStore) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid holding id")
+		return
+	}
+
+	err = repository.DeleteHolding(id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "holding not found")
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleValuation GET /v1/portfolio/valuation?at=, now by default
+func (s *Server) handleValuation(w http.ResponseWriter, r *http.Request, p *portfolio.Portfolio) {
+	at, err := parseTime(r.URL.Query(), "at")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	valuation, err := p.Value(at)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toValuationDto(valuation, p.BaseCurrency(), true))
+}
+
+// handleValuationHistory GET /v1/portfolio/history?from=&to=&step=, step is a bar like 1H or 1D (the default bar by default)
+func (s *Server) handleValuationHistory(w http.ResponseWriter, r *http.Request, p *portfolio.Portfolio) {
+	q := r.URL.Query()
+	from, err := parseTime(q, "from")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	to, err := parseTime(q, "to")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if from.IsZero() {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "from is required")
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+
+	step := q.Get("step")
+	if step == "" {
+		step = s.defaultBar
+	}
+	duration, err := model.BarDuration(step)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	history, err := p.History(from, to, duration)
+	if errors.Is(err, portfolio.ErrInvalidRange) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	data := make([]valuationDto, 0, len(history))
+	for _, valuation := range history {
+		data = append(data, toValuationDto(valuation, p.BaseCurrency(), false))
+	}
+	writeJson(w, http.StatusOK, listBody{Data: data})
+}
+
+func toHoldingDtos(holdings []model.Holding) []holdingDto {
+	data := make([]holdingDto, 0, len(holdings))
+	for _, h := range holdings {
+		dto := holdingDto{
+			Id:         h.Id,
+			Currency:   h.Currency,
+			Amount:     price.Price{Price: h.Amount}.String(),
+			AcquiredAt: h.AcquiredAt.UTC(),
+			Note:       h.Note,
+			CreatedAt:  h.CreatedAt.UTC(),
+		}
+		if h.Price != 0 {
+			dto.Price = price.Price{Price: h.Price}.String()
+		}
+		data = append(data, dto)
+	}
+	return data
+}
+
+// toValuationDto converts the valuation, assets are omitted from history points
+func toValuationDto(valuation portfolio.Valuation, base string, withAssets bool) valuationDto {
+	dto := valuationDto{
+		Timestamp:    valuation.Timestamp.UTC(),
+		BaseCurrency: base,
+		Value:        price.Price{Price: valuation.Value}.String(),
+		Cost:         price.Price{Price: valuation.Cost}.String(),
+		Pnl:          price.Price{Price: valuation.Pnl}.String(),
+		Unpriced:     valuation.Unpriced,
+	}
+	if !withAssets {
+		return dto
+	}
+
+	for _, a := range valuation.Assets {
+		path := a.Path
+		if path == nil {
+			path = []string{}
+		}
+		dto.Assets = append(dto.Assets, assetDto{
+			Currency: a.Currency,
+			Amount:   price.Price{Price: a.Amount}.String(),
+			Rate:     price.Price{Price: a.Rate}.String(),
+			Value:    price.Price{Price: a.Value}.String(),
+			Cost:     price.Price{Price: a.Cost}.String(),
+			Pnl:      price.Price{Price: a.Pnl}.String(),
+			Path:     path,
+		})
+	}
+	return dto
+}
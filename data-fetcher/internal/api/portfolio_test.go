@@ -0,0 +1,107 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/portfolio"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPortfolioEnv(t *testing.T) *testEnv {
+	storage := memory.NewStore()
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []model.Candle{
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Close: 50_000 * 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Hour), Close: 60_000 * 100_000_000},
+		{Pair: "TON-BTC", Bar: "1H", Timestamp: start, Close: 10_000},
+	}
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnablePortfolio(portfolio.New(storage.Portfolio(), store.NewCrossRateRepository(storage.Candle(), store.NewTickerRepository()), "USDT", "1H"), storage.Portfolio())
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+	return env
+}
+
+func TestServer_PortfolioHoldings(t *testing.T) {
+	env := newPortfolioEnv(t)
+
+	resp := env.send(t, http.MethodPost, "/v1/portfolio/holdings", `{"currency": "BTC", "amount": "0.5", "price": "40000", "acquiredAt": "2025-02-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var holding holdingDto
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&holding))
+	assert.Equal(t, "0.5", holding.Amount)
+	assert.Equal(t, "40000", holding.Price)
+
+	resp = env.send(t, http.MethodPost, "/v1/portfolio/holdings/import", "currency,amount,acquired_at,note\nTON,100,2025-02-01,airdrop\n")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var imported struct {
+		Data []holdingDto `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&imported))
+	require.Len(t, imported.Data, 1)
+	assert.Equal(t, "airdrop", imported.Data[0].Note)
+
+	var holdings struct {
+		Data []holdingDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/portfolio/holdings", &holdings))
+	require.Len(t, holdings.Data, 2)
+
+	path := "/v1/portfolio/holdings/" + strconv.FormatInt(imported.Data[0].Id, 10)
+	assert.Equal(t, http.StatusNoContent, env.send(t, http.MethodDelete, path, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, env.send(t, http.MethodDelete, path, "").StatusCode)
+
+	for _, body := range []string{
+		`{"currency": "BTC-USDT", "amount": "1"}`,
+		`{"currency": "BTC", "amount": "0"}`,
+		`{"currency": "BTC", "amount": "one"}`,
+		`{"currency": "BTC", "amount": "1", "price": "-1"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, env.send(t, http.MethodPost, "/v1/portfolio/holdings", body).StatusCode, body)
+	}
+	assert.Equal(t, http.StatusBadRequest, env.send(t, http.MethodPost, "/v1/portfolio/holdings/import", "currency\nBTC\n").StatusCode)
+}
+
+func TestServer_PortfolioValuation(t *testing.T) {
+	env := newPortfolioEnv(t)
+	resp := env.send(t, http.MethodPost, "/v1/portfolio/holdings/import",
+		"currency,amount,price,acquired_at\nBTC,1,40000,2025-02-01\nTON,1000,,2025-02-01\n")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var valuation valuationDto
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/portfolio/valuation?at=2025-02-01T01:00:00Z", &valuation))
+	assert.Equal(t, "USDT", valuation.BaseCurrency)
+	assert.Equal(t, "66000", valuation.Value)
+	assert.Equal(t, "45000", valuation.Cost)
+	assert.Equal(t, "21000", valuation.Pnl)
+	require.Len(t, valuation.Assets, 2)
+	assert.Equal(t, []string{"TON-BTC", "BTC-USDT"}, valuation.Assets[1].Path)
+	assert.Equal(t, "6", valuation.Assets[1].Rate)
+
+	var history struct {
+		Data []valuationDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/portfolio/history?from=2025-01-31T23:00:00Z&to=2025-02-01T01:00:00Z", &history))
+	require.Len(t, history.Data, 3)
+	assert.Equal(t, "0", history.Data[0].Value)
+	assert.Equal(t, "55000", history.Data[1].Value)
+	assert.Empty(t, history.Data[1].Assets)
+	assert.Equal(t, "66000", history.Data[2].Value)
+
+	var body errorBody
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/portfolio/history", &body))
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/portfolio/history?from=2025-02-01T00:00:00Z&step=week", &body))
+	assert.Equal(t, http.StatusBadRequest, env.get(t, "/v1/portfolio/history?from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z&step=1m", &body))
+}
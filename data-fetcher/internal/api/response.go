@@ -0,0 +1,106 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeInternal         = "internal_error"
+	CodeNotLeader        = "not_leader"
+)
+
+type errorBody struct {
+	Error errorDetails `json:"error"`
+}
+
+type errorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type listBody struct {
+	Data any    `json:"data"`
+	Next string `json:"next,omitempty"`
+}
+
+type candleDto struct {
+	Pair      string        `json:"pair"`
+	Bar       string        `json:"bar"`
+	Timestamp time.Time     `json:"timestamp"`
+	Open      string        `json:"open"`
+	High      string        `json:"high"`
+	Low       string        `json:"low"`
+	Close     string        `json:"close"`
+	Volume    string        `json:"volume"`
+	Flow      *tradeFlowDto `json:"flow,omitempty"`
+}
+
+type currencyDto struct {
+	Code        string `json:"code"`
+	Chain       string `json:"chain"`
+	CanDeposit  bool   `json:"canDeposit"`
+	CanWithdraw bool   `json:"canWithdraw"`
+}
+
+type pairDto struct {
+	Pair       string   `json:"pair"`
+	Configured bool     `json:"configured"`
+	Bars       []barDto `json:"bars"`
+}
+
+type barDto struct {
+	Bar   string    `json:"bar"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+	Count int64     `json:"count"`
+}
+
+type tradeDto struct {
+	Pair      string    `json:"pair"`
+	TradeId   string    `json:"tradeId"`
+	Price     string    `json:"price"`
+	Size      string    `json:"size"`
+	Side      string    `json:"side"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func toCandleDto(c model.Candle) candleDto {
+	return candleDto{
+		Pair:      c.Pair,
+		Bar:       c.Bar,
+		Timestamp: c.Timestamp.UTC(),
+		Open:      price.Price{Price: c.Open}.String(),
+		High:      price.Price{Price: c.High}.String(),
+		Low:       price.Price{Price: c.Low}.String(),
+		Close:     price.Price{Price: c.Close}.String(),
+		Volume:    price.Price{Price: c.Volume}.String(),
+	}
+}
+
+func toTradeDto(t model.Trade) tradeDto {
+	return tradeDto{
+		Pair:      t.Pair,
+		TradeId:   t.TradeId,
+		Price:     price.Price{Price: t.Price}.String(),
+		Size:      price.Price{Price: t.Size}.String(),
+		Side:      t.Side,
+		Timestamp: t.Timestamp.UTC(),
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJson(w, status, errorBody{Error: errorDetails{Code: code, Message: message}})
+}
@@ -0,0 +1,83 @@
+// Package api serves stored market data over HTTP
+package api
+
+import (
+	"cur/internal/infrastructure/metrics"
+	"cur/internal/store"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultCandlesLimit = 500
+	MaxCandlesLimit     = 1000
+	DefaultTradesLimit  = 100
+)
+
+type Server struct {
+	currencyRepository store.CurrencyStore
+	candleRepository   store.CandleStore
+	tradeRepository    store.TradeStore
+	flowRepository     store.TradeFlowStore
+	pairs              []string
+	defaultBar         string
+	leading            func() bool
+	log                *log.Logger
+	mux                *http.ServeMux
+}
+
+// NewServer makes api server, pairs are configured pairs which are listed even without stored candles
+func NewServer(
+	currencyRepository store.CurrencyStore,
+	candleRepository store.CandleStore,
+	tradeRepository store.TradeStore,
+	pairs []string,
+	defaultBar string,
+	log *log.Logger,
+) *Server {
+	s := &Server{
+		currencyRepository: currencyRepository,
+		candleRepository:   candleRepository,
+		tradeRepository:    tradeRepository,
+		pairs:              pairs,
+		defaultBar:         defaultBar,
+		log:                log,
+		mux:                http.NewServeMux(),
+	}
+
+	s.Handle("GET /v1/candles", http.HandlerFunc(s.handleCandles))
+	s.Handle("GET /v1/currencies", http.HandlerFunc(s.handleCurrencies))
+	s.Handle("GET /v1/pairs", http.HandlerFunc(s.handlePairs))
+	s.Handle("GET /v1/trades/latest", s.leaderOnly(http.HandlerFunc(s.handleLatestTrades)))
+	s.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "route not found")
+	}))
+
+	return s
+}
+
+// Handle registers additional handler on the api mux, requests are counted by the pattern
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, metrics.InstrumentRoute(pattern, handler))
+}
+
+// SetLeading makes live trade and ticker endpoints answer 503 while leading returns false,
+// only the leader receives the trade WebSocket
+func (s *Server) SetLeading(leading func() bool) {
+	s.leading = leading
+}
+
+func (s *Server) leaderOnly(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.leading != nil && !s.leading() {
+			writeError(w, http.StatusServiceUnavailable, CodeNotLeader, "live data is served by the leader instance")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/okx/response"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"cur/internal/stream"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	storage *memory.Store
+	trades  *store.TradeRepository
+	server  *httptest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{storage: memory.NewStore(), trades: store.NewTradeRepository(10)}
+	api := NewServer(env.storage.Currency(), env.storage.Candle(), env.trades, []string{"BTC-USDT", "ETH-USDT"}, "1H", log.New())
+	env.server = httptest.NewServer(api.Handler())
+	t.Cleanup(env.server.Close)
+	return env
+}
+
+func (env *testEnv) get(t *testing.T, path string, target any) int {
+	resp, err := http.Get(env.server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(target))
+	return resp.StatusCode
+}
+
+type candlesBody struct {
+	Data []candleDto `json:"data"`
+	Next string      `json:"next"`
+}
+
+func TestServer_Candles(t *testing.T) {
+	env := newTestEnv(t)
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []model.Candle{
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Open: 9733870000000, High: 9789330000000, Low: 9568000000000, Close: 9688870000000, Volume: 370688063172},
+		storetest.Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+		storetest.Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 3),
+		storetest.Candle("BTC-USDT", "1D", start, 4),
+	}
+	require.NoError(t, env.storage.Candle().InsertCandles(&candles))
+
+	var body candlesBody
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/candles?pair=BTC-USDT&limit=2", &body))
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, candleDto{
+		Pair: "BTC-USDT", Bar: "1H", Timestamp: start,
+		Open: "97338.7", High: "97893.3", Low: "95680", Close: "96888.7", Volume: "3706.88063172",
+	}, body.Data[0])
+	require.NotEmpty(t, body.Next)
+
+	next := body.Next
+	body = candlesBody{}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/candles?pair=BTC-USDT&limit=2&cursor="+next, &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, start.Add(2*time.Hour), body.Data[0].Timestamp)
+	assert.Empty(t, body.Next)
+
+	body = candlesBody{}
+	from := strconv.FormatInt(start.Add(time.Hour).UnixMilli(), 10)
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/candles?pair=BTC-USDT&bar=1H&from="+from+"&to="+start.Add(2*time.Hour).Format(time.RFC3339), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "0.000002", body.Data[0].Open)
+
+	body = candlesBody{}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/candles?pair=BTC-USDT&bar=1D", &body))
+	assert.Len(t, body.Data, 1)
+}
+
+func TestServer_CandlesErrors(t *testing.T) {
+	env := newTestEnv(t)
+
+	for _, path := range []string{
+		"/v1/candles",
+		"/v1/candles?pair=BTC-USDT&from=yesterday",
+		"/v1/candles?pair=BTC-USDT&from=2025-02-02T00:00:00Z&to=2025-02-01T00:00:00Z",
+		"/v1/candles?pair=BTC-USDT&limit=0",
+		"/v1/candles?pair=BTC-USDT&limit=100000",
+		"/v1/candles?pair=BTC-USDT&cursor=broken",
+	} {
+		var body errorBody
+		assert.Equal(t, http.StatusBadRequest, env.get(t, path, &body), path)
+		assert.Equal(t, CodeInvalidParameter, body.Error.Code, path)
+		assert.NotEmpty(t, body.Error.Message, path)
+	}
+
+	var body errorBody
+	assert.Equal(t, http.StatusNotFound, env.get(t, "/v1/unknown", &body))
+	assert.Equal(t, CodeNotFound, body.Error.Code)
+}
+
+func TestServer_Currencies(t *testing.T) {
+	env := newTestEnv(t)
+	require.NoError(t, env.storage.Currency().InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+		{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: true, CanWd: false},
+	}))
+
+	var body struct {
+		Data []currencyDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/currencies", &body))
+	assert.Equal(t, []currencyDto{{Code: "BTC", Chain: "BTC-Bitcoin", CanDeposit: true, CanWithdraw: false}}, body.Data)
+}
+
+func TestServer_Pairs(t *testing.T) {
+	env := newTestEnv(t)
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, env.storage.Candle().InsertCandles(&[]model.Candle{
+		storetest.Candle("ETH-USDT", "1H", start, 1),
+		storetest.Candle("ETH-USDT", "1H", start.Add(time.Hour), 1),
+		storetest.Candle("ETH-BTC", "1D", start, 1),
+	}))
+
+	var body struct {
+		Data []pairDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/pairs", &body))
+	assert.Equal(t, []pairDto{
+		{Pair: "BTC-USDT", Configured: true, Bars: []barDto{}},
+		{Pair: "ETH-USDT", Configured: true, Bars: []barDto{{Bar: "1H", First: start, Last: start.Add(time.Hour), Count: 2}}},
+		{Pair: "ETH-BTC", Configured: false, Bars: []barDto{{Bar: "1D", First: start, Last: start, Count: 1}}},
+	}, body.Data)
+}
+
+func TestServer_LatestTrades(t *testing.T) {
+	env := newTestEnv(t)
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	env.trades.Add(model.Trade{Pair: "BTC-USDT", TradeId: "1", Price: 9700000000000, Size: 50_000_000, Side: "buy", Timestamp: ts})
+	env.trades.Add(model.Trade{Pair: "BTC-USDT", TradeId: "2", Price: 9700010000000, Size: 100_000_000, Side: "sell", Timestamp: ts})
+	env.trades.Add(model.Trade{Pair: "ETH-USDT", TradeId: "3", Price: 300000000000, Size: 100_000_000, Side: "sell", Timestamp: ts})
+
+	var body struct {
+		Data []tradeDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/trades/latest?pair=BTC-USDT", &body))
+	assert.Equal(t, []tradeDto{
+		{Pair: "BTC-USDT", TradeId: "2", Price: "97000.1", Size: "1", Side: "sell", Timestamp: ts},
+		{Pair: "BTC-USDT", TradeId: "1", Price: "97000", Size: "0.5", Side: "buy", Timestamp: ts},
+	}, body.Data)
+
+	body.Data = nil
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/trades/latest", &body))
+	require.Len(t, body.Data, 2)
+	assert.Equal(t, "2", body.Data[0].TradeId)
+	assert.Equal(t, "3", body.Data[1].TradeId)
+}
+
+func TestServer_LiveDataOnFollower(t *testing.T) {
+	storage := memory.NewStore()
+	trades := store.NewTradeRepository(10)
+	trades.Add(model.Trade{Pair: "BTC-USDT", TradeId: "1", Price: 9700000000000, Size: 50_000_000, Side: "buy", Timestamp: time.Now()})
+	api := NewServer(storage.Currency(), storage.Candle(), trades, []string{"BTC-USDT"}, "1H", log.New())
+	api.EnableStream(stream.NewHub(stream.DefaultBufferSize, stream.DefaultHistorySize))
+	leading := false
+	api.SetLeading(func() bool { return leading })
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+	env := &testEnv{storage: storage, trades: trades, server: server}
+
+	for _, path := range []string{"/v1/trades/latest", "/v1/stream/ws", "/v1/stream/sse"} {
+		var body errorBody
+		require.Equal(t, http.StatusServiceUnavailable, env.get(t, path, &body), path)
+		assert.Equal(t, CodeNotLeader, body.Error.Code, path)
+	}
+
+	var pairs struct {
+		Data []pairDto `json:"data"`
+	}
+	assert.Equal(t, http.StatusOK, env.get(t, "/v1/pairs", &pairs), "stored data is served everywhere")
+
+	leading = true
+	var body struct {
+		Data []tradeDto `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, env.get(t, "/v1/trades/latest", &body))
+	assert.Len(t, body.Data, 1)
+}
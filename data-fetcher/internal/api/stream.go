@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/stream"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamPingInterval = 15 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	CodeSlowConsumer   = "slow_consumer"
+)
+
+type tickerDto struct {
+	Pair      string    `json:"pair"`
+	Last      string    `json:"last"`
+	Open24h   string    `json:"open24h"`
+	High24h   string    `json:"high24h"`
+	Low24h    string    `json:"low24h"`
+	Volume24h string    `json:"volume24h"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type streamMessage struct {
+	Id      uint64          `json:"id"`
+	Channel string          `json:"channel"`
+	Pair    string          `json:"pair"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// StreamPublisher renders models the same way as the REST api and publishes them to the hub
+type StreamPublisher struct {
+	hub *stream.Hub
+}
+
+func NewStreamPublisher(hub *stream.Hub) *StreamPublisher {
+	return &StreamPublisher{hub: hub}
+}
+
+func (p *StreamPublisher) Trade(trade model.Trade) {
+	_, _ = p.hub.Publish(stream.ChannelTrades, trade.Pair, toTradeDto(trade))
+}
+
+func (p *StreamPublisher) Candles(_ context.Context, candles []model.Candle) {
+	for _, c := range candles {
+		_, _ = p.hub.Publish(stream.ChannelCandles, c.Pair, toCandleDto(c))
+	}
+}
+
+func (p *StreamPublisher) Ticker(ticker model.Ticker) {
+	_, _ = p.hub.Publish(stream.ChannelTickers, ticker.Pair, tickerDto{
+		Pair:      ticker.Pair,
+		Last:      price.Price{Price: ticker.Last}.String(),
+		Open24h:   price.Price{Price: ticker.Open24h}.String(),
+		High24h:   price.Price{Price: ticker.High24h}.String(),
+		Low24h:    price.Price{Price: ticker.Low24h}.String(),
+		Volume24h: price.Price{Price: ticker.Volume24h}.String(),
+		Timestamp: ticker.Timestamp.UTC(),
+	})
+}
+
+// EnableStream serves live events of the hub over websocket and server-sent events
+func (s *Server) EnableStream(hub *stream.Hub) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	s.Handle("GET /v1/stream/ws", s.leaderOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleStreamWs(w, r, hub, upgrader)
+	})))
+	s.Handle("GET /v1/stream/sse", s.leaderOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleStreamSse(w, r, hub)
+	})))
+}
+
+// handleStreamWs GET /v1/stream/ws?channels=trades,candles,tickers&pairs=BTC-USDT,ETH-USDT
+func (s *Server) handleStreamWs(w http.ResponseWriter, r *http.Request, hub *stream.Hub, upgrader websocket.Upgrader) {
+	subscription, err := parseSubscription(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	client := hub.Subscribe(subscription, 0)
+	defer client.Close()
+
+	// the reader detects closed connections, incoming messages are ignored
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	dropped := func() {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, CodeSlowConsumer),
+			time.Now().Add(streamWriteTimeout))
+	}
+	for {
+		select {
+		case event := <-client.Events():
+			if isDone(client) {
+				dropped()
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(streamMessage{Id: event.Id, Channel: event.Channel, Pair: event.Pair, Data: event.Data}); err != nil {
+				return
+			}
+		case <-client.Done():
+			dropped()
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleStreamSse GET /v1/stream/sse?channels=&pairs=, resumes after Last-Event-ID header or lastEventId parameter
+func (s *Server) handleStreamSse(w http.ResponseWriter, r *http.Request, hub *stream.Hub) {
+	q := r.URL.Query()
+	subscription, err := parseSubscription(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = q.Get("lastEventId")
+	}
+	var resumeAfter uint64
+	if lastEventId != "" {
+		resumeAfter, err = strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "invalid last event id")
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "streaming is not supported")
+		return
+	}
+
+	client := hub.Subscribe(subscription, resumeAfter)
+	defer client.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	dropped := func() {
+		_, _ = fmt.Fprintf(w, "event: error\ndata: {\"code\":%q}\n\n", CodeSlowConsumer)
+		flusher.Flush()
+	}
+	for {
+		select {
+		case event := <-client.Events():
+			if isDone(client) {
+				dropped()
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Channel, event.Data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-client.Done():
+			dropped()
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func parseSubscription(q url.Values) (stream.Subscription, error) {
+	subscription := stream.Subscription{Channels: map[string]bool{}, Pairs: map[string]bool{}}
+
+	for _, channel := range splitList(q.Get("channels")) {
+		if !slices.Contains(stream.Channels, channel) {
+			return subscription, fmt.Errorf("unknown channel %q, available: %s", channel, strings.Join(stream.Channels, ","))
+		}
+		subscription.Channels[channel] = true
+	}
+	for _, pair := range splitList(q.Get("pairs")) {
+		subscription.Pairs[pair] = true
+	}
+
+	return subscription, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isDone tells whether the client is dropped, select picks a ready case at random
+// so events buffered before it must not be sent
+func isDone(client *stream.Client) bool {
+	select {
+	case <-client.Done():
+		return true
+	default:
+		return false
+	}
+}
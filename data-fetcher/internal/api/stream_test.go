@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"cur/internal/stream"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStreamServer(t *testing.T, hub *stream.Hub) *httptest.Server {
+	storage := memory.NewStore()
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableStream(hub)
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func waitForClients(t *testing.T, hub *stream.Hub, n int) {
+	require.Eventually(t, func() bool { return hub.Clients() == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestStream_Websocket(t *testing.T) {
+	hub := stream.NewHub(10, 10)
+	server := newStreamServer(t, hub)
+	publisher := NewStreamPublisher(hub)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/stream/ws?channels=trades,tickers&pairs=BTC-USDT"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	publisher.Trade(model.Trade{Pair: "ETH-USDT", TradeId: "1", Price: 100_000_000, Size: 100_000_000, Side: "buy", Timestamp: ts})
+	publisher.Candles(context.Background(), []model.Candle{{Pair: "BTC-USDT", Bar: "1H", Timestamp: ts}})
+	publisher.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "2", Price: 9700010000000, Size: 50_000_000, Side: "sell", Timestamp: ts})
+	publisher.Ticker(model.Ticker{Pair: "BTC-USDT", Last: 9700010000000, Timestamp: ts})
+
+	var message streamMessage
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, uint64(3), message.Id)
+	assert.Equal(t, stream.ChannelTrades, message.Channel)
+	var trade tradeDto
+	require.NoError(t, json.Unmarshal(message.Data, &trade))
+	assert.Equal(t, tradeDto{Pair: "BTC-USDT", TradeId: "2", Price: "97000.1", Size: "0.5", Side: "sell", Timestamp: ts}, trade)
+
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, stream.ChannelTickers, message.Channel)
+	var ticker tickerDto
+	require.NoError(t, json.Unmarshal(message.Data, &ticker))
+	assert.Equal(t, "97000.1", ticker.Last)
+}
+
+// overflow publishes large events until the only client is disconnected as a slow consumer
+func overflow(t *testing.T, hub *stream.Hub) {
+	payload := strings.Repeat("x", 64*1024)
+	require.Eventually(t, func() bool {
+		_, _ = hub.Publish(stream.ChannelTrades, "BTC-USDT", payload)
+		return hub.Clients() == 0
+	}, 10*time.Second, time.Millisecond)
+}
+
+func TestStream_WebsocketSlowConsumer(t *testing.T) {
+	hub := stream.NewHub(1, 0)
+	server := newStreamServer(t, hub)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stream/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	waitForClients(t, hub, 1)
+
+	// the test doesn't read until the server stops writing because of full socket buffers
+	overflow(t, hub)
+
+	for {
+		_, _, err := conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+			assert.Equal(t, CodeSlowConsumer, closeErr.Text)
+			break
+		}
+	}
+}
+
+func TestStream_WebsocketInvalidChannel(t *testing.T) {
+	server := newStreamServer(t, stream.NewHub(10, 10))
+
+	resp, err := http.Get(server.URL + "/v1/stream/ws?channels=orders")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+func readSseEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.event != "" || event.data != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStream_SseResume(t *testing.T) {
+	hub := stream.NewHub(10, 10)
+	server := newStreamServer(t, hub)
+	publisher := NewStreamPublisher(hub)
+
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"1", "2", "3"} {
+		publisher.Trade(model.Trade{Pair: "BTC-USDT", TradeId: id, Timestamp: ts})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/stream/sse?channels=trades", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	event := readSseEvent(t, reader)
+	assert.Equal(t, sseEvent{id: "2", event: "trades", data: event.data}, event)
+	assert.Contains(t, event.data, `"tradeId":"2"`)
+	assert.Equal(t, "3", readSseEvent(t, reader).id)
+
+	publisher.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "4", Timestamp: ts})
+	assert.Equal(t, "4", readSseEvent(t, reader).id)
+}
+
+func TestStream_SseSlowConsumer(t *testing.T) {
+	hub := stream.NewHub(1, 0)
+	server := newStreamServer(t, hub)
+
+	resp, err := http.Get(server.URL + "/v1/stream/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	waitForClients(t, hub, 1)
+
+	overflow(t, hub)
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		event := readSseEvent(t, reader)
+		if event.event == "error" {
+			assert.Contains(t, event.data, CodeSlowConsumer)
+			break
+		}
+	}
+}
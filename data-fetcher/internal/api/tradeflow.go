@@ -0,0 +1,54 @@
+package api
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/store"
+	"time"
+)
+
+// tradeFlowDto trades of the candle received from the trade stream, volumes are in the base currency
+type tradeFlowDto struct {
+	Vwap         string `json:"vwap"`
+	BuyVolume    string `json:"buyVolume"`
+	SellVolume   string `json:"sellVolume"`
+	TradeCount   int64  `json:"tradeCount"`
+	LargestTrade string `json:"largestTrade"`
+}
+
+// EnableTradeFlows adds trade flows to candles of GET /v1/candles, candles without trades have no flow
+func (s *Server) EnableTradeFlows(repository store.TradeFlowStore) {
+	s.flowRepository = repository
+}
+
+// attachTradeFlows sets flows of candles of one pair and bar ordered by timestamp
+func (s *Server) attachTradeFlows(candles []candleDto) error {
+	if s.flowRepository == nil || len(candles) == 0 {
+		return nil
+	}
+
+	first, last := candles[0], candles[len(candles)-1]
+	flows, err := s.flowRepository.FetchTradeFlows(store.CandleQuery{
+		Pair: first.Pair,
+		Bar:  first.Bar,
+		From: first.Timestamp,
+		To:   last.Timestamp.Add(time.Microsecond),
+	})
+	if err != nil {
+		return err
+	}
+
+	byTimestamp := make(map[int64]*tradeFlowDto, len(flows))
+	for _, f := range flows {
+		byTimestamp[f.Timestamp.UnixMicro()] = &tradeFlowDto{
+			Vwap:         price.Price{Price: f.Vwap()}.String(),
+			BuyVolume:    price.Price{Price: f.BuyVolume}.String(),
+			SellVolume:   price.Price{Price: f.SellVolume}.String(),
+			TradeCount:   f.TradeCount,
+			LargestTrade: price.Price{Price: f.LargestTrade}.String(),
+		}
+	}
+	for i := range candles {
+		candles[i].Flow = byTimestamp[candles[i].Timestamp.UnixMicro()]
+	}
+	return nil
+}
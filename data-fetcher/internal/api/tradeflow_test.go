@@ -0,0 +1,47 @@
+package api
+
+import (
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_CandlesWithTradeFlows(t *testing.T) {
+	storage := memory.NewStore()
+	api := NewServer(storage.Currency(), storage.Candle(), nil, nil, "1H", log.New())
+	api.EnableTradeFlows(storage.TradeFlow())
+	env := &testEnv{storage: storage, server: httptest.NewServer(api.Handler())}
+	t.Cleanup(env.server.Close)
+
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []model.Candle{
+		storetest.Candle("BTC-USDT", "1H", start, 1),
+		storetest.Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+		storetest.Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 3),
+	}
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+	require.NoError(t, storage.TradeFlow().MergeTradeFlows([]model.TradeFlow{
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, BuyVolume: 150_000_000, SellVolume: 50_000_000,
+			Notional: 20_100 * 100_000_000, TradeCount: 3, LargestTrade: 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(2 * time.Hour), BuyVolume: 100_000_000,
+			Notional: 10_000 * 100_000_000, TradeCount: 1, LargestTrade: 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1m", Timestamp: start.Add(time.Hour), BuyVolume: 100_000_000,
+			Notional: 10_000 * 100_000_000, TradeCount: 1, LargestTrade: 100_000_000},
+	}))
+
+	var body candlesBody
+	require.Equal(t, 200, env.get(t, "/v1/candles?pair=BTC-USDT", &body))
+	require.Len(t, body.Data, 3)
+	require.NotNil(t, body.Data[0].Flow)
+	assert.Equal(t, tradeFlowDto{Vwap: "10050", BuyVolume: "1.5", SellVolume: "0.5", TradeCount: 3, LargestTrade: "1"}, *body.Data[0].Flow)
+	assert.Nil(t, body.Data[1].Flow, "no trades in the bar")
+	require.NotNil(t, body.Data[2].Flow, "the last candle of the page")
+	assert.Equal(t, "10000", body.Data[2].Flow.Vwap)
+}
@@ -2,36 +2,144 @@ package app
 
 import (
 	"context"
+	"cur/internal/api"
 	"cur/internal/config"
+	"cur/internal/config/divergenceConfig"
+	"cur/internal/grpcapi"
+	"cur/internal/health"
+	"cur/internal/helper/price"
+	"cur/internal/indicators"
 	"cur/internal/infrastructure/dbConnection"
+	"cur/internal/infrastructure/kafka"
+	"cur/internal/infrastructure/metrics"
+	"cur/internal/infrastructure/tracing"
+	"cur/internal/leader"
+	"cur/internal/lifecycle"
+	"cur/internal/model"
+	"cur/internal/scheduler"
+	"cur/internal/service/alert"
+	"cur/internal/service/analytics"
+	"cur/internal/service/anomaly"
+	"cur/internal/service/divergence"
+	"cur/internal/service/indicator"
 	"cur/internal/service/okx"
+	"cur/internal/service/paper"
+	"cur/internal/service/portfolio"
+	"cur/internal/service/tradeflow"
+	"cur/internal/service/trend"
 	"cur/internal/store"
+	"cur/internal/stream"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
-	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
 )
 
 type App struct {
 	config      *config.Config
 	log         *log.Logger
 	store       *store.Store
-	cron        *cron.Cron
 	okxService  *okx.OkxService
-	cancelStack []context.CancelFunc
+	apiServer   *api.Server
+	streamHub   *stream.Hub
+	httpServer  *http.Server
+	grpcApi     *grpcapi.Server
+	grpcServer  *grpc.Server
+	trendEngine *trend.Engine
+	indicators  *indicator.Job
+	analytics   *analytics.Job
+	alertEngine *alert.Engine
+	paperEngine *paper.Engine
+	divergence  *divergence.Monitor
+	anomalies   *anomaly.Detector
+	tradeFlows  *tradeflow.Aggregator
+	lifecycle   *lifecycle.Manager
+	elector     *leader.Elector
+	scheduler   *scheduler.Scheduler
+
+	kafkaProducer *kafka.KafkaAsyncProducer
+	kafkaErr      error
+
+	shutdownTracing func(ctx context.Context) error
+}
+
+// shutdownTimeout is given to in-flight work after a signal
+const shutdownTimeout = 30 * time.Second
+
+// initLeaderElection makes the instance run leader work only while it holds the lock
+func (app *App) initLeaderElection() {
+	cfg := app.config.LeaderConfig()
+	if !cfg.Enabled {
+		return
+	}
+	app.elector = leader.NewElector(app.store.AdvisoryLock(cfg.LockName), cfg.Interval, app.log)
+}
+
+// startLeaderWork receives trades, fetches historical candles and then runs scheduled tasks until shutdown,
+// only in the leader when election is enabled
+func (app *App) startLeaderWork() {
+	lead := func(ctx context.Context) {
+		var wg sync.WaitGroup
+		background := func(run func()) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run()
+			}()
+		}
+
+		background(func() { app.okxService.FetchTrades(ctx) })
+		if app.elector != nil {
+			background(func() { app.reloadEngines(ctx, app.config.LeaderConfig().Interval) })
+		}
+		app.fetchHistoricalCandlesData(ctx)
+		app.runScheduledTasks(ctx)
+		wg.Wait()
+	}
+
+	if app.elector == nil {
+		app.lifecycle.Go("trades websocket, historical candles and scheduled tasks", lead)
+		return
+	}
+	app.lifecycle.Go("leader election", func(ctx context.Context) { app.elector.Run(ctx, lead) })
 }
 
-func (app *App) fetchTrades() {
-	// Create context for background task
-	ctx, cancel := context.WithCancel(context.Background())
-	app.cancelStack = append(app.cancelStack, cancel)
+// reloadEngines loads open paper orders and alert rules on election and every interval until ctx is done,
+// other instances change them through the API
+func (app *App) reloadEngines(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if app.paperEngine != nil {
+			if err := app.paperEngine.Load(); err != nil {
+				app.log.Error(err)
+			}
+		}
+		if err := app.alertEngine.Reload(); err != nil {
+			app.log.Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
-	// Run FetchTrades in background as a goroutine
-	go func() {
-		app.okxService.FetchTrades(ctx)
-	}()
+// leading reports whether the instance runs leader work
+func (app *App) leading() bool {
+	return app.elector == nil || app.elector.IsLeader()
 }
 
 func (app *App) initLogger() {
@@ -44,47 +152,110 @@ func StartApplication() {
 	app := newApp()
 	err := app.initConfig()
 	app.initLogger()
-	err = app.initStore()
-	app.initOkxService()
-	// Handle Graceful Shutdown
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	if err != nil {
+		for _, err := range config.Errors(err) {
+			app.log.Error(err)
+		}
+		os.Exit(1)
+	}
 
-	app.fetchTrades()
-	app.fetchHistoricalCandlesData()
-	app.initScheduledTasks()
+	// Handle Graceful Shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	app.lifecycle = lifecycle.NewManager(ctx, app.log)
 
-	if err != nil {
+	app.initTracing()
+	if err := app.initStore(); errors.Is(err, dbConnection.ErrUnreachable) {
+		app.log.Warnf("%v, the app isn't ready until it's up", err)
+	} else if err != nil {
 		app.log.Error(err)
 		os.Exit(1)
 	}
+	app.initLeaderElection()
+	app.initOkxService()
+	app.initAnomalyDetector()
+	app.initTradeFlowAggregator()
+	app.initTrendEngine()
+	app.initIndicatorJob()
+	app.initAnalyticsJob()
+	app.initScheduler()
+	app.initAlertEngine()
+	app.initPaperEngine()
+	app.initDivergenceMonitor()
+	app.initApiServer()
+	app.initGrpcServer()
 
-	<-sigs
+	app.startHttpServer()
+	app.startGrpcServer()
+	app.startLeaderWork()
 
-	for _, f := range app.cancelStack {
-		f()
+	// components start in order, a signal in the middle skips the rest
+	if err := app.lifecycle.Start(); err != nil && ctx.Err() == nil {
+		app.log.Error(err)
+		stop()
 	}
+	<-app.lifecycle.Context().Done()
 
+	app.log.Info("shutting down")
+	if err := app.lifecycle.Shutdown(shutdownTimeout); err != nil {
+		app.log.Errorf("not stopped in time: %v", err)
+	}
+	app.close()
+}
+
+// close flushes messages and spans and closes connections after background work is stopped
+func (app *App) close() {
+	if app.kafkaProducer != nil {
+		if err := app.kafkaProducer.Close(); err != nil {
+			app.log.Error(err)
+		}
+	}
+	if app.store != nil {
+		if err := app.store.CloseConnection(); err != nil {
+			app.log.Error(err)
+		}
+	}
+	if app.shutdownTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := app.shutdownTracing(ctx); err != nil {
+			app.log.Error(err)
+		}
+	}
 }
 
 func newApp() *App {
 	return &App{}
 }
 
+// initTracing exports spans to the configured OTLP endpoint, trace context is propagated without it
+func (app *App) initTracing() {
+	conf := app.config.TracingConfig()
+	var err error
+	app.shutdownTracing, err = tracing.Setup(context.Background(), conf.Endpoint, conf.ServiceName, conf.SampleRatio)
+	if err != nil {
+		app.log.Errorf("spans won't be exported: %v", err)
+	}
+}
+
+// initConfig loads the config file of CONFIG_FILE (config.yaml by default) overridden by environment variables,
+// every invalid setting is returned
 func (app *App) initConfig() error {
-	config.LoadEnvs()               // Load environment variables
-	app.config = config.NewConfig() // Initialize configuration
-	return nil                      //todo
+	var err error
+	app.config, err = config.Load(os.Getenv(config.PathEnvKey))
+	return err
 }
 
+// initStore connects to the database, the store is made even when it is unreachable (dbConnection.ErrUnreachable)
 func (app *App) initStore() error {
 	db, err := dbConnection.GetDbConnection(app.Config().DbConfig())
-	if err != nil {
+	if db == nil {
 		return err
 	}
 
 	app.store = store.NewStore(db) // Initialize configuration
-	return nil
+	app.store.Candle().OnInsert(metrics.CandlesInserted)
+	return err
 }
 
 func (app *App) Log() *log.Logger {
@@ -95,49 +266,499 @@ func (app *App) Config() *config.Config {
 	return app.config
 }
 
-func (app *App) initScheduledTasks() {
+// initScheduler defines jobs run on configured schedules.
+// The scheduler is made in every instance for the admin API, jobs run only in the leader.
+func (app *App) initScheduler() {
+	cfg := app.config.JobsConfig()
+	var err error
+	app.scheduler, err = scheduler.NewScheduler(app.store.Job(), []scheduler.Job{
+		{Name: "update_currencies", Schedule: cfg.UpdateCurrencies, CatchUp: cfg.CatchUp, Run: app.okxService.UpdateCurrencies},
+		{Name: "update_candles", Schedule: cfg.UpdateCandles, CatchUp: cfg.CatchUp, Run: app.updateCandles},
+	}, app.log)
+	if err != nil {
+		app.log.Errorf("scheduled jobs are disabled: %v", err)
+	}
+}
 
-	app.cron = cron.New()
+// updateCandles fetches new candles and computes indicators and analytics of them
+func (app *App) updateCandles(ctx context.Context) (int, error) {
+	stored, err := app.okxService.UpdateCandles(ctx)
+	app.computeIndicators(ctx)
+	app.computeAnalytics(ctx)
+	return stored, err
+}
 
-	// Running at 8:00 и 21:00 every day
-	_, err := app.cron.AddFunc("0 8,21 * * *", func() {
-		app.log.Info("process update currencies started")
-		if err := app.okxService.UpdateCurrencies(); err != nil {
-			app.log.Error(err)
-		}
-		app.log.Info("process update currencies finished")
+// runScheduledTasks runs jobs on schedule, it returns when ctx is done and running jobs finish
+func (app *App) runScheduledTasks(ctx context.Context) {
+	if app.scheduler == nil || ctx.Err() != nil {
+		return
+	}
+
+	// running jobs see ctx is done and finish early
+	app.scheduler.Run(ctx)
+}
+
+// runJob runs the job in the root span of its trace and counts it
+func (app *App) runJob(ctx context.Context, job string, run func(ctx context.Context) error) error {
+	return metrics.CronJob(job, func() (err error) {
+		ctx, span := tracing.Start(ctx, "cron "+job, attribute.String("job", job))
+		defer func() { tracing.End(span, err) }()
+		return run(ctx)
 	})
+}
+
+func (app *App) initOkxService() {
+	app.okxService = okx.NewOkxService(
+		app.store.Currency(),
+		app.store.Candle(),
+		app.config.OkxApiConfig(),
+		app.config.KafkaConfig(),
+		app.log,
+	)
+	app.okxService.OnTrade(app.store.Trade().Add)
+	app.okxService.OnTicker(app.store.Ticker().Set)
+	app.okxService.SetProducer(app.producer())
+}
+
+// initAnomalyDetector quarantines suspect candles and trades
+func (app *App) initAnomalyDetector() {
+	cfg := app.config.AnomalyConfig()
+	if !cfg.Enabled {
+		return
+	}
+
+	config := anomaly.Config{
+		Window:       cfg.Window,
+		MinSamples:   cfg.MinSamples,
+		MadScore:     cfg.MadScore,
+		ZScore:       cfg.ZScore,
+		MinDeviation: cfg.MinDeviation,
+		StaleAfter:   cfg.StaleAfter,
+	}
+	var err error
+	app.anomalies, err = anomaly.NewDetector(app.store.Anomaly(), app.store.Candle(), app.store.Trade(), app.okxService.Pairs(), config, app.log)
+	if err != nil {
+		app.log.Errorf("anomaly detection is disabled: %v", err)
+		return
+	}
+
+	app.okxService.SetFilters(app.anomalies.Candles, app.anomalies.Trade)
+	// trades are received only by the leader
+	app.anomalies.SetFeeding(app.leading)
+
+	app.lifecycle.Go("anomaly detector", func(ctx context.Context) { app.anomalies.Run(ctx, cfg.Interval) })
+}
+
+// initTradeFlowAggregator aggregates trades in flows of candles
+func (app *App) initTradeFlowAggregator() {
+	cfg := app.config.TradeFlowConfig()
+	if !cfg.Enabled {
+		return
+	}
+
+	bars := cfg.Bars
+	if len(bars) == 0 {
+		bars = []string{app.config.OkxApiConfig().CandlesBar}
+	}
+	var err error
+	app.tradeFlows, err = tradeflow.NewAggregator(app.store.TradeFlow(), bars, app.log)
+	if err != nil {
+		app.log.Errorf("trade flows are disabled: %v", err)
+		return
+	}
+
+	app.okxService.OnTrade(app.tradeFlows.Trade)
+
+	// the last flush is done on shutdown
+	app.lifecycle.Go("trade flow aggregator", func(ctx context.Context) { app.tradeFlows.Run(ctx, cfg.FlushInterval) })
+}
+
+// producer returns the Kafka producer shared by engines, nil when it can't be created.
+// It is closed after the cancel stack because engines publish until they are closed.
+func (app *App) producer() kafka.Producer {
+	if app.kafkaProducer == nil && app.kafkaErr == nil {
+		kafkaProducer, err := kafka.NewKafkaAsyncProducer(app.config.KafkaConfig())
+		if err != nil {
+			app.kafkaErr = err
+			app.log.Errorf("failed to create Kafka producer, events won't be published: %v", err)
+		} else {
+			app.kafkaProducer = kafkaProducer
+		}
+	}
+
+	// a nil *KafkaAsyncProducer must not become a non-nil interface
+	if app.kafkaProducer == nil {
+		return nil
+	}
+	return app.kafkaProducer
+}
+
+// initTrendEngine classifies regimes on every candles insert, without kafka the changes are only stored
+func (app *App) initTrendEngine() {
+	var err error
+	app.trendEngine, err = trend.NewEngine(app.store.Candle(), app.store.Trend(), app.producer(), trend.DefaultConfig(), app.log)
 	if err != nil {
 		app.log.Error(err)
 		return
 	}
+	app.store.Candle().OnInsert(app.trendEngine.OnCandles)
+}
+
+// initIndicatorJob prepares precomputation of configured indicators, invalid specs disable it
+func (app *App) initIndicatorJob() {
+	specs, err := indicators.ParseSpecs(strings.Join(app.config.IndicatorsConfig().Indicators, ","))
+	if err != nil {
+		app.log.Errorf("indicators won't be precomputed: %v", err)
+		return
+	}
+	app.indicators = indicator.NewJob(app.store.Candle(), app.store.Indicator(), specs, app.log)
+}
+
+// computeIndicators stores indicators of candles closed since the previous run
+func (app *App) computeIndicators(ctx context.Context) {
+	if app.indicators == nil {
+		return
+	}
+	app.log.Info("process compute indicators started")
+	_ = app.runJob(ctx, "compute_indicators", func(ctx context.Context) error {
+		app.indicators.Run(ctx, app.okxService.Pairs(), app.config.OkxApiConfig().CandlesBar)
+		return nil
+	})
+	app.log.Info("process compute indicators finished")
+}
+
+// initAnalyticsJob prepares rolling volatility and correlation, no windows disable it
+func (app *App) initAnalyticsJob() {
+	windows := app.config.AnalyticsConfig().Windows
+	if len(windows) == 0 {
+		return
+	}
+	var err error
+	app.analytics, err = analytics.NewJob(app.store.Candle(), app.store.Analytics(), windows, app.log)
+	if err != nil {
+		app.log.Errorf("analytics won't be computed: %v", err)
+	}
+}
 
-	// Running every hour
-	_, err = app.cron.AddFunc("0 * * * *", func() {
-		app.log.Info("process update candles started")
-		app.okxService.UpdateCandles()
-		app.log.Info("process update candles finished")
+// computeAnalytics stores volatility and correlation of candles closed since the previous run
+func (app *App) computeAnalytics(ctx context.Context) {
+	if app.analytics == nil {
+		return
+	}
+	app.log.Info("process compute analytics started")
+	_ = app.runJob(ctx, "compute_analytics", func(ctx context.Context) error {
+		app.analytics.Run(ctx, app.okxService.Pairs(), app.config.OkxApiConfig().CandlesBar)
+		return nil
 	})
+	app.log.Info("process compute analytics finished")
+}
+
+// initAlertEngine evaluates alert rules on trades and candle inserts, channels without settings are disabled
+func (app *App) initAlertEngine() {
+	conf := app.config.AlertsConfig()
+	notifiers := make(map[string]alert.Notifier)
+	if conf.WebhookUrl != "" {
+		notifiers[model.ChannelWebhook] = alert.NewWebhookNotifier(conf.WebhookUrl)
+	}
+	if conf.TelegramBotToken != "" && conf.TelegramChatId != "" {
+		notifiers[model.ChannelTelegram] = alert.NewTelegramNotifier(conf.TelegramApiUrl, conf.TelegramBotToken, conf.TelegramChatId)
+	}
+	if conf.SmtpAddr != "" {
+		emailNotifier, err := alert.NewEmailNotifier(conf.SmtpAddr, conf.SmtpUser, conf.SmtpPassword, conf.SmtpFrom, conf.SmtpTo)
+		if err != nil {
+			app.log.Errorf("email alerts are disabled: %v", err)
+		} else {
+			notifiers[model.ChannelEmail] = emailNotifier
+		}
+	}
+
+	app.alertEngine = alert.NewEngine(app.store.Alert(), app.store.Candle(), notifiers, app.log)
+	if err := app.alertEngine.Reload(); err != nil {
+		app.log.Error(err)
+	}
+	app.okxService.OnTrade(app.alertEngine.Trade)
+	app.store.Candle().OnInsert(app.alertEngine.OnCandles)
+	app.lifecycle.Add(lifecycle.Component{Name: "alert engine", Stop: closer(app.alertEngine.Close)})
+}
+
+// initPaperEngine fills paper orders with live trades, an invalid fee disables paper trading
+func (app *App) initPaperEngine() {
+	fee, err := price.ParsePrice(app.config.PaperConfig().FeePercent)
+	if err == nil {
+		app.paperEngine, err = paper.NewEngine(app.store.Paper(), app.producer(), fee, app.log)
+	}
 	if err != nil {
+		app.log.Errorf("paper trading is disabled: %v", err)
+		return
+	}
+
+	if err := app.paperEngine.Load(); err != nil {
 		app.log.Error(err)
+	}
+	app.okxService.OnTrade(app.paperEngine.Trade)
+	app.lifecycle.Add(lifecycle.Component{Name: "paper engine", Stop: closer(app.paperEngine.Close)})
+}
+
+// initDivergenceMonitor compares OKX trades with reference venues, no sources or invalid settings disable it
+func (app *App) initDivergenceMonitor() {
+	cfg := app.config.DivergenceConfig()
+	if len(cfg.Sources) == 0 {
+		return
+	}
+
+	config, sources, err := divergenceSettings(cfg)
+	if err == nil {
+		app.divergence, err = divergence.NewMonitor(app.store.Divergence(), sources, app.producer(), config, app.log)
+	}
+	if err != nil {
+		app.log.Errorf("divergence monitor is disabled: %v", err)
 		return
 	}
 
-	// running
-	app.cron.Start()
+	app.okxService.OnTrade(app.divergence.Trade)
+
+	app.lifecycle.Go("divergence monitor", func(ctx context.Context) { app.divergence.Run(ctx, cfg.Interval) })
 }
 
-func (app *App) initOkxService() {
-	app.okxService = okx.NewOkxService(
+// divergenceSettings converts decimal percents to prices and makes sources
+func divergenceSettings(cfg *divergenceConfig.DivergenceConfig) (divergence.Config, []divergence.Source, error) {
+	config := divergence.Config{Window: cfg.Window, MaxAge: cfg.MaxAge}
+	var err error
+	if config.Threshold, err = price.ParsePrice(cfg.ThresholdPercent); err != nil {
+		return config, nil, fmt.Errorf("invalid threshold %q: %w", cfg.ThresholdPercent, err)
+	}
+
+	config.Fees = make(map[string]int64)
+	for venue, percent := range cfg.Fees {
+		fee, err := price.ParsePrice(percent)
+		if err != nil {
+			return config, nil, fmt.Errorf("invalid fee of %s %q", venue, percent)
+		}
+		config.Fees[strings.ToLower(venue)] = fee
+	}
+
+	var sources []divergence.Source
+	for _, name := range cfg.Sources {
+		switch name = strings.ToLower(name); name {
+		case "binance":
+			sources = append(sources, divergence.NewBinanceSource(cfg.BinanceUrl))
+		default:
+			return config, nil, fmt.Errorf("unknown source %q", name)
+		}
+	}
+
+	return config, sources, nil
+}
+
+func (app *App) initApiServer() {
+	app.apiServer = api.NewServer(
 		app.store.Currency(),
 		app.store.Candle(),
-		app.config.OkxApiConfig(),
-		app.config.KafkaConfig(),
+		app.store.Trade(),
+		app.okxService.Pairs(),
+		app.config.OkxApiConfig().CandlesBar,
 		app.log,
 	)
+
+	app.apiServer.Handle("GET /metrics", metrics.Handler())
+	app.apiServer.EnableHealth(app.healthChecker())
+	app.apiServer.SetLeading(app.leading)
+
+	// live events are published to the hub and served over websocket and sse
+	app.streamHub = stream.NewHub(stream.DefaultBufferSize, stream.DefaultHistorySize)
+	app.apiServer.EnableStream(app.streamHub)
+	publisher := api.NewStreamPublisher(app.streamHub)
+	app.okxService.OnTrade(publisher.Trade)
+	app.okxService.OnTicker(publisher.Ticker)
+	app.store.Candle().OnInsert(publisher.Candles)
+
+	if app.indicators != nil {
+		app.apiServer.EnableIndicators(app.store.Indicator(), app.indicators.Names())
+	}
+
+	if app.tradeFlows != nil {
+		app.apiServer.EnableTradeFlows(app.store.TradeFlow())
+	}
+
+	if app.analytics != nil {
+		app.apiServer.EnableAnalytics(app.store.Analytics(), app.analytics.Windows())
+	}
+
+	app.apiServer.EnableAlerts(app.store.Alert(), func() {
+		if err := app.alertEngine.Reload(); err != nil {
+			app.log.Error(err)
+		}
+	})
+
+	if app.paperEngine != nil {
+		app.apiServer.EnablePaper(app.paperEngine, app.store.Paper())
+	}
+
+	if app.anomalies != nil {
+		app.apiServer.EnableAnomalies(app.anomalies, app.store.Anomaly())
+	}
+
+	if app.divergence != nil {
+		app.apiServer.EnableDivergence(app.divergence, app.store.Divergence())
+	}
+
+	if app.scheduler != nil {
+		app.apiServer.EnableJobs(app.scheduler)
+	}
+
+	app.apiServer.EnableConversion(app.store.CrossRate())
+
+	okxConfig := app.config.OkxApiConfig()
+	app.apiServer.EnablePortfolio(
+		portfolio.New(app.store.Portfolio(), app.store.CrossRate(), okxConfig.BaseCurrency, okxConfig.CandlesBar),
+		app.store.Portfolio(),
+	)
+}
+
+// healthChecker checks the database, Kafka, the trade websocket and freshness of trades and candles of every pair,
+// zero max ages disable freshness checks. Instances which aren't the leader don't receive trades.
+func (app *App) healthChecker() *health.Checker {
+	cfg := app.config.HealthConfig()
+	checker := health.NewChecker(cfg.CheckTimeout)
+	checker.Add("database", app.store.Ping)
+	checker.Add("kafka", func(ctx context.Context) error {
+		if app.kafkaProducer == nil {
+			return app.kafkaErr
+		}
+		return app.kafkaProducer.Status()
+	})
+	checker.Add("websocket", func(ctx context.Context) error {
+		connected, since := app.okxService.WebsocketState()
+		switch {
+		case connected || !app.leading():
+			return nil
+		case since.IsZero():
+			return errors.New("not connected yet")
+		default:
+			return fmt.Errorf("disconnected since %s", since.UTC().Format(time.RFC3339))
+		}
+	})
+
+	bar := app.config.OkxApiConfig().CandlesBar
+	for _, pair := range app.okxService.Pairs() {
+		if cfg.TradeMaxAge > 0 {
+			checker.Add("trades "+pair, app.leaderOnly(health.TradeFreshness(app.store.Trade(), pair, cfg.TradeMaxAge)))
+		}
+		if cfg.CandleMaxAge > 0 {
+			checker.Add("candles "+pair, health.CandleFreshness(app.store.Candle(), pair, bar, cfg.CandleMaxAge))
+		}
+	}
+
+	return checker
+}
+
+// leaderOnly passes checks of leader work in other instances
+func (app *App) leaderOnly(check health.Check) health.Check {
+	return func(ctx context.Context) error {
+		if !app.leading() {
+			return nil
+		}
+		return check(ctx)
+	}
+}
+
+// startHttpServer serves api in background, requests get the root context so streams end on shutdown
+func (app *App) startHttpServer() {
+	app.httpServer = &http.Server{
+		Addr:              app.config.HttpConfig().Addr,
+		Handler:           app.apiServer.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return app.lifecycle.Context() },
+	}
+
+	app.lifecycle.Add(lifecycle.Component{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", app.httpServer.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				app.log.Infof("http server listening on %s", app.httpServer.Addr)
+				if err := app.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					app.log.Error(err)
+				}
+			}()
+			return nil
+		},
+		Stop: app.httpServer.Shutdown,
+	})
 }
 
-// fetchHistoricalCandlesData fetch candles historical data
-func (app *App) fetchHistoricalCandlesData() {
-	app.okxService.UpdateHistoricalCandles()
+func (app *App) initGrpcServer() {
+	app.grpcApi = grpcapi.NewServer(
+		app.store.Currency(),
+		app.store.Candle(),
+		app.store.Ticker(),
+		app.config.OkxApiConfig().CandlesBar,
+		app.log,
+	)
+	app.grpcApi.SetLeading(app.leading)
+	app.okxService.OnTrade(app.grpcApi.Trade)
+}
+
+// startGrpcServer serves grpc api in background, the server is stopped gracefully on shutdown
+func (app *App) startGrpcServer() {
+	addr := app.config.GrpcConfig().Addr
+	app.grpcServer = app.grpcApi.GrpcServer()
+
+	app.lifecycle.Add(lifecycle.Component{
+		Name: "grpc server",
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				app.log.Infof("grpc server listening on %s", addr)
+				if err := app.grpcServer.Serve(listener); err != nil {
+					app.log.Error(err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// trade streams never end by themselves
+			app.grpcApi.Close()
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				app.grpcServer.GracefulStop()
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				app.grpcServer.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// closer makes the stop function of a component closed synchronously
+func closer(close func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		close()
+		return nil
+	}
+}
+
+// fetchHistoricalCandlesData fetch candles historical data and computes indicators and analytics of them
+// before scheduled tasks start, ctx interrupts it
+func (app *App) fetchHistoricalCandlesData(ctx context.Context) {
+	_ = app.runJob(ctx, "update_historical_candles", func(ctx context.Context) error {
+		app.okxService.UpdateHistoricalCandles(ctx)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	app.computeIndicators(ctx)
+	app.computeAnalytics(ctx)
 }
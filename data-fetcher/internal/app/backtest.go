@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"cur/internal/backtest"
+	"cur/internal/helper/price"
+	"cur/internal/store"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RunBacktest runs the backtest command over stored candles, returns the exit code
+func RunBacktest(args []string) int {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	pair := flags.String("pair", "", "pair, e.g. BTC-USDT")
+	bar := flags.String("bar", "1H", "candle bar")
+	from := flags.String("from", "", "first candle time, RFC3339, a date or unix milliseconds")
+	to := flags.String("to", "", "end of the range (exclusive), defaults to now")
+	strategySpec := flags.String("strategy", "sma-cross", "strategy with optional parameters, one of: "+strings.Join(backtest.Strategies(), ",")+", e.g. sma-cross:20,50 or rsi:14,30,70")
+	cash := flags.String("cash", "10000", "initial cash in the quote currency")
+	fee := flags.String("fee", "0.1", "fee in percent of the notional")
+	slippage := flags.String("slippage", "0.05", "slippage in percent of the open price")
+	format := flags.String("format", "json", "report format: json or csv")
+	out := flags.String("out", "", "output file, a prefix of <out>-trades.csv and <out>-equity.csv for csv, stdout for json by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	query, strategy, conf, err := parseBacktestFlags(*pair, *bar, *from, *to, *strategySpec, *cash, *fee, *slippage)
+	if err == nil && *format != "json" && *format != "csv" {
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err == nil && *format == "csv" && *out == "" {
+		err = errors.New("csv format needs -out")
+	}
+	if err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return 2
+	}
+
+	app := newApp()
+	err = app.initConfig()
+	app.initLogger()
+	// stdout is for the report
+	app.log.SetOutput(os.Stderr)
+	// only stored candles are read, other sections may be invalid
+	if app.config == nil {
+		app.log.Error(err)
+		return 1
+	}
+	if err := app.config.DbConfig().Validate(); err != nil {
+		app.log.Errorf("invalid db config: %v", err)
+		return 1
+	}
+	if err := app.initStore(); err != nil {
+		app.log.Error(err)
+		return 1
+	}
+
+	report, err := backtest.Run(context.Background(), app.store.Candle(), query, strategy, conf)
+	if err != nil {
+		app.log.Errorf("backtest failed: %v", err)
+		return 1
+	}
+	app.log.WithFields(log.Fields{
+		"strategy": report.Strategy, "pair": report.Pair, "bar": report.Bar,
+		"return": report.TotalReturn, "max_drawdown": report.MaxDrawdown, "sharpe": report.Sharpe,
+		"trades": len(report.Trades), "win_rate": report.WinRate,
+	}).Info("backtest finished")
+
+	if err := writeReport(report, *format, *out); err != nil {
+		app.log.Errorf("write report: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func parseBacktestFlags(pair, bar, from, to, strategySpec, cash, fee, slippage string) (store.CandleQuery, backtest.Strategy, backtest.Config, error) {
+	query := store.CandleQuery{Pair: pair, Bar: bar, To: time.Now()}
+	if pair == "" {
+		return query, nil, backtest.Config{}, errors.New("-pair is required")
+	}
+
+	var err error
+	if query.From, err = parseFlagTime(from); err != nil || from == "" {
+		return query, nil, backtest.Config{}, errors.New("-from must be RFC3339 time, a date or unix milliseconds")
+	}
+	if to != "" {
+		if query.To, err = parseFlagTime(to); err != nil {
+			return query, nil, backtest.Config{}, errors.New("-to must be RFC3339 time, a date or unix milliseconds")
+		}
+	}
+
+	strategy, err := backtest.ParseStrategy(strategySpec)
+	if err != nil {
+		return query, nil, backtest.Config{}, err
+	}
+
+	var conf backtest.Config
+	for _, field := range []struct {
+		name   string
+		value  string
+		target *int64
+	}{
+		{"cash", cash, &conf.InitialCash},
+		{"fee", fee, &conf.FeePercent},
+		{"slippage", slippage, &conf.SlippagePercent},
+	} {
+		if *field.target, err = price.ParsePrice(field.value); err != nil {
+			return query, nil, backtest.Config{}, fmt.Errorf("-%s must be a decimal number", field.name)
+		}
+	}
+
+	return query, strategy, conf, nil
+}
+
+func parseFlagTime(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeReport(report *backtest.Report, format, out string) error {
+	if format == "json" {
+		if out == "" {
+			return report.WriteJson(os.Stdout)
+		}
+		return writeFile(out, report.WriteJson)
+	}
+
+	if err := writeFile(out+"-trades.csv", report.WriteTradesCsv); err != nil {
+		return err
+	}
+	return writeFile(out+"-equity.csv", report.WriteEquityCsv)
+}
+
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
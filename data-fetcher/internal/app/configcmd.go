@@ -0,0 +1,34 @@
+package app
+
+import (
+	"cur/internal/config"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// RunConfig runs the config command, `config validate` reports every invalid setting, returns the exit code
+func RunConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: config validate [-file path]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	file := flags.String("file", os.Getenv(config.PathEnvKey), "config file, "+config.DefaultPath+" when empty, environment variables override it")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if _, err := config.Load(*file); err != nil {
+		errs := config.Errors(err)
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprintf(os.Stderr, "%d invalid settings\n", len(errs))
+		return 1
+	}
+
+	fmt.Println("config is valid")
+	return 0
+}
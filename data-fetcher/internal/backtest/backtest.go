@@ -0,0 +1,156 @@
+package backtest
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrNoCandles = errors.New("no candles in the range")
+
+// year is used to annualize the Sharpe ratio, crypto markets trade every day
+const year = 365 * 24 * time.Hour
+
+// Config of the simulated broker, amounts are fixed-point like candle prices
+type Config struct {
+	InitialCash     int64 // in the quote currency
+	FeePercent      int64 // charged on the notional of every fill
+	SlippagePercent int64 // buys fill above the open and sells below it
+}
+
+func (c Config) validate() error {
+	if c.InitialCash <= 0 {
+		return errors.New("initial cash must be positive")
+	}
+	if c.FeePercent < 0 || c.FeePercent >= hundred {
+		return errors.New("fee must be in [0, 100) percent")
+	}
+	if c.SlippagePercent < 0 || c.SlippagePercent >= hundred {
+		return errors.New("slippage must be in [0, 100) percent")
+	}
+	return nil
+}
+
+// EquityPoint equity at the close of a candle
+type EquityPoint struct {
+	Timestamp time.Time
+	Equity    int64
+	Drawdown  float64 // percent below the running peak
+}
+
+// Report of a backtest, the open position is valued at the last close and isn't in Trades
+type Report struct {
+	Strategy    string
+	Pair        string
+	Bar         string
+	From        time.Time // the first candle
+	To          time.Time // the last candle
+	InitialCash int64
+	FinalEquity int64
+	Fees        int64
+	TotalReturn float64 // percent
+	MaxDrawdown float64 // percent
+	Sharpe      float64 // annualized, zero risk-free rate
+	WinRate     float64 // percent of trades with positive pnl
+	Equity      []EquityPoint
+	Trades      []Trade
+	Fills       []Fill
+}
+
+// Run replays stored candles of the query through the strategy
+func Run(ctx context.Context, candleRepository store.CandleStore, query store.CandleQuery, strategy Strategy, config Config) (*Report, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	barDuration, err := model.BarDuration(query.Bar)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBroker(config)
+	report := &Report{Strategy: strategy.Name(), Pair: query.Pair, Bar: query.Bar, InitialCash: config.InitialCash}
+	err = candleRepository.Iterate(ctx, query, func(candle model.Candle) error {
+		b.fill(candle)
+		b.mark = candle.Close
+		strategy.OnCandle(candle, b)
+		report.Equity = append(report.Equity, EquityPoint{Timestamp: candle.Timestamp, Equity: b.Equity()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Equity) == 0 {
+		return nil, ErrNoCandles
+	}
+
+	report.From = report.Equity[0].Timestamp
+	report.To = report.Equity[len(report.Equity)-1].Timestamp
+	report.FinalEquity = b.Equity()
+	report.Fees = b.fees
+	report.Trades = b.trades
+	report.Fills = b.fills
+	report.TotalReturn = float64(report.FinalEquity-report.InitialCash) / float64(report.InitialCash) * 100
+	report.MaxDrawdown = drawdowns(report.Equity)
+	report.Sharpe = sharpe(report.Equity, float64(year)/float64(barDuration))
+	report.WinRate = winRate(report.Trades)
+	return report, nil
+}
+
+// drawdowns fills Drawdown of the points, returns the maximum
+func drawdowns(points []EquityPoint) float64 {
+	var peak int64
+	var maxDrawdown float64
+	for i := range points {
+		peak = max(peak, points[i].Equity)
+		if peak > 0 {
+			points[i].Drawdown = float64(peak-points[i].Equity) / float64(peak) * 100
+		}
+		maxDrawdown = max(maxDrawdown, points[i].Drawdown)
+	}
+	return maxDrawdown
+}
+
+// sharpe ratio of per-bar returns scaled by the number of bars in a year
+func sharpe(points []EquityPoint, barsPerYear float64) float64 {
+	if len(points) < 3 {
+		return 0
+	}
+	returns := make([]float64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		if points[i-1].Equity <= 0 {
+			return 0
+		}
+		returns = append(returns, float64(points[i].Equity)/float64(points[i-1].Equity)-1)
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(returns)-1))
+	if deviation == 0 {
+		return 0
+	}
+	return mean / deviation * math.Sqrt(barsPerYear)
+}
+
+func winRate(trades []Trade) float64 {
+	if len(trades) == 0 {
+		return 0
+	}
+	wins := 0
+	for _, trade := range trades {
+		if trade.Pnl > 0 {
+			wins++
+		}
+	}
+	return float64(wins) / float64(len(trades)) * 100
+}
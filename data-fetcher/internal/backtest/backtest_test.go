@@ -0,0 +1,198 @@
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+// scripted places the orders of the candle index
+type scripted map[int]func(b Broker)
+
+func (s scripted) Name() string {
+	return "scripted"
+}
+
+func (s scripted) OnCandle(candle model.Candle, broker Broker) {
+	if order, ok := s[int(candle.Timestamp.Sub(start)/time.Hour)]; ok {
+		order(broker)
+	}
+}
+
+// insert stores hourly candles opening and closing at the prices
+func insert(t *testing.T, prices ...int64) store.CandleStore {
+	var candles []model.Candle
+	for i, p := range prices {
+		p *= price.PriceFactor
+		candles = append(candles, model.Candle{
+			Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open: p, High: p, Low: p, Close: p, Volume: price.PriceFactor,
+		})
+	}
+	repository := memory.NewStore().Candle()
+	require.NoError(t, repository.InsertCandles(&candles))
+	return repository
+}
+
+func run(t *testing.T, repository store.CandleStore, strategy Strategy, config Config) *Report {
+	query := store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: start, To: start.Add(100 * time.Hour)}
+	report, err := Run(context.Background(), repository, query, strategy, config)
+	require.NoError(t, err)
+	return report
+}
+
+func TestRun_FillsAtNextOpenWithFees(t *testing.T) {
+	repository := insert(t, 100, 100, 110, 120, 90)
+	report := run(t, repository, scripted{
+		0: func(b Broker) { b.Buy(pricetest.Units(5)) },
+		2: func(b Broker) { b.Sell(pricetest.Units(10)) }, // reduced to the position
+		3: func(b Broker) { b.Buy(pricetest.Units(100)) }, // reduced to the cash
+	}, Config{InitialCash: pricetest.Units(1000), FeePercent: pricetest.Units(1)})
+
+	require.Len(t, report.Fills, 3)
+	assert.Equal(t, Fill{Timestamp: start.Add(time.Hour), Side: SideBuy, Price: pricetest.Units(100), Size: pricetest.Units(5), Fee: pricetest.Units(5)}, report.Fills[0])
+	assert.Equal(t, Fill{Timestamp: start.Add(3 * time.Hour), Side: SideSell, Price: pricetest.Units(120), Size: pricetest.Units(5), Fee: pricetest.Units(6)}, report.Fills[1])
+
+	bought := report.Fills[2]
+	assert.Equal(t, pricetest.Units(90), bought.Price)
+	// the whole cash of 1089 is spent with the fee
+	spent := price.MulDiv(bought.Price, bought.Size, price.PriceFactor) + bought.Fee
+	assert.LessOrEqual(t, spent, pricetest.Units(1089))
+	assert.Greater(t, spent, pricetest.Units(1089)-100)
+
+	require.Len(t, report.Trades, 1, "the open position isn't a trade")
+	assert.Equal(t, Trade{
+		EntryTime: start.Add(time.Hour), ExitTime: start.Add(3 * time.Hour), Size: pricetest.Units(5),
+		EntryPrice: pricetest.Units(100), ExitPrice: pricetest.Units(120), Fees: pricetest.Units(11), Pnl: pricetest.Units(89), Return: 17.8,
+	}, report.Trades[0])
+
+	equity := make([]int64, 0, len(report.Equity))
+	for _, p := range report.Equity {
+		equity = append(equity, p.Equity)
+	}
+	assert.Equal(t, []int64{pricetest.Units(1000), pricetest.Units(995), pricetest.Units(1045), pricetest.Units(1089)}, equity[:4])
+	assert.InDelta(t, 1078.2178, float64(report.FinalEquity)/price.PriceFactor, 0.0001)
+	assert.InDelta(t, 7.8218, report.TotalReturn, 0.0001)
+	assert.InDelta(t, 0.9901, report.MaxDrawdown, 0.0001)
+	assert.Equal(t, 100.0, report.WinRate)
+	assert.Equal(t, pricetest.Units(11)+bought.Fee, report.Fees)
+	assert.Equal(t, start, report.From)
+	assert.Equal(t, start.Add(4*time.Hour), report.To)
+}
+
+func TestRun_Slippage(t *testing.T) {
+	repository := insert(t, 100, 100, 100, 100)
+	report := run(t, repository, scripted{
+		0: func(b Broker) { b.Buy(pricetest.Units(1)) },
+		1: func(b Broker) { b.Sell(pricetest.Units(1)) },
+	}, Config{InitialCash: pricetest.Units(1000), SlippagePercent: pricetest.Units(1)})
+
+	require.Len(t, report.Trades, 1)
+	assert.Equal(t, pricetest.Units(101), report.Trades[0].EntryPrice)
+	assert.Equal(t, pricetest.Units(99), report.Trades[0].ExitPrice)
+	assert.Equal(t, -pricetest.Units(2), report.Trades[0].Pnl)
+	assert.Equal(t, pricetest.Units(998), report.FinalEquity)
+	assert.Equal(t, 0.0, report.WinRate)
+}
+
+func TestRun_SmaCross(t *testing.T) {
+	repository := insert(t, 10, 9, 8, 7, 8, 10, 12, 11, 9, 7, 6)
+	strategy, err := ParseStrategy("sma-cross:2,3")
+	require.NoError(t, err)
+	report := run(t, repository, strategy, Config{InitialCash: pricetest.Units(120)})
+
+	assert.Equal(t, "sma-cross:2,3", report.Strategy)
+	require.Len(t, report.Trades, 1)
+	trade := report.Trades[0]
+	// crossed above at the close of 10, sold after crossing below at the close of 9
+	assert.Equal(t, start.Add(6*time.Hour), trade.EntryTime)
+	assert.Equal(t, start.Add(9*time.Hour), trade.ExitTime)
+	assert.Equal(t, pricetest.Units(10), trade.Size)
+	assert.Equal(t, -pricetest.Units(50), trade.Pnl)
+	assert.Equal(t, pricetest.Units(70), report.FinalEquity)
+}
+
+func TestRun_Errors(t *testing.T) {
+	repository := insert(t, 100)
+	query := store.CandleQuery{Pair: "ETH-USDT", Bar: "1H", From: start, To: start.Add(time.Hour)}
+	_, err := Run(context.Background(), repository, query, scripted{}, Config{InitialCash: pricetest.Units(1)})
+	assert.ErrorIs(t, err, ErrNoCandles)
+
+	query.Bar = "1M"
+	_, err = Run(context.Background(), repository, query, scripted{}, Config{InitialCash: pricetest.Units(1)})
+	assert.Error(t, err)
+
+	query.Bar = "1H"
+	_, err = Run(context.Background(), repository, query, scripted{}, Config{})
+	assert.ErrorContains(t, err, "initial cash")
+	_, err = Run(context.Background(), repository, query, scripted{}, Config{InitialCash: 1, FeePercent: pricetest.Units(100)})
+	assert.ErrorContains(t, err, "fee")
+}
+
+func TestSharpe(t *testing.T) {
+	points := []EquityPoint{{Equity: 1000}, {Equity: 1100}, {Equity: 990}, {Equity: 1089}}
+	assert.InDelta(t, 0.288675, sharpe(points, 1), 0.000001)
+	assert.InDelta(t, 0.288675*2, sharpe(points, 4), 0.000001)
+
+	flat := []EquityPoint{{Equity: 1000}, {Equity: 1000}, {Equity: 1000}}
+	assert.Equal(t, 0.0, sharpe(flat, 1))
+}
+
+func TestParseStrategy(t *testing.T) {
+	strategy, err := ParseStrategy("rsi")
+	require.NoError(t, err)
+	assert.Equal(t, "rsi:14,30,70", strategy.Name())
+	strategy, err = ParseStrategy(" sma-cross:5,10 ")
+	require.NoError(t, err)
+	assert.Equal(t, "sma-cross:5,10", strategy.Name())
+	assert.Equal(t, []string{"rsi", "sma-cross"}, Strategies())
+
+	for _, spec := range []string{"macd", "sma-cross:5", "sma-cross:10,5", "rsi:14,70,30", "rsi:0,30,70", "sma-cross:a,b"} {
+		_, err := ParseStrategy(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestReport_Export(t *testing.T) {
+	repository := insert(t, 100, 100, 120, 120)
+	report := run(t, repository, scripted{
+		0: func(b Broker) { b.Buy(pricetest.Units(2)) },
+		1: func(b Broker) { b.Sell(pricetest.Units(2)) },
+	}, Config{InitialCash: pricetest.Units(1000)})
+
+	var buffer bytes.Buffer
+	require.NoError(t, report.WriteJson(&buffer))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &decoded))
+	assert.Equal(t, "1040", decoded["finalEquity"])
+	assert.Equal(t, 4.0, decoded["totalReturn"])
+	trades := decoded["trades"].([]any)
+	require.Len(t, trades, 1)
+	assert.Equal(t, "40", trades[0].(map[string]any)["pnl"])
+	assert.Len(t, decoded["equity"], 4)
+
+	buffer.Reset()
+	require.NoError(t, report.WriteTradesCsv(&buffer))
+	assert.Equal(t, "entry_time,exit_time,size,entry_price,exit_price,fees,pnl,return\n"+
+		"2025-02-01T01:00:00Z,2025-02-01T02:00:00Z,2,100,120,0,40,20.0000\n", buffer.String())
+
+	buffer.Reset()
+	require.NoError(t, report.WriteEquityCsv(&buffer))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "timestamp,equity,drawdown", lines[0])
+	assert.Equal(t, "2025-02-01T02:00:00Z,1040,0.0000", lines[3])
+}
@@ -0,0 +1,206 @@
+package backtest
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"time"
+)
+
+// hundred is 100% in the price scale
+const hundred = 100 * price.PriceFactor
+
+type Side string
+
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+// Broker executes market orders of a strategy, amounts are fixed-point like candle prices
+type Broker interface {
+	// Buy places a market order of size in the base currency. It is filled at the open of the
+	// next candle and reduced to what the cash affords with fees and slippage.
+	Buy(size int64)
+	// Sell places a market order filled at the open of the next candle, reduced to the position
+	Sell(size int64)
+	// Cash in the quote currency
+	Cash() int64
+	// Position in the base currency, positions are long only
+	Position() int64
+	// Equity is the cash with the position valued at the latest close
+	Equity() int64
+}
+
+// Fill executed order
+type Fill struct {
+	Timestamp time.Time
+	Side      Side
+	Price     int64 // with slippage
+	Size      int64
+	Fee       int64
+}
+
+// Trade round trip from an empty position back to an empty one
+type Trade struct {
+	EntryTime  time.Time
+	ExitTime   time.Time
+	Size       int64 // total bought size
+	EntryPrice int64 // average buy price
+	ExitPrice  int64 // average sell price
+	Fees       int64
+	Pnl        int64   // proceeds minus costs and fees
+	Return     float64 // pnl in percent of the costs
+}
+
+type order struct {
+	side Side
+	size int64
+}
+
+// roundTrip accumulates fills of the open trade
+type roundTrip struct {
+	entry    time.Time
+	bought   int64
+	sold     int64
+	cost     int64
+	proceeds int64
+	fees     int64
+}
+
+// broker simulated Broker, orders wait for the next candle so strategies can't trade on
+// the close they've just seen
+type broker struct {
+	config   Config
+	cash     int64
+	position int64
+	mark     int64 // the latest close
+
+	orders []order
+	fills  []Fill
+	trades []Trade
+	open   *roundTrip
+	fees   int64
+}
+
+func newBroker(config Config) *broker {
+	return &broker{config: config, cash: config.InitialCash}
+}
+
+func (b *broker) Buy(size int64) {
+	if size > 0 {
+		b.orders = append(b.orders, order{side: SideBuy, size: size})
+	}
+}
+
+func (b *broker) Sell(size int64) {
+	if size > 0 {
+		b.orders = append(b.orders, order{side: SideSell, size: size})
+	}
+}
+
+func (b *broker) Cash() int64 {
+	return b.cash
+}
+
+func (b *broker) Position() int64 {
+	return b.position
+}
+
+func (b *broker) Equity() int64 {
+	return b.cash + price.MulDiv(b.position, b.mark, price.PriceFactor)
+}
+
+// fill executes pending orders at the open of the candle
+func (b *broker) fill(candle model.Candle) {
+	orders := b.orders
+	b.orders = nil
+
+	for _, o := range orders {
+		switch o.side {
+		case SideBuy:
+			b.buy(candle, o.size)
+		case SideSell:
+			b.sell(candle, o.size)
+		}
+	}
+}
+
+func (b *broker) buy(candle model.Candle, size int64) {
+	fillPrice := candle.Open + price.MulDiv(candle.Open, b.config.SlippagePercent, hundred)
+	if fillPrice <= 0 {
+		return
+	}
+
+	// the largest affordable size, rounding may need one more unit off
+	affordable := price.MulDiv(price.MulDiv(b.cash, hundred, hundred+b.config.FeePercent), price.PriceFactor, fillPrice)
+	size = min(size, affordable)
+	notional, fee := b.cost(fillPrice, size)
+	for size > 0 && notional+fee > b.cash {
+		size--
+		notional, fee = b.cost(fillPrice, size)
+	}
+	if size <= 0 {
+		return
+	}
+
+	b.cash -= notional + fee
+	b.position += size
+	b.record(Fill{Timestamp: candle.Timestamp, Side: SideBuy, Price: fillPrice, Size: size, Fee: fee}, notional)
+}
+
+func (b *broker) sell(candle model.Candle, size int64) {
+	size = min(size, b.position)
+	if size <= 0 {
+		return
+	}
+
+	fillPrice := candle.Open - price.MulDiv(candle.Open, b.config.SlippagePercent, hundred)
+	notional, fee := b.cost(fillPrice, size)
+
+	b.cash += notional - fee
+	b.position -= size
+	b.record(Fill{Timestamp: candle.Timestamp, Side: SideSell, Price: fillPrice, Size: size, Fee: fee}, notional)
+}
+
+func (b *broker) cost(fillPrice, size int64) (notional, fee int64) {
+	notional = price.MulDiv(fillPrice, size, price.PriceFactor)
+	return notional, price.MulDiv(notional, b.config.FeePercent, hundred)
+}
+
+func (b *broker) record(fill Fill, notional int64) {
+	b.fills = append(b.fills, fill)
+	b.fees += fill.Fee
+
+	if b.open == nil {
+		b.open = &roundTrip{entry: fill.Timestamp}
+	}
+	b.open.fees += fill.Fee
+	if fill.Side == SideBuy {
+		b.open.bought += fill.Size
+		b.open.cost += notional
+	} else {
+		b.open.sold += fill.Size
+		b.open.proceeds += notional
+	}
+
+	if b.position == 0 {
+		b.trades = append(b.trades, b.open.close(fill.Timestamp))
+		b.open = nil
+	}
+}
+
+func (r *roundTrip) close(exit time.Time) Trade {
+	trade := Trade{
+		EntryTime:  r.entry,
+		ExitTime:   exit,
+		Size:       r.bought,
+		EntryPrice: price.MulDiv(r.cost, price.PriceFactor, r.bought),
+		ExitPrice:  price.MulDiv(r.proceeds, price.PriceFactor, r.sold),
+		Fees:       r.fees,
+		Pnl:        r.proceeds - r.cost - r.fees,
+	}
+	if r.cost > 0 {
+		trade.Return = float64(trade.Pnl) / float64(r.cost) * 100
+	}
+	return trade
+}
@@ -0,0 +1,125 @@
+package backtest
+
+import (
+	"cur/internal/helper/price"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// reportJson amounts as decimal strings like the API renders prices
+type reportJson struct {
+	Strategy    string            `json:"strategy"`
+	Pair        string            `json:"pair"`
+	Bar         string            `json:"bar"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	InitialCash string            `json:"initialCash"`
+	FinalEquity string            `json:"finalEquity"`
+	Fees        string            `json:"fees"`
+	TotalReturn float64           `json:"totalReturn"`
+	MaxDrawdown float64           `json:"maxDrawdown"`
+	Sharpe      float64           `json:"sharpe"`
+	WinRate     float64           `json:"winRate"`
+	Trades      []tradeJson       `json:"trades"`
+	Fills       []fillJson        `json:"fills"`
+	Equity      []equityPointJson `json:"equity"`
+}
+
+type tradeJson struct {
+	EntryTime  time.Time `json:"entryTime"`
+	ExitTime   time.Time `json:"exitTime"`
+	Size       string    `json:"size"`
+	EntryPrice string    `json:"entryPrice"`
+	ExitPrice  string    `json:"exitPrice"`
+	Fees       string    `json:"fees"`
+	Pnl        string    `json:"pnl"`
+	Return     float64   `json:"return"`
+}
+
+type fillJson struct {
+	Timestamp time.Time `json:"timestamp"`
+	Side      Side      `json:"side"`
+	Price     string    `json:"price"`
+	Size      string    `json:"size"`
+	Fee       string    `json:"fee"`
+}
+
+type equityPointJson struct {
+	Timestamp time.Time `json:"timestamp"`
+	Equity    string    `json:"equity"`
+	Drawdown  float64   `json:"drawdown"`
+}
+
+func decimal(value int64) string {
+	return price.Price{Price: value}.String()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
+
+func (r *Report) WriteJson(w io.Writer) error {
+	dto := reportJson{
+		Strategy:    r.Strategy,
+		Pair:        r.Pair,
+		Bar:         r.Bar,
+		From:        r.From,
+		To:          r.To,
+		InitialCash: decimal(r.InitialCash),
+		FinalEquity: decimal(r.FinalEquity),
+		Fees:        decimal(r.Fees),
+		TotalReturn: r.TotalReturn,
+		MaxDrawdown: r.MaxDrawdown,
+		Sharpe:      r.Sharpe,
+		WinRate:     r.WinRate,
+		Trades:      make([]tradeJson, 0, len(r.Trades)),
+		Fills:       make([]fillJson, 0, len(r.Fills)),
+		Equity:      make([]equityPointJson, 0, len(r.Equity)),
+	}
+	for _, t := range r.Trades {
+		dto.Trades = append(dto.Trades, tradeJson{
+			EntryTime: t.EntryTime, ExitTime: t.ExitTime, Size: decimal(t.Size), EntryPrice: decimal(t.EntryPrice),
+			ExitPrice: decimal(t.ExitPrice), Fees: decimal(t.Fees), Pnl: decimal(t.Pnl), Return: t.Return,
+		})
+	}
+	for _, f := range r.Fills {
+		dto.Fills = append(dto.Fills, fillJson{
+			Timestamp: f.Timestamp, Side: f.Side, Price: decimal(f.Price), Size: decimal(f.Size), Fee: decimal(f.Fee),
+		})
+	}
+	for _, p := range r.Equity {
+		dto.Equity = append(dto.Equity, equityPointJson{Timestamp: p.Timestamp, Equity: decimal(p.Equity), Drawdown: p.Drawdown})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dto)
+}
+
+// WriteTradesCsv writes the trade list with a header row
+func (r *Report) WriteTradesCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"entry_time", "exit_time", "size", "entry_price", "exit_price", "fees", "pnl", "return"})
+	for _, t := range r.Trades {
+		_ = writer.Write([]string{
+			t.EntryTime.Format(time.RFC3339), t.ExitTime.Format(time.RFC3339), decimal(t.Size), decimal(t.EntryPrice),
+			decimal(t.ExitPrice), decimal(t.Fees), decimal(t.Pnl), formatFloat(t.Return),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteEquityCsv writes the equity curve with a header row
+func (r *Report) WriteEquityCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"timestamp", "equity", "drawdown"})
+	for _, p := range r.Equity {
+		_ = writer.Write([]string{p.Timestamp.Format(time.RFC3339), decimal(p.Equity), formatFloat(p.Drawdown)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,164 @@
+package backtest
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/indicators"
+	"cur/internal/model"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Strategy receives closed candles bar by bar in time order and places orders on the broker,
+// the orders are filled at the open of the next candle
+type Strategy interface {
+	Name() string
+	OnCandle(candle model.Candle, broker Broker)
+}
+
+type strategyKind struct {
+	defaults []int
+	build    func(params []int) (Strategy, error)
+}
+
+var strategies = map[string]strategyKind{
+	"sma-cross": {[]int{20, 50}, func(p []int) (Strategy, error) {
+		return NewSmaCross(p[0], p[1])
+	}},
+	"rsi": {[]int{14, 30, 70}, func(p []int) (Strategy, error) {
+		return NewRsiReversion(p[0], p[1], p[2])
+	}},
+}
+
+// Strategies names of the built-in strategies
+func Strategies() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseStrategy makes a built-in strategy from a spec like "sma-cross:20,50" or "rsi:14,30,70",
+// omitted parameters take the defaults
+func ParseStrategy(spec string) (Strategy, error) {
+	name, list, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	kind, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, available: %s", name, strings.Join(Strategies(), ","))
+	}
+
+	params := kind.defaults
+	if list != "" {
+		parts := strings.Split(list, ",")
+		if len(parts) != len(kind.defaults) {
+			return nil, fmt.Errorf("strategy %q needs %d parameters", name, len(kind.defaults))
+		}
+		params = make([]int, len(parts))
+		for i, part := range parts {
+			param, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || param <= 0 {
+				return nil, fmt.Errorf("invalid parameter %q of strategy %q", part, name)
+			}
+			params[i] = param
+		}
+	}
+
+	return kind.build(params)
+}
+
+// allIn size buying the whole cash at the close, the broker reduces it by fees and slippage
+func allIn(candle model.Candle, broker Broker) int64 {
+	if candle.Close <= 0 {
+		return 0
+	}
+	return price.MulDiv(broker.Cash(), price.PriceFactor, candle.Close)
+}
+
+// SmaCross goes long when the fast moving average crosses above the slow one and exits on the
+// cross below
+type SmaCross struct {
+	fastPeriod int
+	slowPeriod int
+	fast       *indicators.SmaStream
+	slow       *indicators.SmaStream
+	above      bool
+	ready      bool
+}
+
+func NewSmaCross(fastPeriod, slowPeriod int) (*SmaCross, error) {
+	if fastPeriod >= slowPeriod {
+		return nil, fmt.Errorf("fast period %d must be less than slow period %d", fastPeriod, slowPeriod)
+	}
+	fast, err := indicators.NewSmaStream(fastPeriod)
+	if err != nil {
+		return nil, err
+	}
+	slow, err := indicators.NewSmaStream(slowPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &SmaCross{fastPeriod: fastPeriod, slowPeriod: slowPeriod, fast: fast, slow: slow}, nil
+}
+
+func (s *SmaCross) Name() string {
+	return fmt.Sprintf("sma-cross:%d,%d", s.fastPeriod, s.slowPeriod)
+}
+
+func (s *SmaCross) OnCandle(candle model.Candle, broker Broker) {
+	fast, _ := s.fast.Update(candle)
+	slow, ok := s.slow.Update(candle)
+	if !ok {
+		return
+	}
+
+	above := fast.Price > slow.Price
+	// the first comparison only sets the side, a cross needs a change
+	if s.ready && above != s.above {
+		if above && broker.Position() == 0 {
+			broker.Buy(allIn(candle, broker))
+		} else if !above && broker.Position() > 0 {
+			broker.Sell(broker.Position())
+		}
+	}
+	s.above, s.ready = above, true
+}
+
+// RsiReversion buys when RSI falls below the lower level and sells when it rises above the upper one
+type RsiReversion struct {
+	period int
+	lower  int
+	upper  int
+	rsi    *indicators.RsiStream
+}
+
+func NewRsiReversion(period, lower, upper int) (*RsiReversion, error) {
+	if lower >= upper || upper >= 100 {
+		return nil, fmt.Errorf("rsi levels must satisfy lower < upper < 100, got %d and %d", lower, upper)
+	}
+	rsi, err := indicators.NewRsiStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return &RsiReversion{period: period, lower: lower, upper: upper, rsi: rsi}, nil
+}
+
+func (r *RsiReversion) Name() string {
+	return fmt.Sprintf("rsi:%d,%d,%d", r.period, r.lower, r.upper)
+}
+
+func (r *RsiReversion) OnCandle(candle model.Candle, broker Broker) {
+	rsi, ok := r.rsi.Update(candle)
+	if !ok {
+		return
+	}
+
+	switch {
+	case rsi.Price < int64(r.lower)*price.PriceFactor && broker.Position() == 0:
+		broker.Buy(allIn(candle, broker))
+	case rsi.Price > int64(r.upper)*price.PriceFactor && broker.Position() > 0:
+		broker.Sell(broker.Position())
+	}
+}
@@ -0,0 +1,55 @@
+package alertsConfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+const DefaultTelegramApiUrl = "https://api.telegram.org"
+
+// AlertsConfig delivery channels of alerts, a channel with empty settings is disabled
+type AlertsConfig struct {
+	WebhookUrl string `yaml:"webhookUrl"`
+
+	TelegramApiUrl   string `yaml:"telegramApiUrl"`
+	TelegramBotToken string `yaml:"telegramBotToken"`
+	TelegramChatId   string `yaml:"telegramChatId"`
+
+	SmtpAddr     string   `yaml:"smtpAddr"` // host:port
+	SmtpUser     string   `yaml:"smtpUser"`
+	SmtpPassword string   `yaml:"smtpPassword"`
+	SmtpFrom     string   `yaml:"smtpFrom"`
+	SmtpTo       []string `yaml:"smtpTo"`
+}
+
+func Default() AlertsConfig {
+	return AlertsConfig{TelegramApiUrl: DefaultTelegramApiUrl}
+}
+
+func (c *AlertsConfig) Validate() error {
+	var errs []error
+	if c.WebhookUrl != "" {
+		if u, err := url.Parse(c.WebhookUrl); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid webhookUrl %q", c.WebhookUrl))
+		}
+	}
+	if (c.TelegramBotToken == "") != (c.TelegramChatId == "") {
+		errs = append(errs, errors.New("telegramBotToken and telegramChatId are set together"))
+	}
+	if c.TelegramBotToken != "" {
+		if u, err := url.Parse(c.TelegramApiUrl); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid telegramApiUrl %q", c.TelegramApiUrl))
+		}
+	}
+	if c.SmtpAddr != "" {
+		if _, _, err := net.SplitHostPort(c.SmtpAddr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid smtpAddr %q: %w", c.SmtpAddr, err))
+		}
+		if c.SmtpFrom == "" || len(c.SmtpTo) == 0 {
+			errs = append(errs, errors.New("smtpFrom and smtpTo are required with smtpAddr"))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,15 @@
+package alertsConfig
+
+type AlertsEnvKey string
+
+const (
+	WebhookUrl       = "ALERT_WEBHOOK_URL"
+	TelegramApiUrl   = "ALERT_TELEGRAM_API_URL"
+	TelegramBotToken = "ALERT_TELEGRAM_BOT_TOKEN"
+	TelegramChatId   = "ALERT_TELEGRAM_CHAT_ID"
+	SmtpAddr         = "ALERT_SMTP_ADDR"
+	SmtpUser         = "ALERT_SMTP_USER"
+	SmtpPassword     = "ALERT_SMTP_PASSWORD"
+	SmtpFrom         = "ALERT_SMTP_FROM"
+	SmtpTo           = "ALERT_SMTP_TO"
+)
@@ -0,0 +1,25 @@
+package analyticsConfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+type AnalyticsConfig struct {
+	// Windows numbers of candles of rolling volatility and correlation, empty disables them
+	Windows []int `yaml:"windows"`
+}
+
+func Default() AnalyticsConfig {
+	return AnalyticsConfig{Windows: []int{24, 168}}
+}
+
+func (c *AnalyticsConfig) Validate() error {
+	var errs []error
+	for _, window := range c.Windows {
+		if window < 3 {
+			errs = append(errs, fmt.Errorf("window %d must be at least 3 candles", window))
+		}
+	}
+	return errors.Join(errs...)
+}
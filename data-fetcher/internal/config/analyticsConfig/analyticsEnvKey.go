@@ -0,0 +1,7 @@
+package analyticsConfig
+
+type AnalyticsEnvKey string
+
+const (
+	Windows = "ANALYTICS_WINDOWS"
+)
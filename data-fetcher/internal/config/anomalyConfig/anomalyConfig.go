@@ -0,0 +1,56 @@
+package anomalyConfig
+
+import (
+	"errors"
+	"time"
+)
+
+type AnomalyConfig struct {
+	// Enabled false stores candles and passes trades unchecked
+	Enabled bool `yaml:"enabled"`
+	// Window prices per pair the outliers are looked for in, MinSamples prices are needed to look
+	Window     int `yaml:"window"`
+	MinSamples int `yaml:"minSamples"`
+	// MadScore and ZScore thresholds of the modified z-score and of the z-score
+	MadScore float64 `yaml:"madScore"`
+	ZScore   float64 `yaml:"zScore"`
+	// MinDeviation percent from the median below which a price is never an outlier
+	MinDeviation float64 `yaml:"minDeviationPercent"`
+	// StaleAfter a pair without trades for longer is stale, 0 disables the check
+	StaleAfter time.Duration `yaml:"staleAfter"`
+	Interval   time.Duration `yaml:"interval"`
+}
+
+func Default() AnomalyConfig {
+	return AnomalyConfig{
+		Enabled:      true,
+		Window:       100,
+		MinSamples:   20,
+		MadScore:     6,
+		ZScore:       6,
+		MinDeviation: 1,
+		StaleAfter:   5 * time.Minute,
+		Interval:     30 * time.Second,
+	}
+}
+
+func (c *AnomalyConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if c.Window <= 0 || c.MinSamples <= 0 || c.MinSamples > c.Window {
+		errs = append(errs, errors.New("window must be positive and minSamples must be in [1, window]"))
+	}
+	if c.MadScore <= 0 || c.ZScore <= 0 {
+		errs = append(errs, errors.New("madScore and zScore must be positive"))
+	}
+	if c.MinDeviation < 0 || c.StaleAfter < 0 {
+		errs = append(errs, errors.New("minDeviationPercent and staleAfter must not be negative"))
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
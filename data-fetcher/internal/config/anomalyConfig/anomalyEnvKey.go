@@ -0,0 +1,14 @@
+package anomalyConfig
+
+type AnomalyEnvKey string
+
+const (
+	Enabled      = "ANOMALY_ENABLED"
+	Window       = "ANOMALY_WINDOW"
+	MinSamples   = "ANOMALY_MIN_SAMPLES"
+	MadScore     = "ANOMALY_MAD_SCORE"
+	ZScore       = "ANOMALY_Z_SCORE"
+	MinDeviation = "ANOMALY_MIN_DEVIATION_PERCENT"
+	StaleAfter   = "ANOMALY_STALE_AFTER"
+	Interval     = "ANOMALY_INTERVAL"
+)
@@ -1,65 +1,224 @@
 package config
 
 import (
+	"bytes"
+	"cur/internal/config/alertsConfig"
+	"cur/internal/config/analyticsConfig"
+	"cur/internal/config/anomalyConfig"
 	"cur/internal/config/dbConfig"
+	"cur/internal/config/divergenceConfig"
+	"cur/internal/config/grpcConfig"
+	"cur/internal/config/healthConfig"
+	"cur/internal/config/httpConfig"
+	"cur/internal/config/indicatorsConfig"
+	"cur/internal/config/jobsConfig"
 	"cur/internal/config/kafkaConfig"
+	"cur/internal/config/leaderConfig"
 	"cur/internal/config/okxConfig"
+	"cur/internal/config/paperConfig"
+	"cur/internal/config/tracingConfig"
+	"cur/internal/config/tradeFlowConfig"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PathEnvKey environment variable with the path of the config file
+	PathEnvKey  = "CONFIG_FILE"
+	DefaultPath = "config.yaml"
 )
 
+// Config settings of the app by sections of the config file
 type Config struct {
-	okxConfig   *okxConfig.OkxApiConfig
-	dbConfig    *dbConfig.DbConfig
-	kafkaConfig *kafkaConfig.KafkaConfig
+	Okx        okxConfig.OkxApiConfig            `yaml:"okx"`
+	Db         dbConfig.DbConfig                 `yaml:"db"`
+	Kafka      kafkaConfig.KafkaConfig           `yaml:"kafka"`
+	Http       httpConfig.HttpConfig             `yaml:"http"`
+	Grpc       grpcConfig.GrpcConfig             `yaml:"grpc"`
+	Indicators indicatorsConfig.IndicatorsConfig `yaml:"indicators"`
+	Alerts     alertsConfig.AlertsConfig         `yaml:"alerts"`
+	Paper      paperConfig.PaperConfig           `yaml:"paper"`
+	Divergence divergenceConfig.DivergenceConfig `yaml:"divergence"`
+	Anomaly    anomalyConfig.AnomalyConfig       `yaml:"anomaly"`
+	Analytics  analyticsConfig.AnalyticsConfig   `yaml:"analytics"`
+	TradeFlow  tradeFlowConfig.TradeFlowConfig   `yaml:"tradeFlow"`
+	Health     healthConfig.HealthConfig         `yaml:"health"`
+	Tracing    tracingConfig.TracingConfig       `yaml:"tracing"`
+	Leader     leaderConfig.LeaderConfig         `yaml:"leader"`
+	Jobs       jobsConfig.JobsConfig             `yaml:"jobs"`
 }
 
+// NewConfig returns default settings
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		Okx:        okxConfig.Default(),
+		Db:         dbConfig.Default(),
+		Kafka:      kafkaConfig.Default(),
+		Http:       httpConfig.Default(),
+		Grpc:       grpcConfig.Default(),
+		Indicators: indicatorsConfig.Default(),
+		Alerts:     alertsConfig.Default(),
+		Paper:      paperConfig.Default(),
+		Divergence: divergenceConfig.Default(),
+		Anomaly:    anomalyConfig.Default(),
+		Analytics:  analyticsConfig.Default(),
+		TradeFlow:  tradeFlowConfig.Default(),
+		Health:     healthConfig.Default(),
+		Tracing:    tracingConfig.Default(),
+		Leader:     leaderConfig.Default(),
+		Jobs:       jobsConfig.Default(),
+	}
 }
 
-func (c *Config) OkxApiConfig() *okxConfig.OkxApiConfig {
-	if c.okxConfig != nil {
-		return c.okxConfig
+// Load reads the config file over default settings, then set environment variables override them
+// and the result is validated. Empty path is DefaultPath which may be missing.
+// Errors of unreadable files are returned alone, otherwise the config is returned with every invalid setting
+// so sections which are valid can still be used.
+func Load(path string) (*Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(key string) (string, bool)) (*Config, error) {
+	c := NewConfig()
+
+	optional := path == ""
+	if optional {
+		path = DefaultPath
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && optional:
+	case err != nil:
+		return nil, err
+	default:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
 	}
 
-	var err error
-	c.okxConfig, err = okxConfig.GetOkxApiConfig()
+	env := &environment{lookupEnv: lookupEnv}
+	c.override(env)
+
+	return c, errors.Join(env.err(), c.Validate())
+}
 
-	if err != nil {
-		fmt.Errorf("Error getting okxConfig")
+// Validate returns every invalid setting, each error is prefixed with its section
+func (c *Config) Validate() error {
+	sections := []struct {
+		name    string
+		section interface{ Validate() error }
+	}{
+		{"okx", &c.Okx},
+		{"db", &c.Db},
+		{"kafka", &c.Kafka},
+		{"http", &c.Http},
+		{"grpc", &c.Grpc},
+		{"indicators", &c.Indicators},
+		{"alerts", &c.Alerts},
+		{"paper", &c.Paper},
+		{"divergence", &c.Divergence},
+		{"anomaly", &c.Anomaly},
+		{"analytics", &c.Analytics},
+		{"tradeFlow", &c.TradeFlow},
+		{"health", &c.Health},
+		{"tracing", &c.Tracing},
+		{"leader", &c.Leader},
+		{"jobs", &c.Jobs},
 	}
 
-	return c.okxConfig
+	var errs []error
+	for _, s := range sections {
+		for _, err := range Errors(s.section.Validate()) {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func (c *Config) DbConfig() *dbConfig.DbConfig {
-	if c.dbConfig != nil {
-		return c.dbConfig
+// Errors splits joined errors, e.g. of Load, in single ones
+func Errors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
 	}
-	var err error
-	c.dbConfig, err = dbConfig.GetDbConfig()
 
-	if err != nil {
-		fmt.Errorf("Error getting okxConfig")
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, Errors(err)...)
 	}
+	return errs
+}
 
-	return c.dbConfig
+func (c *Config) OkxApiConfig() *okxConfig.OkxApiConfig {
+	return &c.Okx
+}
+
+func (c *Config) DbConfig() *dbConfig.DbConfig {
+	return &c.Db
 }
 
 func (c *Config) KafkaConfig() *kafkaConfig.KafkaConfig {
-	if c.kafkaConfig == nil {
-		var err error
-		c.kafkaConfig, err = kafkaConfig.GetKafkaConfig()
-		if err != nil {
-			fmt.Errorf("Error getting kafkaConfig")
-		}
-	}
+	return &c.Kafka
+}
+
+func (c *Config) HttpConfig() *httpConfig.HttpConfig {
+	return &c.Http
+}
+
+func (c *Config) GrpcConfig() *grpcConfig.GrpcConfig {
+	return &c.Grpc
+}
+
+func (c *Config) IndicatorsConfig() *indicatorsConfig.IndicatorsConfig {
+	return &c.Indicators
+}
+
+func (c *Config) AlertsConfig() *alertsConfig.AlertsConfig {
+	return &c.Alerts
+}
+
+func (c *Config) PaperConfig() *paperConfig.PaperConfig {
+	return &c.Paper
+}
+
+func (c *Config) DivergenceConfig() *divergenceConfig.DivergenceConfig {
+	return &c.Divergence
+}
+
+func (c *Config) AnomalyConfig() *anomalyConfig.AnomalyConfig {
+	return &c.Anomaly
+}
+
+func (c *Config) AnalyticsConfig() *analyticsConfig.AnalyticsConfig {
+	return &c.Analytics
+}
+
+func (c *Config) TradeFlowConfig() *tradeFlowConfig.TradeFlowConfig {
+	return &c.TradeFlow
+}
+
+func (c *Config) HealthConfig() *healthConfig.HealthConfig {
+	return &c.Health
+}
+
+func (c *Config) TracingConfig() *tracingConfig.TracingConfig {
+	return &c.Tracing
+}
 
-	return c.kafkaConfig
+func (c *Config) LeaderConfig() *leaderConfig.LeaderConfig {
+	return &c.Leader
 }
 
-func LoadEnvs() {
-	okxConfig.LoadEnv()
-	dbConfig.LoadEnv()
-	kafkaConfig.LoadEnv()
+func (c *Config) JobsConfig() *jobsConfig.JobsConfig {
+	return &c.Jobs
 }
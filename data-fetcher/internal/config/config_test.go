@@ -0,0 +1,196 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const examplePath = "../../config.example.yaml"
+
+func lookupEnv(vars map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Example(t *testing.T) {
+	c, err := load(examplePath, lookupEnv(nil))
+	require.NoError(t, err, "the example must stay valid")
+
+	assert.Equal(t, []string{"BTC", "ETH", "TON", "SOL", "XRP"}, c.OkxApiConfig().Currencies)
+	assert.Equal(t, 5432, c.DbConfig().Port)
+	assert.Equal(t, []string{"127.0.0.1:9092", "127.0.0.1:9093"}, c.KafkaConfig().Brokers)
+	assert.Equal(t, 10*time.Second, c.DivergenceConfig().Interval)
+	assert.Equal(t, map[string]string{"okx": "0.1", "binance": "0.1"}, c.DivergenceConfig().Fees)
+	assert.Equal(t, 5*time.Minute, c.AnomalyConfig().StaleAfter)
+	assert.Equal(t, []int{24, 168}, c.AnalyticsConfig().Windows)
+	assert.True(t, c.TradeFlowConfig().Enabled)
+	assert.Empty(t, c.TradeFlowConfig().Bars)
+	assert.Equal(t, 3*time.Hour, c.HealthConfig().CandleMaxAge)
+	assert.Equal(t, 1.0, c.TracingConfig().SampleRatio)
+	assert.Equal(t, "0 8,21 * * *", c.JobsConfig().UpdateCurrencies)
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	c, err := load(examplePath, lookupEnv(map[string]string{
+		"CURRENCIES":                  "BTC, ETH",
+		"DB_HOST":                     "127.0.0.1",
+		"DB_PASSWORD":                 `"secret'`,
+		"DB_PORT":                     "15432",
+		"DIVERGENCE_FEES":             "OKX:0.08, binance:0.1",
+		"ANOMALY_ENABLED":             "false",
+		"ANALYTICS_WINDOWS":           "24",
+		"HEALTH_TRADE_MAX_AGE":        "0",
+		"LEADER_ELECTION_ENABLED":     "true",
+		"TRADE_FLOW_BARS":             "1m,1H",
+		"OTEL_TRACES_SAMPLER_ARG":     "0.25",
+		"JOB_UPDATE_CANDLES_SCHEDULE": "*/5 * * * *",
+		"KAFKA_BROKERS":               "",
+		"DIVERGENCE_SOURCES":          "",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"BTC", "ETH"}, c.OkxApiConfig().Currencies)
+	assert.Equal(t, "127.0.0.1", c.DbConfig().Host)
+	assert.Equal(t, 15432, c.DbConfig().Port)
+	assert.Equal(t, `"secret'`, c.DbConfig().Password, "values are raw")
+	assert.Equal(t, "postgres", c.DbConfig().User, "from the file")
+	assert.Equal(t, map[string]string{"okx": "0.08", "binance": "0.1"}, c.DivergenceConfig().Fees)
+	assert.False(t, c.AnomalyConfig().Enabled)
+	assert.Equal(t, []int{24}, c.AnalyticsConfig().Windows)
+	assert.Zero(t, c.HealthConfig().TradeMaxAge)
+	assert.True(t, c.LeaderConfig().Enabled)
+	assert.Equal(t, []string{"1m", "1H"}, c.TradeFlowConfig().Bars)
+	assert.Equal(t, 0.25, c.TracingConfig().SampleRatio)
+	assert.Equal(t, "*/5 * * * *", c.JobsConfig().UpdateCandles)
+	assert.Empty(t, c.KafkaConfig().Brokers, "empty variables clear lists")
+	assert.Empty(t, c.DivergenceConfig().Sources)
+}
+
+func TestLoad_ReportsEveryError(t *testing.T) {
+	path := writeFile(t, `
+okx:
+  apiUri: okx.com
+db:
+  port: 0
+health:
+  checkTimeout: 0s
+jobs:
+  updateCandles: every hour
+`)
+	c, err := load(path, lookupEnv(map[string]string{"ANOMALY_WINDOW": "many", "HEALTH_CHECK_TIMEOUT": "", "LEADER_CHECK_INTERVAL": "5"}))
+	require.Error(t, err)
+	require.NotNil(t, c, "valid sections can be used")
+
+	var messages []string
+	for _, err := range Errors(err) {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		`environment variable ANOMALY_WINDOW: invalid integer "many"`,
+		`environment variable HEALTH_CHECK_TIMEOUT: invalid duration ""`,
+		`environment variable LEADER_CHECK_INTERVAL: invalid duration "5"`,
+		"okx: apiKey is required",
+		"okx: secret is required",
+		"okx: passPhrase is required",
+		`okx: apiUri: "okx.com" must be a http or https url`,
+		"okx: currencies are required",
+		`db: host, user, password and name are required, got host: "" user: "" passwordLength: 0 name: ""`,
+		"db: invalid port 0",
+		"health: checkTimeout must be positive",
+		`jobs: invalid updateCandles schedule "every hour": expected exactly 5 fields, found 2: [every hour]`,
+	}, messages)
+}
+
+func TestLoad_File(t *testing.T) {
+	_, err := load(writeFile(t, "okx:\n  apikey: key\n"), lookupEnv(nil))
+	assert.ErrorContains(t, err, "field apikey not found", "typos aren't ignored")
+
+	_, err = load(writeFile(t, "db:\n  port: [5432]\n"), lookupEnv(nil))
+	assert.Error(t, err)
+
+	_, err = load(filepath.Join(t.TempDir(), "missing.yaml"), lookupEnv(nil))
+	assert.ErrorIs(t, err, os.ErrNotExist, "a missing file which is set explicitly")
+
+	// there is no config.yaml in the package directory, settings come from the environment
+	c, err := load("", lookupEnv(map[string]string{"API_KEY": "key", "SECRET": "secret", "PASSPHRASE": "phrase",
+		"CURRENCIES": "BTC", "DB_HOST": "localhost", "DB_USER": "postgres", "DB_PASSWORD": "postgres", "DB_NAME": "currency"}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://okx.com", c.OkxApiConfig().ApiUri, "default")
+	assert.Equal(t, ":80", c.HttpConfig().Addr, "default")
+
+	c, err = load(writeFile(t, ""), lookupEnv(nil))
+	require.NotNil(t, c, "an empty file keeps defaults")
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid, err := load(examplePath, lookupEnv(nil))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		change func(c *Config)
+		errors []string
+		// parsed schedules, specs and addresses have messages of their parsers, only the beginning is checked
+		prefix string
+	}{
+		{"disabled sections aren't checked", func(c *Config) {
+			c.Anomaly.Enabled, c.Anomaly.Window = false, 0
+			c.Leader.Enabled, c.Leader.Interval = false, 0
+			c.Divergence.Sources, c.Divergence.Interval = nil, 0
+		}, nil, ""},
+		{"telegram needs both token and chat", func(c *Config) { c.Alerts.TelegramBotToken = "token" },
+			[]string{"alerts: telegramBotToken and telegramChatId are set together"}, ""},
+		{"smtp needs recipients", func(c *Config) { c.Alerts.SmtpAddr, c.Alerts.SmtpFrom = "smtp.example.com:25", "bot@example.com" },
+			[]string{"alerts: smtpFrom and smtpTo are required with smtpAddr"}, ""},
+		{"divergence", func(c *Config) {
+			c.Divergence.Sources = []string{"binance", "kraken"}
+			c.Divergence.Fees = map[string]string{"okx": "100", "binance": "x"}
+		}, []string{
+			`divergence: unknown source "kraken"`,
+			`divergence: fee of binance "x" must be in [0, 100) percent`,
+			`divergence: fee of okx "100" must be in [0, 100) percent`,
+		}, ""},
+		{"anomaly samples", func(c *Config) { c.Anomaly.MinSamples = c.Anomaly.Window + 1 },
+			[]string{"anomaly: window must be positive and minSamples must be in [1, window]"}, ""},
+		{"indicators", func(c *Config) { c.Indicators.Indicators = []string{"sma_20", "magic_3"} }, nil, "indicators: "},
+		{"analytics", func(c *Config) { c.Analytics.Windows = []int{2} },
+			[]string{"analytics: window 2 must be at least 3 candles"}, ""},
+		{"kafka", func(c *Config) { c.Kafka.Brokers = []string{"localhost"} }, nil, `kafka: invalid broker "localhost"`},
+		{"tracing", func(c *Config) { c.Tracing.SampleRatio = 2 }, []string{"tracing: sampleRatio 2 must be in [0, 1]"}, ""},
+		{"paper", func(c *Config) { c.Paper.FeePercent = "-1" }, []string{`paper: invalid feePercent "-1"`}, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := *valid
+			c.Divergence.Fees = map[string]string{"okx": "0.1"}
+			testCase.change(&c)
+
+			var messages []string
+			for _, err := range Errors(c.Validate()) {
+				messages = append(messages, err.Error())
+			}
+			if testCase.prefix != "" {
+				require.Len(t, messages, 1)
+				assert.True(t, strings.HasPrefix(messages[0], testCase.prefix), messages[0])
+				return
+			}
+			assert.Equal(t, testCase.errors, messages)
+		})
+	}
+}
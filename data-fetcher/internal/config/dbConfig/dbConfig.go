@@ -1,43 +1,30 @@
 package dbConfig
 
 import (
+	"errors"
 	"fmt"
-	"log"
-
-	"github.com/gofor-little/env"
 )
 
-const ENV_PATH = "env/db.env"
-
 type DbConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DbName   string
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DbName   string `yaml:"name"`
 }
 
-func LoadEnv() {
-	err := env.Load(ENV_PATH)
-	if err != nil {
-		log.Fatal(err)
-	}
+func Default() DbConfig {
+	return DbConfig{Port: 5432}
 }
 
-func GetDbConfig() (*DbConfig, error) {
-	config := DbConfig{
-		env.Get(Host, ""),
-		env.Get(Port, ""),
-		env.Get(User, ""),
-		env.Get(Password, ""),
-		env.Get(DbName, ""),
+func (c *DbConfig) Validate() error {
+	var errs []error
+	if c.Host == "" || c.User == "" || c.Password == "" || c.DbName == "" {
+		errs = append(errs, fmt.Errorf("host, user, password and name are required, got host: %q user: %q passwordLength: %d name: %q",
+			c.Host, c.User, len(c.Password), c.DbName))
 	}
-
-	if config.Host == "" || config.Port == "" || config.User == "" || config.Password == "" || config.DbName == "" {
-		return nil, fmt.Errorf("missing required environment variables host: %v port: %v user: %v passwordLength: %d dbName: %v",
-			config.Host, config.Port, config.User, len(config.Password), config.DbName,
-		)
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", c.Port))
 	}
-
-	return &config, nil
+	return errors.Join(errs...)
 }
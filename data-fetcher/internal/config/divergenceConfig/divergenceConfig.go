@@ -0,0 +1,78 @@
+package divergenceConfig
+
+import (
+	"cur/internal/helper/price"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+)
+
+// KnownSources known reference venues
+var KnownSources = []string{"binance"}
+
+type DivergenceConfig struct {
+	// Sources reference venues, empty disables the monitor
+	Sources    []string      `yaml:"sources"`
+	BinanceUrl string        `yaml:"binanceUrl"`
+	Interval   time.Duration `yaml:"interval"`
+	// ThresholdPercent decimal net spread that fires an event once held for Window, e.g. "0.5"
+	ThresholdPercent string        `yaml:"thresholdPercent"`
+	Window           time.Duration `yaml:"window"`
+	MaxAge           time.Duration `yaml:"maxAge"`
+	// Fees decimal percent taker fees by venue subtracted from the spread, e.g. okx: "0.1"
+	Fees map[string]string `yaml:"fees"`
+}
+
+func Default() DivergenceConfig {
+	return DivergenceConfig{
+		Sources:          []string{"binance"},
+		BinanceUrl:       "https://api.binance.com",
+		Interval:         10 * time.Second,
+		ThresholdPercent: "0.5",
+		Window:           time.Minute,
+		MaxAge:           time.Minute,
+		Fees:             map[string]string{"okx": "0.1", "binance": "0.1"},
+	}
+}
+
+func (c *DivergenceConfig) Validate() error {
+	if len(c.Sources) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, source := range c.Sources {
+		if !slices.Contains(KnownSources, source) {
+			errs = append(errs, fmt.Errorf("unknown source %q", source))
+		}
+	}
+	if slices.Contains(c.Sources, "binance") {
+		if u, err := url.Parse(c.BinanceUrl); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid binanceUrl %q", c.BinanceUrl))
+		}
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be positive"))
+	}
+	if threshold, err := price.ParsePrice(c.ThresholdPercent); err != nil || threshold < 0 {
+		errs = append(errs, fmt.Errorf("invalid thresholdPercent %q", c.ThresholdPercent))
+	}
+	if c.Window < 0 || c.MaxAge <= 0 {
+		errs = append(errs, errors.New("window must not be negative and maxAge must be positive"))
+	}
+
+	venues := make([]string, 0, len(c.Fees))
+	for venue := range c.Fees {
+		venues = append(venues, venue)
+	}
+	sort.Strings(venues)
+	for _, venue := range venues {
+		if fee, err := price.ParsePrice(c.Fees[venue]); err != nil || fee < 0 || fee >= 100*price.PriceFactor {
+			errs = append(errs, fmt.Errorf("fee of %s %q must be in [0, 100) percent", venue, c.Fees[venue]))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,13 @@
+package divergenceConfig
+
+type DivergenceEnvKey string
+
+const (
+	Sources          = "DIVERGENCE_SOURCES"
+	BinanceUrl       = "DIVERGENCE_BINANCE_URL"
+	Interval         = "DIVERGENCE_INTERVAL"
+	ThresholdPercent = "DIVERGENCE_THRESHOLD_PERCENT"
+	Window           = "DIVERGENCE_WINDOW"
+	MaxAge           = "DIVERGENCE_MAX_AGE"
+	Fees             = "DIVERGENCE_FEES"
+)
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"cur/internal/config/alertsConfig"
+	"cur/internal/config/analyticsConfig"
+	"cur/internal/config/anomalyConfig"
+	"cur/internal/config/dbConfig"
+	"cur/internal/config/divergenceConfig"
+	"cur/internal/config/grpcConfig"
+	"cur/internal/config/healthConfig"
+	"cur/internal/config/httpConfig"
+	"cur/internal/config/indicatorsConfig"
+	"cur/internal/config/jobsConfig"
+	"cur/internal/config/kafkaConfig"
+	"cur/internal/config/leaderConfig"
+	"cur/internal/config/okxConfig"
+	"cur/internal/config/paperConfig"
+	"cur/internal/config/tracingConfig"
+	"cur/internal/config/tradeFlowConfig"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// override sets settings from environment variables named by the env keys of sections
+func (c *Config) override(env *environment) {
+	env.string(okxConfig.ApiKey, &c.Okx.ApiKey)
+	env.string(okxConfig.Secret, &c.Okx.Secret)
+	env.string(okxConfig.PassPhrase, &c.Okx.PassPhrase)
+	env.string(okxConfig.ApiUri, &c.Okx.ApiUri)
+	env.string(okxConfig.CandlesPath, &c.Okx.CandlesPath)
+	env.string(okxConfig.TickersPath, &c.Okx.TickersPath)
+	env.string(okxConfig.CurrenciesPath, &c.Okx.CurrenciesPath)
+	env.string(okxConfig.BaseCurrency, &c.Okx.BaseCurrency)
+	env.list(okxConfig.Currencies, &c.Okx.Currencies)
+	env.string(okxConfig.CandlesBar, &c.Okx.CandlesBar)
+	env.string(okxConfig.WssEndpoint, &c.Okx.WssEndpoint)
+
+	env.string(dbConfig.Host, &c.Db.Host)
+	env.int(dbConfig.Port, &c.Db.Port)
+	env.string(dbConfig.User, &c.Db.User)
+	env.string(dbConfig.Password, &c.Db.Password)
+	env.string(dbConfig.DbName, &c.Db.DbName)
+
+	env.list(kafkaConfig.Brokers, &c.Kafka.Brokers)
+	env.string(httpConfig.Addr, &c.Http.Addr)
+	env.string(grpcConfig.Addr, &c.Grpc.Addr)
+	env.list(indicatorsConfig.Indicators, &c.Indicators.Indicators)
+
+	env.string(alertsConfig.WebhookUrl, &c.Alerts.WebhookUrl)
+	env.string(alertsConfig.TelegramApiUrl, &c.Alerts.TelegramApiUrl)
+	env.string(alertsConfig.TelegramBotToken, &c.Alerts.TelegramBotToken)
+	env.string(alertsConfig.TelegramChatId, &c.Alerts.TelegramChatId)
+	env.string(alertsConfig.SmtpAddr, &c.Alerts.SmtpAddr)
+	env.string(alertsConfig.SmtpUser, &c.Alerts.SmtpUser)
+	env.string(alertsConfig.SmtpPassword, &c.Alerts.SmtpPassword)
+	env.string(alertsConfig.SmtpFrom, &c.Alerts.SmtpFrom)
+	env.list(alertsConfig.SmtpTo, &c.Alerts.SmtpTo)
+
+	env.string(paperConfig.FeePercent, &c.Paper.FeePercent)
+
+	env.list(divergenceConfig.Sources, &c.Divergence.Sources)
+	env.string(divergenceConfig.BinanceUrl, &c.Divergence.BinanceUrl)
+	env.duration(divergenceConfig.Interval, &c.Divergence.Interval)
+	env.string(divergenceConfig.ThresholdPercent, &c.Divergence.ThresholdPercent)
+	env.duration(divergenceConfig.Window, &c.Divergence.Window)
+	env.duration(divergenceConfig.MaxAge, &c.Divergence.MaxAge)
+	env.fees(divergenceConfig.Fees, &c.Divergence.Fees)
+
+	env.bool(anomalyConfig.Enabled, &c.Anomaly.Enabled)
+	env.int(anomalyConfig.Window, &c.Anomaly.Window)
+	env.int(anomalyConfig.MinSamples, &c.Anomaly.MinSamples)
+	env.float(anomalyConfig.MadScore, &c.Anomaly.MadScore)
+	env.float(anomalyConfig.ZScore, &c.Anomaly.ZScore)
+	env.float(anomalyConfig.MinDeviation, &c.Anomaly.MinDeviation)
+	env.duration(anomalyConfig.StaleAfter, &c.Anomaly.StaleAfter)
+	env.duration(anomalyConfig.Interval, &c.Anomaly.Interval)
+
+	env.ints(analyticsConfig.Windows, &c.Analytics.Windows)
+
+	env.bool(tradeFlowConfig.Enabled, &c.TradeFlow.Enabled)
+	env.list(tradeFlowConfig.Bars, &c.TradeFlow.Bars)
+	env.duration(tradeFlowConfig.FlushInterval, &c.TradeFlow.FlushInterval)
+
+	env.duration(healthConfig.TradeMaxAge, &c.Health.TradeMaxAge)
+	env.duration(healthConfig.CandleMaxAge, &c.Health.CandleMaxAge)
+	env.duration(healthConfig.CheckTimeout, &c.Health.CheckTimeout)
+
+	env.string(tracingConfig.Endpoint, &c.Tracing.Endpoint)
+	env.string(tracingConfig.ServiceName, &c.Tracing.ServiceName)
+	env.float(tracingConfig.SampleRatio, &c.Tracing.SampleRatio)
+
+	env.bool(leaderConfig.Enabled, &c.Leader.Enabled)
+	env.string(leaderConfig.LockName, &c.Leader.LockName)
+	env.duration(leaderConfig.Interval, &c.Leader.Interval)
+
+	env.string(jobsConfig.UpdateCurrencies, &c.Jobs.UpdateCurrencies)
+	env.string(jobsConfig.UpdateCandles, &c.Jobs.UpdateCandles)
+	env.bool(jobsConfig.CatchUp, &c.Jobs.CatchUp)
+}
+
+// environment parses raw values of variables into settings, unset ones are skipped, invalid ones are collected.
+// Empty variables clear strings and lists and are invalid numbers, booleans and durations.
+type environment struct {
+	lookupEnv func(key string) (string, bool)
+	errs      []error
+}
+
+func (e *environment) err() error {
+	return errors.Join(e.errs...)
+}
+
+func (e *environment) get(key string) (string, bool) {
+	return e.lookupEnv(key)
+}
+
+func (e *environment) invalid(key, value, kind string) {
+	e.errs = append(e.errs, fmt.Errorf("environment variable %s: invalid %s %q", key, kind, value))
+}
+
+func (e *environment) string(key string, target *string) {
+	if value, ok := e.get(key); ok {
+		*target = value
+	}
+}
+
+func (e *environment) bool(key string, target *bool) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.invalid(key, value, "boolean")
+		return
+	}
+	*target = parsed
+}
+
+func (e *environment) int(key string, target *int) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.invalid(key, value, "integer")
+		return
+	}
+	*target = parsed
+}
+
+func (e *environment) float(key string, target *float64) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.invalid(key, value, "number")
+		return
+	}
+	*target = parsed
+}
+
+func (e *environment) duration(key string, target *time.Duration) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.invalid(key, value, "duration")
+		return
+	}
+	*target = parsed
+}
+
+// list parses comma separated values, e.g. "BTC,ETH"
+func (e *environment) list(key string, target *[]string) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	*target = split(value)
+}
+
+func (e *environment) ints(key string, target *[]int) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	var parsed []int
+	for _, item := range split(value) {
+		number, err := strconv.Atoi(item)
+		if err != nil {
+			e.invalid(key, item, "integer")
+			return
+		}
+		parsed = append(parsed, number)
+	}
+	*target = parsed
+}
+
+// fees parses comma separated venue:percent pairs, e.g. "okx:0.1,binance:0.1"
+func (e *environment) fees(key string, target *map[string]string) {
+	value, ok := e.get(key)
+	if !ok {
+		return
+	}
+	parsed := make(map[string]string)
+	for _, item := range split(value) {
+		venue, percent, ok := strings.Cut(item, ":")
+		if !ok {
+			e.invalid(key, item, "venue:percent fee")
+			return
+		}
+		parsed[strings.ToLower(strings.TrimSpace(venue))] = strings.TrimSpace(percent)
+	}
+	*target = parsed
+}
+
+func split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
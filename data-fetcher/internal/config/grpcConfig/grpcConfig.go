@@ -0,0 +1,23 @@
+package grpcConfig
+
+import (
+	"fmt"
+	"net"
+)
+
+const DefaultAddr = ":9090"
+
+type GrpcConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+func Default() GrpcConfig {
+	return GrpcConfig{Addr: DefaultAddr}
+}
+
+func (c *GrpcConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("invalid addr %q: %w", c.Addr, err)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package grpcConfig
+
+type GrpcEnvKey string
+
+const (
+	Addr = "GRPC_ADDR"
+)
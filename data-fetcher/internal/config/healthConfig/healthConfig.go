@@ -0,0 +1,33 @@
+package healthConfig
+
+import (
+	"errors"
+	"time"
+)
+
+type HealthConfig struct {
+	// TradeMaxAge and CandleMaxAge a pair with older latest trade or candle isn't ready, 0 disables the check
+	TradeMaxAge  time.Duration `yaml:"tradeMaxAge"`
+	CandleMaxAge time.Duration `yaml:"candleMaxAge"`
+	// CheckTimeout each dependency check is given
+	CheckTimeout time.Duration `yaml:"checkTimeout"`
+}
+
+func Default() HealthConfig {
+	return HealthConfig{
+		TradeMaxAge:  2 * time.Minute,
+		CandleMaxAge: 3 * time.Hour,
+		CheckTimeout: 2 * time.Second,
+	}
+}
+
+func (c *HealthConfig) Validate() error {
+	var errs []error
+	if c.TradeMaxAge < 0 || c.CandleMaxAge < 0 {
+		errs = append(errs, errors.New("tradeMaxAge and candleMaxAge must not be negative"))
+	}
+	if c.CheckTimeout <= 0 {
+		errs = append(errs, errors.New("checkTimeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,9 @@
+package healthConfig
+
+type HealthEnvKey string
+
+const (
+	TradeMaxAge  = "HEALTH_TRADE_MAX_AGE"
+	CandleMaxAge = "HEALTH_CANDLE_MAX_AGE"
+	CheckTimeout = "HEALTH_CHECK_TIMEOUT"
+)
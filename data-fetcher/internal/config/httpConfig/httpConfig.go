@@ -0,0 +1,23 @@
+package httpConfig
+
+import (
+	"fmt"
+	"net"
+)
+
+const DefaultAddr = ":80"
+
+type HttpConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+func Default() HttpConfig {
+	return HttpConfig{Addr: DefaultAddr}
+}
+
+func (c *HttpConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("invalid addr %q: %w", c.Addr, err)
+	}
+	return nil
+}
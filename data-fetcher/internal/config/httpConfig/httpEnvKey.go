@@ -0,0 +1,7 @@
+package httpConfig
+
+type HttpEnvKey string
+
+const (
+	Addr = "HTTP_ADDR"
+)
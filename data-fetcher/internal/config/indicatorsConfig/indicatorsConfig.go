@@ -0,0 +1,20 @@
+package indicatorsConfig
+
+import (
+	"cur/internal/indicators"
+	"strings"
+)
+
+type IndicatorsConfig struct {
+	// Indicators specs precomputed for every pair, e.g. sma_20 or macd_12_26_9, empty disables precomputation
+	Indicators []string `yaml:"indicators"`
+}
+
+func Default() IndicatorsConfig {
+	return IndicatorsConfig{Indicators: []string{"sma_20", "sma_50", "ema_20", "ema_50", "rsi_14", "macd_12_26_9", "bollinger_20_2", "atr_14"}}
+}
+
+func (c *IndicatorsConfig) Validate() error {
+	_, err := indicators.ParseSpecs(strings.Join(c.Indicators, ","))
+	return err
+}
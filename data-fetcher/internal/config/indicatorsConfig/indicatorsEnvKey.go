@@ -0,0 +1,7 @@
+package indicatorsConfig
+
+type IndicatorsEnvKey string
+
+const (
+	Indicators = "INDICATORS"
+)
@@ -0,0 +1,31 @@
+package jobsConfig
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+)
+
+type JobsConfig struct {
+	// UpdateCurrencies cron schedule of fetching currencies
+	UpdateCurrencies string `yaml:"updateCurrencies"`
+	// UpdateCandles cron schedule of fetching new candles and computing indicators and analytics of them
+	UpdateCandles string `yaml:"updateCandles"`
+	// CatchUp runs jobs once at start if their scheduled run was missed while the app was down
+	CatchUp bool `yaml:"catchUp"`
+}
+
+func Default() JobsConfig {
+	return JobsConfig{UpdateCurrencies: "0 8,21 * * *", UpdateCandles: "0 * * * *", CatchUp: true}
+}
+
+func (c *JobsConfig) Validate() error {
+	var errs []error
+	for _, schedule := range [][2]string{{"updateCurrencies", c.UpdateCurrencies}, {"updateCandles", c.UpdateCandles}} {
+		if _, err := cron.ParseStandard(schedule[1]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s schedule %q: %w", schedule[0], schedule[1], err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,9 @@
+package jobsConfig
+
+type JobsEnvKey string
+
+const (
+	UpdateCurrencies = "JOB_UPDATE_CURRENCIES_SCHEDULE"
+	UpdateCandles    = "JOB_UPDATE_CANDLES_SCHEDULE"
+	CatchUp          = "JOBS_CATCH_UP"
+)
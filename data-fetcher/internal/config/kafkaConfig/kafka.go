@@ -1,40 +1,26 @@
 package kafkaConfig
 
 import (
-	"log"
-	"strings"
-
-	"github.com/gofor-little/env"
+	"errors"
+	"fmt"
+	"net"
 )
 
-const ENV_PATH = "env/kafka.env"
-
 type KafkaConfig struct {
-	Brokers []string
+	// Brokers host:port addresses, events aren't published without them
+	Brokers []string `yaml:"brokers"`
 }
 
-func LoadEnv() {
-	err := env.Load(ENV_PATH)
-	if err != nil {
-		log.Fatal(err)
-	}
+func Default() KafkaConfig {
+	return KafkaConfig{}
 }
 
-func GetKafkaConfig() (*KafkaConfig, error) {
-
-	brokersString := strings.Trim(env.Get(Brokers, ""), "\n'")
-
-	return &KafkaConfig{
-		parseBrokers(brokersString),
-	}, nil
-}
-
-func parseBrokers(brokersString string) []string {
-	b := strings.Split(brokersString, ",")
-
-	if len(b) == 0 {
-		log.Fatalf("Invalid brokers: %s", brokersString)
+func (c *KafkaConfig) Validate() error {
+	var errs []error
+	for _, broker := range c.Brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			errs = append(errs, fmt.Errorf("invalid broker %q: %w", broker, err))
+		}
 	}
-
-	return b
+	return errors.Join(errs...)
 }
@@ -0,0 +1,34 @@
+package leaderConfig
+
+import (
+	"errors"
+	"time"
+)
+
+type LeaderConfig struct {
+	// Enabled runs scheduled tasks and the trade websocket only in the instance holding the lock, otherwise every instance runs them
+	Enabled bool `yaml:"enabled"`
+	// LockName instances with the same name elect one leader
+	LockName string `yaml:"lockName"`
+	// Interval between attempts to take the lock and checks it's still held
+	Interval time.Duration `yaml:"interval"`
+}
+
+func Default() LeaderConfig {
+	return LeaderConfig{LockName: "data-fetcher", Interval: 5 * time.Second}
+}
+
+func (c *LeaderConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if c.LockName == "" {
+		errs = append(errs, errors.New("lockName is required"))
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,9 @@
+package leaderConfig
+
+type LeaderEnvKey string
+
+const (
+	Enabled  = "LEADER_ELECTION_ENABLED"
+	LockName = "LEADER_LOCK_NAME"
+	Interval = "LEADER_CHECK_INTERVAL"
+)
@@ -1,56 +1,77 @@
 package okxConfig
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"net/url"
 	"strings"
-
-	"github.com/gofor-little/env"
 )
 
-const API_ENV_PATH = "env/okx.env"
-
 type OkxApiConfig struct {
-	ApiKey         string
-	Secret         string
-	PassPhrase     string
-	ApiUri         string
-	CandlesPath    string
-	TickersPath    string
-	CurrenciesPath string
-	BaseCurrency   string
-	Currencies     []string
-	CandlesBar     string
-	WssEndpoint    string
+	ApiKey         string   `yaml:"apiKey"`
+	Secret         string   `yaml:"secret"`
+	PassPhrase     string   `yaml:"passPhrase"`
+	ApiUri         string   `yaml:"apiUri"`
+	CandlesPath    string   `yaml:"candlesPath"`
+	TickersPath    string   `yaml:"tickersPath"`
+	CurrenciesPath string   `yaml:"currenciesPath"`
+	BaseCurrency   string   `yaml:"baseCurrency"`
+	Currencies     []string `yaml:"currencies"`
+	CandlesBar     string   `yaml:"candlesBar"`
+	WssEndpoint    string   `yaml:"wssEndpoint"`
 }
 
-func LoadEnv() {
-	err := env.Load(API_ENV_PATH)
-
-	if err != nil {
-		log.Fatal(err)
+// Default public OKX endpoints, credentials and currencies have no defaults
+func Default() OkxApiConfig {
+	return OkxApiConfig{
+		ApiUri:         "https://okx.com",
+		CandlesPath:    "/api/v5/market/history-candles",
+		TickersPath:    "/api/v5/market/tickers?instType=SPOT",
+		CurrenciesPath: "/api/v5/asset/currencies",
+		BaseCurrency:   "USDT",
+		CandlesBar:     "1H",
+		WssEndpoint:    "wss://ws.okx.com:8443/ws/v5/public",
 	}
 }
 
-func GetOkxApiConfig() (*OkxApiConfig, error) {
-
-	config := OkxApiConfig{
-		ApiKey:         env.Get(ApiKey, ""),
-		Secret:         env.Get(Secret, ""),
-		PassPhrase:     env.Get(PassPhrase, ""),
-		ApiUri:         strings.Trim(env.Get(ApiUri, ""), "'\""),
-		CandlesPath:    strings.Trim(env.Get(CandlesPath, ""), "'\""),
-		TickersPath:    strings.Trim(env.Get(TickersPath, ""), "'\""),
-		CurrenciesPath: strings.Trim(env.Get(CurrenciesPath, ""), "'\""),
-		BaseCurrency:   strings.Trim(env.Get(BaseCurrency, ""), "'\""),
-		Currencies:     strings.Split(strings.Trim(env.Get(Currencies, ""), "[]'\" "), ","),
-		CandlesBar:     strings.Trim(env.Get(CandlesBar, ""), "'\""),
-		WssEndpoint:    strings.Trim(env.Get(WssEndpoint, ""), "'\""),
+func (c *OkxApiConfig) Validate() error {
+	var errs []error
+	for _, setting := range [][2]string{{"apiKey", c.ApiKey}, {"secret", c.Secret}, {"passPhrase", c.PassPhrase}, {"baseCurrency", c.BaseCurrency}, {"candlesBar", c.CandlesBar}} {
+		if setting[1] == "" {
+			errs = append(errs, fmt.Errorf("%s is required", setting[0]))
+		}
 	}
-
-	if config.ApiKey == "" || config.Secret == "" || config.PassPhrase == "" {
-		return nil, fmt.Errorf("missing required environment variables %v", config)
+	for _, setting := range [][2]string{{"candlesPath", c.CandlesPath}, {"tickersPath", c.TickersPath}, {"currenciesPath", c.CurrenciesPath}} {
+		if !strings.HasPrefix(setting[1], "/") {
+			errs = append(errs, fmt.Errorf("%s %q must start with /", setting[0], setting[1]))
+		}
+	}
+	if err := checkUrl(c.ApiUri, "http", "https"); err != nil {
+		errs = append(errs, fmt.Errorf("apiUri: %w", err))
 	}
+	if err := checkUrl(c.WssEndpoint, "ws", "wss"); err != nil {
+		errs = append(errs, fmt.Errorf("wssEndpoint: %w", err))
+	}
+	if len(c.Currencies) == 0 {
+		errs = append(errs, errors.New("currencies are required"))
+	}
+	for _, currency := range c.Currencies {
+		if currency == "" || strings.Contains(currency, "-") {
+			errs = append(errs, fmt.Errorf("invalid currency %q", currency))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	return &config, nil
+func checkUrl(value string, schemes ...string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must be a %s url", value, strings.Join(schemes, " or "))
 }
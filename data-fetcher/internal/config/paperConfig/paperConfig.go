@@ -0,0 +1,22 @@
+package paperConfig
+
+import (
+	"cur/internal/helper/price"
+	"fmt"
+)
+
+type PaperConfig struct {
+	// FeePercent decimal percent of the notional charged on every paper fill, e.g. "0.1"
+	FeePercent string `yaml:"feePercent"`
+}
+
+func Default() PaperConfig {
+	return PaperConfig{FeePercent: "0.1"}
+}
+
+func (c *PaperConfig) Validate() error {
+	if fee, err := price.ParsePrice(c.FeePercent); err != nil || fee < 0 {
+		return fmt.Errorf("invalid feePercent %q", c.FeePercent)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+package paperConfig
+
+type PaperEnvKey string
+
+const (
+	FeePercent = "PAPER_FEE_PERCENT"
+)
@@ -0,0 +1,35 @@
+package tracingConfig
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+type TracingConfig struct {
+	// Endpoint OTLP/HTTP collector url, e.g. "http://localhost:4318", empty disables exporting spans
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"serviceName"`
+	// SampleRatio share of traces in [0, 1] which are recorded
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+func Default() TracingConfig {
+	return TracingConfig{ServiceName: "data-fetcher", SampleRatio: 1}
+}
+
+func (c *TracingConfig) Validate() error {
+	var errs []error
+	if c.Endpoint != "" {
+		if u, err := url.Parse(c.Endpoint); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid endpoint %q", c.Endpoint))
+		}
+	}
+	if c.ServiceName == "" {
+		errs = append(errs, errors.New("serviceName is required"))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("sampleRatio %v must be in [0, 1]", c.SampleRatio))
+	}
+	return errors.Join(errs...)
+}
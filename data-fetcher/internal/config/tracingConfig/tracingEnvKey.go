@@ -0,0 +1,9 @@
+package tracingConfig
+
+type TracingEnvKey string
+
+const (
+	Endpoint    = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	ServiceName = "OTEL_SERVICE_NAME"
+	SampleRatio = "OTEL_TRACES_SAMPLER_ARG"
+)
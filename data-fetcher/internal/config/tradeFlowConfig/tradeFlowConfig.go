@@ -0,0 +1,26 @@
+package tradeFlowConfig
+
+import (
+	"errors"
+	"time"
+)
+
+type TradeFlowConfig struct {
+	// Enabled false doesn't aggregate trades
+	Enabled bool `yaml:"enabled"`
+	// Bars trades are aggregated in, e.g. 1m and 1H, empty means the bar of candles
+	Bars []string `yaml:"bars"`
+	// FlushInterval between writes of aggregated trades
+	FlushInterval time.Duration `yaml:"flushInterval"`
+}
+
+func Default() TradeFlowConfig {
+	return TradeFlowConfig{Enabled: true, FlushInterval: 10 * time.Second}
+}
+
+func (c *TradeFlowConfig) Validate() error {
+	if c.Enabled && c.FlushInterval <= 0 {
+		return errors.New("flushInterval must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package tradeFlowConfig
+
+type TradeFlowEnvKey string
+
+const (
+	Enabled       = "TRADE_FLOW_ENABLED"
+	Bars          = "TRADE_FLOW_BARS"
+	FlushInterval = "TRADE_FLOW_FLUSH_INTERVAL"
+)
@@ -0,0 +1,45 @@
+package grpcapi
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	marketdatav1 "cur/pkg/marketdata/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toCandle(c model.Candle) *marketdatav1.Candle {
+	return &marketdatav1.Candle{
+		Pair:      c.Pair,
+		Bar:       c.Bar,
+		Timestamp: timestamppb.New(c.Timestamp),
+		Open:      price.Price{Price: c.Open}.String(),
+		High:      price.Price{Price: c.High}.String(),
+		Low:       price.Price{Price: c.Low}.String(),
+		Close:     price.Price{Price: c.Close}.String(),
+		Volume:    price.Price{Price: c.Volume}.String(),
+	}
+}
+
+func toTicker(t model.Ticker) *marketdatav1.Ticker {
+	return &marketdatav1.Ticker{
+		Pair:       t.Pair,
+		Last:       price.Price{Price: t.Last}.String(),
+		Open_24H:   price.Price{Price: t.Open24h}.String(),
+		High_24H:   price.Price{Price: t.High24h}.String(),
+		Low_24H:    price.Price{Price: t.Low24h}.String(),
+		Volume_24H: price.Price{Price: t.Volume24h}.String(),
+		Timestamp:  timestamppb.New(t.Timestamp),
+	}
+}
+
+func toTrade(t model.Trade) *marketdatav1.Trade {
+	return &marketdatav1.Trade{
+		Pair:      t.Pair,
+		TradeId:   t.TradeId,
+		Price:     price.Price{Price: t.Price}.String(),
+		Size:      price.Price{Price: t.Size}.String(),
+		Side:      t.Side,
+		Timestamp: timestamppb.New(t.Timestamp),
+	}
+}
@@ -0,0 +1,109 @@
+package grpcapi
+
+import (
+	"cur/internal/model"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type tradeSubscription struct {
+	pairs  map[string]bool
+	trades chan model.Trade
+	done   chan struct{}
+	slow   bool // set before done is closed
+}
+
+func (sub *tradeSubscription) isDone() bool {
+	select {
+	case <-sub.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// err is the status a stream ends with once the subscription is done
+func (sub *tradeSubscription) err() error {
+	if sub.slow {
+		return status.Error(codes.ResourceExhausted, "slow consumer")
+	}
+	return status.Error(codes.Unavailable, "server is shutting down")
+}
+
+// tradeFeed fans out trades to streams, a subscription with full buffer is dropped
+type tradeFeed struct {
+	bufferSize int
+
+	mu     sync.Mutex
+	subs   map[*tradeSubscription]struct{}
+	closed bool
+}
+
+func newTradeFeed(bufferSize int) *tradeFeed {
+	return &tradeFeed{bufferSize: bufferSize, subs: make(map[*tradeSubscription]struct{})}
+}
+
+func (f *tradeFeed) subscribe(pairs []string) *tradeSubscription {
+	sub := &tradeSubscription{
+		pairs:  make(map[string]bool, len(pairs)),
+		trades: make(chan model.Trade, f.bufferSize),
+		done:   make(chan struct{}),
+	}
+	for _, pair := range pairs {
+		sub.pairs[pair] = true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		close(sub.done)
+		return sub
+	}
+	f.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (f *tradeFeed) unsubscribe(sub *tradeSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remove(sub)
+}
+
+func (f *tradeFeed) publish(trade model.Trade) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for sub := range f.subs {
+		if len(sub.pairs) > 0 && !sub.pairs[trade.Pair] {
+			continue
+		}
+		select {
+		case sub.trades <- trade:
+		default:
+			sub.slow = true
+			f.remove(sub)
+		}
+	}
+}
+
+func (f *tradeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for sub := range f.subs {
+		f.remove(sub)
+	}
+}
+
+// remove must be called under lock
+func (f *tradeFeed) remove(sub *tradeSubscription) {
+	if _, ok := f.subs[sub]; !ok {
+		return
+	}
+	delete(f.subs, sub)
+	close(sub.done)
+}
@@ -0,0 +1,200 @@
+// Package grpcapi serves stored and live market data over gRPC
+package grpcapi
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	marketdatav1 "cur/pkg/marketdata/v1"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultCandlesLimit = 500
+	MaxCandlesLimit     = 1000
+	DefaultStreamBuffer = 256
+)
+
+type Server struct {
+	marketdatav1.UnimplementedMarketDataServiceServer
+
+	currencyRepository store.CurrencyStore
+	candleRepository   store.CandleStore
+	tickerRepository   store.TickerStore
+	defaultBar         string
+	trades             *tradeFeed
+	leading            func() bool
+	log                *log.Logger
+}
+
+func NewServer(
+	currencyRepository store.CurrencyStore,
+	candleRepository store.CandleStore,
+	tickerRepository store.TickerStore,
+	defaultBar string,
+	log *log.Logger,
+) *Server {
+	return &Server{
+		currencyRepository: currencyRepository,
+		candleRepository:   candleRepository,
+		tickerRepository:   tickerRepository,
+		defaultBar:         defaultBar,
+		trades:             newTradeFeed(DefaultStreamBuffer),
+		log:                log,
+	}
+}
+
+// GrpcServer makes a grpc server serving the market data service with reflection enabled
+func (s *Server) GrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	marketdatav1.RegisterMarketDataServiceServer(server, s)
+	reflection.Register(server)
+	return server
+}
+
+// Trade is the trade listener feeding StreamTrades
+func (s *Server) Trade(trade model.Trade) {
+	s.trades.publish(trade)
+}
+
+// SetLeading makes GetTicker and StreamTrades fail with Unavailable while leading returns false,
+// only the leader receives the trade WebSocket
+func (s *Server) SetLeading(leading func() bool) {
+	s.leading = leading
+}
+
+func (s *Server) checkLeading() error {
+	if s.leading != nil && !s.leading() {
+		return status.Error(codes.Unavailable, "live data is served by the leader instance")
+	}
+	return nil
+}
+
+// Close ends running trade streams, a grpc server can't stop gracefully while they are open
+func (s *Server) Close() {
+	s.trades.close()
+}
+
+func (s *Server) GetCandles(ctx context.Context, req *marketdatav1.GetCandlesRequest) (*marketdatav1.GetCandlesResponse, error) {
+	if req.GetPair() == "" {
+		return nil, status.Error(codes.InvalidArgument, "pair is required")
+	}
+
+	bar := req.GetBar()
+	if bar == "" {
+		bar = s.defaultBar
+	}
+
+	query := store.CandleQuery{Pair: req.GetPair(), Bar: bar}
+	if req.GetFrom() != nil {
+		query.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		query.To = req.GetTo().AsTime()
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, status.Error(codes.InvalidArgument, "from must be earlier than to")
+	}
+
+	limit := int(req.GetLimit())
+	switch {
+	case limit < 0 || limit > MaxCandlesLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", MaxCandlesLimit)
+	case limit == 0:
+		limit = DefaultCandlesLimit
+	}
+
+	page, err := s.candleRepository.FetchPage(query, req.GetCursor(), limit)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+	if err != nil {
+		return nil, s.internalError(err)
+	}
+
+	resp := &marketdatav1.GetCandlesResponse{
+		Candles:    make([]*marketdatav1.Candle, 0, len(page.Candles)),
+		NextCursor: page.Next,
+	}
+	for _, c := range page.Candles {
+		resp.Candles = append(resp.Candles, toCandle(c))
+	}
+
+	return resp, nil
+}
+
+func (s *Server) ListCurrencies(ctx context.Context, req *marketdatav1.ListCurrenciesRequest) (*marketdatav1.ListCurrenciesResponse, error) {
+	currencies, err := s.currencyRepository.FetchAll()
+	if err != nil {
+		return nil, s.internalError(err)
+	}
+
+	resp := &marketdatav1.ListCurrenciesResponse{Currencies: make([]*marketdatav1.Currency, 0, len(currencies))}
+	for _, c := range currencies {
+		resp.Currencies = append(resp.Currencies, &marketdatav1.Currency{
+			Code:        c.Code,
+			Chain:       c.Chain,
+			CanDeposit:  c.CanDeposit,
+			CanWithdraw: c.CanWithdraw,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *Server) GetTicker(ctx context.Context, req *marketdatav1.GetTickerRequest) (*marketdatav1.GetTickerResponse, error) {
+	if req.GetPair() == "" {
+		return nil, status.Error(codes.InvalidArgument, "pair is required")
+	}
+	if err := s.checkLeading(); err != nil {
+		return nil, err
+	}
+
+	ticker, err := s.tickerRepository.Get(req.GetPair())
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "no ticker for %s", req.GetPair())
+	}
+	if err != nil {
+		return nil, s.internalError(err)
+	}
+
+	return &marketdatav1.GetTickerResponse{Ticker: toTicker(ticker)}, nil
+}
+
+// StreamTrades sends live trades until the client cancels, a client which doesn't keep up gets ResourceExhausted
+func (s *Server) StreamTrades(req *marketdatav1.StreamTradesRequest, stream grpc.ServerStreamingServer[marketdatav1.StreamTradesResponse]) error {
+	if err := s.checkLeading(); err != nil {
+		return err
+	}
+
+	sub := s.trades.subscribe(req.GetPairs())
+	defer s.trades.unsubscribe(sub)
+
+	for {
+		select {
+		case trade := <-sub.trades:
+			// select picks a ready case at random, trades buffered before the subscription is dropped aren't sent
+			if sub.isDone() {
+				return sub.err()
+			}
+			if err := stream.Send(&marketdatav1.StreamTradesResponse{Trade: toTrade(trade)}); err != nil {
+				return err
+			}
+		case <-sub.done:
+			return sub.err()
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *Server) internalError(err error) error {
+	s.log.Error(err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,273 @@
+package grpcapi
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/service/okx/response"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	marketdatav1 "cur/pkg/marketdata/v1"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type testEnv struct {
+	storage *memory.Store
+	tickers *store.TickerRepository
+	server  *Server
+	client  *marketdatav1.Client
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{storage: memory.NewStore(), tickers: store.NewTickerRepository()}
+	env.server = NewServer(env.storage.Currency(), env.storage.Candle(), env.tickers, "1H", log.New())
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := env.server.GrpcServer()
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(func() {
+		env.server.Close()
+		grpcServer.GracefulStop()
+	})
+
+	client, err := marketdatav1.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	env.client = client
+
+	return env
+}
+
+func TestServer_GetCandles(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []model.Candle{
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Open: 9733870000000, High: 9789330000000, Low: 9568000000000, Close: 9688870000000, Volume: 370688063172},
+		storetest.Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+		storetest.Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 3),
+		storetest.Candle("BTC-USDT", "1D", start, 4),
+	}
+	require.NoError(t, env.storage.Candle().InsertCandles(&candles))
+
+	resp, err := env.client.GetCandles(ctx, &marketdatav1.GetCandlesRequest{Pair: "BTC-USDT", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 2)
+	first := resp.Candles[0]
+	assert.Equal(t, "1H", first.Bar)
+	assert.Equal(t, start, first.Timestamp.AsTime())
+	assert.Equal(t, "97338.7", first.Open)
+	assert.Equal(t, "3706.88063172", first.Volume)
+	require.NotEmpty(t, resp.NextCursor)
+
+	resp, err = env.client.GetCandles(ctx, &marketdatav1.GetCandlesRequest{Pair: "BTC-USDT", Limit: 2, Cursor: resp.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 1)
+	assert.Equal(t, start.Add(2*time.Hour), resp.Candles[0].Timestamp.AsTime())
+	assert.Empty(t, resp.NextCursor)
+
+	resp, err = env.client.GetCandles(ctx, &marketdatav1.GetCandlesRequest{
+		Pair: "BTC-USDT",
+		Bar:  "1H",
+		From: timestamppb.New(start.Add(time.Hour)),
+		To:   timestamppb.New(start.Add(2 * time.Hour)),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 1)
+	assert.Equal(t, start.Add(time.Hour), resp.Candles[0].Timestamp.AsTime())
+}
+
+func TestServer_GetCandlesInvalidArguments(t *testing.T) {
+	env := newTestEnv(t)
+	ts := timestamppb.New(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	for name, req := range map[string]*marketdatav1.GetCandlesRequest{
+		"no pair":        {},
+		"empty range":    {Pair: "BTC-USDT", From: ts, To: ts},
+		"limit too big":  {Pair: "BTC-USDT", Limit: MaxCandlesLimit + 1},
+		"invalid cursor": {Pair: "BTC-USDT", Cursor: "???"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := env.client.GetCandles(context.Background(), req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestServer_ListCurrencies(t *testing.T) {
+	env := newTestEnv(t)
+	require.NoError(t, env.storage.Currency().InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+		{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: true, CanWd: true},
+		{Ccy: "USDT", Chain: "USDT-TRC20", CanDep: true},
+	}))
+
+	resp, err := env.client.ListCurrencies(context.Background(), &marketdatav1.ListCurrenciesRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Currencies, 2)
+	assert.Equal(t, "BTC", resp.Currencies[0].Code)
+	assert.Equal(t, "USDT-TRC20", resp.Currencies[1].Chain)
+	assert.False(t, resp.Currencies[1].CanWithdraw)
+}
+
+func TestServer_GetTicker(t *testing.T) {
+	env := newTestEnv(t)
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	env.tickers.Set(model.Ticker{Pair: "BTC-USDT", Last: 9700010000000, Volume24h: 150_000_000, Timestamp: ts})
+
+	resp, err := env.client.GetTicker(context.Background(), &marketdatav1.GetTickerRequest{Pair: "BTC-USDT"})
+	require.NoError(t, err)
+	assert.Equal(t, "97000.1", resp.Ticker.Last)
+	assert.Equal(t, "1.5", resp.Ticker.Volume_24H)
+	assert.Equal(t, ts, resp.Ticker.Timestamp.AsTime())
+
+	_, err = env.client.GetTicker(context.Background(), &marketdatav1.GetTickerRequest{Pair: "ETH-USDT"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func waitForStreams(t *testing.T, s *Server, n int) {
+	require.Eventually(t, func() bool {
+		s.trades.mu.Lock()
+		defer s.trades.mu.Unlock()
+		return len(s.trades.subs) == n
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestServer_StreamTrades(t *testing.T) {
+	env := newTestEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := env.client.StreamTrades(ctx, &marketdatav1.StreamTradesRequest{Pairs: []string{"BTC-USDT"}})
+	require.NoError(t, err)
+	waitForStreams(t, env.server, 1)
+
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	env.server.Trade(model.Trade{Pair: "ETH-USDT", TradeId: "1", Timestamp: ts})
+	env.server.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "2", Price: 9700010000000, Size: 50_000_000, Side: "sell", Timestamp: ts})
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "2", resp.Trade.TradeId)
+	assert.Equal(t, "97000.1", resp.Trade.Price)
+	assert.Equal(t, "0.5", resp.Trade.Size)
+	assert.Equal(t, ts, resp.Trade.Timestamp.AsTime())
+
+	cancel()
+	waitForStreams(t, env.server, 0)
+}
+
+func TestServer_StreamTradesSlowConsumer(t *testing.T) {
+	env := newTestEnv(t)
+	stream, err := env.client.StreamTrades(context.Background(), &marketdatav1.StreamTradesRequest{})
+	require.NoError(t, err)
+	waitForStreams(t, env.server, 1)
+
+	// the client doesn't receive until flow control stops the server and the buffer overflows
+	require.Eventually(t, func() bool {
+		env.server.Trade(model.Trade{Pair: "BTC-USDT", TradeId: string(make([]byte, 1024))})
+		env.server.trades.mu.Lock()
+		defer env.server.trades.mu.Unlock()
+		return len(env.server.trades.subs) == 0
+	}, 10*time.Second, time.Millisecond)
+
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// blockingStream holds the first Send until release is closed
+type blockingStream struct {
+	grpc.ServerStreamingServer[marketdatav1.StreamTradesResponse]
+	sending chan struct{}
+	release chan struct{}
+	sent    []string
+}
+
+func (s *blockingStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *blockingStream) Send(resp *marketdatav1.StreamTradesResponse) error {
+	if len(s.sent) == 0 {
+		close(s.sending)
+		<-s.release
+	}
+	s.sent = append(s.sent, resp.Trade.TradeId)
+	return nil
+}
+
+func TestServer_StreamTradesStopsWhenDone(t *testing.T) {
+	server := NewServer(nil, nil, nil, "1H", log.New())
+	stream := &blockingStream{sending: make(chan struct{}), release: make(chan struct{})}
+	ended := make(chan error, 1)
+	go func() { ended <- server.StreamTrades(&marketdatav1.StreamTradesRequest{}, stream) }()
+	waitForStreams(t, server, 1)
+
+	server.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "1"})
+	<-stream.sending
+	for i := 2; i <= 10; i++ {
+		server.Trade(model.Trade{Pair: "BTC-USDT", TradeId: strconv.Itoa(i)})
+	}
+	server.Close()
+	close(stream.release)
+
+	assert.Equal(t, codes.Unavailable, status.Code(<-ended))
+	assert.Equal(t, []string{"1"}, stream.sent, "trades buffered before closing aren't sent")
+}
+
+func TestServer_LiveDataOnFollower(t *testing.T) {
+	env := newTestEnv(t)
+	env.tickers.Set(model.Ticker{Pair: "BTC-USDT", Last: 9700010000000, Timestamp: time.Now()})
+	leading := false
+	env.server.SetLeading(func() bool { return leading })
+
+	_, err := env.client.GetTicker(context.Background(), &marketdatav1.GetTickerRequest{Pair: "BTC-USDT"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	stream, err := env.client.StreamTrades(context.Background(), &marketdatav1.StreamTradesRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	leading = true
+	_, err = env.client.GetTicker(context.Background(), &marketdatav1.GetTickerRequest{Pair: "BTC-USDT"})
+	assert.NoError(t, err)
+}
+
+func TestServer_Reflection(t *testing.T) {
+	env := newTestEnv(t)
+	stream, err := reflectionpb.NewServerReflectionClient(env.client.Connection()).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	assert.Contains(t, services, marketdatav1.MarketDataService_ServiceDesc.ServiceName)
+}
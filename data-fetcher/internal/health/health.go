@@ -0,0 +1,123 @@
+// Package health checks dependencies and data freshness of the app for liveness and readiness probes
+package health
+
+import (
+	"context"
+	"cur/internal/store"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Check returns an error when the dependency doesn't work, it must return when ctx is done
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+type Report struct {
+	Results   []Result
+	CheckedAt time.Time
+}
+
+// Healthy reports whether every check passed
+func (r Report) Healthy() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs checks concurrently, each one is given Timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add adds the check, must be called before Run
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check, results are in the order of adding
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Results: make([]Result, len(c.checks)), CheckedAt: time.Now()}
+
+	var wg sync.WaitGroup
+	for i, named := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := run(checkCtx, named.check)
+			report.Results[i] = Result{Name: named.name, Err: err, Duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run returns when ctx is done even if the check doesn't
+func run(ctx context.Context, check Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
+
+// TradeFreshness fails when the latest trade of the pair is older than maxAge or there is none
+func TradeFreshness(trades store.TradeStore, pair string, maxAge time.Duration) Check {
+	return func(ctx context.Context) error {
+		latest := trades.Latest(pair, 1)
+		if len(latest) == 0 {
+			return fmt.Errorf("no trades of %s received", pair)
+		}
+		return fresh("trade", latest[0].Timestamp, maxAge)
+	}
+}
+
+// CandleFreshness fails when the latest candle of the pair opened longer than maxAge ago or there is none
+func CandleFreshness(candles store.CandleStore, pair, bar string, maxAge time.Duration) Check {
+	return func(ctx context.Context) error {
+		latest, err := candles.FetchLatest(pair, bar, 1)
+		if err != nil {
+			return err
+		}
+		if len(latest) == 0 {
+			return fmt.Errorf("no %s candles of %s stored", bar, pair)
+		}
+		return fresh("candle", latest[0].Timestamp, maxAge)
+	}
+}
+
+func fresh(what string, ts time.Time, maxAge time.Duration) error {
+	if age := time.Since(ts); age > maxAge {
+		return fmt.Errorf("the latest %s is %s old, the threshold is %s", what, age.Round(time.Second), maxAge)
+	}
+	return nil
+}
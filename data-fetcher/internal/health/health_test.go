@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("ok", func(ctx context.Context) error { return nil })
+	checker.Add("failing", func(ctx context.Context) error { return errors.New("connection refused") })
+	checker.Add("hanging", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := checker.Run(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "a hanging check times out")
+
+	require.Len(t, report.Results, 3)
+	assert.Equal(t, "ok", report.Results[0].Name)
+	assert.NoError(t, report.Results[0].Err)
+	assert.EqualError(t, report.Results[1].Err, "connection refused")
+	assert.ErrorIs(t, report.Results[2].Err, context.DeadlineExceeded)
+	assert.False(t, report.Healthy())
+
+	assert.True(t, NewChecker(time.Second).Run(context.Background()).Healthy(), "nothing to check")
+}
+
+func TestTradeFreshness(t *testing.T) {
+	trades := store.NewTradeRepository(10)
+	check := TradeFreshness(trades, "BTC-USDT", time.Minute)
+	assert.ErrorContains(t, check(context.Background()), "no trades")
+
+	trades.Add(model.Trade{Pair: "BTC-USDT", TradeId: "1", Timestamp: time.Now().Add(-2 * time.Minute)})
+	assert.ErrorContains(t, check(context.Background()), "the latest trade is 2m0s old")
+
+	trades.Add(model.Trade{Pair: "BTC-USDT", TradeId: "2", Timestamp: time.Now()})
+	assert.NoError(t, check(context.Background()))
+}
+
+func TestCandleFreshness(t *testing.T) {
+	candles := memory.NewCandleRepository()
+	check := CandleFreshness(candles, "BTC-USDT", "1H", 2*time.Hour)
+	assert.ErrorContains(t, check(context.Background()), "no 1H candles")
+
+	hour := time.Now().Truncate(time.Hour)
+	batch := []model.Candle{storetest.Candle("BTC-USDT", "1H", hour.Add(-3*time.Hour), 1)}
+	require.NoError(t, candles.InsertCandles(&batch))
+	assert.Error(t, check(context.Background()))
+
+	batch = []model.Candle{storetest.Candle("BTC-USDT", "1H", hour.Add(-time.Hour), 2)}
+	require.NoError(t, candles.InsertCandles(&batch))
+	assert.NoError(t, check(context.Background()))
+}
@@ -2,7 +2,10 @@ package price
 
 import (
 	"errors"
+	"math"
+	"math/bits"
 	"strconv"
+	"strings"
 )
 
 const PriceFactor = 100_000_000
@@ -35,11 +38,76 @@ func (p Price) ToFloat() float64 {
 	return float64(p.Price) / float64(PriceFactor)
 }
 
-// ParsePrice returns price in int64
+// String returns price as a decimal string without trailing zeroes, e.g. "97338.7"
+func (p Price) String() string {
+	value := p.Price
+	sign := ""
+	if value < 0 {
+		sign = "-"
+	}
+
+	integer := strconv.FormatUint(abs(value)/PriceFactor, 10)
+	fraction := strconv.FormatUint(abs(value)%PriceFactor, 10)
+	fraction = strings.TrimRight(strings.Repeat("0", AdditionalZeroes-len(fraction))+fraction, "0")
+
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction
+}
+
+// MulDiv returns a*b/c rounded half away from zero, the product doesn't overflow.
+// A quotient not fitting int64 is saturated.
+func MulDiv(a, b, c int64) int64 {
+	negative := (a < 0) != (b < 0) != (c < 0)
+	hi, lo := bits.Mul64(abs(a), abs(b))
+	divisor := abs(c)
+	if hi >= divisor {
+		if negative {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+
+	q, r := bits.Div64(hi, lo, divisor)
+	if r >= divisor-r {
+		q++
+	}
+	switch {
+	case negative && q >= 1<<63:
+		return math.MinInt64
+	case negative:
+		return -int64(q)
+	case q > math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(q)
+}
+
+func abs(value int64) uint64 {
+	if value < 0 {
+		return uint64(-(value + 1)) + 1
+	}
+	return uint64(value)
+}
+
+// ParsePrice returns price in int64. Decimal strings are parsed exactly, digits after
+// the 8th decimal place are truncated; exponent notation falls back to float parsing.
 func ParsePrice(priceStr string) (int64, error) {
-	priceFloat, err := strconv.ParseFloat(priceStr, 64)
-	if err != nil {
-		return 0, err
+	integer, fraction, hasFraction := strings.Cut(priceStr, ".")
+	if strings.ContainsAny(priceStr, "eE") || integer == "" || integer == "-" || integer == "+" ||
+		(hasFraction && fraction == "") || strings.ContainsAny(fraction, "+-") {
+		priceFloat, err := strconv.ParseFloat(priceStr, 64)
+		if err != nil {
+			return 0, err
+		}
+		return int64(priceFloat * PriceFactor), nil
+	}
+
+	if len(fraction) > AdditionalZeroes {
+		fraction = fraction[:AdditionalZeroes]
 	}
-	return int64(priceFloat * PriceFactor), nil
+	fraction += strings.Repeat("0", AdditionalZeroes-len(fraction))
+
+	return strconv.ParseInt(integer+fraction, 10, 64)
 }
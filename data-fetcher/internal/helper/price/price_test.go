@@ -0,0 +1,63 @@
+package price
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrice_String(t *testing.T) {
+	testCases := []struct {
+		price    int64
+		expected string
+	}{
+		{0, "0"},
+		{9733870000000, "97338.7"},
+		{100_000_000, "1"},
+		{1, "0.00000001"},
+		{-150_000_000, "-1.5"},
+		{-1, "-0.00000001"},
+		{math.MinInt64, "-92233720368.54775808"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, Price{testCase.price}.String())
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	testCases := []struct {
+		price    string
+		expected int64
+	}{
+		{"97338.7", 9733870000000},
+		{"0.29", 29_000_000},
+		{"3706.88063172", 370688063172},
+		{"0.123456789", 12345678},
+		{"-1.5", -150_000_000},
+		{"42", 4_200_000_000},
+		{".5", 50_000_000},
+		{"1e-3", 100_000},
+	}
+
+	for _, testCase := range testCases {
+		p, err := ParsePrice(testCase.price)
+		assert.NoError(t, err, testCase.price)
+		assert.Equal(t, testCase.expected, p, testCase.price)
+	}
+
+	for _, wrong := range []string{"", "not a price", "1.2.3", "1.-2"} {
+		_, err := ParsePrice(wrong)
+		assert.Error(t, err, wrong)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	assert.Equal(t, int64(48_500*PriceFactor), MulDiv(97_000*PriceFactor, 50_000_000, PriceFactor), "notional of 0.5 at 97000")
+	assert.Equal(t, int64(-2), MulDiv(-3, 1, 2), "rounded half away from zero")
+	assert.Equal(t, int64(2), MulDiv(-3, -1, 2))
+	assert.Equal(t, int64(math.MaxInt64), MulDiv(math.MaxInt64, 2, 1))
+	assert.Equal(t, int64(math.MinInt64), MulDiv(math.MinInt64, 1, 1))
+	assert.Equal(t, int64(math.MinInt64), MulDiv(math.MaxInt64, -2, 1))
+}
@@ -0,0 +1,9 @@
+// Package pricetest helps tests to write fixed-point prices and amounts.
+package pricetest
+
+import "cur/internal/helper/price"
+
+// Units returns the fixed-point value of whole units, e.g. Units(100) is 100 USDT
+func Units(value int64) int64 {
+	return value * price.PriceFactor
+}
@@ -0,0 +1,105 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+type AdxValue struct {
+	Adx     price.Price // trend strength 0..100 regardless of direction
+	PlusDi  price.Price // +DI, strength of upward moves
+	MinusDi price.Price // -DI, strength of downward moves
+}
+
+// AdxStream average directional index with Wilder's smoothing
+type AdxStream struct {
+	period    int64
+	count     int64
+	prevHigh  int64
+	prevLow   int64
+	prevClose int64
+
+	// Wilder's sums of true range and directional movements
+	trueRange int64
+	plusDm    int64
+	minusDm   int64
+
+	dxCount int64
+	adx     int64
+}
+
+func NewAdxStream(period int) (*AdxStream, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	return &AdxStream{period: int64(period)}, nil
+}
+
+func (a *AdxStream) Update(candle model.Candle) (AdxValue, bool) {
+	a.count++
+	if a.count == 1 {
+		a.prevHigh, a.prevLow, a.prevClose = candle.High, candle.Low, candle.Close
+		return AdxValue{}, false
+	}
+
+	trueRange := max(candle.High-candle.Low, absI(candle.High-a.prevClose), absI(candle.Low-a.prevClose))
+	up, down := candle.High-a.prevHigh, a.prevLow-candle.Low
+	var plusDm, minusDm int64
+	if up > down && up > 0 {
+		plusDm = up
+	}
+	if down > up && down > 0 {
+		minusDm = down
+	}
+	a.prevHigh, a.prevLow, a.prevClose = candle.High, candle.Low, candle.Close
+
+	// the first sums are plain sums of period movements
+	if a.count <= a.period+1 {
+		a.trueRange += trueRange
+		a.plusDm += plusDm
+		a.minusDm += minusDm
+		if a.count <= a.period {
+			return AdxValue{}, false
+		}
+	} else {
+		a.trueRange += trueRange - divRound(a.trueRange, a.period)
+		a.plusDm += plusDm - divRound(a.plusDm, a.period)
+		a.minusDm += minusDm - divRound(a.minusDm, a.period)
+	}
+
+	var plusDi, minusDi, dx int64
+	if a.trueRange > 0 {
+		plusDi = mulDiv(hundred, a.plusDm, a.trueRange)
+		minusDi = mulDiv(hundred, a.minusDm, a.trueRange)
+	}
+	if plusDi+minusDi > 0 {
+		dx = mulDiv(hundred, absI(plusDi-minusDi), plusDi+minusDi)
+	}
+
+	// ADX starts as the average of the first period DX values
+	a.dxCount++
+	switch {
+	case a.dxCount < a.period:
+		a.adx += dx
+		return AdxValue{}, false
+	case a.dxCount == a.period:
+		a.adx = divRound(a.adx+dx, a.period)
+	default:
+		a.adx = divRound(a.adx*(a.period-1)+dx, a.period)
+	}
+
+	return AdxValue{
+		Adx:     price.Price{Price: a.adx},
+		PlusDi:  price.Price{Price: plusDi},
+		MinusDi: price.Price{Price: minusDi},
+	}, true
+}
+
+// Adx average directional index, the first value is at the (2*period)-th candle, usual period is 14
+func Adx(candles []model.Candle, period int) ([]Point[AdxValue], error) {
+	a, err := NewAdxStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[AdxValue](a, candles), nil
+}
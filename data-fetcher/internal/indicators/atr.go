@@ -0,0 +1,57 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// AtrStream average true range with Wilder's smoothing, seeded with the average of the first period true ranges
+type AtrStream struct {
+	period    int64
+	count     int64
+	prevClose int64
+	value     int64
+}
+
+func NewAtrStream(period int) (*AtrStream, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	return &AtrStream{period: int64(period)}, nil
+}
+
+func (a *AtrStream) Update(candle model.Candle) (price.Price, bool) {
+	trueRange := candle.High - candle.Low
+	if a.count > 0 {
+		trueRange = max(trueRange, absI(candle.High-a.prevClose), absI(candle.Low-a.prevClose))
+	}
+	a.prevClose = candle.Close
+	a.count++
+
+	switch {
+	case a.count < a.period:
+		a.value += trueRange
+		return price.Price{}, false
+	case a.count == a.period:
+		a.value = divRound(a.value+trueRange, a.period)
+	default:
+		a.value = divRound(a.value*(a.period-1)+trueRange, a.period)
+	}
+	return price.Price{Price: a.value}, true
+}
+
+// Atr average true range, the first value is at the period-th candle
+func Atr(candles []model.Candle, period int) ([]Point[price.Price], error) {
+	a, err := NewAtrStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[price.Price](a, candles), nil
+}
+
+func absI(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
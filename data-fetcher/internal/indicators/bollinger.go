@@ -0,0 +1,57 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"math"
+)
+
+type BollingerValue struct {
+	Upper  price.Price
+	Middle price.Price // SMA
+	Lower  price.Price
+}
+
+// BollingerStream bands at multiplier population standard deviations around the SMA of close prices
+type BollingerStream struct {
+	sma        *SmaStream
+	multiplier float64
+}
+
+func NewBollingerStream(period int, multiplier float64) (*BollingerStream, error) {
+	sma, err := NewSmaStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return &BollingerStream{sma: sma, multiplier: multiplier}, nil
+}
+
+func (b *BollingerStream) Update(candle model.Candle) (BollingerValue, bool) {
+	middle, ok := b.sma.Update(candle)
+	if !ok {
+		return BollingerValue{}, false
+	}
+
+	// deviations are exact, only their squares are summed in floating point
+	var squares float64
+	b.sma.window.each(func(value int64) {
+		deviation := float64(value - middle.Price)
+		squares += deviation * deviation
+	})
+	width := int64(math.Round(b.multiplier * math.Sqrt(squares/float64(len(b.sma.window.values)))))
+
+	return BollingerValue{
+		Upper:  price.Price{Price: middle.Price + width},
+		Middle: middle,
+		Lower:  price.Price{Price: middle.Price - width},
+	}, true
+}
+
+// Bollinger bands, usually 20 periods and multiplier 2
+func Bollinger(candles []model.Candle, period int, multiplier float64) ([]Point[BollingerValue], error) {
+	b, err := NewBollingerStream(period, multiplier)
+	if err != nil {
+		return nil, err
+	}
+	return Series[BollingerValue](b, candles), nil
+}
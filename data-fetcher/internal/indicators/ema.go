@@ -0,0 +1,51 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// EmaStream exponential moving average of close prices with 2/(period+1) smoothing,
+// seeded with the simple average of the first period values
+type EmaStream struct {
+	period int
+	seed   *SmaStream
+	value  int64
+	ready  bool
+}
+
+func NewEmaStream(period int) (*EmaStream, error) {
+	seed, err := NewSmaStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return &EmaStream{period: period, seed: seed}, nil
+}
+
+func (e *EmaStream) Update(candle model.Candle) (price.Price, bool) {
+	return e.Add(candle.Close)
+}
+
+// Add updates the average with an arbitrary value, e.g. another indicator
+func (e *EmaStream) Add(value int64) (price.Price, bool) {
+	if !e.ready {
+		sma, ok := e.seed.Add(value)
+		if !ok {
+			return price.Price{}, false
+		}
+		e.value, e.ready = sma.Price, true
+		return sma, true
+	}
+
+	e.value += mulDiv(value-e.value, 2, int64(e.period+1))
+	return price.Price{Price: e.value}, true
+}
+
+// Ema exponential moving average of close prices, the first value is at the period-th candle
+func Ema(candles []model.Candle, period int) ([]Point[price.Price], error) {
+	e, err := NewEmaStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[price.Price](e, candles), nil
+}
@@ -0,0 +1,106 @@
+// Package indicators computes technical indicators over candles.
+//
+// Every indicator has a streaming variant which is updated with each new candle
+// (candles must come in ascending order of time) and a batch function over a slice
+// of candles built on top of it, so both always give the same values.
+//
+// Values are fixed-point price.Price: prices and volumes keep the candle scale,
+// oscillators (RSI, Stochastic) are in the 0..100 range with the same 8 decimal
+// places. Intermediate products are computed in 128 bits and rounded half away
+// from zero, only the Bollinger Bands standard deviation uses floating point.
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"errors"
+	"time"
+)
+
+var ErrInvalidPeriod = errors.New("period must be positive")
+
+// hundred is 100 in the price scale
+const hundred = 100 * price.PriceFactor
+
+// Indicator is updated with every new candle, ok is false until enough candles are seen
+type Indicator[T any] interface {
+	Update(candle model.Candle) (value T, ok bool)
+}
+
+// Point value of an indicator at the candle timestamp
+type Point[T any] struct {
+	Timestamp time.Time
+	Value     T
+}
+
+// Series feeds candles to the indicator and collects its values
+func Series[T any](indicator Indicator[T], candles []model.Candle) []Point[T] {
+	points := make([]Point[T], 0, len(candles))
+	for _, c := range candles {
+		if value, ok := indicator.Update(c); ok {
+			points = append(points, Point[T]{Timestamp: c.Timestamp, Value: value})
+		}
+	}
+	return points
+}
+
+func checkPeriods(periods ...int) error {
+	for _, period := range periods {
+		if period <= 0 {
+			return ErrInvalidPeriod
+		}
+	}
+	return nil
+}
+
+// window is a fixed size ring of the latest values
+type window struct {
+	values []int64
+	next   int
+	full   bool
+}
+
+func newWindow(size int) *window {
+	return &window{values: make([]int64, size)}
+}
+
+// push adds the value and returns the evicted one when the window was full
+func (w *window) push(value int64) (evicted int64, ok bool) {
+	evicted, ok = w.values[w.next], w.full
+	w.values[w.next] = value
+	w.next++
+	if w.next == len(w.values) {
+		w.next = 0
+		w.full = true
+	}
+	return evicted, ok
+}
+
+func (w *window) len() int {
+	if w.full {
+		return len(w.values)
+	}
+	return w.next
+}
+
+// each iterates values from the oldest to the newest
+func (w *window) each(fn func(value int64)) {
+	if w.full {
+		for _, v := range w.values[w.next:] {
+			fn(v)
+		}
+	}
+	for _, v := range w.values[:w.next] {
+		fn(v)
+	}
+}
+
+// mulDiv returns a*b/c rounded half away from zero, see price.MulDiv
+func mulDiv(a, b, c int64) int64 {
+	return price.MulDiv(a, b, c)
+}
+
+// divRound returns a/b rounded half away from zero
+func divRound(a, b int64) int64 {
+	return mulDiv(a, 1, b)
+}
@@ -0,0 +1,276 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Reference values were computed independently with float64 textbook formulas,
+// fixed-point results may differ by a few units of the 8th decimal place.
+
+var (
+	closes = []string{
+		"44.34", "44.09", "44.15", "43.61", "44.33", "44.83", "45.10", "45.42", "45.84", "46.08",
+		"45.89", "46.03", "45.61", "46.28", "46.28", "46.00", "46.03", "46.41", "46.22", "45.64",
+		"46.21", "46.25", "45.71", "46.45", "45.78", "45.35", "44.03", "44.17", "45.09", "45.52",
+		"45.56", "45.11", "45.18", "46.12", "46.58", "46.03", "45.42", "45.97", "46.71", "47.02",
+	}
+	highs = []string{
+		"44.44", "44.24", "44.35", "43.86", "44.43", "44.98", "45.30", "45.67", "45.94", "46.23",
+		"46.09", "46.28", "45.71", "46.43", "46.48", "46.25", "46.13", "46.56", "46.42", "45.89",
+		"46.31", "46.40", "45.91", "46.70", "45.88", "45.50", "44.23", "44.42", "45.19", "45.67",
+		"45.76", "45.36", "45.28", "46.27", "46.78", "46.28", "45.52", "46.12", "46.91", "47.27",
+	}
+	lows = []string{
+		"44.22", "43.93", "43.95", "43.49", "44.17", "44.63", "44.98", "45.26", "45.64", "45.96",
+		"45.73", "45.83", "45.49", "46.12", "46.08", "45.88", "45.87", "46.21", "46.10", "45.48",
+		"46.01", "46.13", "45.55", "46.25", "45.66", "45.19", "43.83", "44.05", "44.93", "45.32",
+		"45.44", "44.95", "44.98", "46.00", "46.42", "45.83", "45.30", "45.81", "46.51", "46.90",
+	}
+	volumes = []string{
+		"1000.00", "1262.50", "1112.50", "1375.00", "1225.00", "1075.00", "1337.50", "1187.50", "1037.50", "1300.00",
+		"1150.00", "1000.00", "1262.50", "1112.50", "1375.00", "1225.00", "1075.00", "1337.50", "1187.50", "1037.50",
+		"1300.00", "1150.00", "1000.00", "1262.50", "1112.50", "1375.00", "1225.00", "1075.00", "1337.50", "1187.50",
+		"1037.50", "1300.00", "1150.00", "1000.00", "1262.50", "1112.50", "1375.00", "1225.00", "1075.00", "1337.50",
+	}
+	start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func p(t *testing.T, value string) int64 {
+	parsed, err := price.ParsePrice(value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func testCandles(t *testing.T) []model.Candle {
+	candles := make([]model.Candle, 0, len(closes))
+	for i := range closes {
+		candles = append(candles, model.Candle{
+			Pair:      "BTC-USDT",
+			Bar:       "1D",
+			Timestamp: start.Add(time.Duration(i) * 24 * time.Hour),
+			Open:      p(t, closes[i]),
+			High:      p(t, highs[i]),
+			Low:       p(t, lows[i]),
+			Close:     p(t, closes[i]),
+			Volume:    p(t, volumes[i]),
+		})
+	}
+	return candles
+}
+
+// assertTail compares the latest values with reference decimals
+func assertTail(t *testing.T, expected []string, actual []price.Price, delta int64) {
+	t.Helper()
+	require.GreaterOrEqual(t, len(actual), len(expected))
+	actual = actual[len(actual)-len(expected):]
+	for i := range expected {
+		assert.InDelta(t, p(t, expected[i]), actual[i].Price, float64(delta), "value %d: expected %s, got %s", i, expected[i], actual[i])
+	}
+}
+
+func values(points []Point[price.Price]) []price.Price {
+	result := make([]price.Price, 0, len(points))
+	for _, point := range points {
+		result = append(result, point.Value)
+	}
+	return result
+}
+
+func TestSma(t *testing.T) {
+	points, err := Sma(testCandles(t), 5)
+	require.NoError(t, err)
+	assert.Len(t, points, 36)
+	assert.Equal(t, start.Add(4*24*time.Hour), points[0].Timestamp)
+	assertTail(t, []string{"45.804", "45.866", "46.024", "46.142", "46.23"}, values(points), 1)
+}
+
+func TestEma(t *testing.T) {
+	points, err := Ema(testCandles(t), 5)
+	require.NoError(t, err)
+	assert.Len(t, points, 36)
+	assertTail(t, []string{"45.92157495", "45.75438330", "45.82625553", "46.12083702", "46.42055801"}, values(points), 5)
+}
+
+func TestWma(t *testing.T) {
+	points, err := Wma(testCandles(t), 5)
+	require.NoError(t, err)
+	assert.Len(t, points, 36)
+	assertTail(t, []string{"46.02", "45.892", "45.92666667", "46.15533333", "46.448"}, values(points), 1)
+}
+
+func TestRsi(t *testing.T) {
+	points, err := Rsi(testCandles(t), 14)
+	require.NoError(t, err)
+	assert.Len(t, points, 26)
+	assert.Equal(t, start.Add(14*24*time.Hour), points[0].Timestamp)
+	assertTail(t, []string{"70.46", "66.25", "66.48", "69.35"}, values(points)[:4], 500_000)
+	// averages of small changes keep 8 decimal places, their ratio is precise to about 1e-5
+	assertTail(t, []string{"55.31430909", "50.16463675", "54.29617860", "59.19751848", "61.08049238"}, values(points), 1000)
+}
+
+func TestMacd(t *testing.T) {
+	points, err := Macd(testCandles(t), 12, 26, 9)
+	require.NoError(t, err)
+	require.Len(t, points, 7)
+
+	var macd, signal, histogram []price.Price
+	for _, point := range points {
+		macd = append(macd, point.Value.Macd)
+		signal = append(signal, point.Value.Signal)
+		histogram = append(histogram, point.Value.Histogram)
+	}
+	assertTail(t, []string{"0.15175021", "0.22205770", "0.29934069"}, macd, 10)
+	assertTail(t, []string{"0.11385388", "0.13549464", "0.16826385"}, signal, 10)
+	assertTail(t, []string{"0.03789634", "0.08656306", "0.13107684"}, histogram, 10)
+}
+
+func TestBollinger(t *testing.T) {
+	points, err := Bollinger(testCandles(t), 20, 2)
+	require.NoError(t, err)
+	require.Len(t, points, 21)
+
+	var upper, middle, lower []price.Price
+	for _, point := range points {
+		upper = append(upper, point.Value.Upper)
+		middle = append(middle, point.Value.Middle)
+		lower = append(lower, point.Value.Lower)
+	}
+	assertTail(t, []string{"46.94478450", "47.02953095", "47.22274302"}, upper, 2)
+	assertTail(t, []string{"45.6195", "45.644", "45.713"}, middle, 1)
+	assertTail(t, []string{"44.29421550", "44.25846905", "44.20325698"}, lower, 2)
+}
+
+func TestAtr(t *testing.T) {
+	points, err := Atr(testCandles(t), 14)
+	require.NoError(t, err)
+	assert.Len(t, points, 27)
+	assertTail(t, []string{"0.63814753", "0.64470842", "0.64865782", "0.66946797", "0.66164883"}, values(points), 10)
+}
+
+func TestAdx(t *testing.T) {
+	points, err := Adx(testCandles(t), 14)
+	require.NoError(t, err)
+	require.Len(t, points, 13)
+	assert.Equal(t, start.Add(27*24*time.Hour), points[0].Timestamp)
+
+	var adx, plusDi, minusDi []price.Price
+	for _, point := range points {
+		adx = append(adx, point.Value.Adx)
+		plusDi = append(plusDi, point.Value.PlusDi)
+		minusDi = append(minusDi, point.Value.MinusDi)
+	}
+	assertTail(t, []string{"16.98903755", "17.24447695", "17.77909979"}, adx, 1000)
+	assertTail(t, []string{"40.48799418", "44.83272028", "46.00280914"}, plusDi, 1000)
+	assertTail(t, []string{"32.81124078", "29.53819985", "27.76149038"}, minusDi, 1000)
+}
+
+func TestStochastic(t *testing.T) {
+	points, err := Stochastic(testCandles(t), 14, 3)
+	require.NoError(t, err)
+	require.Len(t, points, 25)
+
+	var k, d []price.Price
+	for _, point := range points {
+		k = append(k, point.Value.K)
+		d = append(d, point.Value.D)
+	}
+	assertTail(t, []string{"72.54237288", "93.50649351", "92.73255814"}, k, 1)
+	assertTail(t, []string{"67.00564972", "73.31572382", "86.26047484"}, d, 2)
+}
+
+func TestObv(t *testing.T) {
+	points := Obv(testCandles(t))
+	assert.Len(t, points, 40)
+	assert.Equal(t, price.Price{}, points[0].Value)
+	assertTail(t, []string{"8937.5", "7562.5", "8787.5", "9862.5", "11200"}, values(points), 0)
+}
+
+func TestStreamsMatchBatch(t *testing.T) {
+	candles := testCandles(t)
+	batch, err := Rsi(candles, 14)
+	require.NoError(t, err)
+
+	stream, err := NewRsiStream(14)
+	require.NoError(t, err)
+	var streamed []price.Price
+	for _, c := range candles {
+		if value, ok := stream.Update(c); ok {
+			streamed = append(streamed, value)
+		}
+	}
+	assert.Equal(t, values(batch), streamed)
+}
+
+func TestFlatSeries(t *testing.T) {
+	candles := make([]model.Candle, 20)
+	for i := range candles {
+		candles[i] = model.Candle{Timestamp: start.Add(time.Duration(i) * time.Hour), Open: 100, High: 100, Low: 100, Close: 100}
+	}
+
+	rsi, err := Rsi(candles, 14)
+	require.NoError(t, err)
+	assert.Equal(t, price.Price{Price: 50 * price.PriceFactor}, rsi[0].Value)
+
+	stochastic, err := Stochastic(candles, 14, 3)
+	require.NoError(t, err)
+	assert.Equal(t, StochasticValue{K: price.Price{Price: 50 * price.PriceFactor}, D: price.Price{Price: 50 * price.PriceFactor}}, stochastic[0].Value)
+
+	bollinger, err := Bollinger(candles, 20, 2)
+	require.NoError(t, err)
+	assert.Equal(t, BollingerValue{Upper: price.Price{Price: 100}, Middle: price.Price{Price: 100}, Lower: price.Price{Price: 100}}, bollinger[0].Value)
+}
+
+func TestInvalidPeriod(t *testing.T) {
+	_, err := Sma(nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+	_, err = Macd(nil, 12, -1, 9)
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+	_, err = NewStochasticStream(14, 0)
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestMulDiv(t *testing.T) {
+	// BTC price * 100 * 1e8 overflows int64 without 128-bit product
+	assert.Equal(t, int64(50*price.PriceFactor), mulDiv(hundred, 5_000_000*price.PriceFactor, 10_000_000*price.PriceFactor))
+	assert.Equal(t, int64(2), divRound(5, 3))
+	assert.Equal(t, int64(-2), divRound(-5, 3))
+	assert.Equal(t, int64(1), divRound(4, 3))
+	assert.Equal(t, int64(math.MaxInt64), mulDiv(math.MaxInt64, 2, 1))
+	assert.Equal(t, int64(math.MinInt64), mulDiv(math.MinInt64, 1, 1))
+}
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs("sma_20, macd_12_26_9,obv,,sma_20")
+	require.NoError(t, err)
+	require.Len(t, specs, 3)
+	assert.Equal(t, Spec{Name: "sma_20", Kind: "sma", Params: []int{20}}, specs[0])
+	assert.Equal(t, []string{"macd", "signal", "histogram"}, specs[1].Components())
+	assert.Equal(t, []string{ComponentValue}, specs[2].Components())
+
+	for _, name := range []string{"foo_1", "sma", "sma_0", "sma_x", "macd_12_26", "obv_1"} {
+		_, err := ParseSpec(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSpecMatchesIndicator(t *testing.T) {
+	candles := testCandles(t)
+	spec, err := ParseSpec("macd_12_26_9")
+	require.NoError(t, err)
+
+	batch, err := Macd(candles, 12, 26, 9)
+	require.NoError(t, err)
+	points := Series(spec.New(), candles)
+	require.Len(t, points, len(batch))
+	for i := range batch {
+		assert.Equal(t, batch[i].Timestamp, points[i].Timestamp)
+		assert.Equal(t, []price.Price{batch[i].Value.Macd, batch[i].Value.Signal, batch[i].Value.Histogram}, points[i].Value)
+	}
+}
@@ -0,0 +1,54 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+type MacdValue struct {
+	Macd      price.Price // fast EMA - slow EMA
+	Signal    price.Price // EMA of Macd
+	Histogram price.Price // Macd - Signal
+}
+
+// MacdStream moving average convergence/divergence of close prices
+type MacdStream struct {
+	fast   *EmaStream
+	slow   *EmaStream
+	signal *EmaStream
+}
+
+func NewMacdStream(fastPeriod, slowPeriod, signalPeriod int) (*MacdStream, error) {
+	if err := checkPeriods(fastPeriod, slowPeriod, signalPeriod); err != nil {
+		return nil, err
+	}
+	m := &MacdStream{}
+	m.fast, _ = NewEmaStream(fastPeriod)
+	m.slow, _ = NewEmaStream(slowPeriod)
+	m.signal, _ = NewEmaStream(signalPeriod)
+	return m, nil
+}
+
+func (m *MacdStream) Update(candle model.Candle) (MacdValue, bool) {
+	fast, fastOk := m.fast.Update(candle)
+	slow, slowOk := m.slow.Update(candle)
+	if !fastOk || !slowOk {
+		return MacdValue{}, false
+	}
+
+	macd := fast.Sub(slow)
+	signal, ok := m.signal.Add(macd.Price)
+	if !ok {
+		return MacdValue{}, false
+	}
+	return MacdValue{Macd: macd, Signal: signal, Histogram: macd.Sub(signal)}, true
+}
+
+// Macd the first value is available when the signal line is, usual periods are 12, 26 and 9
+func Macd(candles []model.Candle, fastPeriod, slowPeriod, signalPeriod int) ([]Point[MacdValue], error) {
+	m, err := NewMacdStream(fastPeriod, slowPeriod, signalPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return Series[MacdValue](m, candles), nil
+}
@@ -0,0 +1,36 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// ObvStream on-balance volume, volume is added on a higher close and subtracted on a lower one
+type ObvStream struct {
+	started   bool
+	prevClose int64
+	value     int64
+}
+
+func NewObvStream() *ObvStream {
+	return &ObvStream{}
+}
+
+func (o *ObvStream) Update(candle model.Candle) (price.Price, bool) {
+	if o.started {
+		switch {
+		case candle.Close > o.prevClose:
+			o.value += candle.Volume
+		case candle.Close < o.prevClose:
+			o.value -= candle.Volume
+		}
+	}
+	o.started = true
+	o.prevClose = candle.Close
+	return price.Price{Price: o.value}, true
+}
+
+// Obv on-balance volume starting from 0 at the first candle
+func Obv(candles []model.Candle) []Point[price.Price] {
+	return Series[price.Price](NewObvStream(), candles)
+}
@@ -0,0 +1,61 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// RsiStream relative strength index of close prices with Wilder's smoothing
+type RsiStream struct {
+	period    int64
+	prevClose int64
+	count     int64 // number of seen candles
+	avgGain   int64
+	avgLoss   int64
+}
+
+func NewRsiStream(period int) (*RsiStream, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	return &RsiStream{period: int64(period)}, nil
+}
+
+func (r *RsiStream) Update(candle model.Candle) (price.Price, bool) {
+	r.count++
+	change := candle.Close - r.prevClose
+	r.prevClose = candle.Close
+	if r.count == 1 {
+		return price.Price{}, false
+	}
+
+	gain, loss := max(change, 0), max(-change, 0)
+	switch {
+	case r.count <= r.period:
+		// sums of the first period changes
+		r.avgGain += gain
+		r.avgLoss += loss
+		return price.Price{}, false
+	case r.count == r.period+1:
+		r.avgGain = divRound(r.avgGain+gain, r.period)
+		r.avgLoss = divRound(r.avgLoss+loss, r.period)
+	default:
+		r.avgGain = divRound(r.avgGain*(r.period-1)+gain, r.period)
+		r.avgLoss = divRound(r.avgLoss*(r.period-1)+loss, r.period)
+	}
+
+	if r.avgGain+r.avgLoss == 0 {
+		return price.Price{Price: hundred / 2}, true
+	}
+	// 100 - 100/(1+RS) = 100*gain/(gain+loss)
+	return price.Price{Price: mulDiv(hundred, r.avgGain, r.avgGain+r.avgLoss)}, true
+}
+
+// Rsi relative strength index, the first value is at the (period+1)-th candle
+func Rsi(candles []model.Candle, period int) ([]Point[price.Price], error) {
+	r, err := NewRsiStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[price.Price](r, candles), nil
+}
@@ -0,0 +1,42 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// SmaStream simple moving average of close prices
+type SmaStream struct {
+	window *window
+	sum    int64
+}
+
+func NewSmaStream(period int) (*SmaStream, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	return &SmaStream{window: newWindow(period)}, nil
+}
+
+func (s *SmaStream) Update(candle model.Candle) (price.Price, bool) {
+	return s.Add(candle.Close)
+}
+
+// Add updates the average with an arbitrary value, e.g. another indicator
+func (s *SmaStream) Add(value int64) (price.Price, bool) {
+	evicted, _ := s.window.push(value)
+	s.sum += value - evicted
+	if !s.window.full {
+		return price.Price{}, false
+	}
+	return price.Price{Price: divRound(s.sum, int64(len(s.window.values)))}, true
+}
+
+// Sma simple moving average of close prices, the first value is at the period-th candle
+func Sma(candles []model.Candle, period int) ([]Point[price.Price], error) {
+	s, err := NewSmaStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[price.Price](s, candles), nil
+}
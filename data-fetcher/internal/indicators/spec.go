@@ -0,0 +1,155 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ComponentValue is the component of single-valued indicators
+const ComponentValue = "value"
+
+type kind struct {
+	params     int // number of required parameters
+	components []string
+	build      func(params []int) (Indicator[[]price.Price], error)
+}
+
+var kinds = map[string]kind{
+	"sma": {1, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewSmaStream(p[0]))
+	}},
+	"ema": {1, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewEmaStream(p[0]))
+	}},
+	"wma": {1, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewWmaStream(p[0]))
+	}},
+	"rsi": {1, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewRsiStream(p[0]))
+	}},
+	"atr": {1, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewAtrStream(p[0]))
+	}},
+	"obv": {0, []string{ComponentValue}, func(p []int) (Indicator[[]price.Price], error) {
+		return single(NewObvStream(), nil)
+	}},
+	"macd": {3, []string{"macd", "signal", "histogram"}, func(p []int) (Indicator[[]price.Price], error) {
+		return multi(NewMacdStream(p[0], p[1], p[2]))(func(v MacdValue) []price.Price {
+			return []price.Price{v.Macd, v.Signal, v.Histogram}
+		})
+	}},
+	"bollinger": {2, []string{"upper", "middle", "lower"}, func(p []int) (Indicator[[]price.Price], error) {
+		return multi(NewBollingerStream(p[0], float64(p[1])))(func(v BollingerValue) []price.Price {
+			return []price.Price{v.Upper, v.Middle, v.Lower}
+		})
+	}},
+	"stochastic": {2, []string{"k", "d"}, func(p []int) (Indicator[[]price.Price], error) {
+		return multi(NewStochasticStream(p[0], p[1]))(func(v StochasticValue) []price.Price {
+			return []price.Price{v.K, v.D}
+		})
+	}},
+	"adx": {1, []string{"adx", "plus_di", "minus_di"}, func(p []int) (Indicator[[]price.Price], error) {
+		return multi(NewAdxStream(p[0]))(func(v AdxValue) []price.Price {
+			return []price.Price{v.Adx, v.PlusDi, v.MinusDi}
+		})
+	}},
+}
+
+// Spec is a configured indicator named by kind and parameters, e.g. "sma_20", "macd_12_26_9",
+// "bollinger_20_2" or "obv"
+type Spec struct {
+	Name   string
+	Kind   string
+	Params []int
+}
+
+func ParseSpec(name string) (Spec, error) {
+	parts := strings.Split(strings.TrimSpace(name), "_")
+	k, ok := kinds[parts[0]]
+	if !ok {
+		return Spec{}, fmt.Errorf("unknown indicator %q", name)
+	}
+	if len(parts)-1 != k.params {
+		return Spec{}, fmt.Errorf("indicator %q needs %d parameters", name, k.params)
+	}
+
+	spec := Spec{Name: strings.Join(parts, "_"), Kind: parts[0]}
+	for _, part := range parts[1:] {
+		param, err := strconv.Atoi(part)
+		if err != nil || param <= 0 {
+			return Spec{}, fmt.Errorf("invalid parameter %q of indicator %q", part, name)
+		}
+		spec.Params = append(spec.Params, param)
+	}
+
+	if _, err := k.build(spec.Params); err != nil {
+		return Spec{}, fmt.Errorf("indicator %q: %w", name, err)
+	}
+
+	return spec, nil
+}
+
+// ParseSpecs parses a comma separated list of indicators
+func ParseSpecs(list string) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		spec, err := ParseSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[spec.Name] {
+			seen[spec.Name] = true
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// Components names of values produced by the indicator, single-valued ones have only ComponentValue
+func (s Spec) Components() []string {
+	return kinds[s.Kind].components
+}
+
+// New makes a streaming indicator producing values in the order of Components
+func (s Spec) New() Indicator[[]price.Price] {
+	indicator, _ := kinds[s.Kind].build(s.Params) // parameters are validated by ParseSpec
+	return indicator
+}
+
+type indicatorFunc[T any] func(candle model.Candle) (T, bool)
+
+func (f indicatorFunc[T]) Update(candle model.Candle) (T, bool) {
+	return f(candle)
+}
+
+func single(indicator Indicator[price.Price], err error) (Indicator[[]price.Price], error) {
+	if err != nil {
+		return nil, err
+	}
+	return indicatorFunc[[]price.Price](func(candle model.Candle) ([]price.Price, bool) {
+		value, ok := indicator.Update(candle)
+		return []price.Price{value}, ok
+	}), nil
+}
+
+func multi[T any, I Indicator[T]](indicator I, err error) func(func(T) []price.Price) (Indicator[[]price.Price], error) {
+	return func(components func(T) []price.Price) (Indicator[[]price.Price], error) {
+		if err != nil {
+			return nil, err
+		}
+		return indicatorFunc[[]price.Price](func(candle model.Candle) ([]price.Price, bool) {
+			value, ok := indicator.Update(candle)
+			if !ok {
+				return nil, false
+			}
+			return components(value), true
+		}), nil
+	}
+}
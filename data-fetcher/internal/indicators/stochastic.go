@@ -0,0 +1,59 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+type StochasticValue struct {
+	K price.Price // position of the close in the high-low range of the period, 0..100
+	D price.Price // SMA of K
+}
+
+// StochasticStream fast stochastic oscillator
+type StochasticStream struct {
+	highs *window
+	lows  *window
+	d     *SmaStream
+}
+
+func NewStochasticStream(kPeriod, dPeriod int) (*StochasticStream, error) {
+	if err := checkPeriods(kPeriod, dPeriod); err != nil {
+		return nil, err
+	}
+	d, _ := NewSmaStream(dPeriod)
+	return &StochasticStream{highs: newWindow(kPeriod), lows: newWindow(kPeriod), d: d}, nil
+}
+
+func (s *StochasticStream) Update(candle model.Candle) (StochasticValue, bool) {
+	s.highs.push(candle.High)
+	s.lows.push(candle.Low)
+	if !s.highs.full {
+		return StochasticValue{}, false
+	}
+
+	highest, lowest := candle.High, candle.Low
+	s.highs.each(func(value int64) { highest = max(highest, value) })
+	s.lows.each(func(value int64) { lowest = min(lowest, value) })
+
+	// a flat range has no position, the middle is taken
+	k := int64(hundred / 2)
+	if highest > lowest {
+		k = mulDiv(hundred, candle.Close-lowest, highest-lowest)
+	}
+
+	d, ok := s.d.Add(k)
+	if !ok {
+		return StochasticValue{}, false
+	}
+	return StochasticValue{K: price.Price{Price: k}, D: d}, true
+}
+
+// Stochastic oscillator, the first value is available when D is, usual periods are 14 and 3
+func Stochastic(candles []model.Candle, kPeriod, dPeriod int) ([]Point[StochasticValue], error) {
+	s, err := NewStochasticStream(kPeriod, dPeriod)
+	if err != nil {
+		return nil, err
+	}
+	return Series[StochasticValue](s, candles), nil
+}
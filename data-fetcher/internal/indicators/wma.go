@@ -0,0 +1,49 @@
+package indicators
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+)
+
+// WmaStream linearly weighted moving average of close prices, the newest close has weight period
+type WmaStream struct {
+	window   *window
+	sum      int64 // plain sum of the window
+	weighted int64 // weighted sum of the window
+}
+
+func NewWmaStream(period int) (*WmaStream, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	return &WmaStream{window: newWindow(period)}, nil
+}
+
+func (w *WmaStream) Update(candle model.Candle) (price.Price, bool) {
+	period := int64(len(w.window.values))
+	count := int64(w.window.len())
+
+	// every value already in the window loses one weight point, the oldest one drops out
+	evicted, full := w.window.push(candle.Close)
+	if full {
+		w.weighted += period*candle.Close - w.sum
+		w.sum += candle.Close - evicted
+	} else {
+		w.weighted += (count + 1) * candle.Close
+		w.sum += candle.Close
+	}
+
+	if !w.window.full {
+		return price.Price{}, false
+	}
+	return price.Price{Price: divRound(w.weighted, period*(period+1)/2)}, true
+}
+
+// Wma weighted moving average of close prices, the first value is at the period-th candle
+func Wma(candles []model.Candle, period int) ([]Point[price.Price], error) {
+	w, err := NewWmaStream(period)
+	if err != nil {
+		return nil, err
+	}
+	return Series[price.Price](w, candles), nil
+}
@@ -2,37 +2,37 @@ package dbConnection
 
 import (
 	"cur/internal/config/dbConfig"
+	"cur/internal/infrastructure/metrics"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ErrUnreachable the database didn't answer the ping. The returned pool is still usable,
+// database/sql connects on demand once the database is up.
+var ErrUnreachable = errors.New("unable to ping the database")
+
 func GetDbConnection(config *dbConfig.DbConfig) (*sql.DB, error) {
 
 	// Format the connection string
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		config.Host, config.Port, config.User, config.Password, config.DbName)
 
-	// Open a connection to the database
-	db, err := sql.Open("postgres", connStr)
-
+	// Open a connection to the database, latency of queries is measured
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
-		log.Fatalf("Unable to connect to the database: %v\n", err)
+		return nil, fmt.Errorf("unable to connect to the database: %w", err)
 	}
+	db := sql.OpenDB(metrics.InstrumentConnector(connector))
 
 	// Test the connection
-	err = db.Ping()
-	if err != nil {
-		defer func() {
-			_ = db.Close()
-			fmt.Println("db closed")
-		}()
-		log.Fatalf("Unable to ping the database: %v\n", err)
+	if err := db.Ping(); err != nil {
+		return db, fmt.Errorf("%w: %v", ErrUnreachable, err)
 	}
 
 	fmt.Println("Successfully connected to the database!")
 
-	return db, err
+	return db, nil
 }
@@ -1,22 +1,41 @@
 package kafka
 
 import (
+	"context"
 	"cur/internal/config/kafkaConfig"
+	"cur/internal/infrastructure/metrics"
+	"cur/internal/infrastructure/tracing"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Producer sends messages to kafka topics, messages with the same key go to the same partition in order.
+// Trace context of ctx is passed in headers of messages.
+type Producer interface {
+	SendMessage(ctx context.Context, topic, message string)
+	SendKeyedMessage(ctx context.Context, topic, key, message string)
+}
+
+var _ Producer = (*KafkaAsyncProducer)(nil)
+
 type KafkaAsyncProducer struct {
 	producer sarama.AsyncProducer
+
+	mu      sync.Mutex
+	lastErr error
 }
 
 func NewKafkaAsyncProducer(kafkaConfig *kafkaConfig.KafkaConfig) (*KafkaAsyncProducer, error) {
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForLocal // Only wait for leader ack
 	config.Producer.Retry.Max = 5                      // Retry up to 5 times
-	config.Producer.Return.Successes = false           // No success confirmations
+	config.Producer.Return.Successes = true            // Confirmations are counted
 	config.Producer.Return.Errors = true               // Listen for errors
 	config.Producer.Timeout = 10 * time.Second         // Message delivery timeout
 
@@ -25,21 +44,111 @@ func NewKafkaAsyncProducer(kafkaConfig *kafkaConfig.KafkaConfig) (*KafkaAsyncPro
 		return nil, err
 	}
 
-	// Handle errors asynchronously
+	return newKafkaAsyncProducer(producer), nil
+}
+
+// newKafkaAsyncProducer handles results of the producer, it must return successes and errors
+func newKafkaAsyncProducer(producer sarama.AsyncProducer) *KafkaAsyncProducer {
+	kp := &KafkaAsyncProducer{producer: producer}
+
+	// Handle results asynchronously
+	go func() {
+		for message := range producer.Successes() {
+			metrics.KafkaProduced(message.Topic)
+			endSpan(message, nil)
+			kp.setStatus(nil)
+		}
+	}()
 	go func() {
 		for err := range producer.Errors() {
+			metrics.KafkaFailed(err.Msg.Topic)
+			endSpan(err.Msg, err.Err)
+			kp.setStatus(err)
 			log.Printf("Failed to send message to Kafka: %v", err)
 		}
 	}()
 
-	return &KafkaAsyncProducer{producer: producer}, nil
+	return kp
+}
+
+func (kp *KafkaAsyncProducer) setStatus(err error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.lastErr = err
+}
+
+// Status returns the error of the latest failed message unless a later one was delivered
+func (kp *KafkaAsyncProducer) Status() error {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	return kp.lastErr
+}
+
+func (kp *KafkaAsyncProducer) SendMessage(ctx context.Context, topic, message string) {
+	kp.send(ctx, &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.StringEncoder(message),
+	})
 }
 
-func (kp *KafkaAsyncProducer) SendMessage(topic, message string) {
-	kp.producer.Input() <- &sarama.ProducerMessage{
+func (kp *KafkaAsyncProducer) SendKeyedMessage(ctx context.Context, topic, key, message string) {
+	kp.send(ctx, &sarama.ProducerMessage{
 		Topic: topic,
+		Key:   sarama.StringEncoder(key),
 		Value: sarama.StringEncoder(message),
+	})
+}
+
+// send enqueues the message with trace context in its headers, the span is a child of the span of ctx
+// and ends when kafka acknowledges the message
+func (kp *KafkaAsyncProducer) send(ctx context.Context, message *sarama.ProducerMessage) {
+	ctx, span := otel.Tracer(tracing.Name).Start(ctx, "kafka.send "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKafka, semconv.MessagingDestinationName(message.Topic)),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{message})
+	message.Metadata = span
+
+	metrics.KafkaEnqueued()
+	kp.producer.Input() <- message
+}
+
+func endSpan(message *sarama.ProducerMessage, err error) {
+	if span, ok := message.Metadata.(trace.Span); ok {
+		tracing.End(span, err)
+	}
+}
+
+// headerCarrier passes trace context in headers of the message
+type headerCarrier struct {
+	message *sarama.ProducerMessage
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, header := range c.message.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, header := range c.message.Headers {
+		if string(header.Key) == key {
+			c.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.message.Headers = append(c.message.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(c.message.Headers))
+	for i, header := range c.message.Headers {
+		keys[i] = string(header.Key)
 	}
+	return keys
 }
 
 func (kp *KafkaAsyncProducer) Close() error {
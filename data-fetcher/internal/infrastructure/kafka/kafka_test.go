@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"context"
+	"cur/internal/infrastructure/tracing"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestKafkaAsyncProducer_TraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install("test", 1, sdktrace.NewSimpleSpanProcessor(exporter))
+	defer provider.Shutdown(context.Background())
+
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	mock := mocks.NewAsyncProducer(t, config)
+	var headers []sarama.RecordHeader
+	mock.ExpectInputWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+		headers = message.Headers
+		return nil
+	})
+	mock.ExpectInputAndFail(errors.New("broker is down"))
+
+	producer := newKafkaAsyncProducer(mock)
+	ctx, parent := tracing.Start(context.Background(), "cron update_candles")
+	producer.SendKeyedMessage(ctx, "trades", "BTC-USDT", "{}")
+	producer.SendMessage(context.Background(), "alerts", "{}")
+	parent.End()
+
+	assert.Eventually(t, func() bool { return len(exporter.GetSpans()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, producer.Close())
+
+	spans := exporter.GetSpans()
+	var sent, failed tracetest.SpanStub
+	for _, span := range spans {
+		switch span.Name {
+		case "kafka.send trades":
+			sent = span
+		case "kafka.send alerts":
+			failed = span
+		}
+	}
+	assert.Equal(t, trace.SpanKindProducer, sent.SpanKind)
+	assert.Equal(t, codes.Unset, sent.Status.Code)
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, parent.SpanContext().SpanID(), sent.Parent.SpanID(), "a child of the caller's span")
+	assert.Equal(t, parent.SpanContext().TraceID(), sent.SpanContext.TraceID())
+	assert.False(t, failed.Parent.IsValid(), "a root span without a caller's span")
+
+	// the consumer continues the trace of the message
+	message := &sarama.ProducerMessage{Headers: headers}
+	ctx = otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier{message})
+	assert.Equal(t, sent.SpanContext.TraceID(), trace.SpanContextFromContext(ctx).TraceID())
+	assert.Equal(t, sent.SpanContext.SpanID(), trace.SpanContextFromContext(ctx).SpanID())
+}
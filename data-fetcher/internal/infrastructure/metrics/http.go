@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// OkxTransport counts requests to OKX REST endpoints, the endpoint is the path of the request
+type OkxTransport struct {
+	Next http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t OkxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	okxRequestDuration.WithLabelValues(req.URL.Path).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	okxRequests.WithLabelValues(req.URL.Path, status).Inc()
+
+	return resp, err
+}
+
+// InstrumentRoute counts requests of the handler registered for the route pattern,
+// the response writer keeps being a http.Flusher and a http.Hijacker
+func InstrumentRoute(route string, handler http.Handler) http.Handler {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerDuration(apiRequestDuration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(apiRequests.MustCurryWith(labels), handler))
+}
@@ -0,0 +1,179 @@
+// Package metrics collects Prometheus metrics of the fetcher and serves them for scraping
+package metrics
+
+import (
+	"context"
+	"cur/internal/model"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fetcher"
+
+// Registry every metric of the package is registered in, with go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	okxRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "okx_rest_requests_total",
+		Help:      "OKX REST requests by endpoint and status code, status is \"error\" when no response came.",
+	}, []string{"endpoint", "status"})
+	okxRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "okx_rest_request_duration_seconds",
+		Help:      "OKX REST request latency by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "API requests by route and status code.",
+	}, []string{"route", "code"})
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "API request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	candlesInserted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "candles_inserted_total",
+		Help:      "Candles inserted or updated by pair and bar.",
+	}, []string{"pair", "bar"})
+
+	websocketReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_reconnects_total",
+		Help:      "OKX websocket connection attempts after the first one.",
+	})
+	websocketMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_messages_total",
+		Help:      "OKX websocket messages by channel, \"invalid\" for unparsable ones.",
+	}, []string{"channel"})
+
+	kafkaProduced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_produced_total",
+		Help:      "Messages acknowledged by Kafka by topic.",
+	}, []string{"topic"})
+	kafkaProduceErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_produce_errors_total",
+		Help:      "Messages Kafka failed to accept by topic.",
+	}, []string{"topic"})
+	kafkaQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_producer_queue_depth",
+		Help:      "Messages passed to Kafka producers and not acknowledged or failed yet.",
+	})
+
+	cronDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cron_job_duration_seconds",
+		Help:      "Duration of scheduled jobs.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"job"})
+	cronLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cron_job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of scheduled jobs.",
+	}, []string{"job"})
+
+	leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 while the instance is the leader running scheduled jobs and the trade websocket, 0 otherwise.",
+	})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database latency by operation: query, exec, begin, commit or rollback.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation"})
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Failed database operations by operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		okxRequests, okxRequestDuration,
+		apiRequests, apiRequestDuration,
+		candlesInserted,
+		websocketReconnects, websocketMessages,
+		kafkaProduced, kafkaProduceErrors, kafkaQueueDepth,
+		cronDuration, cronLastSuccess,
+		leader,
+		dbDuration, dbErrors,
+	)
+}
+
+// Handler serves metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// CandlesInserted counts inserted candles, fits store.CandlesListener
+func CandlesInserted(_ context.Context, candles []model.Candle) {
+	for _, candle := range candles {
+		candlesInserted.WithLabelValues(candle.Pair, candle.Bar).Inc()
+	}
+}
+
+func WebsocketReconnect() {
+	websocketReconnects.Inc()
+}
+
+func WebsocketMessage(channel string) {
+	websocketMessages.WithLabelValues(channel).Inc()
+}
+
+// KafkaEnqueued counts a message passed to a producer
+func KafkaEnqueued() {
+	kafkaQueueDepth.Inc()
+}
+
+// KafkaProduced counts a message acknowledged by Kafka
+func KafkaProduced(topic string) {
+	kafkaQueueDepth.Dec()
+	kafkaProduced.WithLabelValues(topic).Inc()
+}
+
+// KafkaFailed counts a message Kafka failed to accept
+func KafkaFailed(topic string) {
+	kafkaQueueDepth.Dec()
+	kafkaProduceErrors.WithLabelValues(topic).Inc()
+}
+
+// CronJob runs the job recording its duration, the last success time is set when it returns no error
+func CronJob(job string, run func() error) error {
+	start := time.Now()
+	err := run()
+	cronDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if err == nil {
+		cronLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+	return err
+}
+
+// Leader sets whether the instance is the leader
+func Leader(leading bool) {
+	if leading {
+		leader.Set(1)
+	} else {
+		leader.Set(0)
+	}
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"context"
+	"cur/internal/model"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	CandlesInserted(context.Background(), []model.Candle{{Pair: "BTC-USDT", Bar: "1H"}, {Pair: "BTC-USDT", Bar: "1H"}})
+	WebsocketMessage("trades")
+	KafkaEnqueued()
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `fetcher_candles_inserted_total{bar="1H",pair="BTC-USDT"} 2`)
+	assert.Contains(t, string(body), `fetcher_websocket_messages_total{channel="trades"}`)
+	assert.Contains(t, string(body), "fetcher_kafka_producer_queue_depth")
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestKafka(t *testing.T) {
+	depth := testutil.ToFloat64(kafkaQueueDepth)
+	KafkaEnqueued()
+	KafkaEnqueued()
+	KafkaEnqueued()
+	KafkaProduced("trades")
+	KafkaFailed("trades")
+
+	assert.Equal(t, depth+1, testutil.ToFloat64(kafkaQueueDepth))
+	assert.Equal(t, 1.0, testutil.ToFloat64(kafkaProduceErrors.WithLabelValues("trades")))
+}
+
+func TestCronJob(t *testing.T) {
+	require.NoError(t, CronJob("test_ok", func() error { return nil }))
+	assert.Positive(t, testutil.ToFloat64(cronLastSuccess.WithLabelValues("test_ok")))
+
+	require.Error(t, CronJob("test_failed", func() error { return errors.New("failed") }))
+	assert.Zero(t, testutil.ToFloat64(cronLastSuccess.WithLabelValues("test_failed")), "no success yet")
+	assert.Equal(t, 2, testutil.CollectAndCount(cronDuration, "fetcher_cron_job_duration_seconds"))
+}
+
+func TestOkxTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: OkxTransport{}}
+	for _, path := range []string{"/api/v5/market/history-candles?instId=BTC-USDT", "/api/v5/market/history-candles", "/missing"} {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	_, err := client.Get("http://127.0.0.1:1/unreachable")
+	require.Error(t, err)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(okxRequests.WithLabelValues("/api/v5/market/history-candles", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(okxRequests.WithLabelValues("/missing", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(okxRequests.WithLabelValues("/unreachable", "error")))
+}
+
+func TestInstrumentRoute(t *testing.T) {
+	handler := InstrumentRoute("GET /v1/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok, "streams are flushed")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/test", nil))
+	assert.Equal(t, 1.0, testutil.ToFloat64(apiRequests.WithLabelValues("GET /v1/test", "418")))
+}
+
+// fakeConnector makes connections answering every query with no rows
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+func (fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "broken") {
+		return nil, errors.New("syntax error")
+	}
+	return fakeRows{}, nil
+}
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+func (fakeStmt) ExecContext(context.Context, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"value"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+// histogramCount returns the number of observed durations of the operation
+func histogramCount(t *testing.T, operation string) uint64 {
+	var metric dto.Metric
+	require.NoError(t, dbDuration.WithLabelValues(operation).(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentConnector(t *testing.T) {
+	db := sql.OpenDB(InstrumentConnector(fakeConnector{}))
+	defer db.Close()
+
+	before := histogramCount(t, "query")
+	rows, err := db.Query("SELECT 1")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	_, err = db.Query("broken")
+	require.Error(t, err)
+	assert.Equal(t, before+2, histogramCount(t, "query"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(dbErrors.WithLabelValues("query")))
+
+	before = histogramCount(t, "exec")
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("UPDATE candles SET volume = 0")
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("INSERT INTO candles VALUES ($1)")
+	require.NoError(t, err)
+	_, err = stmt.Exec(1)
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, before+2, histogramCount(t, "exec"), "prepared statements are timed too")
+	assert.Equal(t, uint64(1), histogramCount(t, "commit"))
+}
+
+// countingConnector counts connections of the connector
+type countingConnector struct {
+	connects *int
+	conn     driver.Conn
+}
+
+func (c countingConnector) Connect(context.Context) (driver.Conn, error) {
+	*c.connects++
+	return c.conn, nil
+}
+func (countingConnector) Driver() driver.Driver { return nil }
+
+// invalidConn is broken after it is used
+type invalidConn struct{ fakeConn }
+
+func (invalidConn) IsValid() bool { return false }
+
+// resetFailingConn can't reset its session
+type resetFailingConn struct{ fakeConn }
+
+func (resetFailingConn) ResetSession(context.Context) error { return driver.ErrBadConn }
+
+func TestInstrumentConnector_DropsBrokenConnections(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		conn     driver.Conn
+		connects int
+	}{
+		{"valid", fakeConn{}, 1},
+		{"invalid", invalidConn{}, 3},
+		{"reset failing", resetFailingConn{}, 3},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			connects := 0
+			db := sql.OpenDB(InstrumentConnector(countingConnector{&connects, testCase.conn}))
+			defer db.Close()
+
+			for i := 0; i < 3; i++ {
+				rows, err := db.Query("SELECT 1")
+				require.NoError(t, err)
+				require.NoError(t, rows.Close())
+			}
+			assert.Equal(t, testCase.connects, connects)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func install(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := Install("test", 1, sdktrace.NewSimpleSpanProcessor(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return exporter
+}
+
+func TestEnd(t *testing.T) {
+	exporter := install(t)
+
+	_, span := Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = Start(context.Background(), "failed")
+	End(span, errors.New("boom"))
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+		assert.Equal(t, codes.Error, spans[1].Status.Code)
+		assert.Equal(t, "boom", spans[1].Status.Description)
+		assert.Len(t, spans[1].Events, 1)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter := install(t)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Transport{}}
+	ctx, parent := Start(context.Background(), "parent")
+	for _, path := range []string{"/candles", "/missing"} {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+path, nil)
+		resp, err := client.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if assert.Len(t, spans, 3) {
+		candles, missing := spans[0], spans[1]
+		assert.Equal(t, "GET /candles", candles.Name)
+		assert.Equal(t, trace.SpanKindClient, candles.SpanKind)
+		assert.Equal(t, parent.SpanContext().SpanID(), candles.Parent.SpanID())
+		assert.Equal(t, codes.Unset, candles.Status.Code)
+		assert.Equal(t, codes.Error, missing.Status.Code)
+		assert.Contains(t, traceparent, missing.SpanContext.SpanID().String())
+	}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "", "test", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
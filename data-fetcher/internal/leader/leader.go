@@ -0,0 +1,125 @@
+// Package leader elects one of the app instances to run work which mustn't be duplicated
+package leader
+
+import (
+	"context"
+	"cur/internal/infrastructure/metrics"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Lock is held by one instance at a time, e.g. store.AdvisoryLock
+type Lock interface {
+	// TryAcquire takes the lock unless another instance holds it
+	TryAcquire(ctx context.Context) (bool, error)
+	// Check returns an error when the lock is lost
+	Check(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// Elector campaigns for the lock and runs the leader work while holding it
+type Elector struct {
+	lock     Lock
+	interval time.Duration
+	log      *log.Logger
+	leading  atomic.Bool
+}
+
+// NewElector makes the elector trying the lock and checking it every interval
+func NewElector(lock Lock, interval time.Duration, log *log.Logger) *Elector {
+	return &Elector{lock: lock, interval: interval, log: log}
+}
+
+// IsLeader reports whether the instance holds the lock and runs the leader work
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns until ctx is done. Each time the lock is taken lead is called with a context
+// which is cancelled when the lock is lost, the lock is released after lead returns.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		acquired, err := e.lock.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.log.Warnf("leader election failed: %v", err)
+		}
+		if acquired {
+			e.lead(ctx, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, lead func(ctx context.Context)) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+
+	e.setLeader(true)
+	e.log.Info("elected as the leader")
+	go func() {
+		defer close(done)
+		lead(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := e.check(ctx); err != nil {
+					e.log.Errorf("leadership is lost: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	// the lock is held until the leader work stops, so the next leader doesn't overlap with it
+	cancel()
+	<-done
+	e.setLeader(false)
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), e.interval)
+	defer cancelRelease()
+	if err := e.lock.Release(releaseCtx); err != nil {
+		e.log.Error(err)
+	}
+	e.log.Info("stepped down as the leader")
+}
+
+// check fails when the lock isn't confirmed within the interval, e.g. on a half-open connection
+// which blocks while Postgres may give the lock to another instance
+func (e *Elector) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+
+	checked := make(chan error, 1)
+	go func() { checked <- e.lock.Check(ctx) }()
+	select {
+	case err := <-checked:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("lock check timed out: %w", ctx.Err())
+	}
+}
+
+func (e *Elector) setLeader(leading bool) {
+	e.leading.Store(leading)
+	metrics.Leader(leading)
+}
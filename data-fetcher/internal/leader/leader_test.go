@@ -0,0 +1,193 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLock is shared by electors like the advisory lock is shared by instances
+type fakeLock struct {
+	mu     sync.Mutex
+	holder *instanceLock
+}
+
+// instanceLock is the lock as seen by one instance
+type instanceLock struct {
+	shared *fakeLock
+	lost   bool
+	hang   chan struct{} // checks block until it's closed, ignoring ctx
+}
+
+func (l *fakeLock) instance() *instanceLock {
+	return &instanceLock{shared: l}
+}
+
+func (l *instanceLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	if l.shared.holder != nil && l.shared.holder != l {
+		return false, nil
+	}
+	l.shared.holder = l
+	l.lost = false
+	return true, nil
+}
+
+func (l *instanceLock) Check(ctx context.Context) error {
+	l.shared.mu.Lock()
+	hang := l.hang
+	l.shared.mu.Unlock()
+	if hang != nil {
+		<-hang
+	}
+
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	if l.lost {
+		return errors.New("session is lost")
+	}
+	return nil
+}
+
+func (l *instanceLock) Release(ctx context.Context) error {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	if l.shared.holder == l {
+		l.shared.holder = nil
+	}
+	return nil
+}
+
+// loseSession drops the lock like Postgres does when the session of the holder ends
+func (l *instanceLock) loseSession() {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	l.lost = true
+	if l.shared.holder == l {
+		l.shared.holder = nil
+	}
+}
+
+// leaderWork counts instances running the leader work at once
+type leaderWork struct {
+	mu      sync.Mutex
+	running map[string]bool
+	overlap bool
+	starts  []string
+}
+
+func (w *leaderWork) lead(name string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		w.mu.Lock()
+		if len(w.running) > 0 {
+			w.overlap = true
+		}
+		w.running[name] = true
+		w.starts = append(w.starts, name)
+		w.mu.Unlock()
+
+		<-ctx.Done()
+
+		w.mu.Lock()
+		delete(w.running, name)
+		w.mu.Unlock()
+	}
+}
+
+func (w *leaderWork) leaders() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var names []string
+	for name := range w.running {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestElector_SingleLeaderAndFailover(t *testing.T) {
+	lock := &fakeLock{}
+	work := &leaderWork{running: map[string]bool{}}
+	first, second := lock.instance(), lock.instance()
+	firstElector := NewElector(first, 10*time.Millisecond, log.New())
+	secondElector := NewElector(second, 10*time.Millisecond, log.New())
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		firstElector.Run(firstCtx, work.lead("first"))
+	}()
+	assert.Eventually(t, firstElector.IsLeader, time.Second, time.Millisecond)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	go secondElector.Run(secondCtx, work.lead("second"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"first"}, work.leaders())
+	assert.False(t, secondElector.IsLeader())
+
+	// the first instance shuts down, the second one takes over
+	stopFirst()
+	<-firstDone
+	assert.False(t, firstElector.IsLeader())
+	assert.Eventually(t, secondElector.IsLeader, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return len(work.leaders()) == 1 && work.leaders()[0] == "second" }, time.Second, time.Millisecond)
+
+	work.mu.Lock()
+	defer work.mu.Unlock()
+	assert.False(t, work.overlap)
+	assert.Equal(t, []string{"first", "second"}, work.starts)
+}
+
+func TestElector_StepsDownWhenLockIsLost(t *testing.T) {
+	lock := &fakeLock{}
+	work := &leaderWork{running: map[string]bool{}}
+	instance := lock.instance()
+	elector := NewElector(instance, 10*time.Millisecond, log.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx, work.lead("only"))
+	assert.Eventually(t, elector.IsLeader, time.Second, time.Millisecond)
+
+	// another instance grabs the lock after the session of this one is gone
+	instance.loseSession()
+	other := lock.instance()
+	acquired, _ := other.TryAcquire(ctx)
+	assert.True(t, acquired)
+
+	assert.Eventually(t, func() bool { return !elector.IsLeader() && len(work.leaders()) == 0 }, time.Second, time.Millisecond)
+
+	// and it's elected again once the lock is free
+	assert.NoError(t, other.Release(ctx))
+	assert.Eventually(t, elector.IsLeader, time.Second, time.Millisecond)
+}
+
+func TestElector_StepsDownWhenCheckHangs(t *testing.T) {
+	lock := &fakeLock{}
+	work := &leaderWork{running: map[string]bool{}}
+	instance := lock.instance()
+	elector := NewElector(instance, 10*time.Millisecond, log.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx, work.lead("only"))
+	assert.Eventually(t, elector.IsLeader, time.Second, time.Millisecond)
+
+	// a half-open connection doesn't answer while Postgres ends the session and gives the lock to another instance
+	hang := make(chan struct{})
+	defer close(hang)
+	lock.mu.Lock()
+	instance.hang = hang
+	lock.holder = lock.instance()
+	lock.mu.Unlock()
+
+	assert.Eventually(t, func() bool { return !elector.IsLeader() && len(work.leaders()) == 0 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,129 @@
+// Package lifecycle starts components of the app in order and stops them in reverse order
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Component is a part of the app, nil Start or Stop does nothing
+type Component struct {
+	Name string
+	// Start gets the root context, it may block until the component is ready
+	Start func(ctx context.Context) error
+	// Stop is called after the root context is cancelled, it returns when in-flight work is done or ctx expires
+	Stop func(ctx context.Context) error
+}
+
+// Manager runs components with the root context which is cancelled on shutdown
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	log    *log.Logger
+
+	mu         sync.Mutex
+	components []Component
+	started    int
+}
+
+// NewManager makes the manager, the root context is derived from parent, e.g. cancelled on a signal
+func NewManager(parent context.Context, log *log.Logger) *Manager {
+	ctx, cancel := context.WithCancel(parent)
+	return &Manager{ctx: ctx, cancel: cancel, log: log}
+}
+
+// Context returns the root context, it's done when shutdown begins
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Add adds the component started after the ones added before
+func (m *Manager) Add(component Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component)
+}
+
+// Go adds the component running in background until the root context is done, it's stopped when run returns
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	done := make(chan struct{})
+	m.Add(Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				select {
+				case <-done: // finished while the previous components were stopping
+					return nil
+				default:
+					return ctx.Err()
+				}
+			}
+		},
+	})
+}
+
+// Start starts components in order, it stops at the first failed one or when the root context is done
+func (m *Manager) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ; m.started < len(m.components); m.started++ {
+		if err := m.ctx.Err(); err != nil {
+			return err
+		}
+		component := m.components[m.started]
+		if component.Start == nil {
+			continue
+		}
+		m.log.Infof("starting %s", component.Name)
+		if err := component.Start(m.ctx); err != nil {
+			return fmt.Errorf("failed to start %s: %w", component.Name, err)
+		}
+	}
+
+	return m.ctx.Err()
+}
+
+// Shutdown cancels the root context and stops started components in reverse order within the timeout,
+// it returns errors of components which failed or didn't stop in time
+func (m *Manager) Shutdown(timeout time.Duration) error {
+	m.cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for i := m.started - 1; i >= 0; i-- {
+		component := m.components[i]
+		if component.Stop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := component.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", component.Name, err))
+			continue
+		}
+		m.log.Infof("stopped %s in %s", component.Name, time.Since(start).Round(time.Millisecond))
+	}
+	m.started = 0
+
+	return errors.Join(errs...)
+}
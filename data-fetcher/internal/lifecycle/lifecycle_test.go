@@ -0,0 +1,93 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func recorder(calls *[]string, name string) Component {
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestManager_Order(t *testing.T) {
+	manager := NewManager(context.Background(), log.New())
+	var calls []string
+	manager.Add(recorder(&calls, "store"))
+	manager.Add(recorder(&calls, "http"))
+	manager.Add(recorder(&calls, "cron"))
+
+	assert.NoError(t, manager.Start())
+	assert.NoError(t, manager.Context().Err())
+	assert.NoError(t, manager.Shutdown(time.Second))
+	assert.ErrorIs(t, manager.Context().Err(), context.Canceled)
+
+	assert.Equal(t, []string{"start store", "start http", "start cron", "stop cron", "stop http", "stop store"}, calls)
+}
+
+func TestManager_StartFailure(t *testing.T) {
+	manager := NewManager(context.Background(), log.New())
+	var calls []string
+	manager.Add(recorder(&calls, "store"))
+	manager.Add(Component{Name: "grpc", Start: func(ctx context.Context) error { return errors.New("address in use") }})
+	manager.Add(recorder(&calls, "cron"))
+
+	assert.EqualError(t, manager.Start(), "failed to start grpc: address in use")
+	assert.NoError(t, manager.Shutdown(time.Second))
+
+	assert.Equal(t, []string{"start store", "stop store"}, calls)
+}
+
+func TestManager_CancelledWhileStarting(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	manager := NewManager(parent, log.New())
+	var calls []string
+	manager.Add(Component{Name: "history", Start: func(ctx context.Context) error {
+		cancel() // a signal comes during the long first step
+		<-ctx.Done()
+		calls = append(calls, "history interrupted")
+		return nil
+	}})
+	manager.Add(recorder(&calls, "cron"))
+
+	assert.ErrorIs(t, manager.Start(), context.Canceled)
+	assert.NoError(t, manager.Shutdown(time.Second))
+
+	assert.Equal(t, []string{"history interrupted"}, calls)
+}
+
+func TestManager_Go(t *testing.T) {
+	manager := NewManager(context.Background(), log.New())
+	finished := false
+	manager.Go("flush", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond) // in-flight work
+		finished = true
+	})
+	stuck := make(chan struct{})
+	defer close(stuck)
+	manager.Go("stuck", func(ctx context.Context) {
+		<-stuck
+	})
+
+	assert.NoError(t, manager.Start())
+	err := manager.Shutdown(50 * time.Millisecond)
+
+	assert.True(t, finished)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "stuck")
+}
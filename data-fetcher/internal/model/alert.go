@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+type AlertKind string
+
+const (
+	AlertThreshold     AlertKind = "threshold"      // trade price crosses Level
+	AlertPercentChange AlertKind = "percent_change" // trade price moves by Percent within Window
+	AlertVolumeSpike   AlertKind = "volume_spike"   // closed candle volume reaches Multiplier times the average of Lookback previous candles
+	AlertIndicator     AlertKind = "indicator"      // indicator of closed candles crosses Level
+)
+
+type AlertDirection string
+
+const (
+	DirectionAbove AlertDirection = "above"
+	DirectionBelow AlertDirection = "below"
+	DirectionAny   AlertDirection = "any"
+)
+
+// Alert delivery channels
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+	ChannelEmail    = "email"
+)
+
+// AlertRule condition on a pair, fixed-point values are multiplied by price.PriceFactor
+type AlertRule struct {
+	Id         int64
+	Name       string
+	Pair       string
+	Bar        string // candles of volume spike and indicator rules
+	Kind       AlertKind
+	Direction  AlertDirection
+	Level      int64         // price of threshold rules, indicator value of indicator rules
+	Percent    int64         // percent of percent change rules, e.g. 5% is 5e8
+	Window     time.Duration // window of percent change rules
+	Multiplier int64         // multiplier of volume spike rules, e.g. 3x is 3e8
+	Lookback   int           // number of averaged candles of volume spike rules
+	Indicator  string        // IndicatorKey of indicator rules, e.g. "rsi_14" or "macd_12_26_9.histogram"
+	Cooldown   time.Duration // minimal time between two notifications
+	Channels   []string
+	Enabled    bool
+	// LastTriggeredAt zero if the rule never triggered
+	LastTriggeredAt time.Time
+	CreatedAt       time.Time
+}
+
+// AlertEvent triggered rule
+type AlertEvent struct {
+	Id          int64
+	RuleId      int64
+	Pair        string
+	Kind        AlertKind
+	Value       int64 // price, percent, volume multiple or indicator value which triggered the rule
+	Message     string
+	TriggeredAt time.Time
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type VolatilityEstimator string
+
+const (
+	VolatilityCloseToClose VolatilityEstimator = "close_to_close" // standard deviation of log returns of closes
+	VolatilityParkinson    VolatilityEstimator = "parkinson"      // by high-low ranges
+	VolatilityGarmanKlass  VolatilityEstimator = "garman_klass"   // by high-low ranges and open-close moves
+)
+
+type CorrelationMethod string
+
+const (
+	CorrelationPearson  CorrelationMethod = "pearson"
+	CorrelationSpearman CorrelationMethod = "spearman" // Pearson correlation of ranks
+)
+
+// Volatility annualized realized volatility of Window candles ending at Timestamp, percent multiplied by price.PriceFactor
+type Volatility struct {
+	Pair      string
+	Bar       string
+	Window    int
+	Estimator VolatilityEstimator
+	Timestamp time.Time
+	Value     int64
+}
+
+// Correlation of log returns of two pairs over Window common candles ending at Timestamp,
+// the coefficient is multiplied by price.PriceFactor and PairA < PairB
+type Correlation struct {
+	Bar       string
+	Window    int
+	Method    CorrelationMethod
+	Timestamp time.Time
+	PairA     string
+	PairB     string
+	Value     int64
+}
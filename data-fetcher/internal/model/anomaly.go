@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+type AnomalyKind string
+
+const (
+	AnomalyOutlier   AnomalyKind = "outlier"   // price far from the rolling median of the pair
+	AnomalyInvariant AnomalyKind = "invariant" // values of the row contradict each other, e.g. high < low
+	AnomalyStale     AnomalyKind = "stale"     // no trades of the pair for too long
+)
+
+type AnomalySource string
+
+const (
+	AnomalySourceCandle AnomalySource = "candle"
+	AnomalySourceTrade  AnomalySource = "trade"
+	AnomalySourceFeed   AnomalySource = "feed" // the trade feed of the pair, nothing is quarantined
+)
+
+type AnomalyStatus string
+
+const (
+	AnomalyPending   AnomalyStatus = "pending"
+	AnomalyReleased  AnomalyStatus = "released"  // the row was found valid and stored
+	AnomalyDiscarded AnomalyStatus = "discarded" // the row was found bad and dropped
+)
+
+// Anomaly suspect row kept out of analytics until it is reviewed, Candle or Trade is set by Source.
+// Timestamp is the time of the row, the time of the last trade for stale feeds.
+type Anomaly struct {
+	Id         int64
+	Kind       AnomalyKind
+	Source     AnomalySource
+	Pair       string
+	Bar        string // of candles
+	Timestamp  time.Time
+	Reason     string
+	Candle     *Candle
+	Trade      *Trade
+	Status     AnomalyStatus
+	DetectedAt time.Time
+	ReviewedAt time.Time // zero while pending
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BarDuration returns the length of an OKX bar such as "1m", "4H", "1D" or "1Wutc".
+// Monthly bars have no fixed length and are rejected.
+func BarDuration(bar string) (time.Duration, error) {
+	value := strings.TrimSuffix(bar, "utc")
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid bar %q", bar)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid bar %q", bar)
+	}
+
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'H':
+		unit = time.Hour
+	case 'D':
+		unit = 24 * time.Hour
+	case 'W':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("unsupported bar %q", bar)
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+// ClosedBefore returns the latest opening time of a bar closed at now, candles after it are still changing
+func ClosedBefore(bar string, now time.Time) (time.Time, error) {
+	duration, err := BarDuration(bar)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-duration), nil
+}
+
+// ClosedCandles drops the trailing candles of bars which aren't closed at now,
+// candles of bars without fixed length are kept
+func ClosedCandles(candles []Candle, bar string, now time.Time) []Candle {
+	cutOff, err := ClosedBefore(bar, now)
+	if err != nil {
+		return candles
+	}
+	for len(candles) > 0 && candles[len(candles)-1].Timestamp.After(cutOff) {
+		candles = candles[:len(candles)-1]
+	}
+	return candles
+}
+
+// hongKong bars of OKX without the "utc" suffix open at midnight of UTC+8
+const hongKong = 8 * time.Hour
+
+// monday weeks are counted from the first Monday of the Unix epoch
+var monday = time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// BarStart returns the opening time of the bar containing ts like OKX aligns candles:
+// bars without the "utc" suffix open in Hong Kong time, weeks open on Monday.
+func BarStart(bar string, ts time.Time) (time.Time, error) {
+	duration, err := BarDuration(bar)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	origin := monday
+	if !strings.HasSuffix(bar, "utc") {
+		origin = origin.Add(-hongKong)
+	}
+
+	elapsed := ts.Sub(origin)
+	offset := elapsed % duration
+	if offset < 0 {
+		offset += duration
+	}
+	return ts.Add(-offset).UTC(), nil
+}
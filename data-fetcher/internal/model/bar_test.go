@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarDuration(t *testing.T) {
+	for bar, expected := range map[string]time.Duration{
+		"1s":    time.Second,
+		"15m":   15 * time.Minute,
+		"4H":    4 * time.Hour,
+		"1D":    24 * time.Hour,
+		"1Dutc": 24 * time.Hour,
+		"1W":    7 * 24 * time.Hour,
+	} {
+		duration, err := BarDuration(bar)
+		require.NoError(t, err, bar)
+		assert.Equal(t, expected, duration, bar)
+	}
+
+	for _, bar := range []string{"", "H", "0m", "1M", "1Mutc", "xH"} {
+		_, err := BarDuration(bar)
+		assert.Error(t, err, bar)
+	}
+}
+
+func TestBarStart(t *testing.T) {
+	ts := time.Date(2024, 3, 6, 5, 37, 12, 500, time.UTC) // Wednesday
+	for bar, expected := range map[string]time.Time{
+		"1s":    time.Date(2024, 3, 6, 5, 37, 12, 0, time.UTC),
+		"15m":   time.Date(2024, 3, 6, 5, 30, 0, 0, time.UTC),
+		"1H":    time.Date(2024, 3, 6, 5, 0, 0, 0, time.UTC),
+		"4H":    time.Date(2024, 3, 6, 4, 0, 0, 0, time.UTC),
+		"6H":    time.Date(2024, 3, 6, 4, 0, 0, 0, time.UTC),
+		"6Hutc": time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+		"1D":    time.Date(2024, 3, 5, 16, 0, 0, 0, time.UTC),
+		"1Dutc": time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+		"1W":    time.Date(2024, 3, 3, 16, 0, 0, 0, time.UTC),
+		"1Wutc": time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+	} {
+		start, err := BarStart(bar, ts)
+		require.NoError(t, err, bar)
+		assert.Equal(t, expected, start, bar)
+	}
+
+	_, err := BarStart("1M", ts)
+	assert.Error(t, err)
+}
+
+func TestClosedCandles(t *testing.T) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candles := []Candle{{Timestamp: start}, {Timestamp: start.Add(time.Hour)}, {Timestamp: start.Add(2 * time.Hour)}}
+
+	assert.Len(t, ClosedCandles(candles, "1H", start.Add(3*time.Hour)), 3, "the last bar closes at now")
+	assert.Len(t, ClosedCandles(candles, "1H", start.Add(3*time.Hour-time.Second)), 2)
+	assert.Len(t, ClosedCandles(candles, "1H", start.Add(time.Hour)), 1, "every changing candle is dropped")
+	assert.Empty(t, ClosedCandles(candles, "1H", start))
+	assert.Len(t, ClosedCandles(candles, "1M", start), 3, "monthly bars have no fixed length")
+
+	cutOff, err := ClosedBefore("4H", start)
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(-4*time.Hour), cutOff)
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Divergence price of a pair on OKX compared to a reference venue, percents are multiplied by price.PriceFactor
+type Divergence struct {
+	Id             int64
+	Pair           string
+	Source         string
+	OkxPrice       int64
+	ReferencePrice int64
+	Spread         int64 // (reference - okx) / okx percent, e.g. 0.5% is 5e7
+	NetSpread      int64 // absolute spread minus taker fees of both venues, negative when it doesn't pay off
+	ObservedAt     time.Time
+}
+
+// DivergenceEvent net spread of a pair stayed at or above Threshold from StartedAt until TriggeredAt
+type DivergenceEvent struct {
+	Id             int64
+	Pair           string
+	Source         string
+	OkxPrice       int64
+	ReferencePrice int64
+	Spread         int64
+	NetSpread      int64
+	Threshold      int64
+	StartedAt      time.Time
+	TriggeredAt    time.Time
+}
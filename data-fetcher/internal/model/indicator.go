@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// IndicatorValue precomputed value of an indicator component at the candle timestamp
+type IndicatorValue struct {
+	Pair      string
+	Bar       string
+	Timestamp time.Time
+	Indicator string // e.g. "macd_12_26_9"
+	Component string // e.g. "signal", "value" for single-valued indicators
+	Value     int64
+}
+
+// CandleIndicators candle with indicator values computed at its timestamp, keyed by IndicatorKey
+type CandleIndicators struct {
+	Candle Candle
+	Values map[string]int64
+}
+
+// IndicatorKey is the indicator name for single-valued indicators and "indicator.component" otherwise
+func IndicatorKey(indicator, component string) string {
+	if component == "value" {
+		return indicator
+	}
+	return indicator + "." + component
+}
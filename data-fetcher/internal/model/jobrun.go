@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type JobRunStatus string
+
+const (
+	JobRunning     JobRunStatus = "running"
+	JobSucceeded   JobRunStatus = "succeeded"
+	JobFailed      JobRunStatus = "failed"
+	JobInterrupted JobRunStatus = "interrupted" // the app stopped during the run
+)
+
+type JobTrigger string
+
+const (
+	JobTriggerSchedule JobTrigger = "schedule"
+	JobTriggerCatchUp  JobTrigger = "catch_up" // the first run after the app was down at a scheduled time
+	JobTriggerManual   JobTrigger = "manual"
+)
+
+// JobRun a run of a scheduled job, Items is the number of processed items, e.g. stored candles
+type JobRun struct {
+	Id         int64
+	Job        string
+	Trigger    JobTrigger
+	Status     JobRunStatus
+	StartedAt  time.Time
+	FinishedAt time.Time // zero while running
+	Error      string
+	Items      int
+}
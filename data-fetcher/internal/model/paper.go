@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+type OrderSide string
+
+const (
+	SideBuy  OrderSide = "buy"
+	SideSell OrderSide = "sell"
+)
+
+type OrderType string
+
+const (
+	OrderMarket OrderType = "market" // filled by the next trade of the pair
+	OrderLimit  OrderType = "limit"  // filled at LimitPrice by a trade at the limit or better
+)
+
+type OrderStatus string
+
+const (
+	OrderOpen      OrderStatus = "open"
+	OrderFilled    OrderStatus = "filled"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRejected  OrderStatus = "rejected" // the balance didn't cover the fill
+)
+
+// PaperOrder simulated order, fixed-point values are multiplied by price.PriceFactor
+type PaperOrder struct {
+	Id         int64
+	Pair       string
+	Side       OrderSide
+	Type       OrderType
+	Size       int64 // in the base currency
+	LimitPrice int64
+	Status     OrderStatus
+	Reason     string // why the order was rejected
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// PaperFill execution of a paper order, the fee is in the quote currency
+type PaperFill struct {
+	Id       int64
+	OrderId  int64
+	Pair     string
+	Side     OrderSide
+	Price    int64
+	Size     int64
+	Fee      int64
+	TradeId  string // the trade which filled the order
+	FilledAt time.Time
+}
+
+// PaperBalance simulated holding of a currency
+type PaperBalance struct {
+	Currency string
+	Amount   int64
+}
+
+// PaperPosition long position of a pair, realized P&L is in the quote currency and includes fees
+type PaperPosition struct {
+	Pair        string
+	Size        int64
+	AvgPrice    int64
+	RealizedPnl int64
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Holding a balance change of the portfolio, fixed-point values are multiplied by price.PriceFactor.
+// Negative amounts are sales or withdrawals, Price is the unit price in the base currency (zero when unknown)
+type Holding struct {
+	Id         int64
+	Currency   string
+	Amount     int64
+	Price      int64
+	AcquiredAt time.Time
+	Note       string
+	CreatedAt  time.Time
+}
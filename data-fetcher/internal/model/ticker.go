@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type Ticker struct {
+	Pair      string
+	Last      int64
+	Open24h   int64
+	High24h   int64
+	Low24h    int64
+	Volume24h int64
+	Timestamp time.Time
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Trade struct {
+	Pair      string
+	TradeId   string
+	Price     int64
+	Size      int64
+	Side      string
+	Timestamp time.Time
+}
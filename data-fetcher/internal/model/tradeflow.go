@@ -0,0 +1,53 @@
+package model
+
+import (
+	"cur/internal/helper/price"
+	"time"
+)
+
+// TradeFlow trades of the pair in the bar opening at Timestamp, the bar of the candle with the same key.
+// Volumes are in the base currency, Notional is in the quote currency, sides are the taker ones.
+type TradeFlow struct {
+	Pair         string
+	Bar          string
+	Timestamp    time.Time
+	BuyVolume    int64
+	SellVolume   int64
+	Notional     int64
+	TradeCount   int64
+	LargestTrade int64 // size of the largest trade
+}
+
+func (f TradeFlow) Volume() int64 {
+	return f.BuyVolume + f.SellVolume
+}
+
+// Vwap volume weighted average price, zero without volume
+func (f TradeFlow) Vwap() int64 {
+	volume := f.Volume()
+	if volume == 0 {
+		return 0
+	}
+	return price.MulDiv(f.Notional, price.PriceFactor, volume)
+}
+
+// Add counts the trade in the flow
+func (f *TradeFlow) Add(trade Trade) {
+	if trade.Side == "sell" {
+		f.SellVolume += trade.Size
+	} else {
+		f.BuyVolume += trade.Size
+	}
+	f.Notional += price.MulDiv(trade.Price, trade.Size, price.PriceFactor)
+	f.TradeCount++
+	f.LargestTrade = max(f.LargestTrade, trade.Size)
+}
+
+// Merge adds trades of another flow of the same bar
+func (f *TradeFlow) Merge(other TradeFlow) {
+	f.BuyVolume += other.BuyVolume
+	f.SellVolume += other.SellVolume
+	f.Notional += other.Notional
+	f.TradeCount += other.TradeCount
+	f.LargestTrade = max(f.LargestTrade, other.LargestTrade)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type Regime string
+
+const (
+	RegimeUptrend   Regime = "uptrend"
+	RegimeDowntrend Regime = "downtrend"
+	RegimeRange     Regime = "range"
+)
+
+// Structure of swing highs and lows
+type Structure string
+
+const (
+	StructureHigherHighs Structure = "higher_highs" // higher highs and higher lows
+	StructureLowerLows   Structure = "lower_lows"   // lower highs and lower lows
+	StructureMixed       Structure = "mixed"
+)
+
+// TrendState regime of the pair and bar which started at the candle Timestamp
+type TrendState struct {
+	Pair      string
+	Bar       string
+	Timestamp time.Time
+	Regime    Regime
+	FastMa    int64
+	SlowMa    int64
+	Adx       int64
+	Structure Structure
+}
@@ -0,0 +1,309 @@
+// Package scheduler runs jobs on cron schedules recording every run in the store
+package scheduler
+
+import (
+	"context"
+	"cur/internal/infrastructure/metrics"
+	"cur/internal/infrastructure/tracing"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning the previous run of the job hasn't finished, runs of a job never overlap
+	ErrJobRunning = errors.New("job is already running")
+	// ErrInactive jobs are run only while Run is running, e.g. in the leader instance
+	ErrInactive = errors.New("scheduler isn't running")
+)
+
+// Func does the work of a job, it returns the number of processed items, e.g. stored candles
+type Func func(ctx context.Context) (int, error)
+
+// Job definition
+type Job struct {
+	Name string
+	// Schedule standard cron spec, e.g. "0 * * * *"
+	Schedule string
+	// CatchUp runs the job once when the scheduler starts if a scheduled run was missed while the app was down
+	CatchUp bool
+	Run     Func
+}
+
+// JobState job definition with its current state
+type JobState struct {
+	Job
+	Paused  bool
+	Running bool
+	Next    time.Time    // zero while the scheduler isn't running
+	LastRun model.JobRun // zero before the first run
+}
+
+type job struct {
+	Job
+	schedule cron.Schedule
+	running  bool
+}
+
+// Scheduler runs jobs on schedule, catches up missed runs and runs jobs on demand.
+// Paused jobs aren't run on schedule but can be triggered.
+type Scheduler struct {
+	repository store.JobStore
+	jobs       []*job
+	log        *log.Logger
+	now        func() time.Time
+
+	mu   sync.Mutex
+	ctx  context.Context // of Run, nil while it isn't running
+	cron *cron.Cron
+	runs sync.WaitGroup
+}
+
+// NewScheduler checks job definitions, names must be unique
+func NewScheduler(repository store.JobStore, jobs []Job, log *log.Logger) (*Scheduler, error) {
+	s := &Scheduler{repository: repository, log: log, now: time.Now}
+	for _, definition := range jobs {
+		if definition.Name == "" || definition.Run == nil {
+			return nil, errors.New("job must have a name and a function")
+		}
+		if slices.ContainsFunc(s.jobs, func(j *job) bool { return j.Name == definition.Name }) {
+			return nil, fmt.Errorf("job %s is defined twice", definition.Name)
+		}
+		schedule, err := cron.ParseStandard(definition.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q of job %s: %w", definition.Schedule, definition.Name, err)
+		}
+		s.jobs = append(s.jobs, &job{Job: definition, schedule: schedule})
+	}
+	return s, nil
+}
+
+// Run runs jobs until ctx is done, then it waits for running jobs which see ctx is done.
+// Runs left running by a previous app are marked interrupted first.
+func (s *Scheduler) Run(ctx context.Context) {
+	if interrupted, err := s.repository.InterruptJobRuns(s.now()); err != nil {
+		s.log.Errorf("failed to interrupt unfinished job runs: %v", err)
+	} else if interrupted > 0 {
+		s.log.Warnf("%d unfinished job runs are interrupted", interrupted)
+	}
+
+	c := cron.New()
+	s.mu.Lock()
+	s.ctx, s.cron = ctx, c
+	for _, j := range s.jobs {
+		c.Schedule(j.schedule, cron.FuncJob(func() {
+			_, _ = s.start(j, model.JobTriggerSchedule)
+		}))
+	}
+	s.mu.Unlock()
+
+	s.catchUp()
+	c.Start()
+	<-ctx.Done()
+	<-c.Stop().Done()
+
+	s.mu.Lock()
+	s.ctx, s.cron = nil, nil
+	s.mu.Unlock()
+	s.runs.Wait()
+}
+
+// catchUp runs jobs once whose scheduled time passed since their last run
+func (s *Scheduler) catchUp() {
+	now := s.now()
+	for _, j := range s.jobs {
+		if !j.CatchUp {
+			continue
+		}
+		last, err := s.repository.LastJobRun(j.Name)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			s.log.Errorf("failed to check missed runs of job %s: %v", j.Name, err)
+			continue
+		}
+		if missed := j.schedule.Next(last.StartedAt); missed.Before(now) {
+			s.log.Infof("job %s missed the run at %s, catching up", j.Name, missed.Format(time.RFC3339))
+			_, _ = s.start(j, model.JobTriggerCatchUp)
+		}
+	}
+}
+
+// Trigger starts a run of the job now, the run is returned once it's recorded
+func (s *Scheduler) Trigger(name string) (model.JobRun, error) {
+	j, err := s.job(name)
+	if err != nil {
+		return model.JobRun{}, err
+	}
+	return s.start(j, model.JobTriggerManual)
+}
+
+// Pause stops scheduled runs of the job until it's resumed, it's kept in the store across restarts
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	if _, err := s.job(name); err != nil {
+		return err
+	}
+	return s.repository.SetJobPaused(name, paused, s.now())
+}
+
+// Jobs returns states of jobs in the order of definitions
+func (s *Scheduler) Jobs() ([]JobState, error) {
+	paused, err := s.repository.PausedJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]JobState, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		state, err := s.state(j, paused)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// JobState returns the state of the job
+func (s *Scheduler) JobState(name string) (JobState, error) {
+	j, err := s.job(name)
+	if err != nil {
+		return JobState{}, err
+	}
+	paused, err := s.repository.PausedJobs()
+	if err != nil {
+		return JobState{}, err
+	}
+	return s.state(j, paused)
+}
+
+func (s *Scheduler) state(j *job, paused []string) (JobState, error) {
+	last, err := s.repository.LastJobRun(j.Name)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return JobState{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := JobState{Job: j.Job, Paused: slices.Contains(paused, j.Name), Running: j.running, LastRun: last}
+	if s.ctx != nil {
+		state.Next = j.schedule.Next(s.now())
+	}
+	return state, nil
+}
+
+// Runs returns the latest runs of the job, newest first
+func (s *Scheduler) Runs(name string, limit int) ([]model.JobRun, error) {
+	if _, err := s.job(name); err != nil {
+		return nil, err
+	}
+	return s.repository.FetchJobRuns(name, limit)
+}
+
+func (s *Scheduler) job(name string) (*job, error) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownJob, name)
+}
+
+// start records the run and runs the job in background unless it's running or paused (for runs on schedule)
+func (s *Scheduler) start(j *job, trigger model.JobTrigger) (model.JobRun, error) {
+	if trigger != model.JobTriggerManual {
+		paused, err := s.repository.PausedJobs()
+		if err != nil {
+			s.log.Errorf("failed to check whether job %s is paused, running it: %v", j.Name, err)
+		}
+		if slices.Contains(paused, j.Name) {
+			s.log.Infof("job %s is paused, skipping the run", j.Name)
+			return model.JobRun{}, nil
+		}
+	}
+
+	s.mu.Lock()
+	ctx := s.ctx
+	if ctx == nil || ctx.Err() != nil {
+		s.mu.Unlock()
+		return model.JobRun{}, ErrInactive
+	}
+	if j.running {
+		s.mu.Unlock()
+		s.log.Warnf("job %s is still running, skipping the %s run", j.Name, trigger)
+		return model.JobRun{}, ErrJobRunning
+	}
+	j.running = true
+	s.runs.Add(1)
+	s.mu.Unlock()
+
+	run, err := s.repository.StartJobRun(model.JobRun{Job: j.Name, Trigger: trigger, Status: model.JobRunning, StartedAt: s.now()})
+	if err != nil {
+		// the work matters more than its record
+		s.log.Errorf("job %s runs unrecorded: %v", j.Name, err)
+		run = model.JobRun{Job: j.Name, Trigger: trigger, Status: model.JobRunning, StartedAt: s.now()}
+	}
+
+	go func() {
+		defer s.runs.Done()
+		defer func() {
+			s.mu.Lock()
+			j.running = false
+			s.mu.Unlock()
+		}()
+		s.execute(ctx, j, run)
+	}()
+
+	return run, nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job, run model.JobRun) {
+	s.log.Infof("job %s started (%s)", j.Name, run.Trigger)
+	err := metrics.CronJob(j.Name, func() (err error) {
+		ctx, span := tracing.Start(ctx, "cron "+j.Name, attribute.String("job", j.Name), attribute.String("trigger", string(run.Trigger)))
+		defer func() { tracing.End(span, err) }()
+		run.Items, err = j.Run(ctx)
+		return err
+	})
+
+	run.FinishedAt = s.now()
+	switch {
+	case ctx.Err() != nil:
+		run.Status = model.JobInterrupted
+	case err != nil:
+		run.Status = model.JobFailed
+	default:
+		run.Status = model.JobSucceeded
+	}
+	if err != nil {
+		run.Error = err.Error()
+		s.log.Errorf("job %s %s: %v", j.Name, run.Status, err)
+	} else {
+		s.log.Infof("job %s %s, %d items", j.Name, run.Status, run.Items)
+	}
+
+	if run.Id == 0 {
+		return
+	}
+	if err := s.repository.FinishJobRun(run); err != nil {
+		s.log.Errorf("failed to record the finished run of job %s: %v", j.Name, err)
+	}
+}
@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// never is a schedule which doesn't fire during tests
+const never = "0 0 1 1 *"
+
+// start runs the scheduler until the test ends
+func start(t *testing.T, s *Scheduler) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.ctx != nil
+	}, time.Second, time.Millisecond)
+	return cancel
+}
+
+// finished waits for the last run of the job to finish
+func finished(t *testing.T, repository *memory.JobRepository, job string) model.JobRun {
+	var run model.JobRun
+	require.Eventually(t, func() bool {
+		var err error
+		run, err = repository.LastJobRun(job)
+		return err == nil && run.Status != model.JobRunning
+	}, time.Second, time.Millisecond)
+	return run
+}
+
+func TestNewScheduler(t *testing.T) {
+	run := func(ctx context.Context) (int, error) { return 0, nil }
+	repository := memory.NewJobRepository()
+
+	_, err := NewScheduler(repository, []Job{{Name: "a", Schedule: "every hour", Run: run}}, log.New())
+	assert.Error(t, err)
+
+	_, err = NewScheduler(repository, []Job{{Name: "a", Schedule: never, Run: run}, {Name: "a", Schedule: never, Run: run}}, log.New())
+	assert.Error(t, err)
+
+	_, err = NewScheduler(repository, []Job{{Name: "a", Schedule: never}}, log.New())
+	assert.Error(t, err)
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	repository := memory.NewJobRepository()
+	release := make(chan struct{})
+	s, err := NewScheduler(repository, []Job{
+		{Name: "slow", Schedule: never, Run: func(ctx context.Context) (int, error) {
+			<-release
+			return 3, nil
+		}},
+		{Name: "failing", Schedule: never, Run: func(ctx context.Context) (int, error) {
+			return 1, errors.New("exchange is down")
+		}},
+	}, log.New())
+	require.NoError(t, err)
+
+	_, err = s.Trigger("slow")
+	assert.ErrorIs(t, err, ErrInactive, "not running, e.g. a follower")
+
+	start(t, s)
+
+	_, err = s.Trigger("unknown")
+	assert.ErrorIs(t, err, ErrUnknownJob)
+
+	run, err := s.Trigger("slow")
+	require.NoError(t, err)
+	assert.NotZero(t, run.Id)
+	assert.Equal(t, model.JobTriggerManual, run.Trigger)
+	assert.Equal(t, model.JobRunning, run.Status)
+
+	_, err = s.Trigger("slow")
+	assert.ErrorIs(t, err, ErrJobRunning, "runs never overlap")
+
+	states, err := s.Jobs()
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.True(t, states[0].Running)
+	assert.False(t, states[0].Next.IsZero())
+	assert.Equal(t, run.Id, states[0].LastRun.Id)
+
+	close(release)
+	done := finished(t, repository, "slow")
+	assert.Equal(t, model.JobSucceeded, done.Status)
+	assert.Equal(t, 3, done.Items)
+	assert.False(t, done.FinishedAt.IsZero())
+
+	_, err = s.Trigger("failing")
+	require.NoError(t, err)
+	failed := finished(t, repository, "failing")
+	assert.Equal(t, model.JobFailed, failed.Status)
+	assert.Equal(t, "exchange is down", failed.Error)
+	assert.Equal(t, 1, failed.Items)
+
+	runs, err := s.Runs("slow", 10)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+	_, err = s.Runs("unknown", 10)
+	assert.ErrorIs(t, err, ErrUnknownJob)
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	repository := memory.NewJobRepository()
+	now := time.Date(2025, 2, 3, 12, 30, 0, 0, time.Local)
+	for job, startedAt := range map[string]time.Time{
+		"missed":      now.Add(-2 * time.Hour),
+		"on time":     now.Add(-20 * time.Minute),
+		"paused":      now.Add(-2 * time.Hour),
+		"no catch up": now.Add(-2 * time.Hour),
+	} {
+		run, err := repository.StartJobRun(model.JobRun{Job: job, Trigger: model.JobTriggerSchedule, Status: model.JobRunning, StartedAt: startedAt})
+		require.NoError(t, err)
+		run.Status, run.FinishedAt = model.JobSucceeded, startedAt.Add(time.Minute)
+		require.NoError(t, repository.FinishJobRun(run))
+	}
+	require.NoError(t, repository.SetJobPaused("paused", true, now))
+
+	ran := make(chan string, 4)
+	job := func(name string) Job {
+		return Job{Name: name, Schedule: "0 * * * *", CatchUp: name != "no catch up", Run: func(ctx context.Context) (int, error) {
+			ran <- name
+			return 0, nil
+		}}
+	}
+	s, err := NewScheduler(repository, []Job{job("missed"), job("on time"), job("paused"), job("no catch up"), job("new")}, log.New())
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+
+	start(t, s)
+
+	assert.Equal(t, "missed", <-ran)
+	run := finished(t, repository, "missed")
+	assert.Equal(t, model.JobTriggerCatchUp, run.Trigger)
+	select {
+	case name := <-ran:
+		t.Fatalf("%s is run", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestScheduler_Interrupt(t *testing.T) {
+	repository := memory.NewJobRepository()
+	stale, err := repository.StartJobRun(model.JobRun{Job: "waiting", Trigger: model.JobTriggerSchedule, Status: model.JobRunning, StartedAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	s, err := NewScheduler(repository, []Job{{Name: "waiting", Schedule: never, Run: func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}}}, log.New())
+	require.NoError(t, err)
+
+	stop := start(t, s)
+
+	runs, err := s.Runs("waiting", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, stale.Id, runs[0].Id)
+	assert.Equal(t, model.JobInterrupted, runs[0].Status, "left running by the previous app")
+
+	_, err = s.Trigger("waiting")
+	require.NoError(t, err)
+	<-started
+	stop()
+
+	run := finished(t, repository, "waiting")
+	assert.Equal(t, model.JobInterrupted, run.Status)
+	_, err = s.Trigger("waiting")
+	assert.ErrorIs(t, err, ErrInactive)
+}
+
+func TestScheduler_Pause(t *testing.T) {
+	repository := memory.NewJobRepository()
+	s, err := NewScheduler(repository, []Job{{Name: "a", Schedule: never, Run: func(ctx context.Context) (int, error) { return 0, nil }}}, log.New())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Pause("a"))
+	state, err := s.JobState("a")
+	require.NoError(t, err)
+	assert.True(t, state.Paused)
+	assert.True(t, state.Next.IsZero(), "not running")
+
+	require.NoError(t, s.Resume("a"))
+	state, err = s.JobState("a")
+	require.NoError(t, err)
+	assert.False(t, state.Paused)
+
+	assert.ErrorIs(t, s.Pause("b"), ErrUnknownJob)
+}
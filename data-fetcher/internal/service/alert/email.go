@@ -0,0 +1,108 @@
+package alert
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends notifications over SMTP, STARTTLS is used when the server supports it
+type EmailNotifier struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// NewEmailNotifier makes email notifier, the authentication is skipped when username is empty
+func NewEmailNotifier(addr, username, password, from string, to []string) (*EmailNotifier, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address %q: %w", addr, err)
+	}
+	if from == "" || len(to) == 0 {
+		return nil, errors.New("email sender and recipients are required")
+	}
+
+	n := &EmailNotifier{addr: addr, host: host, from: from, to: to}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n, nil
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification Notification) error {
+	conn, err := (&net.Dialer{Timeout: notifierTimeout}).DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(notifierTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("smtp starttls failed: %w", err)
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(notification)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (n *EmailNotifier) message(notification Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.from + "\r\n")
+	b.WriteString("To: " + strings.Join(n.to, ", ") + "\r\n")
+	// rule names come from the api, line breaks would inject headers
+	b.WriteString("Subject: Alert: " + strings.NewReplacer("\r", " ", "\n", " ").Replace(notification.RuleName) + "\r\n")
+	b.WriteString("Date: " + notification.TriggeredAt.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(notification.Message + "\r\n")
+	b.WriteString("\r\n")
+	b.WriteString("Pair: " + notification.Pair + "\r\n")
+	b.WriteString("Value: " + notification.Value + "\r\n")
+	b.WriteString("Triggered at: " + notification.TriggeredAt.Format(time.RFC3339) + "\r\n")
+	return []byte(b.String())
+}
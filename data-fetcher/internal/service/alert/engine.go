@@ -0,0 +1,485 @@
+// Package alert evaluates alert rules against live trades and stored candles and
+// delivers triggered alerts to webhook, Telegram and email notifiers.
+package alert
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// indicatorCandles closed candles fed to indicators of indicator rules
+	indicatorCandles = 500
+	deliveryTimeout  = 30 * time.Second
+	// queueSize pending deliveries, alerts triggered while the queue is full are dropped
+	queueSize = 256
+)
+
+// Notifier delivers triggered alerts to a channel
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Notification is a triggered alert as delivered to notifiers
+type Notification struct {
+	RuleId      int64     `json:"ruleId"`
+	RuleName    string    `json:"ruleName"`
+	Pair        string    `json:"pair"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Message     string    `json:"message"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// Text single line rendering of the notification for chats and emails
+func (n Notification) Text() string {
+	return fmt.Sprintf("[%s] %s", n.RuleName, n.Message)
+}
+
+type pricePoint struct {
+	timestamp time.Time
+	price     int64
+}
+
+// extremes keeps the lowest and the highest price of a time window in monotonic queues
+type extremes struct {
+	lows  []pricePoint
+	highs []pricePoint
+}
+
+func (w *extremes) add(p pricePoint) {
+	for len(w.lows) > 0 && w.lows[len(w.lows)-1].price >= p.price {
+		w.lows = w.lows[:len(w.lows)-1]
+	}
+	w.lows = append(w.lows, p)
+	for len(w.highs) > 0 && w.highs[len(w.highs)-1].price <= p.price {
+		w.highs = w.highs[:len(w.highs)-1]
+	}
+	w.highs = append(w.highs, p)
+}
+
+// expire drops prices older than from
+func (w *extremes) expire(from time.Time) {
+	for len(w.lows) > 0 && w.lows[0].timestamp.Before(from) {
+		w.lows = w.lows[1:]
+	}
+	for len(w.highs) > 0 && w.highs[0].timestamp.Before(from) {
+		w.highs = w.highs[1:]
+	}
+}
+
+func (w *extremes) reset(p pricePoint) {
+	w.lows = append(w.lows[:0], p)
+	w.highs = append(w.highs[:0], p)
+}
+
+type ruleState struct {
+	rule      model.AlertRule
+	triggered time.Time // the latest notification, cooldown starts there
+
+	lastPrice  int64     // threshold rules
+	window     extremes  // percent change rules
+	lastCandle time.Time // candle rules, the latest evaluated closed candle
+}
+
+// Engine evaluates enabled rules. Threshold and percent change rules are evaluated on
+// every trade, volume spike and indicator rules on the latest closed candle after inserts.
+// A rule triggers on the edge of its condition (a cross, a completed move) and is then
+// silenced for its cooldown, so a price hovering around a level doesn't flood channels.
+type Engine struct {
+	alertRepository  store.AlertStore
+	candleRepository store.CandleStore
+	notifiers        map[string]Notifier
+	log              *log.Logger
+	now              func() time.Time
+
+	mu      sync.Mutex
+	states  []*ruleState // ordered by rule id
+	stopped bool
+
+	queue   chan delivery
+	pending sync.WaitGroup
+}
+
+type delivery struct {
+	rule  model.AlertRule
+	event model.AlertEvent
+}
+
+// NewEngine makes alert engine and starts delivering in background, notifiers are keyed
+// by channel, e.g. model.ChannelWebhook. Rules are loaded by Reload.
+func NewEngine(
+	alertRepository store.AlertStore,
+	candleRepository store.CandleStore,
+	notifiers map[string]Notifier,
+	log *log.Logger,
+) *Engine {
+	e := &Engine{
+		alertRepository:  alertRepository,
+		candleRepository: candleRepository,
+		notifiers:        notifiers,
+		log:              log,
+		now:              time.Now,
+		queue:            make(chan delivery, queueSize),
+	}
+
+	// a single worker keeps alerts in order and slow channels away from the trade handler
+	go func() {
+		for d := range e.queue {
+			e.deliver(d.rule, d.event)
+			e.pending.Done()
+		}
+	}()
+
+	return e
+}
+
+// Reload loads rules from the repository, evaluation state of unchanged rules is kept
+func (e *Engine) Reload() error {
+	rules, err := e.alertRepository.FetchAlertRules()
+	if err != nil {
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous := make(map[int64]*ruleState, len(e.states))
+	for _, s := range e.states {
+		previous[s.rule.Id] = s
+	}
+
+	states := make([]*ruleState, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		s, ok := previous[rule.Id]
+		if !ok {
+			s = &ruleState{}
+		}
+		s.rule = rule
+		if rule.LastTriggeredAt.After(s.triggered) {
+			s.triggered = rule.LastTriggeredAt
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].rule.Id < states[j].rule.Id })
+	e.states = states
+
+	return nil
+}
+
+// Trade is the trade handler evaluating threshold and percent change rules of the pair
+func (e *Engine) Trade(trade model.Trade) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range e.states {
+		if s.rule.Pair != trade.Pair {
+			continue
+		}
+
+		var event *model.AlertEvent
+		switch s.rule.Kind {
+		case model.AlertThreshold:
+			event = e.evaluateThreshold(s, trade)
+		case model.AlertPercentChange:
+			event = e.evaluatePercentChange(s, trade)
+		}
+		if event != nil {
+			e.trigger(s, *event)
+		}
+	}
+}
+
+func (e *Engine) evaluateThreshold(s *ruleState, trade model.Trade) *model.AlertEvent {
+	previous := s.lastPrice
+	s.lastPrice = trade.Price
+	if previous == 0 || !crossed(s.rule.Direction, previous, trade.Price, s.rule.Level) {
+		return nil
+	}
+
+	return &model.AlertEvent{
+		Value: trade.Price,
+		Message: fmt.Sprintf("%s crossed %s %s: %s", trade.Pair, crossDirection(previous, trade.Price),
+			price.Price{Price: s.rule.Level}, price.Price{Price: trade.Price}),
+	}
+}
+
+func (e *Engine) evaluatePercentChange(s *ruleState, trade model.Trade) *model.AlertEvent {
+	point := pricePoint{timestamp: trade.Timestamp, price: trade.Price}
+	s.window.expire(trade.Timestamp.Add(-s.rule.Window))
+	s.window.add(point)
+
+	low, high := s.window.lows[0].price, s.window.highs[0].price
+	threshold := float64(s.rule.Percent) / price.PriceFactor
+	rise := percentChange(low, trade.Price)
+	fall := percentChange(high, trade.Price)
+
+	var change float64
+	switch {
+	case s.rule.Direction != model.DirectionBelow && rise >= threshold:
+		change = rise
+	case s.rule.Direction != model.DirectionAbove && fall <= -threshold:
+		change = fall
+	default:
+		return nil
+	}
+
+	// the move is reported once, the next one is measured from the current price
+	s.window.reset(point)
+
+	return &model.AlertEvent{
+		Value: int64(math.Round(change * price.PriceFactor)),
+		Message: fmt.Sprintf("%s moved %+.2f%% within %s: %s", trade.Pair, change, s.rule.Window,
+			price.Price{Price: trade.Price}),
+	}
+}
+
+// OnCandles is the candle insert listener evaluating volume spike and indicator rules
+func (e *Engine) OnCandles(_ context.Context, candles []model.Candle) {
+	type seriesKey struct{ pair, bar string }
+	seen := make(map[seriesKey]bool)
+	for _, c := range candles {
+		key := seriesKey{c.Pair, c.Bar}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if err := e.EvaluateCandles(c.Pair, c.Bar); err != nil {
+			e.log.Errorf("alerts of %s %s failed: %v", c.Pair, c.Bar, err)
+		}
+	}
+}
+
+// EvaluateCandles evaluates volume spike and indicator rules of the pair and bar on the latest closed candle
+func (e *Engine) EvaluateCandles(pair, bar string) error {
+	e.mu.Lock()
+	var states []*ruleState
+	need := 0
+	for _, s := range e.states {
+		if s.rule.Pair != pair || s.rule.Bar != bar {
+			continue
+		}
+		switch s.rule.Kind {
+		case model.AlertVolumeSpike:
+			need = max(need, s.rule.Lookback+1)
+		case model.AlertIndicator:
+			need = max(need, indicatorCandles)
+		default:
+			continue
+		}
+		states = append(states, s)
+	}
+	e.mu.Unlock()
+
+	if len(states) == 0 {
+		return nil
+	}
+
+	// one more candle is fetched in case the latest one is still open
+	candles, err := e.candleRepository.FetchLatest(pair, bar, need+1)
+	if err != nil {
+		return err
+	}
+	candles = model.ClosedCandles(candles, bar, e.now())
+	if len(candles) == 0 {
+		return nil
+	}
+	latest := candles[len(candles)-1]
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range states {
+		if !latest.Timestamp.After(s.lastCandle) {
+			continue
+		}
+		s.lastCandle = latest.Timestamp
+
+		var event *model.AlertEvent
+		switch s.rule.Kind {
+		case model.AlertVolumeSpike:
+			event = evaluateVolumeSpike(s.rule, candles)
+		case model.AlertIndicator:
+			event = evaluateIndicator(s.rule, candles)
+		}
+		if event != nil {
+			e.trigger(s, *event)
+		}
+	}
+
+	return nil
+}
+
+func evaluateVolumeSpike(rule model.AlertRule, candles []model.Candle) *model.AlertEvent {
+	if len(candles) < rule.Lookback+1 {
+		return nil
+	}
+
+	latest := candles[len(candles)-1]
+	var sum float64
+	for _, c := range candles[len(candles)-1-rule.Lookback : len(candles)-1] {
+		sum += float64(c.Volume)
+	}
+	average := sum / float64(rule.Lookback)
+	if average <= 0 {
+		return nil
+	}
+
+	ratio := float64(latest.Volume) / average
+	if ratio < float64(rule.Multiplier)/price.PriceFactor {
+		return nil
+	}
+
+	return &model.AlertEvent{
+		Value: int64(math.Round(ratio * price.PriceFactor)),
+		Message: fmt.Sprintf("%s %s volume %s is %.2fx the average of %d candles", latest.Pair, latest.Bar,
+			price.Price{Price: latest.Volume}, ratio, rule.Lookback),
+	}
+}
+
+func evaluateIndicator(rule model.AlertRule, candles []model.Candle) *model.AlertEvent {
+	spec, component, err := parseIndicatorKey(rule.Indicator)
+	if err != nil {
+		return nil
+	}
+
+	indicator := spec.New()
+	var values []int64
+	for _, c := range candles {
+		if value, ok := indicator.Update(c); ok {
+			values = append(values, value[component].Price)
+		}
+	}
+	if len(values) < 2 {
+		return nil
+	}
+
+	previous, current := values[len(values)-2], values[len(values)-1]
+	if !crossed(rule.Direction, previous, current, rule.Level) {
+		return nil
+	}
+
+	latest := candles[len(candles)-1]
+	return &model.AlertEvent{
+		Value: current,
+		Message: fmt.Sprintf("%s %s %s crossed %s %s: %s", latest.Pair, latest.Bar, rule.Indicator,
+			crossDirection(previous, current), price.Price{Price: rule.Level}, price.Price{Price: current}),
+	}
+}
+
+// trigger notifies about the event unless the rule cools down, must be called under lock
+func (e *Engine) trigger(s *ruleState, event model.AlertEvent) {
+	now := e.now()
+	if !s.triggered.IsZero() && now.Before(s.triggered.Add(s.rule.Cooldown)) {
+		e.log.Debugf("alert %d is cooling down: %s", s.rule.Id, event.Message)
+		return
+	}
+	if e.stopped {
+		return
+	}
+	s.triggered = now
+
+	event.RuleId = s.rule.Id
+	event.Pair = s.rule.Pair
+	event.Kind = s.rule.Kind
+	event.TriggeredAt = now
+
+	e.pending.Add(1)
+	select {
+	case e.queue <- delivery{rule: s.rule, event: event}:
+	default:
+		e.pending.Done()
+		e.log.Errorf("alert %d is dropped, delivery queue is full: %s", s.rule.Id, event.Message)
+	}
+}
+
+// deliver stores the event and sends it to channels of the rule
+func (e *Engine) deliver(rule model.AlertRule, event model.AlertEvent) {
+	stored, err := e.alertRepository.InsertAlertEvent(event)
+	if errors.Is(err, store.ErrNotFound) {
+		// the rule was deleted meanwhile
+		return
+	}
+	if err != nil {
+		e.log.Errorf("failed to store alert %d: %v", rule.Id, err)
+		stored = event
+	}
+
+	notification := Notification{
+		RuleId:      rule.Id,
+		RuleName:    rule.Name,
+		Pair:        stored.Pair,
+		Kind:        string(stored.Kind),
+		Value:       price.Price{Price: stored.Value}.String(),
+		Message:     stored.Message,
+		TriggeredAt: stored.TriggeredAt.UTC(),
+	}
+
+	for _, channel := range rule.Channels {
+		notifier, ok := e.notifiers[channel]
+		if !ok {
+			e.log.Warnf("alert %d can't be sent, %s channel isn't configured", rule.Id, channel)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		if err := notifier.Notify(ctx, notification); err != nil {
+			e.log.Errorf("failed to send alert %d to %s: %v", rule.Id, channel, err)
+		}
+		cancel()
+	}
+}
+
+// Close waits for pending deliveries and stops the worker, later triggers aren't delivered
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+	e.stopped = true
+
+	e.pending.Wait()
+	close(e.queue)
+}
+
+func crossed(direction model.AlertDirection, previous, current, level int64) bool {
+	above := previous < level && current >= level
+	below := previous > level && current <= level
+	switch direction {
+	case model.DirectionAbove:
+		return above
+	case model.DirectionBelow:
+		return below
+	default:
+		return above || below
+	}
+}
+
+func crossDirection(previous, current int64) model.AlertDirection {
+	if current > previous {
+		return model.DirectionAbove
+	}
+	return model.DirectionBelow
+}
+
+func percentChange(from, to int64) float64 {
+	if from == 0 {
+		return 0
+	}
+	return float64(to-from) / float64(from) * 100
+}
@@ -0,0 +1,253 @@
+package alert
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) received() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notification(nil), n.notifications...)
+}
+
+type testEngine struct {
+	*Engine
+	storage  *memory.Store
+	notifier *recordingNotifier
+	now      time.Time
+}
+
+func newTestEngine(t *testing.T, rules ...model.AlertRule) *testEngine {
+	env := &testEngine{storage: memory.NewStore(), notifier: &recordingNotifier{}, now: start}
+	for _, rule := range rules {
+		rule.Enabled = true
+		rule.Channels = []string{model.ChannelWebhook}
+		_, err := env.storage.Alert().InsertAlertRule(rule)
+		require.NoError(t, err)
+	}
+
+	env.Engine = NewEngine(env.storage.Alert(), env.storage.Candle(), map[string]Notifier{model.ChannelWebhook: env.notifier}, log.New())
+	env.Engine.now = func() time.Time { return env.now }
+	require.NoError(t, env.Reload())
+	return env
+}
+
+// trade sends a trade at the engine time moved by offset
+func (env *testEngine) trade(offset time.Duration, p int64) {
+	env.now = start.Add(offset)
+	env.Trade(model.Trade{Pair: "BTC-USDT", Price: p * 100_000_000, Timestamp: env.now})
+}
+
+// notifications waits for pending deliveries
+func (env *testEngine) notifications() []Notification {
+	env.pending.Wait()
+	return env.notifier.received()
+}
+
+func TestEngine_ThresholdCross(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "BTC above 100k", Pair: "BTC-USDT", Kind: model.AlertThreshold, Direction: model.DirectionAbove,
+		Level: 100_000 * 100_000_000, Cooldown: 10 * time.Minute,
+	})
+
+	env.trade(0, 100_500) // no previous price, nothing is crossed
+	env.trade(time.Minute, 99_000)
+	env.trade(2*time.Minute, 100_100) // crossed
+	env.trade(3*time.Minute, 100_200) // stays above
+	env.trade(4*time.Minute, 99_900)
+	env.trade(5*time.Minute, 100_300) // crossed again within cooldown
+	env.trade(20*time.Minute, 99_000)
+	env.trade(21*time.Minute, 100_001) // crossed after cooldown
+
+	notifications := env.notifications()
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "BTC above 100k", notifications[0].RuleName)
+	assert.Equal(t, "BTC-USDT crossed above 100000: 100100", notifications[0].Message)
+	assert.Equal(t, "100100", notifications[0].Value)
+	assert.Equal(t, start.Add(2*time.Minute), notifications[0].TriggeredAt)
+	assert.Equal(t, "100001", notifications[1].Value)
+
+	events, err := env.storage.Alert().FetchAlertEvents(0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, model.AlertThreshold, events[0].Kind)
+	assert.Equal(t, int64(100_001*100_000_000), events[0].Value)
+}
+
+func TestEngine_CooldownSurvivesReload(t *testing.T) {
+	rule := model.AlertRule{
+		Name: "BTC below 90k", Pair: "BTC-USDT", Kind: model.AlertThreshold, Direction: model.DirectionBelow,
+		Level: 90_000 * 100_000_000, Cooldown: time.Hour,
+	}
+	env := newTestEngine(t, rule)
+	env.trade(0, 91_000)
+	env.trade(time.Minute, 89_000)
+	require.Len(t, env.notifications(), 1)
+
+	// a new engine loads the trigger time stored with the event
+	restarted := NewEngine(env.storage.Alert(), env.storage.Candle(), map[string]Notifier{model.ChannelWebhook: env.notifier}, log.New())
+	restarted.now = func() time.Time { return start.Add(30 * time.Minute) }
+	require.NoError(t, restarted.Reload())
+	restarted.Trade(model.Trade{Pair: "BTC-USDT", Price: 91_000 * 100_000_000, Timestamp: start.Add(29 * time.Minute)})
+	restarted.Trade(model.Trade{Pair: "BTC-USDT", Price: 89_000 * 100_000_000, Timestamp: start.Add(30 * time.Minute)})
+	restarted.Close()
+	assert.Len(t, env.notifier.received(), 1)
+}
+
+func TestEngine_PercentChange(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "BTC moves 5%", Pair: "BTC-USDT", Kind: model.AlertPercentChange, Direction: model.DirectionAny,
+		Percent: 5 * 100_000_000, Window: time.Hour,
+	})
+
+	env.trade(0, 100_000)
+	env.trade(30*time.Minute, 96_000)
+	env.trade(50*time.Minute, 104_000) // +8.33% from the low
+	env.trade(70*time.Minute, 104_500) // measured from 104000 after the alert
+	env.trade(3*time.Hour, 100_000)
+	env.trade(3*time.Hour+30*time.Minute, 98_000)
+	env.trade(4*time.Hour+31*time.Minute, 94_000) // 100000 and 98000 left the window
+	env.trade(4*time.Hour+40*time.Minute, 93_000)
+
+	notifications := env.notifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "BTC-USDT moved +8.33% within 1h0m0s: 104000", notifications[0].Message)
+	assert.Equal(t, "8.33333333", notifications[0].Value)
+}
+
+func TestEngine_PercentChangeDirection(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "BTC drops 5%", Pair: "BTC-USDT", Kind: model.AlertPercentChange, Direction: model.DirectionBelow,
+		Percent: 5 * 100_000_000, Window: time.Hour,
+	})
+
+	env.trade(0, 100_000)
+	env.trade(time.Minute, 110_000)
+	env.trade(2*time.Minute, 104_000)
+
+	notifications := env.notifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "BTC-USDT moved -5.45% within 1h0m0s: 104000", notifications[0].Message)
+}
+
+// insertCandles inserts hourly candles from the start, the engine time must be set before
+func insertCandles(t *testing.T, env *testEngine, volumes []int64, closes []int64) {
+	var candles []model.Candle
+	for i := range volumes {
+		c := storetest.Candle("BTC-USDT", "1H", start.Add(time.Duration(i)*time.Hour), 1)
+		c.Volume = volumes[i] * 100_000_000
+		if closes != nil {
+			c.Close, c.High, c.Low = closes[i], closes[i], closes[i]
+		}
+		candles = append(candles, c)
+	}
+	require.NoError(t, env.storage.Candle().InsertCandles(&candles))
+}
+
+func TestEngine_VolumeSpike(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "BTC volume", Pair: "BTC-USDT", Bar: "1H", Kind: model.AlertVolumeSpike, Direction: model.DirectionAbove,
+		Multiplier: 3 * 100_000_000, Lookback: 3,
+	})
+	env.storage.Candle().OnInsert(env.OnCandles)
+
+	env.now = start.Add(4 * time.Hour)
+	insertCandles(t, env, []int64{10, 10, 10, 20}, nil)
+	assert.Empty(t, env.notifications())
+
+	// the latest candle is still open
+	env.now = start.Add(5*time.Hour + time.Minute)
+	insertCandles(t, env, []int64{10, 10, 10, 20, 60, 5}, nil)
+	// evaluated once per closed candle
+	require.NoError(t, env.EvaluateCandles("BTC-USDT", "1H"))
+
+	notifications := env.notifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "BTC-USDT 1H volume 60 is 4.50x the average of 3 candles", notifications[0].Message)
+	assert.Equal(t, "4.5", notifications[0].Value)
+}
+
+func TestEngine_IndicatorCross(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "SMA above 105", Pair: "BTC-USDT", Bar: "1H", Kind: model.AlertIndicator, Direction: model.DirectionAbove,
+		Indicator: "sma_2", Level: 105,
+	})
+	env.storage.Candle().OnInsert(env.OnCandles)
+
+	env.now = start.Add(3 * time.Hour)
+	insertCandles(t, env, []int64{1, 1, 1}, []int64{100, 100, 100})
+	env.now = start.Add(4 * time.Hour)
+	insertCandles(t, env, []int64{1, 1, 1, 1}, []int64{100, 100, 100, 120})
+
+	notifications := env.notifications()
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "BTC-USDT 1H sma_2 crossed above 0.00000105: 0.0000011", notifications[0].Message)
+}
+
+func TestEngine_DisabledAndDeletedRules(t *testing.T) {
+	env := newTestEngine(t, model.AlertRule{
+		Name: "BTC above 100k", Pair: "BTC-USDT", Kind: model.AlertThreshold, Direction: model.DirectionAbove,
+		Level: 100_000 * 100_000_000,
+	})
+	_, err := env.storage.Alert().InsertAlertRule(model.AlertRule{
+		Name: "disabled", Pair: "BTC-USDT", Kind: model.AlertThreshold, Direction: model.DirectionAny,
+		Level: 100_000 * 100_000_000, Channels: []string{model.ChannelWebhook},
+	})
+	require.NoError(t, err)
+	require.NoError(t, env.Reload())
+
+	require.NoError(t, env.storage.Alert().DeleteAlertRule(1))
+	env.trade(0, 99_000)
+	env.trade(time.Minute, 101_000)
+	// the engine learns about the deletion on reload, the event isn't delivered meanwhile
+	assert.Empty(t, env.notifications())
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := model.AlertRule{
+		Name: "rsi", Pair: "BTC-USDT", Bar: "1H", Kind: model.AlertIndicator, Direction: model.DirectionAbove,
+		Indicator: "macd_12_26_9.histogram", Channels: []string{model.ChannelTelegram},
+	}
+	require.NoError(t, ValidateRule(valid))
+
+	for name, change := range map[string]func(r *model.AlertRule){
+		"name":      func(r *model.AlertRule) { r.Name = " " },
+		"direction": func(r *model.AlertRule) { r.Direction = "up" },
+		"channel":   func(r *model.AlertRule) { r.Channels = []string{"sms"} },
+		"bar":       func(r *model.AlertRule) { r.Bar = "1M" },
+		"indicator": func(r *model.AlertRule) { r.Indicator = "macd_12_26_9" },
+		"component": func(r *model.AlertRule) { r.Indicator = "rsi_14.signal" },
+		"kind":      func(r *model.AlertRule) { r.Kind = "news" },
+		"level":     func(r *model.AlertRule) { r.Kind = model.AlertThreshold },
+		"percent":   func(r *model.AlertRule) { r.Kind, r.Percent = model.AlertPercentChange, 1 },
+		"lookback":  func(r *model.AlertRule) { r.Kind, r.Multiplier = model.AlertVolumeSpike, 1 },
+	} {
+		rule := valid
+		change(&rule)
+		assert.ErrorIs(t, ValidateRule(rule), ErrInvalidRule, name)
+	}
+}
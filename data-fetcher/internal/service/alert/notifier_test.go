@@ -0,0 +1,174 @@
+package alert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var notification = Notification{
+	RuleId:      7,
+	RuleName:    "BTC above 100k",
+	Pair:        "BTC-USDT",
+	Kind:        "threshold",
+	Value:       "100100",
+	Message:     "BTC-USDT crossed above 100000: 100100",
+	TriggeredAt: time.Date(2025, 2, 1, 0, 2, 0, 0, time.UTC),
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewWebhookNotifier(server.URL).Notify(context.Background(), notification))
+	assert.Equal(t, notification, received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	assert.ErrorContains(t, NewWebhookNotifier(failing.URL).Notify(context.Background(), notification), "502")
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	var message telegramMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:secret/sendMessage" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	require.NoError(t, NewTelegramNotifier(server.URL+"/", "123:secret", "-100200").Notify(context.Background(), notification))
+	assert.Equal(t, telegramMessage{ChatId: "-100200", Text: "[BTC above 100k] BTC-USDT crossed above 100000: 100100"}, message)
+
+	err := NewTelegramNotifier(server.URL, "wrong", "-100200").Notify(context.Background(), notification)
+	assert.ErrorContains(t, err, "Not Found")
+
+	err = NewTelegramNotifier("http://127.0.0.1:1", "123:secret", "1").Notify(context.Background(), notification)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret", "the token must not leak to logs")
+}
+
+// smtpServer is a minimal SMTP stand-in accepting every message
+type smtpServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	from     string
+	to       []string
+	messages []string
+}
+
+func newSmtpServer(t *testing.T) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.mu.Lock()
+			s.to = append(s.to, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	server := newSmtpServer(t)
+
+	notifier, err := NewEmailNotifier(server.listener.Addr().String(), "", "", "alerts@example.com", []string{"a@example.com", "b@example.com"})
+	require.NoError(t, err)
+	injected := notification
+	injected.RuleName = "BTC\r\nBcc: everyone@example.com"
+	require.NoError(t, notifier.Notify(context.Background(), injected))
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, "alerts@example.com", server.from)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, server.to)
+	require.Len(t, server.messages, 1)
+	message := server.messages[0]
+	assert.Contains(t, message, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, message, "Subject: Alert: BTC  Bcc: everyone@example.com\r\n")
+	assert.Contains(t, message, "\r\n\r\nBTC-USDT crossed above 100000: 100100\r\n")
+	assert.Contains(t, message, "Value: 100100\r\n")
+
+	_, err = NewEmailNotifier("localhost", "", "", "alerts@example.com", []string{"a@example.com"})
+	assert.Error(t, err)
+}
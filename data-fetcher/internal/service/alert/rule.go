@@ -0,0 +1,84 @@
+package alert
+
+import (
+	"cur/internal/indicators"
+	"cur/internal/model"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+var channels = []string{model.ChannelWebhook, model.ChannelTelegram, model.ChannelEmail}
+
+// ValidateRule checks that the rule has every parameter its kind needs
+func ValidateRule(rule model.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if rule.Pair == "" {
+		return fmt.Errorf("%w: pair is required", ErrInvalidRule)
+	}
+	switch rule.Direction {
+	case model.DirectionAbove, model.DirectionBelow, model.DirectionAny:
+	default:
+		return fmt.Errorf("%w: direction must be above, below or any", ErrInvalidRule)
+	}
+	if rule.Cooldown < 0 {
+		return fmt.Errorf("%w: cooldown can't be negative", ErrInvalidRule)
+	}
+	for _, channel := range rule.Channels {
+		if !slices.Contains(channels, channel) {
+			return fmt.Errorf("%w: unknown channel %q, available: %s", ErrInvalidRule, channel, strings.Join(channels, ","))
+		}
+	}
+
+	switch rule.Kind {
+	case model.AlertThreshold:
+		if rule.Level <= 0 {
+			return fmt.Errorf("%w: threshold rule needs a positive level", ErrInvalidRule)
+		}
+	case model.AlertPercentChange:
+		if rule.Percent <= 0 || rule.Window <= 0 {
+			return fmt.Errorf("%w: percent change rule needs a positive percent and window", ErrInvalidRule)
+		}
+	case model.AlertVolumeSpike:
+		if _, err := model.BarDuration(rule.Bar); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		if rule.Multiplier <= 0 || rule.Lookback <= 0 {
+			return fmt.Errorf("%w: volume spike rule needs a positive multiplier and lookback", ErrInvalidRule)
+		}
+	case model.AlertIndicator:
+		if _, err := model.BarDuration(rule.Bar); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		if _, _, err := parseIndicatorKey(rule.Indicator); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidRule, rule.Kind)
+	}
+
+	return nil
+}
+
+// parseIndicatorKey parses model.IndicatorKey, returns the indicator and the index of the component
+func parseIndicatorKey(key string) (indicators.Spec, int, error) {
+	name, component, _ := strings.Cut(key, ".")
+	spec, err := indicators.ParseSpec(name)
+	if err != nil {
+		return indicators.Spec{}, 0, err
+	}
+	if component == "" {
+		component = indicators.ComponentValue
+	}
+
+	i := slices.Index(spec.Components(), component)
+	if i < 0 {
+		return indicators.Spec{}, 0, fmt.Errorf("indicator %s has no component %q, available: %s", spec.Name, component, strings.Join(spec.Components(), ","))
+	}
+	return spec, i, nil
+}
@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TelegramNotifier sends notifications to a chat through the Telegram bot api (or a compatible one)
+type TelegramNotifier struct {
+	apiUrl string
+	token  string
+	chatId string
+	client *http.Client
+}
+
+func NewTelegramNotifier(apiUrl, token, chatId string) *TelegramNotifier {
+	return &TelegramNotifier{
+		apiUrl: strings.TrimRight(apiUrl, "/"),
+		token:  token,
+		chatId: chatId,
+		client: &http.Client{Timeout: notifierTimeout},
+	}
+}
+
+type telegramMessage struct {
+	ChatId string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type telegramResponse struct {
+	Ok          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(telegramMessage{ChatId: n.chatId, Text: notification.Text()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.apiUrl+"/bot"+n.token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid telegram api url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// the url contains the bot token, it must not get to logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram responded with status %d", resp.StatusCode)
+	}
+	if !result.Ok {
+		return fmt.Errorf("telegram rejected the message: %s", result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package analytics
+
+import (
+	"cur/internal/model"
+	"math"
+	"slices"
+)
+
+// CloseToClose sample standard deviation of log returns between closes of consecutive candles, per bar
+func CloseToClose(candles []model.Candle) (float64, bool) {
+	if len(candles) < 3 || !positive(candles) {
+		return 0, false
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	for i := 1; i < len(candles); i++ {
+		returns = append(returns, math.Log(float64(candles[i].Close)/float64(candles[i-1].Close)))
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1)), true
+}
+
+// Parkinson volatility by high-low ranges, per bar
+func Parkinson(candles []model.Candle) (float64, bool) {
+	if len(candles) == 0 || !positive(candles) {
+		return 0, false
+	}
+
+	sum := 0.0
+	for _, c := range candles {
+		hl := math.Log(float64(c.High) / float64(c.Low))
+		sum += hl * hl
+	}
+	return math.Sqrt(sum / (4 * math.Ln2 * float64(len(candles)))), true
+}
+
+// GarmanKlass volatility by high-low ranges and open-close moves, per bar
+func GarmanKlass(candles []model.Candle) (float64, bool) {
+	if len(candles) == 0 || !positive(candles) {
+		return 0, false
+	}
+
+	sum := 0.0
+	for _, c := range candles {
+		hl := math.Log(float64(c.High) / float64(c.Low))
+		co := math.Log(float64(c.Close) / float64(c.Open))
+		sum += 0.5*hl*hl - (2*math.Ln2-1)*co*co
+	}
+	return math.Sqrt(max(sum, 0) / float64(len(candles))), true
+}
+
+func positive(candles []model.Candle) bool {
+	for _, c := range candles {
+		if c.Open <= 0 || c.High <= 0 || c.Low <= 0 || c.Close <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Pearson correlation coefficient, false when either series is constant or has NaN
+func Pearson(x, y []float64) (float64, bool) {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0, false
+	}
+
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	r := cov / math.Sqrt(varX*varY)
+	if varX == 0 || varY == 0 || math.IsNaN(r) {
+		return 0, false
+	}
+	return max(-1, min(1, r)), true
+}
+
+// Spearman rank correlation coefficient, ties get the average rank
+func Spearman(x, y []float64) (float64, bool) {
+	if len(x) != len(y) {
+		return 0, false
+	}
+	return Pearson(ranks(x), ranks(y))
+}
+
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case values[a] < values[b]:
+			return -1
+		case values[a] > values[b]:
+			return 1
+		}
+		return 0
+	})
+
+	ranked := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranked[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return ranked
+}
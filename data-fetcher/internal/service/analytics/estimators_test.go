@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"cur/internal/model"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseToClose(t *testing.T) {
+	value, ok := CloseToClose([]model.Candle{ohlc(100, 100, 100, 100), ohlc(110, 110, 110, 110), ohlc(99, 99, 99, 99)})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.1418956, value, 1e-6)
+
+	_, ok = CloseToClose([]model.Candle{ohlc(100, 100, 100, 100), ohlc(110, 110, 110, 110)})
+	assert.False(t, ok, "one return has no sample deviation")
+	_, ok = CloseToClose([]model.Candle{ohlc(1, 1, 1, 1), ohlc(0, 0, 0, 0), ohlc(1, 1, 1, 1)})
+	assert.False(t, ok)
+}
+
+func TestParkinsonAndGarmanKlass(t *testing.T) {
+	// high is e times low, open equals close
+	candles := []model.Candle{ohlc(100_000_000, math.E*100_000_000, 100_000_000, 100_000_000)}
+
+	value, ok := Parkinson(candles)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.6005612, value, 1e-6)
+
+	value, ok = GarmanKlass(candles)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.7071068, value, 1e-6)
+
+	value, ok = Parkinson([]model.Candle{ohlc(100, 100, 100, 100)})
+	assert.True(t, ok)
+	assert.Zero(t, value)
+
+	_, ok = GarmanKlass(nil)
+	assert.False(t, ok)
+}
+
+func TestPearsonAndSpearman(t *testing.T) {
+	value, ok := Pearson([]float64{1, 2, 3}, []float64{2, 4, 6})
+	assert.True(t, ok)
+	assert.InDelta(t, 1, value, 1e-12)
+
+	value, ok = Pearson([]float64{1, 2, 3}, []float64{6, 4, 2})
+	assert.True(t, ok)
+	assert.InDelta(t, -1, value, 1e-12)
+
+	_, ok = Pearson([]float64{1, 2, 3}, []float64{5, 5, 5})
+	assert.False(t, ok, "constant series")
+	_, ok = Pearson([]float64{1, math.NaN(), 3}, []float64{1, 2, 3})
+	assert.False(t, ok)
+
+	// monotonic but not linear
+	x, y := []float64{1, 2, 3, 4}, []float64{1, 4, 9, 100}
+	pearson, _ := Pearson(x, y)
+	spearman, ok := Spearman(x, y)
+	assert.True(t, ok)
+	assert.Less(t, pearson, 0.9)
+	assert.InDelta(t, 1, spearman, 1e-12)
+
+	assert.Equal(t, []float64{1, 2.5, 2.5, 4}, ranks([]float64{10, 20, 20, 30}))
+	assert.Equal(t, []float64{3, 1, 2}, ranks([]float64{7, -1, 0}))
+}
+
+func ohlc(open, high, low, close float64) model.Candle {
+	return model.Candle{Open: int64(open), High: int64(high), Low: int64(low), Close: int64(close)}
+}
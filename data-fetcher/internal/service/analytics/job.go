@@ -0,0 +1,278 @@
+// Package analytics computes rolling realized volatility of pairs and correlation matrices across pairs
+package analytics
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// batchSize number of values stored in one transaction
+const batchSize = 1000
+
+// hundred volatility is stored in percent
+const hundred = 100 * price.PriceFactor
+
+// year crypto markets trade around the clock, volatility is annualized by bars in 365 days
+const year = 365 * 24 * time.Hour
+
+var estimators = []struct {
+	name     model.VolatilityEstimator
+	estimate func([]model.Candle) (float64, bool)
+}{
+	{model.VolatilityCloseToClose, CloseToClose},
+	{model.VolatilityParkinson, Parkinson},
+	{model.VolatilityGarmanKlass, GarmanKlass},
+}
+
+var methods = []struct {
+	name      model.CorrelationMethod
+	correlate func(x, y []float64) (float64, bool)
+}{
+	{model.CorrelationPearson, Pearson},
+	{model.CorrelationSpearman, Spearman},
+}
+
+// Job computes volatility and correlation of closed candles for every window, a window is a number of candles.
+// Values newer than the stored ones are computed on each run.
+type Job struct {
+	candleRepository    store.CandleStore
+	analyticsRepository store.AnalyticsStore
+	windows             []int
+	log                 *log.Logger
+	now                 func() time.Time
+}
+
+func NewJob(candleRepository store.CandleStore, analyticsRepository store.AnalyticsStore, windows []int, log *log.Logger) (*Job, error) {
+	if len(windows) == 0 {
+		return nil, errors.New("no windows")
+	}
+	for _, window := range windows {
+		if window < 3 {
+			return nil, fmt.Errorf("window %d must be at least 3 candles", window)
+		}
+	}
+
+	return &Job{
+		candleRepository:    candleRepository,
+		analyticsRepository: analyticsRepository,
+		windows:             windows,
+		log:                 log,
+		now:                 time.Now,
+	}, nil
+}
+
+// Windows windows of computed values
+func (j *Job) Windows() []int {
+	return slices.Clone(j.windows)
+}
+
+// Run computes volatility of each pair and correlation of every two pairs
+func (j *Job) Run(ctx context.Context, pairs []string, bar string) {
+	volatilities, correlations, err := j.RunBar(ctx, pairs, bar)
+	if err != nil {
+		j.log.Errorf("analytics of %s failed: %v", bar, err)
+	}
+	j.log.Infof("stored %d volatility and %d correlation values of %s", volatilities, correlations, bar)
+}
+
+// RunBar computes new values of the bar, returns numbers of stored volatility and correlation values
+func (j *Job) RunBar(ctx context.Context, pairs []string, bar string) (int, int, error) {
+	duration, err := model.BarDuration(bar)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// correlations are stored by sorted pairs
+	sorted := slices.Clone(pairs)
+	sort.Strings(sorted)
+
+	volatilityLast := make(map[string]map[int]time.Time, len(pairs))
+	correlationLast := make(map[[2]string]map[int]time.Time)
+	var from time.Time
+	full := false
+	need := func(last time.Time, err error) (time.Time, error) {
+		if errors.Is(err, store.ErrNotFound) {
+			full = true
+			return time.Time{}, nil
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if from.IsZero() || last.Before(from) {
+			from = last
+		}
+		return last, nil
+	}
+
+	for a := 0; a < len(sorted); a++ {
+		for b := a + 1; b < len(sorted); b++ {
+			key := [2]string{sorted[a], sorted[b]}
+			correlationLast[key] = make(map[int]time.Time, len(j.windows))
+			for _, window := range j.windows {
+				if correlationLast[key][window], err = need(j.analyticsRepository.LastCorrelationTimestamp(key[0], key[1], bar, window)); err != nil {
+					return 0, 0, err
+				}
+			}
+		}
+	}
+	for _, pair := range pairs {
+		volatilityLast[pair] = make(map[int]time.Time, len(j.windows))
+		for _, window := range j.windows {
+			if volatilityLast[pair][window], err = need(j.analyticsRepository.LastVolatilityTimestamp(pair, bar, window)); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	// candles before the earliest stored value fill the windows of the next values
+	query := store.CandleQuery{Bar: bar, To: j.now().Add(-duration)}
+	if !full && !from.IsZero() {
+		query.From = from.Add(-time.Duration(slices.Max(j.windows)) * duration)
+	}
+	series := make(map[string][]model.Candle, len(pairs))
+	for _, pair := range pairs {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		query.Pair = pair
+		if series[pair], err = j.candleRepository.FetchRange(query); err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch candles of %s: %w", pair, err)
+		}
+	}
+
+	volatilities, err := j.storeVolatilities(series, volatilityLast, bar, duration)
+	if err != nil {
+		return volatilities, 0, err
+	}
+	correlations, err := j.storeCorrelations(sorted, series, correlationLast, bar)
+	return volatilities, correlations, err
+}
+
+func (j *Job) storeVolatilities(series map[string][]model.Candle, last map[string]map[int]time.Time, bar string, duration time.Duration) (int, error) {
+	annualize := math.Sqrt(float64(year) / float64(duration))
+
+	stored := 0
+	var batch []model.Volatility
+	for pair, candles := range series {
+		for _, window := range j.windows {
+			for i := window - 1; i < len(candles); i++ {
+				if !candles[i].Timestamp.After(last[pair][window]) {
+					continue
+				}
+				for _, e := range estimators {
+					value, ok := e.estimate(candles[i-window+1 : i+1])
+					if !ok {
+						continue
+					}
+					batch = append(batch, model.Volatility{
+						Pair:      pair,
+						Bar:       bar,
+						Window:    window,
+						Estimator: e.name,
+						Timestamp: candles[i].Timestamp,
+						Value:     int64(math.Round(value * annualize * hundred)),
+					})
+				}
+				if len(batch) >= batchSize {
+					if err := j.analyticsRepository.InsertVolatilities(batch); err != nil {
+						return stored, err
+					}
+					stored += len(batch)
+					batch = batch[:0]
+				}
+			}
+		}
+	}
+
+	if err := j.analyticsRepository.InsertVolatilities(batch); err != nil {
+		return stored, err
+	}
+	return stored + len(batch), nil
+}
+
+// storeCorrelations stores correlations of every two of the sorted pairs newer than the last ones of the pairs
+func (j *Job) storeCorrelations(sorted []string, series map[string][]model.Candle, last map[[2]string]map[int]time.Time, bar string) (int, error) {
+	stored := 0
+	var batch []model.Correlation
+	for a := 0; a < len(sorted); a++ {
+		for b := a + 1; b < len(sorted); b++ {
+			timestamps, x, y := common(series[sorted[a]], series[sorted[b]])
+			pairLast := last[[2]string{sorted[a], sorted[b]}]
+			for _, window := range j.windows {
+				for i := window - 1; i < len(timestamps); i++ {
+					if !timestamps[i].After(pairLast[window]) {
+						continue
+					}
+					returnsX, returnsY := logReturns(x[i-window+1:i+1]), logReturns(y[i-window+1:i+1])
+					for _, m := range methods {
+						value, ok := m.correlate(returnsX, returnsY)
+						if !ok {
+							continue
+						}
+						batch = append(batch, model.Correlation{
+							Bar:       bar,
+							Window:    window,
+							Method:    m.name,
+							Timestamp: timestamps[i],
+							PairA:     sorted[a],
+							PairB:     sorted[b],
+							Value:     int64(math.Round(value * price.PriceFactor)),
+						})
+					}
+					if len(batch) >= batchSize {
+						if err := j.analyticsRepository.InsertCorrelations(batch); err != nil {
+							return stored, err
+						}
+						stored += len(batch)
+						batch = batch[:0]
+					}
+				}
+			}
+		}
+	}
+
+	if err := j.analyticsRepository.InsertCorrelations(batch); err != nil {
+		return stored, err
+	}
+	return stored + len(batch), nil
+}
+
+// common returns timestamps both series have candles at with closes of both, series are ordered by timestamp
+func common(a, b []model.Candle) ([]time.Time, []float64, []float64) {
+	var timestamps []time.Time
+	var x, y []float64
+	for i, k := 0, 0; i < len(a) && k < len(b); {
+		switch {
+		case a[i].Timestamp.Before(b[k].Timestamp):
+			i++
+		case b[k].Timestamp.Before(a[i].Timestamp):
+			k++
+		default:
+			timestamps = append(timestamps, a[i].Timestamp)
+			x = append(x, float64(a[i].Close))
+			y = append(y, float64(b[k].Close))
+			i++
+			k++
+		}
+	}
+	return timestamps, x, y
+}
+
+// logReturns of consecutive closes, non-positive closes give NaN which makes the correlation undefined
+func logReturns(closes []float64) []float64 {
+	returns := make([]float64, 0, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		returns = append(returns, math.Log(closes[i]/closes[i-1]))
+	}
+	return returns
+}
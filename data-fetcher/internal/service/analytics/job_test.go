@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"math"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+// candles returns hourly candles with closes moving the same way for every pair, scaled by the factor
+func candles(pair string, factor int64, from, n int) []model.Candle {
+	var batch []model.Candle
+	for i := from; i < from+n; i++ {
+		close := (100 + int64(i*7%11)) * factor * 100_000_000
+		batch = append(batch, model.Candle{
+			Pair:      pair,
+			Bar:       "1H",
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open:      close - factor*100_000_000,
+			High:      close + 2*factor*100_000_000,
+			Low:       close - 2*factor*100_000_000,
+			Close:     close,
+			Volume:    100_000_000,
+		})
+	}
+	return batch
+}
+
+func TestJob_Run(t *testing.T) {
+	storage := memory.NewStore()
+	for _, batch := range [][]model.Candle{candles("BTC-USDT", 1, 0, 30), candles("ETH-USDT", 2, 0, 30), candles("SOL-USDT", 1, 10, 20)} {
+		require.NoError(t, storage.Candle().InsertCandles(&batch))
+	}
+
+	job, err := NewJob(storage.Candle(), storage.Analytics(), []int{5}, log.New())
+	require.NoError(t, err)
+	job.now = func() time.Time { return start.Add(31 * time.Hour) }
+	pairs := []string{"SOL-USDT", "BTC-USDT", "ETH-USDT"}
+
+	volatilities, correlations, err := job.RunBar(context.Background(), pairs, "1H")
+	require.NoError(t, err)
+	// candles 0..29 are closed, windows end at 4..29 (14..29 for SOL)
+	assert.Equal(t, (26+26+16)*3, volatilities)
+	// BTC-ETH at 26 timestamps, BTC-SOL and ETH-SOL at 16 by two methods
+	assert.Equal(t, (26+16+16)*2, correlations)
+
+	values, err := storage.Analytics().FetchVolatilities(store.VolatilityQuery{Pair: "BTC-USDT", Estimator: model.VolatilityParkinson}, 1)
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.True(t, start.Add(29*time.Hour).Equal(values[0].Timestamp))
+	expected, _ := Parkinson(candles("BTC-USDT", 1, 25, 5))
+	assert.Equal(t, int64(math.Round(expected*math.Sqrt(365*24)*100*price.PriceFactor)), values[0].Value)
+
+	matrix, err := storage.Analytics().FetchCorrelationMatrix("1H", 5, model.CorrelationPearson, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, matrix, 3)
+	assert.Equal(t, "BTC-USDT", matrix[0].PairA)
+	assert.Equal(t, "ETH-USDT", matrix[0].PairB)
+	assert.Equal(t, "ETH-USDT", matrix[2].PairA)
+	assert.Equal(t, "SOL-USDT", matrix[2].PairB)
+	for _, c := range matrix {
+		assert.InDelta(t, price.PriceFactor, c.Value, 10, "closes move together")
+	}
+
+	// nothing new
+	volatilities, correlations, err = job.RunBar(context.Background(), pairs, "1H")
+	require.NoError(t, err)
+	assert.Zero(t, volatilities)
+	assert.Zero(t, correlations)
+
+	// one more closed candle of every pair
+	for _, pair := range pairs {
+		factor := int64(1)
+		if pair == "ETH-USDT" {
+			factor = 2
+		}
+		batch := candles(pair, factor, 30, 2)
+		require.NoError(t, storage.Candle().InsertCandles(&batch))
+	}
+	job.now = func() time.Time { return start.Add(32 * time.Hour) }
+	volatilities, correlations, err = job.RunBar(context.Background(), pairs, "1H")
+	require.NoError(t, err)
+	assert.Equal(t, 3*3, volatilities)
+	assert.Equal(t, 3*2, correlations)
+}
+
+func TestJob_RunNewPair(t *testing.T) {
+	storage := memory.NewStore()
+	for _, batch := range [][]model.Candle{candles("BTC-USDT", 1, 0, 30), candles("ETH-USDT", 2, 0, 30), candles("SOL-USDT", 1, 0, 30)} {
+		require.NoError(t, storage.Candle().InsertCandles(&batch))
+	}
+
+	job, err := NewJob(storage.Candle(), storage.Analytics(), []int{5}, log.New())
+	require.NoError(t, err)
+	job.now = func() time.Time { return start.Add(31 * time.Hour) }
+
+	_, correlations, err := job.RunBar(context.Background(), []string{"BTC-USDT", "SOL-USDT"}, "1H")
+	require.NoError(t, err)
+	assert.Equal(t, 26*2, correlations)
+
+	// a pair added later gets the whole history with both pairs, the stored ones aren't recomputed
+	volatilities, correlations, err := job.RunBar(context.Background(), []string{"BTC-USDT", "ETH-USDT", "SOL-USDT"}, "1H")
+	require.NoError(t, err)
+	assert.Equal(t, 26*3, volatilities)
+	assert.Equal(t, (26+26)*2, correlations, "BTC-ETH and ETH-SOL")
+}
+
+func TestNewJob_Validates(t *testing.T) {
+	_, err := NewJob(nil, nil, nil, log.New())
+	assert.Error(t, err)
+	_, err = NewJob(nil, nil, []int{20, 2}, log.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,411 @@
+// Package anomaly keeps bad prints out of stored candles and trade handlers
+package anomaly
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultQueueSize anomalies of trades waiting to be stored, more are dropped with an error
+const DefaultQueueSize = 1024
+
+// Config outlier thresholds, prices are compared with the rolling window of the pair (and bar for candles)
+type Config struct {
+	Window       int           // prices kept per series
+	MinSamples   int           // series with fewer prices aren't checked for outliers
+	MadScore     float64       // modified z-score which makes an outlier, e.g. 6
+	ZScore       float64       // z-score which makes an outlier when the median absolute deviation is zero
+	MinDeviation float64       // percent from the median below which a price is never an outlier
+	StaleAfter   time.Duration // a pair without trades for longer is reported stale, zero disables
+}
+
+func (c Config) validate() error {
+	if c.Window <= 0 || c.MinSamples <= 0 || c.MinSamples > c.Window {
+		return errors.New("window must be positive and min samples must be in [1, window]")
+	}
+	if c.MadScore <= 0 || c.ZScore <= 0 || c.MinDeviation < 0 || c.StaleAfter < 0 {
+		return errors.New("scores must be positive, min deviation and stale after must not be negative")
+	}
+	return nil
+}
+
+type seriesKey struct {
+	pair, bar string
+}
+
+// series candles are checked forwards after the latest seen candle and backwards before the earliest one,
+// so backfilled history is compared with its neighbours
+type series struct {
+	forward, backward *window
+	first, last       time.Time
+}
+
+type Detector struct {
+	repository store.AnomalyStore
+	candles    store.CandleStore
+	trades     store.TradeStore
+	pairs      []string
+	config     Config
+	log        *log.Logger
+	now        func() time.Time
+	queue      chan model.Anomaly
+
+	mu         sync.Mutex
+	series     map[seriesKey]*series
+	prices     map[string]*window
+	lastTrades map[string]time.Time
+	stale      map[string]bool // reported pairs
+	started    time.Time
+	feeding    func() bool
+}
+
+// NewDetector makes a detector of the pairs, released candles are stored to candles and trades to trades
+func NewDetector(repository store.AnomalyStore, candles store.CandleStore, trades store.TradeStore, pairs []string, config Config, log *log.Logger) (*Detector, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Detector{
+		repository: repository,
+		candles:    candles,
+		trades:     trades,
+		pairs:      pairs,
+		config:     config,
+		log:        log,
+		now:        time.Now,
+		queue:      make(chan model.Anomaly, DefaultQueueSize),
+		series:     make(map[seriesKey]*series),
+		prices:     make(map[string]*window),
+		lastTrades: make(map[string]time.Time),
+		stale:      make(map[string]bool),
+		started:    time.Now(),
+	}, nil
+}
+
+// SetFeeding makes feeds checked only while feeding returns true, e.g. in the instance receiving trades.
+// Pairs become stale after StaleAfter since feeding starts.
+func (d *Detector) SetFeeding(feeding func() bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.feeding = feeding
+}
+
+// Candles is the candle filter, it quarantines suspect candles of the batch and returns the rest
+func (d *Detector) Candles(candles []model.Candle) []model.Candle {
+	if len(candles) == 0 {
+		return candles
+	}
+
+	now := d.now()
+	var anomalies []model.Anomaly
+	suspect := make(map[int]bool)
+
+	d.mu.Lock()
+	for key, indexes := range groupSeries(candles) {
+		for _, v := range d.checkSeries(key, candles, indexes) {
+			anomalies = append(anomalies, model.Anomaly{
+				Kind:       v.kind,
+				Source:     model.AnomalySourceCandle,
+				Pair:       candles[v.index].Pair,
+				Bar:        candles[v.index].Bar,
+				Timestamp:  candles[v.index].Timestamp,
+				Reason:     v.reason,
+				Candle:     &candles[v.index],
+				DetectedAt: now,
+			})
+			suspect[v.index] = true
+		}
+	}
+	d.mu.Unlock()
+
+	if len(anomalies) == 0 {
+		return candles
+	}
+	d.store(anomalies)
+
+	clean := make([]model.Candle, 0, len(candles)-len(suspect))
+	for i, candle := range candles {
+		if !suspect[i] {
+			clean = append(clean, candle)
+		}
+	}
+	return clean
+}
+
+// groupSeries returns indexes of candles by series ordered by timestamp
+func groupSeries(candles []model.Candle) map[seriesKey][]int {
+	groups := make(map[seriesKey][]int)
+	for i, candle := range candles {
+		key := seriesKey{candle.Pair, candle.Bar}
+		groups[key] = append(groups[key], i)
+	}
+	for _, indexes := range groups {
+		sort.Slice(indexes, func(a, b int) bool {
+			return candles[indexes[a]].Timestamp.Before(candles[indexes[b]].Timestamp)
+		})
+	}
+	return groups
+}
+
+type verdict struct {
+	index  int
+	kind   model.AnomalyKind
+	reason string
+}
+
+// checkSeries returns verdicts of suspect candles of the series, indexes are ordered by timestamp
+func (d *Detector) checkSeries(key seriesKey, candles []model.Candle, indexes []int) []verdict {
+	s := d.series[key]
+	fresh := s == nil
+	if fresh {
+		s = &series{forward: &window{}}
+		d.series[key] = s
+	}
+
+	var verdicts []verdict
+	check := func(w *window, i int) {
+		if kind, reason, ok := d.checkCandle(w, candles[i]); ok {
+			verdicts = append(verdicts, verdict{i, kind, reason})
+		}
+	}
+
+	var older []int
+	for _, i := range indexes {
+		ts := candles[i].Timestamp
+		switch {
+		case fresh || ts.After(s.last):
+			check(s.forward, i)
+			s.last = ts
+		case ts.Before(s.first):
+			older = append(older, i)
+		default:
+			// a seen candle is compared with the latest prices without changing them
+			check(s.forward.clone(), i)
+		}
+	}
+
+	if fresh {
+		s.first = candles[indexes[0]].Timestamp
+		s.backward = s.forward.clone()
+	}
+	for j := len(older) - 1; j >= 0; j-- {
+		check(s.backward, older[j])
+		s.first = candles[older[j]].Timestamp
+	}
+
+	return verdicts
+}
+
+// checkCandle tests invariants of the candle, then its close against the window
+func (d *Detector) checkCandle(w *window, candle model.Candle) (model.AnomalyKind, string, bool) {
+	if reason := candleInvariant(candle); reason != "" {
+		return model.AnomalyInvariant, reason, true
+	}
+	if reason, outlier := w.check(candle.Close, d.config); outlier {
+		return model.AnomalyOutlier, "close " + price.Price{Price: candle.Close}.String() + " has " + reason, true
+	}
+	return "", "", false
+}
+
+// candleInvariant returns the violated OHLC invariant or an empty string
+func candleInvariant(c model.Candle) string {
+	switch {
+	case c.Open <= 0 || c.High <= 0 || c.Low <= 0 || c.Close <= 0:
+		return "non-positive price"
+	case c.High < c.Low:
+		return "high below low"
+	case c.Open < c.Low || c.Open > c.High:
+		return "open outside the high-low range"
+	case c.Close < c.Low || c.Close > c.High:
+		return "close outside the high-low range"
+	case c.Volume < 0:
+		return "negative volume"
+	case c.Volume == 0:
+		return "zero volume"
+	}
+	return ""
+}
+
+// Trade is the trade filter, it reports whether the trade is passed on.
+// Suspect trades are quarantined in the background by Run.
+func (d *Detector) Trade(trade model.Trade) bool {
+	d.mu.Lock()
+	d.lastTrades[trade.Pair] = trade.Timestamp
+	delete(d.stale, trade.Pair)
+
+	kind, reason := model.AnomalyInvariant, tradeInvariant(trade)
+	if reason == "" {
+		w := d.prices[trade.Pair]
+		if w == nil {
+			w = &window{}
+			d.prices[trade.Pair] = w
+		}
+		if why, outlier := w.check(trade.Price, d.config); outlier {
+			kind, reason = model.AnomalyOutlier, "price "+price.Price{Price: trade.Price}.String()+" has "+why
+		}
+	}
+	d.mu.Unlock()
+
+	if reason == "" {
+		return true
+	}
+
+	select {
+	case d.queue <- model.Anomaly{
+		Kind:       kind,
+		Source:     model.AnomalySourceTrade,
+		Pair:       trade.Pair,
+		Timestamp:  trade.Timestamp,
+		Reason:     reason,
+		Trade:      &trade,
+		DetectedAt: d.now(),
+	}:
+	default:
+		d.log.Errorf("anomaly queue is full, suspect trade %s of %s is dropped: %s", trade.TradeId, trade.Pair, reason)
+	}
+	return false
+}
+
+func tradeInvariant(t model.Trade) string {
+	switch {
+	case t.Price <= 0:
+		return "non-positive price"
+	case t.Size <= 0:
+		return "non-positive size"
+	}
+	return ""
+}
+
+// Run stores quarantined trades and checks feeds every interval until the context is done
+func (d *Detector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case anomaly := <-d.queue:
+			d.store([]model.Anomaly{anomaly})
+		case <-ticker.C:
+			d.store(d.staleFeeds(d.now()))
+		}
+	}
+}
+
+// staleFeeds returns anomalies of pairs without trades for longer than StaleAfter, once per pair until a trade comes
+func (d *Detector) staleFeeds(now time.Time) []model.Anomaly {
+	if d.config.StaleAfter == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.feeding != nil && !d.feeding() {
+		d.started = now
+		d.lastTrades = make(map[string]time.Time)
+		d.stale = make(map[string]bool)
+		return nil
+	}
+
+	var anomalies []model.Anomaly
+	for _, pair := range d.pairs {
+		last, ok := d.lastTrades[pair]
+		if !ok {
+			last = d.started
+		}
+		if d.stale[pair] || now.Sub(last) <= d.config.StaleAfter {
+			continue
+		}
+		d.stale[pair] = true
+		anomalies = append(anomalies, model.Anomaly{
+			Kind:       model.AnomalyStale,
+			Source:     model.AnomalySourceFeed,
+			Pair:       pair,
+			Timestamp:  last,
+			Reason:     fmt.Sprintf("no trades for %s", now.Sub(last).Round(time.Second)),
+			DetectedAt: now,
+		})
+	}
+	return anomalies
+}
+
+// store saves anomalies and logs the new ones
+func (d *Detector) store(anomalies []model.Anomaly) {
+	if len(anomalies) == 0 {
+		return
+	}
+
+	inserted, err := d.repository.InsertAnomalies(anomalies)
+	if err != nil {
+		d.log.Errorf("failed to quarantine anomalies: %v", err)
+		return
+	}
+	for _, a := range inserted {
+		series := a.Pair
+		if a.Bar != "" {
+			series += " " + a.Bar
+		}
+		d.log.Warnf("anomaly %d: %s %s of %s at %s: %s", a.Id, a.Kind, a.Source, series,
+			a.Timestamp.UTC().Format(time.RFC3339), a.Reason)
+	}
+}
+
+// Release stores the quarantined row of the pending anomaly and marks it released,
+// candles go to the candle store and trades to the latest trades only.
+// ErrNotFound is returned when the anomaly isn't pending.
+func (d *Detector) Release(id int64) (model.Anomaly, error) {
+	anomaly, err := d.pending(id)
+	if err != nil {
+		return model.Anomaly{}, err
+	}
+
+	switch {
+	case anomaly.Candle != nil:
+		if err := d.candles.InsertCandles(&[]model.Candle{*anomaly.Candle}); err != nil {
+			return model.Anomaly{}, err
+		}
+	case anomaly.Trade != nil:
+		d.trades.Add(*anomaly.Trade)
+	}
+
+	return d.review(anomaly, model.AnomalyReleased)
+}
+
+// Discard marks the pending anomaly discarded, its row is never stored
+func (d *Detector) Discard(id int64) (model.Anomaly, error) {
+	anomaly, err := d.pending(id)
+	if err != nil {
+		return model.Anomaly{}, err
+	}
+	return d.review(anomaly, model.AnomalyDiscarded)
+}
+
+func (d *Detector) pending(id int64) (model.Anomaly, error) {
+	anomaly, err := d.repository.FetchAnomaly(id)
+	if err != nil {
+		return model.Anomaly{}, err
+	}
+	if anomaly.Status != model.AnomalyPending {
+		return model.Anomaly{}, store.ErrNotFound
+	}
+	return anomaly, nil
+}
+
+func (d *Detector) review(anomaly model.Anomaly, status model.AnomalyStatus) (model.Anomaly, error) {
+	now := d.now()
+	if err := d.repository.ReviewAnomaly(anomaly.Id, status, now); err != nil {
+		return model.Anomaly{}, err
+	}
+	anomaly.Status, anomaly.ReviewedAt = status, now
+	return anomaly, nil
+}
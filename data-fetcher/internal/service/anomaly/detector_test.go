@@ -0,0 +1,257 @@
+package anomaly
+
+import (
+	"context"
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func candle(hour int, close int64) model.Candle {
+	return model.Candle{
+		Pair:      "BTC-USDT",
+		Bar:       "1H",
+		Timestamp: start.Add(time.Duration(hour) * time.Hour),
+		Open:      pricetest.Units(close),
+		High:      pricetest.Units(close + 1),
+		Low:       pricetest.Units(close - 1),
+		Close:     pricetest.Units(close),
+		Volume:    pricetest.Units(1),
+	}
+}
+
+func candles(fromHour int, closes ...int64) []model.Candle {
+	var batch []model.Candle
+	for i, close := range closes {
+		batch = append(batch, candle(fromHour+i, close))
+	}
+	return batch
+}
+
+func trade(id string, seconds int, value int64) model.Trade {
+	return model.Trade{Pair: "BTC-USDT", TradeId: id, Price: pricetest.Units(value), Size: 1_000_000, Side: "buy",
+		Timestamp: start.Add(time.Duration(seconds) * time.Second)}
+}
+
+type testDetector struct {
+	*Detector
+	storage *memory.Store
+	trades  *store.TradeRepository
+}
+
+// newTestDetector checks windows of 10 prices once there are 5 of them, a pair is stale after a minute
+func newTestDetector(t *testing.T) *testDetector {
+	env := &testDetector{storage: memory.NewStore(), trades: store.NewTradeRepository(10)}
+	var err error
+	env.Detector, err = NewDetector(env.storage.Anomaly(), env.storage.Candle(), env.trades, []string{"BTC-USDT", "ETH-USDT"}, Config{
+		Window:       10,
+		MinSamples:   5,
+		MadScore:     6,
+		ZScore:       6,
+		MinDeviation: 1,
+		StaleAfter:   time.Minute,
+	}, log.New())
+	require.NoError(t, err)
+	env.now = func() time.Time { return start }
+	env.started = start
+	return env
+}
+
+func (env *testDetector) anomalies(t *testing.T, query store.AnomalyQuery) []model.Anomaly {
+	anomalies, err := env.storage.Anomaly().FetchAnomalies(query, 100)
+	require.NoError(t, err)
+	return anomalies
+}
+
+func reverse(batch []model.Candle) []model.Candle {
+	reversed := make([]model.Candle, len(batch))
+	for i, c := range batch {
+		reversed[len(batch)-1-i] = c
+	}
+	return reversed
+}
+
+func TestDetector_Candles(t *testing.T) {
+	env := newTestDetector(t)
+
+	batch := candles(0, 100, 101, 99, 100, 102, 101, 100, 99, 150, 100, 100, 101)
+	batch[9].High = batch[9].Low - 1
+	batch[10].Volume = 0
+
+	// newest first as OKX returns them
+	clean := env.Candles(reverse(batch))
+	require.Len(t, clean, 9)
+	for _, c := range clean {
+		assert.NotEqual(t, pricetest.Units(150), c.Close)
+	}
+
+	anomalies := env.anomalies(t, store.AnomalyQuery{})
+	require.Len(t, anomalies, 3)
+	byHour := make(map[int]model.Anomaly)
+	for _, a := range anomalies {
+		assert.Equal(t, model.AnomalySourceCandle, a.Source)
+		assert.Equal(t, model.AnomalyPending, a.Status)
+		byHour[int(a.Timestamp.Sub(start)/time.Hour)] = a
+	}
+	assert.Equal(t, model.AnomalyOutlier, byHour[8].Kind)
+	assert.Contains(t, byHour[8].Reason, "close 150 has modified z-score")
+	assert.Equal(t, pricetest.Units(150), byHour[8].Candle.Close)
+	assert.Equal(t, model.AnomalyInvariant, byHour[9].Kind)
+	assert.Equal(t, "high below low", byHour[9].Reason)
+	assert.Equal(t, "zero volume", byHour[10].Reason)
+
+	// a refetched candle is quarantined once
+	assert.Empty(t, env.Candles(batch[8:9]))
+	assert.Len(t, env.anomalies(t, store.AnomalyQuery{}), 3)
+}
+
+func TestDetector_NewPriceLevel(t *testing.T) {
+	env := newTestDetector(t)
+
+	clean := env.Candles(candles(0, 100, 101, 99, 100, 102, 150, 151, 150, 149, 150, 151))
+	assert.Len(t, clean, 7, "a streak of min samples outliers is a new level")
+	assert.Len(t, env.anomalies(t, store.AnomalyQuery{Kind: model.AnomalyOutlier}), 4)
+}
+
+func TestDetector_Backfill(t *testing.T) {
+	env := newTestDetector(t)
+
+	require.Len(t, env.Candles(candles(100, 100, 101, 99, 100, 102, 101, 100, 99, 100, 101)), 10)
+
+	// history is compared backwards with its newer neighbours
+	older := candles(90, 90, 91, 92, 93, 94, 300, 96, 97, 98, 99)
+	clean := env.Candles(reverse(older))
+	assert.Len(t, clean, 9)
+
+	anomalies := env.anomalies(t, store.AnomalyQuery{})
+	require.Len(t, anomalies, 1)
+	assert.True(t, start.Add(95*time.Hour).Equal(anomalies[0].Timestamp))
+
+	// seen candles are checked against the latest prices and don't change them
+	assert.Empty(t, env.Candles(candles(105, 300)))
+	assert.Len(t, env.Candles(candles(110, 100)), 1)
+}
+
+func TestDetector_Trades(t *testing.T) {
+	env := newTestDetector(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go env.Run(ctx, time.Hour)
+
+	for i, value := range []int64{100, 101, 99, 100, 102, 101} {
+		assert.True(t, env.Trade(trade(string(rune('a'+i)), i, value)))
+	}
+	assert.False(t, env.Trade(trade("fat", 10, 1000)))
+	assert.False(t, env.Trade(trade("zero", 11, 0)))
+	assert.True(t, env.Trade(trade("next", 12, 100)))
+
+	require.Eventually(t, func() bool {
+		return len(env.anomalies(t, store.AnomalyQuery{})) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	anomalies := env.anomalies(t, store.AnomalyQuery{Kind: model.AnomalyOutlier})
+	require.Len(t, anomalies, 1)
+	require.NotNil(t, anomalies[0].Trade)
+	assert.Equal(t, "fat", anomalies[0].Trade.TradeId)
+	assert.Equal(t, model.AnomalySourceTrade, anomalies[0].Source)
+}
+
+func TestDetector_StaleFeeds(t *testing.T) {
+	env := newTestDetector(t)
+	env.Trade(trade("1", 90, 100))
+
+	stale := env.staleFeeds(start.Add(2 * time.Minute))
+	require.Len(t, stale, 1, "BTC-USDT traded 30 seconds ago")
+	assert.Equal(t, "ETH-USDT", stale[0].Pair)
+	assert.Equal(t, model.AnomalyStale, stale[0].Kind)
+	assert.True(t, start.Equal(stale[0].Timestamp), "no trades since start")
+	assert.Equal(t, "no trades for 2m0s", stale[0].Reason)
+
+	stale = env.staleFeeds(start.Add(3 * time.Minute))
+	require.Len(t, stale, 1, "ETH-USDT is reported once")
+	assert.Equal(t, "BTC-USDT", stale[0].Pair)
+	assert.True(t, start.Add(90*time.Second).Equal(stale[0].Timestamp))
+
+	env.Trade(model.Trade{Pair: "ETH-USDT", TradeId: "2", Price: pricetest.Units(3000), Size: 1, Timestamp: start.Add(4 * time.Minute)})
+	assert.Empty(t, env.staleFeeds(start.Add(5*time.Minute)))
+	stale = env.staleFeeds(start.Add(6 * time.Minute))
+	require.Len(t, stale, 1, "reported again after a trade")
+	assert.Equal(t, "ETH-USDT", stale[0].Pair)
+}
+
+func TestDetector_StaleFeedsWhileFeeding(t *testing.T) {
+	env := newTestDetector(t)
+	feeding := false
+	env.SetFeeding(func() bool { return feeding })
+	env.Trade(trade("1", 10, 100))
+
+	assert.Empty(t, env.staleFeeds(start.Add(5*time.Minute)), "trades aren't received")
+
+	feeding = true
+	assert.Empty(t, env.staleFeeds(start.Add(6*time.Minute)), "counted since feeding starts")
+	stale := env.staleFeeds(start.Add(7 * time.Minute))
+	require.Len(t, stale, 2)
+	assert.True(t, start.Add(5*time.Minute).Equal(stale[0].Timestamp), "trades before aren't counted")
+
+	feeding = false
+	assert.Empty(t, env.staleFeeds(start.Add(8*time.Minute)))
+	feeding = true
+	assert.Empty(t, env.staleFeeds(start.Add(9*time.Minute)), "reported pairs are reset")
+}
+
+func TestDetector_Review(t *testing.T) {
+	env := newTestDetector(t)
+	batch := candles(0, 100, 101)
+	batch[1].Volume = 0
+	env.Candles(batch)
+	env.store([]model.Anomaly{{Kind: model.AnomalyOutlier, Source: model.AnomalySourceTrade, Pair: "BTC-USDT",
+		Timestamp: start, Reason: "test", Trade: &model.Trade{Pair: "BTC-USDT", TradeId: "7", Price: pricetest.Units(1), Timestamp: start}}})
+
+	candleAnomaly := env.anomalies(t, store.AnomalyQuery{Kind: model.AnomalyInvariant})[0]
+	tradeAnomaly := env.anomalies(t, store.AnomalyQuery{Kind: model.AnomalyOutlier})[0]
+
+	released, err := env.Release(candleAnomaly.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.AnomalyReleased, released.Status)
+	assert.True(t, start.Equal(released.ReviewedAt))
+	stored, err := env.storage.Candle().FetchLatest("BTC-USDT", "1H", 10)
+	require.NoError(t, err)
+	require.Len(t, stored, 1, "only the released candle, the filter doesn't store")
+	assert.Equal(t, int64(0), stored[0].Volume)
+
+	_, err = env.Release(candleAnomaly.Id)
+	assert.ErrorIs(t, err, store.ErrNotFound, "reviewed already")
+
+	discarded, err := env.Discard(tradeAnomaly.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.AnomalyDiscarded, discarded.Status)
+	assert.Empty(t, env.trades.Latest("BTC-USDT", 10))
+
+	_, err = env.Discard(tradeAnomaly.Id + 100)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestNewDetector_Validates(t *testing.T) {
+	valid := Config{Window: 10, MinSamples: 5, MadScore: 6, ZScore: 6, MinDeviation: 1}
+	_, err := NewDetector(nil, nil, nil, nil, valid, log.New())
+	assert.NoError(t, err)
+
+	for _, config := range []Config{
+		{Window: 0, MinSamples: 0, MadScore: 6, ZScore: 6},
+		{Window: 10, MinSamples: 11, MadScore: 6, ZScore: 6},
+		{Window: 10, MinSamples: 5, MadScore: 0, ZScore: 6},
+		{Window: 10, MinSamples: 5, MadScore: 6, ZScore: 6, StaleAfter: -time.Second},
+	} {
+		_, err := NewDetector(nil, nil, nil, nil, config, log.New())
+		assert.Error(t, err, "%+v", config)
+	}
+}
@@ -0,0 +1,106 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// madScale makes the median absolute deviation comparable with the standard deviation of a normal distribution
+const madScale = 0.6745
+
+// window rolling prices of a series, outliers aren't added to it.
+// A streak of outliers as long as MinSamples is taken as a new price level and replaces the window.
+type window struct {
+	values []int64 // ring buffer
+	next   int
+	streak []int64
+}
+
+func (w *window) add(v int64, size int) {
+	w.streak = w.streak[:0]
+	if len(w.values) < size {
+		w.values = append(w.values, v)
+		return
+	}
+	w.values[w.next] = v
+	w.next = (w.next + 1) % size
+}
+
+func (w *window) clone() *window {
+	return &window{values: slices.Clone(w.values), next: w.next}
+}
+
+// check adds the price to the window unless it is an outlier, returns the reason of an outlier
+func (w *window) check(v int64, config Config) (string, bool) {
+	reason, outlier := w.outlier(v, config)
+	if !outlier {
+		w.add(v, config.Window)
+		return "", false
+	}
+
+	w.streak = append(w.streak, v)
+	if len(w.streak) < config.MinSamples {
+		return reason, true
+	}
+
+	streak := w.streak
+	w.values, w.next, w.streak = nil, 0, nil
+	for _, value := range streak {
+		w.add(value, config.Window)
+	}
+	return "", false
+}
+
+// outlier tests the price by the modified z-score over the median absolute deviation,
+// by the z-score when the deviation is zero
+func (w *window) outlier(v int64, config Config) (string, bool) {
+	if len(w.values) < config.MinSamples {
+		return "", false
+	}
+
+	values := make([]float64, len(w.values))
+	for i, value := range w.values {
+		values[i] = float64(value)
+	}
+	x := float64(v)
+	median := medianOf(values)
+	deviation := math.Abs(x - median)
+	if median <= 0 || deviation/median*100 < config.MinDeviation {
+		return "", false
+	}
+
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - median)
+	}
+	if mad := medianOf(deviations); mad > 0 {
+		score := madScale * deviation / mad
+		return fmt.Sprintf("modified z-score %.1f over %d samples", score, len(values)), score > config.MadScore
+	}
+
+	var mean, variance float64
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	if std := math.Sqrt(variance / float64(len(values))); std > 0 {
+		score := math.Abs(x-mean) / std
+		return fmt.Sprintf("z-score %.1f over %d samples", score, len(values)), score > config.ZScore
+	}
+
+	return fmt.Sprintf("%.2f%% off a flat series of %d samples", deviation/median*100, len(values)), true
+}
+
+// medianOf sorts the values
+func medianOf(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,186 @@
+// Package conversion prices any two currencies through a graph of available pairs,
+// a pair BASE-QUOTE converts BASE to QUOTE by its price and QUOTE to BASE by the inverse
+package conversion
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNoPath    = errors.New("no conversion path")
+	ErrZeroPrice = errors.New("zero price")
+)
+
+// Edge converts From to To by the price of the pair, by its inverse when the pair is To-From
+type Edge struct {
+	Pair    string
+	From    string
+	To      string
+	Inverse bool
+}
+
+// Path chain of edges converting a currency to another one, empty when both are the same
+type Path []Edge
+
+// Pairs returns the pairs of the path in conversion order
+func (p Path) Pairs() []string {
+	pairs := make([]string, 0, len(p))
+	for _, e := range p {
+		pairs = append(pairs, e.Pair)
+	}
+	return pairs
+}
+
+// Graph currencies connected by pairs
+type Graph struct {
+	edges map[string][]Edge
+	pairs map[string]bool
+	order []string
+}
+
+// NewGraph makes graph of the pairs, among equally short paths the one through the pairs listed first is preferred
+func NewGraph(pairs []string) *Graph {
+	g := &Graph{edges: make(map[string][]Edge), pairs: make(map[string]bool)}
+	for _, pair := range pairs {
+		g.Add(pair)
+	}
+	return g
+}
+
+// Add adds the pair unless it's added already or isn't like BTC-USDT
+func (g *Graph) Add(pair string) {
+	base, quote, ok := strings.Cut(pair, "-")
+	if !ok || base == "" || quote == "" || base == quote || g.pairs[pair] {
+		return
+	}
+	g.pairs[pair] = true
+	g.order = append(g.order, pair)
+	g.edges[base] = append(g.edges[base], Edge{Pair: pair, From: base, To: quote})
+	g.edges[quote] = append(g.edges[quote], Edge{Pair: pair, From: quote, To: base, Inverse: true})
+}
+
+// Pairs returns the pairs in the order they were added
+func (g *Graph) Pairs() []string {
+	return slices.Clone(g.order)
+}
+
+// Path finds the path with the fewest pairs by breadth-first search
+func (g *Graph) Path(from, to string) (Path, error) {
+	if from == to {
+		return Path{}, nil
+	}
+
+	previous := map[string]Edge{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			break
+		}
+		for _, e := range g.edges[current] {
+			if _, seen := previous[e.To]; !seen {
+				previous[e.To] = e
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	if _, ok := previous[to]; !ok {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoPath, from, to)
+	}
+
+	var path Path
+	for current := to; current != from; current = previous[current].From {
+		path = append(path, previous[current])
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
+// Rate multiplies prices of the path pairs (in path order) into the price of a unit of the first currency
+func (p Path) Rate(prices []int64) (int64, error) {
+	if len(prices) != len(p) {
+		return 0, fmt.Errorf("%d prices for %d pairs", len(prices), len(p))
+	}
+
+	rate := int64(price.PriceFactor)
+	for i, e := range p {
+		var err error
+		if rate, err = apply(rate, prices[i], e.Inverse); err != nil {
+			return 0, fmt.Errorf("%s: %w", e.Pair, err)
+		}
+	}
+	return rate, nil
+}
+
+// apply converts the value by the pair price
+func apply(value, pairPrice int64, inverse bool) (int64, error) {
+	if pairPrice <= 0 {
+		return 0, ErrZeroPrice
+	}
+	if inverse {
+		return price.MulDiv(value, price.PriceFactor, pairPrice), nil
+	}
+	return price.MulDiv(value, pairPrice, price.PriceFactor), nil
+}
+
+// Candles synthesizes candles of the pair from candles of the path pairs (legs in path order, ordered by timestamp),
+// only timestamps present in every leg are kept. Highs and lows are bounds: the product of leg highs (lows),
+// the inverse of a leg low (high) for inverse edges. Volume is in the first currency.
+func (p Path) Candles(pair, bar string, legs [][]model.Candle) ([]model.Candle, error) {
+	if len(legs) != len(p) || len(p) == 0 {
+		return nil, fmt.Errorf("%d legs for %d pairs", len(legs), len(p))
+	}
+
+	byTimestamp := make([]map[int64]model.Candle, len(legs))
+	for i, leg := range legs {
+		byTimestamp[i] = make(map[int64]model.Candle, len(leg))
+		for _, c := range leg {
+			byTimestamp[i][c.Timestamp.UnixMicro()] = c
+		}
+	}
+
+	var candles []model.Candle
+outer:
+	for _, first := range legs[0] {
+		synthetic := model.Candle{
+			Pair: pair, Bar: bar, Timestamp: first.Timestamp,
+			Open: price.PriceFactor, High: price.PriceFactor, Low: price.PriceFactor, Close: price.PriceFactor,
+		}
+		for i, e := range p {
+			c, ok := byTimestamp[i][first.Timestamp.UnixMicro()]
+			if !ok {
+				continue outer
+			}
+			high, low := c.High, c.Low
+			if e.Inverse {
+				high, low = c.Low, c.High
+			}
+			for _, v := range []struct {
+				target *int64
+				price  int64
+			}{{&synthetic.Open, c.Open}, {&synthetic.High, high}, {&synthetic.Low, low}, {&synthetic.Close, c.Close}} {
+				value, err := apply(*v.target, v.price, e.Inverse)
+				if err != nil {
+					return nil, fmt.Errorf("%s candle at %s: %w", e.Pair, c.Timestamp.UTC(), err)
+				}
+				*v.target = value
+			}
+		}
+
+		synthetic.High = max(synthetic.High, synthetic.Open, synthetic.Close)
+		synthetic.Low = min(synthetic.Low, synthetic.Open, synthetic.Close)
+		synthetic.Volume = first.Volume
+		if p[0].Inverse {
+			// the volume of the first pair is in its base currency which is the second currency of the edge
+			synthetic.Volume = price.MulDiv(first.Volume, first.Close, price.PriceFactor)
+		}
+		candles = append(candles, synthetic)
+	}
+	return candles, nil
+}
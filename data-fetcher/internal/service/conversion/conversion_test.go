@@ -0,0 +1,90 @@
+package conversion
+
+import (
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_Path(t *testing.T) {
+	g := NewGraph([]string{"BTC-USDT", "ETH-USDT", "TON-BTC", "ETH-BTC", "EUR-USDT", "invalid", "BTC-USDT"})
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT", "TON-BTC", "ETH-BTC", "EUR-USDT"}, g.Pairs())
+
+	p, err := g.Path("BTC", "USDT")
+	require.NoError(t, err)
+	assert.Equal(t, Path{{Pair: "BTC-USDT", From: "BTC", To: "USDT"}}, p)
+
+	p, err = g.Path("USDT", "ETH")
+	require.NoError(t, err)
+	assert.Equal(t, Path{{Pair: "ETH-USDT", From: "USDT", To: "ETH", Inverse: true}}, p)
+
+	p, err = g.Path("TON", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TON-BTC", "BTC-USDT", "EUR-USDT"}, p.Pairs())
+	assert.True(t, p[2].Inverse)
+
+	p, err = g.Path("ETH", "ETH")
+	require.NoError(t, err)
+	assert.Empty(t, p)
+
+	_, err = g.Path("BTC", "SOL")
+	assert.ErrorIs(t, err, ErrNoPath)
+}
+
+func TestPath_Rate(t *testing.T) {
+	p, err := NewGraph([]string{"TON-BTC", "BTC-USDT", "EUR-USDT"}).Path("TON", "EUR")
+	require.NoError(t, err)
+
+	// 0.0001 BTC * 50000 USDT / 1.25 USDT
+	rate, err := p.Rate([]int64{10_000, pricetest.Units(50_000), 125_000_000})
+	require.NoError(t, err)
+	assert.Equal(t, pricetest.Units(4), rate)
+
+	_, err = p.Rate([]int64{10_000, 0, 1})
+	assert.ErrorIs(t, err, ErrZeroPrice)
+	_, err = p.Rate([]int64{1})
+	assert.Error(t, err)
+
+	rate, err = Path{}.Rate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, pricetest.Units(1), rate)
+}
+
+func TestPath_Candles(t *testing.T) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	p, err := NewGraph([]string{"ETH-USDT", "BTC-USDT"}).Path("ETH", "BTC")
+	require.NoError(t, err)
+
+	eth := []model.Candle{
+		{Pair: "ETH-USDT", Timestamp: start, Open: pricetest.Units(3_000), High: pricetest.Units(3_300), Low: pricetest.Units(2_700), Close: pricetest.Units(3_000), Volume: pricetest.Units(10)},
+		{Pair: "ETH-USDT", Timestamp: start.Add(time.Hour), Open: pricetest.Units(3_000), High: pricetest.Units(3_000), Low: pricetest.Units(3_000), Close: pricetest.Units(3_000), Volume: pricetest.Units(1)},
+	}
+	btc := []model.Candle{
+		{Pair: "BTC-USDT", Timestamp: start, Open: pricetest.Units(50_000), High: pricetest.Units(60_000), Low: pricetest.Units(40_000), Close: pricetest.Units(60_000), Volume: pricetest.Units(2)},
+	}
+
+	candles, err := p.Candles("ETH-BTC", "1H", [][]model.Candle{eth, btc})
+	require.NoError(t, err)
+	require.Len(t, candles, 1, "BTC-USDT misses the second candle")
+	assert.Equal(t, model.Candle{
+		Pair: "ETH-BTC", Bar: "1H", Timestamp: start,
+		Open: 6_000_000, High: 8_250_000, Low: 4_500_000, Close: 5_000_000, Volume: pricetest.Units(10),
+	}, candles[0])
+
+	// the first pair is inverse, its volume is converted to USDT
+	inverse, err := NewGraph([]string{"BTC-USDT"}).Path("USDT", "BTC")
+	require.NoError(t, err)
+	candles, err = inverse.Candles("USDT-BTC", "1H", [][]model.Candle{btc})
+	require.NoError(t, err)
+	require.Len(t, candles, 1)
+	assert.Equal(t, int64(2_500), candles[0].High)
+	assert.Equal(t, int64(1_667), candles[0].Low)
+	assert.Equal(t, pricetest.Units(120_000), candles[0].Volume)
+
+	_, err = p.Candles("ETH-BTC", "1H", [][]model.Candle{eth})
+	assert.Error(t, err)
+}
@@ -0,0 +1,291 @@
+// Package divergence compares OKX prices with other venues and reports spreads which persist
+package divergence
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/infrastructure/kafka"
+	"cur/internal/infrastructure/tracing"
+	"cur/internal/model"
+	"cur/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	EventTopic = "price-divergences"
+	// OkxVenue name of OKX in fee settings
+	OkxVenue = "okx"
+	hundred  = 100 * price.PriceFactor
+)
+
+// Config percents are multiplied by price.PriceFactor
+type Config struct {
+	Threshold int64            // net spread which fires events, e.g. 0.5% is 5e7
+	Window    time.Duration    // how long the net spread must stay at or above the threshold
+	MaxAge    time.Duration    // OKX prices of older trades aren't compared
+	Fees      map[string]int64 // taker fee by venue, OkxVenue for OKX, zero when missing
+}
+
+func (c Config) validate() error {
+	if c.Threshold < 0 || c.Window < 0 || c.MaxAge <= 0 {
+		return errors.New("threshold and window must not be negative and max age must be positive")
+	}
+	for venue, fee := range c.Fees {
+		if fee < 0 || fee >= hundred {
+			return fmt.Errorf("fee of %s must be in [0, 100) percent", venue)
+		}
+	}
+	return nil
+}
+
+type venueKey struct {
+	pair, source string
+}
+
+// spreadState tracks since when the net spread has been at or above the threshold
+type spreadState struct {
+	since time.Time
+	fired bool
+}
+
+type Monitor struct {
+	repository store.DivergenceStore
+	sources    []Source
+	producer   kafka.Producer
+	config     Config
+	log        *log.Logger
+	now        func() time.Time
+
+	mu     sync.Mutex
+	trades map[string]model.Trade // the latest OKX trade by pair
+	latest map[venueKey]model.Divergence
+	states map[venueKey]*spreadState
+}
+
+// NewMonitor makes divergence monitor, events aren't published when producer is nil
+func NewMonitor(repository store.DivergenceStore, sources []Source, producer kafka.Producer, config Config, log *log.Logger) (*Monitor, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("no reference sources")
+	}
+
+	return &Monitor{
+		repository: repository,
+		sources:    sources,
+		producer:   producer,
+		config:     config,
+		log:        log,
+		now:        time.Now,
+		trades:     make(map[string]model.Trade),
+		latest:     make(map[venueKey]model.Divergence),
+		states:     make(map[venueKey]*spreadState),
+	}, nil
+}
+
+// Trade is the trade handler, it keeps the latest OKX price of the pair
+func (m *Monitor) Trade(trade model.Trade) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.trades[trade.Pair]; ok && current.Timestamp.After(trade.Timestamp) {
+		return
+	}
+	m.trades[trade.Pair] = trade
+}
+
+// Run polls the sources every interval until the context is done
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Poll(ctx); err != nil {
+				m.log.Errorf("divergence poll failed: %v", err)
+			}
+		}
+	}
+}
+
+// Poll compares fresh OKX prices with prices of every source, stores the divergences and fires events.
+// A failed source doesn't stop the others. Published events continue the trace of the poll.
+func (m *Monitor) Poll(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "divergence poll", attribute.Int("sources", len(m.sources)))
+	defer func() { tracing.End(span, err) }()
+
+	now := m.now()
+	okx := m.okxPrices(now)
+	if len(okx) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(okx))
+	for pair := range okx {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	var divergences []model.Divergence
+	var errs []error
+	for _, source := range m.sources {
+		prices, err := source.Prices(ctx, pairs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		for _, pair := range pairs {
+			if reference, ok := prices[pair]; ok && reference > 0 {
+				divergences = append(divergences, m.divergence(pair, source.Name(), okx[pair], reference, now))
+			}
+		}
+	}
+
+	if err := m.repository.InsertDivergences(divergences); err != nil {
+		errs = append(errs, err)
+	}
+	for _, d := range divergences {
+		if event := m.evaluate(d); event != nil {
+			m.fire(ctx, *event)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Latest returns the latest divergence of every pair and source ordered by pair and source
+func (m *Monitor) Latest() []model.Divergence {
+	m.mu.Lock()
+	divergences := make([]model.Divergence, 0, len(m.latest))
+	for _, d := range m.latest {
+		divergences = append(divergences, d)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(divergences, func(i, j int) bool {
+		if divergences[i].Pair != divergences[j].Pair {
+			return divergences[i].Pair < divergences[j].Pair
+		}
+		return divergences[i].Source < divergences[j].Source
+	})
+	return divergences
+}
+
+// okxPrices returns prices of trades not older than the max age
+func (m *Monitor) okxPrices(now time.Time) map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices := make(map[string]int64)
+	for pair, trade := range m.trades {
+		if now.Sub(trade.Timestamp) <= m.config.MaxAge && trade.Price > 0 {
+			prices[pair] = trade.Price
+		}
+	}
+	return prices
+}
+
+func (m *Monitor) divergence(pair, source string, okx, reference int64, now time.Time) model.Divergence {
+	spread := price.MulDiv(reference-okx, hundred, okx)
+	return model.Divergence{
+		Pair:           pair,
+		Source:         source,
+		OkxPrice:       okx,
+		ReferencePrice: reference,
+		Spread:         spread,
+		NetSpread:      max(spread, -spread) - m.config.Fees[OkxVenue] - m.config.Fees[source],
+		ObservedAt:     now,
+	}
+}
+
+// evaluate returns an event when the net spread has stayed at or above the threshold for the window,
+// it fires once until the spread falls below the threshold
+func (m *Monitor) evaluate(d model.Divergence) *model.DivergenceEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := venueKey{d.Pair, d.Source}
+	m.latest[key] = d
+	state, ok := m.states[key]
+	if !ok {
+		state = &spreadState{}
+		m.states[key] = state
+	}
+
+	if d.NetSpread < m.config.Threshold {
+		*state = spreadState{}
+		return nil
+	}
+	if state.since.IsZero() {
+		state.since = d.ObservedAt
+	}
+	if state.fired || d.ObservedAt.Sub(state.since) < m.config.Window {
+		return nil
+	}
+
+	state.fired = true
+	return &model.DivergenceEvent{
+		Pair:           d.Pair,
+		Source:         d.Source,
+		OkxPrice:       d.OkxPrice,
+		ReferencePrice: d.ReferencePrice,
+		Spread:         d.Spread,
+		NetSpread:      d.NetSpread,
+		Threshold:      m.config.Threshold,
+		StartedAt:      state.since,
+		TriggeredAt:    d.ObservedAt,
+	}
+}
+
+type eventMessage struct {
+	Id             int64     `json:"id"`
+	Pair           string    `json:"pair"`
+	Source         string    `json:"source"`
+	OkxPrice       string    `json:"okxPrice"`
+	ReferencePrice string    `json:"referencePrice"`
+	Spread         string    `json:"spread"`
+	NetSpread      string    `json:"netSpread"`
+	Threshold      string    `json:"threshold"`
+	StartedAt      time.Time `json:"startedAt"`
+	TriggeredAt    time.Time `json:"triggeredAt"`
+}
+
+// fire stores the event and publishes it keyed by pair
+func (m *Monitor) fire(ctx context.Context, event model.DivergenceEvent) {
+	event, err := m.repository.InsertDivergenceEvent(event)
+	if err != nil {
+		m.log.Errorf("failed to store divergence event of %s: %v", event.Pair, err)
+		return
+	}
+	m.log.Warnf("%s spread to %s is %s%% (%s%% net of fees) since %s", event.Pair, event.Source,
+		price.Price{Price: event.Spread}, price.Price{Price: event.NetSpread}, event.StartedAt.UTC().Format(time.RFC3339))
+
+	if m.producer == nil {
+		return
+	}
+	body, err := json.Marshal(eventMessage{
+		Id:             event.Id,
+		Pair:           event.Pair,
+		Source:         event.Source,
+		OkxPrice:       price.Price{Price: event.OkxPrice}.String(),
+		ReferencePrice: price.Price{Price: event.ReferencePrice}.String(),
+		Spread:         price.Price{Price: event.Spread}.String(),
+		NetSpread:      price.Price{Price: event.NetSpread}.String(),
+		Threshold:      price.Price{Price: event.Threshold}.String(),
+		StartedAt:      event.StartedAt.UTC(),
+		TriggeredAt:    event.TriggeredAt.UTC(),
+	})
+	if err != nil {
+		m.log.Error(err)
+		return
+	}
+	m.producer.SendKeyedMessage(ctx, EventTopic, event.Pair, string(body))
+}
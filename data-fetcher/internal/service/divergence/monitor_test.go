@@ -0,0 +1,186 @@
+package divergence
+
+import (
+	"context"
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+type fakeSource struct {
+	name   string
+	prices map[string]int64
+	err    error
+}
+
+func (s *fakeSource) Name() string {
+	return s.name
+}
+
+func (s *fakeSource) Prices(_ context.Context, _ []string) (map[string]int64, error) {
+	return s.prices, s.err
+}
+
+type recordingProducer struct {
+	mu       sync.Mutex
+	messages []eventMessage
+}
+
+func (p *recordingProducer) SendMessage(ctx context.Context, topic, message string) {
+	p.SendKeyedMessage(ctx, topic, "", message)
+}
+
+func (p *recordingProducer) SendKeyedMessage(ctx context.Context, topic, key, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var event eventMessage
+	if err := json.Unmarshal([]byte(message), &event); err != nil || topic != EventTopic || key != event.Pair {
+		panic("unexpected message " + message)
+	}
+	p.messages = append(p.messages, event)
+}
+
+type testMonitor struct {
+	*Monitor
+	repository *memory.DivergenceRepository
+	producer   *recordingProducer
+	source     *fakeSource
+	clock      time.Time
+}
+
+// newTestMonitor fires after 0.5% net spread for 1 minute, fees are 0.1% on OKX and 0.05% on the source
+func newTestMonitor(t *testing.T) *testMonitor {
+	env := &testMonitor{
+		repository: memory.NewDivergenceRepository(),
+		producer:   &recordingProducer{},
+		source:     &fakeSource{name: "binance"},
+		clock:      start,
+	}
+	var err error
+	env.Monitor, err = NewMonitor(env.repository, []Source{env.source}, env.producer, Config{
+		Threshold: 50_000_000,
+		Window:    time.Minute,
+		MaxAge:    time.Minute,
+		Fees:      map[string]int64{OkxVenue: 10_000_000, "binance": 5_000_000},
+	}, log.New())
+	require.NoError(t, err)
+	env.now = func() time.Time { return env.clock }
+	return env
+}
+
+// poll trades on OKX at the price and polls the reference price after the delay
+func (env *testMonitor) poll(t *testing.T, delay time.Duration, okx, reference int64) {
+	env.clock = env.clock.Add(delay)
+	env.Trade(model.Trade{Pair: "BTC-USDT", Price: pricetest.Units(okx), Timestamp: env.clock})
+	env.source.prices = map[string]int64{"BTC-USDT": pricetest.Units(reference)}
+	require.NoError(t, env.Poll(context.Background()))
+}
+
+func TestMonitor_FiresAfterWindow(t *testing.T) {
+	env := newTestMonitor(t)
+
+	env.poll(t, 0, 100_000, 101_000)              // 1% spread, 0.85% net
+	env.poll(t, 30*time.Second, 100_000, 100_500) // 0.35% net, resets the window
+	env.poll(t, 30*time.Second, 100_000, 99_000)  // -1% spread starts the window
+	env.poll(t, 30*time.Second, 100_000, 99_000)
+	events, err := env.repository.FetchDivergenceEvents("", 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	env.poll(t, 30*time.Second, 100_000, 99_000)
+	env.poll(t, 30*time.Second, 100_000, 99_000) // fires once
+	events, err = env.repository.FetchDivergenceEvents("", 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, model.DivergenceEvent{
+		Id: events[0].Id, Pair: "BTC-USDT", Source: "binance", OkxPrice: pricetest.Units(100_000), ReferencePrice: pricetest.Units(99_000),
+		Spread: -pricetest.Units(1), NetSpread: 85_000_000, Threshold: 50_000_000,
+		StartedAt: start.Add(time.Minute), TriggeredAt: start.Add(2 * time.Minute),
+	}, events[0])
+
+	require.Len(t, env.producer.messages, 1)
+	assert.Equal(t, "-1", env.producer.messages[0].Spread)
+	assert.Equal(t, "0.85", env.producer.messages[0].NetSpread)
+
+	// below the threshold the window starts over
+	env.poll(t, 30*time.Second, 100_000, 100_000)
+	env.poll(t, 30*time.Second, 100_000, 102_000)
+	env.poll(t, time.Minute, 100_000, 102_000)
+	events, err = env.repository.FetchDivergenceEvents("", 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	divergences, err := env.repository.FetchDivergences(store.DivergenceQuery{Pair: "BTC-USDT"}, 100)
+	require.NoError(t, err)
+	assert.Len(t, divergences, 9)
+	latest := divergences[0]
+	latest.Id = 0
+	assert.Equal(t, []model.Divergence{latest}, env.Latest())
+}
+
+func TestMonitor_SkipsStaleAndUnlisted(t *testing.T) {
+	env := newTestMonitor(t)
+	env.Trade(model.Trade{Pair: "BTC-USDT", Price: pricetest.Units(100_000), Timestamp: start.Add(-2 * time.Minute)})
+	env.Trade(model.Trade{Pair: "ETH-USDT", Price: pricetest.Units(3_000), Timestamp: start})
+	env.Trade(model.Trade{Pair: "ETH-USDT", Price: pricetest.Units(2_000), Timestamp: start.Add(-time.Second)}) // out of order
+	env.source.prices = map[string]int64{"BTC-USDT": pricetest.Units(101_000), "ETH-USDT": pricetest.Units(3_030)}
+	require.NoError(t, env.Poll(context.Background()))
+
+	latest := env.Latest()
+	require.Len(t, latest, 1, "the BTC-USDT trade is too old")
+	assert.Equal(t, "ETH-USDT", latest[0].Pair)
+	assert.Equal(t, pricetest.Units(1), latest[0].Spread)
+
+	env.source.err = errors.New("unavailable")
+	assert.ErrorContains(t, env.Poll(context.Background()), "binance: unavailable")
+}
+
+func TestNewMonitor_Validates(t *testing.T) {
+	valid := Config{Threshold: 1, MaxAge: time.Minute}
+	_, err := NewMonitor(nil, nil, nil, valid, log.New())
+	assert.Error(t, err, "no sources")
+
+	sources := []Source{&fakeSource{name: "binance"}}
+	for name, config := range map[string]Config{
+		"threshold": {Threshold: -1, MaxAge: time.Minute},
+		"max age":   {Threshold: 1},
+		"fee":       {Threshold: 1, MaxAge: time.Minute, Fees: map[string]int64{OkxVenue: pricetest.Units(100)}},
+	} {
+		_, err := NewMonitor(nil, sources, nil, config, log.New())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestBinanceSource_Prices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/ticker/price", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"symbol":"BTCUSDT","price":"97000.12000000"},{"symbol":"ETHBTC","price":"0.03"},{"symbol":"TONUSDT","price":"3.5"}]`))
+	}))
+	defer server.Close()
+
+	source := NewBinanceSource(server.URL + "/")
+	prices, err := source.Prices(context.Background(), []string{"BTC-USDT", "TON-USDT", "SOL-USDT"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"BTC-USDT": 9_700_012_000_000, "TON-USDT": 350_000_000}, prices)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	_, err = NewBinanceSource(failing.URL).Prices(context.Background(), []string{"BTC-USDT"})
+	assert.ErrorContains(t, err, "status 429")
+}
@@ -0,0 +1,84 @@
+package divergence
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBinanceUrl = "https://api.binance.com"
+	sourceTimeout     = 10 * time.Second
+)
+
+// Source reference price feed of another venue
+type Source interface {
+	// Name identifies the venue in divergences and fee settings
+	Name() string
+	// Prices returns the latest prices of the pairs (like BTC-USDT) listed on the venue, unlisted pairs are omitted
+	Prices(ctx context.Context, pairs []string) (map[string]int64, error)
+}
+
+// BinanceSource last prices of the Binance public ticker, BTC-USDT is BTCUSDT there
+type BinanceSource struct {
+	url    string
+	client *http.Client
+}
+
+func NewBinanceSource(url string) *BinanceSource {
+	return &BinanceSource{url: strings.TrimSuffix(url, "/"), client: &http.Client{Timeout: sourceTimeout}}
+}
+
+func (s *BinanceSource) Name() string {
+	return "binance"
+}
+
+type binanceTicker struct {
+	Symbol string `json:"symbol"`
+	Price  string `json:"price"`
+}
+
+// Prices fetches tickers of every symbol, requesting unlisted symbols would fail the whole request
+func (s *BinanceSource) Prices(ctx context.Context, pairs []string) (map[string]int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v3/ticker/price", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("binance request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("binance responded with status %d", resp.StatusCode)
+	}
+
+	var tickers []binanceTicker
+	if err := json.NewDecoder(resp.Body).Decode(&tickers); err != nil {
+		return nil, fmt.Errorf("failed to decode binance tickers: %w", err)
+	}
+
+	symbols := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		symbols[strings.ReplaceAll(pair, "-", "")] = pair
+	}
+
+	prices := make(map[string]int64)
+	for _, ticker := range tickers {
+		pair, ok := symbols[ticker.Symbol]
+		if !ok {
+			continue
+		}
+		value, err := price.ParsePrice(ticker.Price)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binance price %q of %s", ticker.Price, ticker.Symbol)
+		}
+		prices[pair] = value
+	}
+	return prices, nil
+}
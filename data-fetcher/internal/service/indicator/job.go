@@ -0,0 +1,190 @@
+// Package indicator precomputes configured indicators of stored candles
+package indicator
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/indicators"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// batchSize number of values stored in one transaction
+const batchSize = 1000
+
+type seriesKey struct {
+	pair string
+	bar  string
+}
+
+type computed struct {
+	spec      indicators.Spec
+	indicator indicators.Indicator[[]price.Price]
+	stored    time.Time // values up to this timestamp are stored already
+}
+
+// series keeps indicators fed with every closed candle of the pair and bar since the first one
+type series struct {
+	first      time.Time
+	last       time.Time
+	indicators []*computed
+}
+
+// Job incrementally computes indicators of closed candles. Streaming indicators are kept
+// in memory between runs, after a restart they are replayed from the first candle and only
+// values newer than the stored ones are written, so results equal a full recomputation.
+type Job struct {
+	candleRepository    store.CandleStore
+	indicatorRepository store.IndicatorStore
+	specs               []indicators.Spec
+	log                 *log.Logger
+	now                 func() time.Time
+
+	mu     sync.Mutex
+	series map[seriesKey]*series
+}
+
+func NewJob(
+	candleRepository store.CandleStore,
+	indicatorRepository store.IndicatorStore,
+	specs []indicators.Spec,
+	log *log.Logger,
+) *Job {
+	return &Job{
+		candleRepository:    candleRepository,
+		indicatorRepository: indicatorRepository,
+		specs:               specs,
+		log:                 log,
+		now:                 time.Now,
+		series:              make(map[seriesKey]*series),
+	}
+}
+
+// Names names of computed indicators
+func (j *Job) Names() []string {
+	names := make([]string, 0, len(j.specs))
+	for _, spec := range j.specs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// Run computes indicators of new closed candles of the pairs
+func (j *Job) Run(ctx context.Context, pairs []string, bar string) {
+	for _, pair := range pairs {
+		stored, err := j.RunSeries(ctx, pair, bar)
+		if err != nil {
+			j.log.Errorf("indicators of %s %s failed: %v", pair, bar, err)
+			continue
+		}
+		j.log.Infof("stored %d indicator values of %s %s", stored, pair, bar)
+	}
+}
+
+// RunSeries computes indicators of new closed candles of the pair and bar, returns the number of stored values
+func (j *Job) RunSeries(ctx context.Context, pair, bar string) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key := seriesKey{pair, bar}
+	stored, err := j.runSeries(ctx, key)
+	if err != nil {
+		// the state can be ahead of stored values, it is rebuilt on the next run
+		delete(j.series, key)
+	}
+	return stored, err
+}
+
+func (j *Job) runSeries(ctx context.Context, key seriesKey) (int, error) {
+	first, err := j.candleRepository.FetchPage(store.CandleQuery{Pair: key.pair, Bar: key.bar}, "", 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(first.Candles) == 0 {
+		return 0, nil
+	}
+
+	s := j.series[key]
+	if s == nil || !s.first.Equal(first.Candles[0].Timestamp) {
+		// older candles were loaded after the indicators were computed, every value changes
+		rebuild := s != nil
+		if s, err = j.newSeries(key, first.Candles[0].Timestamp, rebuild); err != nil {
+			return 0, err
+		}
+		j.series[key] = s
+	}
+
+	query := store.CandleQuery{Pair: key.pair, Bar: key.bar}
+	if cutOff, err := model.ClosedBefore(key.bar, j.now()); err == nil {
+		query.To = cutOff
+	}
+	if !s.last.IsZero() {
+		query.From = s.last.Add(time.Microsecond)
+	}
+
+	stored := 0
+	var batch []model.IndicatorValue
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := j.indicatorRepository.InsertIndicatorValues(batch); err != nil {
+			return err
+		}
+		stored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = j.candleRepository.Iterate(ctx, query, func(c model.Candle) error {
+		for _, ind := range s.indicators {
+			values, ok := ind.indicator.Update(c)
+			if !ok || !c.Timestamp.After(ind.stored) {
+				continue
+			}
+			for i, component := range ind.spec.Components() {
+				batch = append(batch, model.IndicatorValue{
+					Pair:      c.Pair,
+					Bar:       c.Bar,
+					Timestamp: c.Timestamp,
+					Indicator: ind.spec.Name,
+					Component: component,
+					Value:     values[i].Price,
+				})
+			}
+		}
+		s.last = c.Timestamp
+
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return stored, err
+	}
+
+	return stored, flush()
+}
+
+func (j *Job) newSeries(key seriesKey, first time.Time, rebuild bool) (*series, error) {
+	s := &series{first: first}
+	for _, spec := range j.specs {
+		ind := &computed{spec: spec, indicator: spec.New()}
+		if !rebuild {
+			stored, err := j.indicatorRepository.LastIndicatorTimestamp(key.pair, key.bar, spec.Name)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return nil, fmt.Errorf("failed to fetch last value of %s: %w", spec.Name, err)
+			}
+			ind.stored = stored
+		}
+		s.indicators = append(s.indicators, ind)
+	}
+	return s, nil
+}
@@ -0,0 +1,135 @@
+package indicator
+
+import (
+	"context"
+	"cur/internal/indicators"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func hourly(from, count int) []model.Candle {
+	candles := make([]model.Candle, 0, count)
+	for i := from; i < from+count; i++ {
+		// zigzag to get both gains and losses
+		candles = append(candles, storetest.Candle("BTC-USDT", "1H", start.Add(time.Duration(i)*time.Hour), int64(100+i%7*3-i%3)))
+	}
+	return candles
+}
+
+func newTestJob(t *testing.T, storage *memory.Store, now time.Time) *Job {
+	specs, err := indicators.ParseSpecs("sma_5,macd_3_6_2,obv")
+	require.NoError(t, err)
+	job := NewJob(storage.Candle(), storage.Indicator(), specs, log.New())
+	job.now = func() time.Time { return now }
+	return job
+}
+
+// assertFullRecomputation compares stored values with indicators computed over all closed candles
+func assertFullRecomputation(t *testing.T, storage *memory.Store, closed int) {
+	t.Helper()
+	candles, err := storage.Candle().FetchRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	candles = candles[:closed]
+
+	rows, err := storage.Indicator().FetchIndicatorRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"}, []string{"sma_5", "macd_3_6_2", "obv"})
+	require.NoError(t, err)
+	values := make(map[time.Time]map[string]int64)
+	for _, row := range rows {
+		values[row.Candle.Timestamp] = row.Values
+	}
+
+	sma, _ := indicators.Sma(candles, 5)
+	macd, _ := indicators.Macd(candles, 3, 6, 2)
+	obv := indicators.Obv(candles)
+	for _, p := range sma {
+		assert.Equal(t, p.Value.Price, values[p.Timestamp]["sma_5"], "sma at %s", p.Timestamp)
+	}
+	for _, p := range macd {
+		assert.Equal(t, p.Value.Signal.Price, values[p.Timestamp]["macd_3_6_2.signal"], "macd at %s", p.Timestamp)
+		assert.Equal(t, p.Value.Histogram.Price, values[p.Timestamp]["macd_3_6_2.histogram"], "macd at %s", p.Timestamp)
+	}
+	for _, p := range obv {
+		assert.Equal(t, p.Value.Price, values[p.Timestamp]["obv"], "obv at %s", p.Timestamp)
+	}
+
+	// the open candle has no values
+	for _, row := range rows[closed:] {
+		assert.Empty(t, row.Values)
+	}
+}
+
+func TestJob_Incremental(t *testing.T) {
+	storage := memory.NewStore()
+	candles := hourly(0, 30)
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	// the candle 29 is open
+	job := newTestJob(t, storage, start.Add(29*time.Hour+time.Minute))
+	stored, err := job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	// sma from candle 4, macd from 6, obv from 0 of 29 closed candles
+	assert.Equal(t, 25+3*23+29, stored)
+	assertFullRecomputation(t, storage, 29)
+
+	stored, err = job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assert.Zero(t, stored)
+
+	more := hourly(30, 10)
+	require.NoError(t, storage.Candle().InsertCandles(&more))
+	job.now = func() time.Time { return start.Add(40 * time.Hour) }
+	stored, err = job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assert.Equal(t, 10*5, stored)
+	assertFullRecomputation(t, storage, 39)
+}
+
+func TestJob_ResumesAfterRestart(t *testing.T) {
+	storage := memory.NewStore()
+	candles := hourly(0, 30)
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+	now := start.Add(30 * time.Hour)
+
+	_, err := newTestJob(t, storage, now).RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+
+	more := hourly(30, 5)
+	require.NoError(t, storage.Candle().InsertCandles(&more))
+	stored, err := newTestJob(t, storage, now.Add(5*time.Hour)).RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assert.Equal(t, 5*5, stored, "only values of new candles are written")
+	assertFullRecomputation(t, storage, 34)
+}
+
+func TestJob_RebuildsWhenOlderCandlesAppear(t *testing.T) {
+	storage := memory.NewStore()
+	candles := hourly(10, 20)
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+	job := newTestJob(t, storage, start.Add(30*time.Hour))
+
+	_, err := job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+
+	older := hourly(0, 10)
+	require.NoError(t, storage.Candle().InsertCandles(&older))
+	_, err = job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assertFullRecomputation(t, storage, 29)
+}
+
+func TestJob_NoCandles(t *testing.T) {
+	job := newTestJob(t, memory.NewStore(), start)
+	stored, err := job.RunSeries(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assert.Zero(t, stored)
+}
@@ -8,12 +8,15 @@ import (
 	"cur/internal/config/okxConfig"
 	"cur/internal/helper/price"
 	"cur/internal/infrastructure/kafka"
+	"cur/internal/infrastructure/metrics"
+	"cur/internal/infrastructure/tracing"
 	"cur/internal/model"
 	"cur/internal/service/okx/request"
 	"cur/internal/service/okx/response"
 	"cur/internal/store"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
@@ -22,6 +25,7 @@ import (
 
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -30,17 +34,41 @@ const (
 	Limit                = 100
 )
 
+// httpClient counts and traces REST requests to OKX
+var httpClient = &http.Client{Transport: tracing.Transport{Next: metrics.OkxTransport{}}}
+
+// TradeHandler is called for each trade received from the websocket, it must not block
+type TradeHandler func(trade model.Trade)
+
+// CandleFilter returns candles of the batch which may be stored, it takes care of the rest
+type CandleFilter func(candles []model.Candle) []model.Candle
+
+// TradeFilter reports whether the trade is passed to trade handlers, it must not block
+type TradeFilter func(trade model.Trade) bool
+
+// TickerHandler is called for each ticker received from the websocket, it must not block
+type TickerHandler func(ticker model.Ticker)
+
 type OkxService struct {
-	currencyRepository *store.CurrencyRepository
-	candleRepository   *store.CandleRepository
+	currencyRepository store.CurrencyStore
+	candleRepository   store.CandleStore
 	okxConfig          *okxConfig.OkxApiConfig
 	kafkaConfig        *kafkaConfig.KafkaConfig
 	log                *log.Logger
+	tradeHandlers      []TradeHandler
+	tickerHandlers     []TickerHandler
+	candleFilter       CandleFilter
+	tradeFilter        TradeFilter
+	producer           kafka.Producer
+
+	wsMu        sync.Mutex
+	wsConnected bool
+	wsSince     time.Time
 }
 
 func NewOkxService(
-	currencyRepository *store.CurrencyRepository,
-	candleRepository *store.CandleRepository,
+	currencyRepository store.CurrencyStore,
+	candleRepository store.CandleStore,
 	config *okxConfig.OkxApiConfig,
 	kafkaConf *kafkaConfig.KafkaConfig,
 	log *log.Logger,
@@ -58,26 +86,74 @@ func (okx *OkxService) SetConfig(okxConfig *okxConfig.OkxApiConfig) {
 	okx.okxConfig = okxConfig
 }
 
-func (okx *OkxService) UpdateCurrencies() error {
-	data, err := fetchCurrencies(okx.okxConfig)
-	if err != nil {
-		return err
+// SetProducer sets the producer trade messages are published by, FetchTrades makes one of the kafka config without it
+func (okx *OkxService) SetProducer(producer kafka.Producer) {
+	okx.producer = producer
+}
+
+// WebsocketState reports whether the trade websocket is connected and subscribed and since when, since is zero before the first attempt
+func (okx *OkxService) WebsocketState() (bool, time.Time) {
+	okx.wsMu.Lock()
+	defer okx.wsMu.Unlock()
+	return okx.wsConnected, okx.wsSince
+}
+
+func (okx *OkxService) setWebsocketState(connected bool) {
+	okx.wsMu.Lock()
+	defer okx.wsMu.Unlock()
+	if okx.wsConnected != connected || okx.wsSince.IsZero() {
+		okx.wsConnected = connected
+		okx.wsSince = time.Now()
+	}
+}
+
+// OnTrade adds handler for trades received by FetchTrades, must be called before FetchTrades
+func (okx *OkxService) OnTrade(handler TradeHandler) {
+	okx.tradeHandlers = append(okx.tradeHandlers, handler)
+}
+
+// OnTicker adds handler for tickers, FetchTrades subscribes to the tickers channel only if there are handlers
+func (okx *OkxService) OnTicker(handler TickerHandler) {
+	okx.tickerHandlers = append(okx.tickerHandlers, handler)
+}
+
+// SetFilters sets filters of fetched candles and received trades, nil filters pass everything
+func (okx *OkxService) SetFilters(candles CandleFilter, trades TradeFilter) {
+	okx.candleFilter = candles
+	okx.tradeFilter = trades
+}
+
+// Pairs returns configured pairs
+func (okx *OkxService) Pairs() []string {
+	pairs := make([]string, 0, len(okx.okxConfig.Currencies))
+	for _, cur2 := range okx.okxConfig.Currencies {
+		pairs = append(pairs, cur2+"-"+okx.okxConfig.BaseCurrency)
 	}
-	return okx.currencyRepository.InsertOrUpdateCurrencies(data)
+	return pairs
 }
 
-func fetchCurrencies(okxConfig *okxConfig.OkxApiConfig) (*[]response.CurrencyResponseData, error) {
+// UpdateCurrencies stores currencies of the exchange, returns how many there are
+func (okx *OkxService) UpdateCurrencies(ctx context.Context) (int, error) {
+	data, err := fetchCurrencies(ctx, okx.okxConfig)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.CurrenciesWithContext(ctx, okx.currencyRepository).InsertOrUpdateCurrencies(data); err != nil {
+		return 0, err
+	}
+	return len(*data), nil
+}
 
-	client := &http.Client{}
+func fetchCurrencies(ctx context.Context, okxConfig *okxConfig.OkxApiConfig) (*[]response.CurrencyResponseData, error) {
 
-	req, err := http.NewRequest("GET", okxConfig.ApiUri+okxConfig.CurrenciesPath, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", okxConfig.ApiUri+okxConfig.CurrenciesPath, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create request: %v", err)
 	}
 
 	req = getAuthHeaders(req, okxConfig, okxConfig.CurrenciesPath)
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 
 	if err != nil {
 		return nil, err
@@ -104,38 +180,52 @@ func fetchCurrencies(okxConfig *okxConfig.OkxApiConfig) (*[]response.CurrencyRes
 	return &currencyResponse.Data, nil
 }
 
-func (okx *OkxService) UpdateCandles() {
+// UpdateCandles fetches candles newer than stored ones, it stops when ctx is done.
+// It returns how many candles are stored and errors of pairs, a failed pair doesn't stop others.
+func (okx *OkxService) UpdateCandles(ctx context.Context) (int, error) {
+	candleRepository := store.CandlesWithContext(ctx, okx.candleRepository)
+	stored := 0
+	var errs []error
 	for _, cur2 := range okx.okxConfig.Currencies {
 		pair := cur2 + "-" + okx.okxConfig.BaseCurrency
 
-		for {
-			before := okx.getLastTsForPair(pair)
-			candles, err := okx.fetchCandles(pair, before, "")
+		before := getLastTsForPair(candleRepository, pair, okx.okxConfig.CandlesBar)
+		for ctx.Err() == nil {
+			candles, err := okx.fetchCandles(ctx, pair, before, "")
 
 			if err != nil {
 				log.Error(err)
+				errs = append(errs, fmt.Errorf("%s: %w", pair, err))
 				break
 			}
 			if len(candles) == 0 {
 				break
 			}
 
-			err = okx.candleRepository.InsertCandles(&candles)
+			inserted, err := okx.insertCandles(candleRepository, candles)
 			if err != nil {
 				log.Error(err)
+				errs = append(errs, fmt.Errorf("%s: %w", pair, err))
 			}
+			stored += inserted
+
+			// filtered candles aren't stored, the next chunk goes after the fetched ones
+			before = laterTs(getLastTsForPair(candleRepository, pair, okx.okxConfig.CandlesBar), candles)
 		}
 	}
+	return stored, errors.Join(errs...)
 }
 
-func (okx *OkxService) UpdateHistoricalCandles() {
+// UpdateHistoricalCandles fetches candles older than stored ones, it stops when ctx is done
+func (okx *OkxService) UpdateHistoricalCandles(ctx context.Context) {
+	candleRepository := store.CandlesWithContext(ctx, okx.candleRepository)
 	for _, cur2 := range okx.okxConfig.Currencies {
 		pair := cur2 + "-" + okx.okxConfig.BaseCurrency
-		minAfter := okx.getLastTsForPair(pair)
+		minAfter := getLastTsForPair(candleRepository, pair, okx.okxConfig.CandlesBar)
 		after := strconv.FormatInt(time.Now().UnixMilli(), 10)
-		for {
+		for ctx.Err() == nil {
 			log.Infof("fetching chunk candles for pair %s, earlier than %s\n", pair, after)
-			candles, err := okx.fetchCandles(pair, "", after)
+			candles, err := okx.fetchCandles(ctx, pair, "", after)
 
 			if err != nil {
 				log.Error(err)
@@ -145,13 +235,13 @@ func (okx *OkxService) UpdateHistoricalCandles() {
 				break
 			}
 
-			err = okx.candleRepository.InsertCandles(&candles)
+			_, err = okx.insertCandles(candleRepository, candles)
 			if err != nil {
 				log.Error(err)
 				break
 			}
 
-			after = okx.getFirstTsForPair(pair)
+			after = earlierTs(getFirstTsForPair(candleRepository, pair, okx.okxConfig.CandlesBar), candles)
 			if after <= minAfter {
 				break
 			}
@@ -159,25 +249,54 @@ func (okx *OkxService) UpdateHistoricalCandles() {
 	}
 }
 
-// getLastTsForPair getting max timestamp for pair
-func (okx *OkxService) getLastTsForPair(pair string) string {
-	lastTimestamp, err := okx.candleRepository.GetLastTsForPair(pair)
+// insertCandles stores candles passed by the candle filter, returns how many are stored
+func (okx *OkxService) insertCandles(candleRepository store.CandleStore, candles []model.Candle) (int, error) {
+	if okx.candleFilter != nil {
+		candles = okx.candleFilter(candles)
+	}
+	if err := candleRepository.InsertCandles(&candles); err != nil {
+		return 0, err
+	}
+	return len(candles), nil
+}
+
+// laterTs returns the later of the unix ms timestamp and the latest candle
+func laterTs(ts string, candles []model.Candle) string {
+	latest, _ := strconv.ParseInt(ts, 10, 64)
+	for _, candle := range candles {
+		latest = max(latest, candle.Timestamp.UnixMilli())
+	}
+	return strconv.FormatInt(latest, 10)
+}
+
+// earlierTs returns the earlier of the unix ms timestamp and the earliest candle
+func earlierTs(ts string, candles []model.Candle) string {
+	earliest, _ := strconv.ParseInt(ts, 10, 64)
+	for _, candle := range candles {
+		earliest = min(earliest, candle.Timestamp.UnixMilli())
+	}
+	return strconv.FormatInt(earliest, 10)
+}
+
+// getLastTsForPair getting max timestamp for pair and bar
+func getLastTsForPair(candleRepository store.CandleStore, pair, bar string) string {
+	lastTimestamp, err := candleRepository.GetLastTsForPair(pair, bar)
 	if err != nil {
 		return BeforeCandles
 	}
 	return lastTimestamp
 }
 
-// getFirstTsForPair getting min timestamp for pair
-func (okx *OkxService) getFirstTsForPair(pair string) string {
-	lastTimestamp, err := okx.candleRepository.GetFirstTsForPair(pair)
+// getFirstTsForPair getting min timestamp for pair and bar
+func getFirstTsForPair(candleRepository store.CandleStore, pair, bar string) string {
+	lastTimestamp, err := candleRepository.GetFirstTsForPair(pair, bar)
 	if err != nil {
 		return strconv.FormatInt(time.Now().UnixMilli(), 10)
 	}
 	return lastTimestamp
 }
 
-func (okx *OkxService) fetchCandles(pair, before, after string) ([]model.Candle, error) {
+func (okx *OkxService) fetchCandles(ctx context.Context, pair, before, after string) ([]model.Candle, error) {
 	url := fmt.Sprintf("%s?instId=%s&bar=%s&limit=%s", okx.okxConfig.ApiUri+
 		okx.okxConfig.CandlesPath, pair, okx.okxConfig.CandlesBar, strconv.Itoa(Limit))
 
@@ -189,11 +308,10 @@ func (okx *OkxService) fetchCandles(pair, before, after string) ([]model.Candle,
 		url += "&after=" + after
 	}
 
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Add("Accept", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -229,82 +347,96 @@ func (okx *OkxService) fetchCandles(pair, before, after string) ([]model.Candle,
 			Low:       low,
 			Close:     closePrice,
 			Volume:    volume,
+			Bar:       okx.okxConfig.CandlesBar,
 		})
 	}
 
 	return candles, nil
 }
 
-// FetchTrades TODO needs to do something with results
+// FetchTrades receives trades from the websocket reconnecting until ctx is done, it returns after the connection is closed
 func (okx *OkxService) FetchTrades(ctx context.Context) {
 
 	var reconnectInterval time.Duration = 1 * time.Second
-	kafkaProducer, err := kafka.NewKafkaAsyncProducer(okx.kafkaConfig)
-
-	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
+	producer := okx.producer
+	if producer == nil {
+		kafkaProducer, err := kafka.NewKafkaAsyncProducer(okx.kafkaConfig)
+		if err != nil {
+			log.Errorf("Failed to create Kafka producer, trades won't be published: %v", err)
+		} else {
+			defer kafkaProducer.Close()
+			producer = kafkaProducer
+		}
 	}
-	defer kafkaProducer.Close()
+	defer okx.setWebsocketState(false)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			metrics.WebsocketReconnect()
+		}
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, okx.okxConfig.WssEndpoint, nil)
+		if err == nil {
+			log.Println("Connected to WebSocket")
+			reconnectInterval = 1 * time.Second
+			okx.receiveTrades(ctx, conn, producer)
+		} else if ctx.Err() == nil {
+			okx.setWebsocketState(false)
+			log.Printf("Failed to connect to WebSocket: %v", err)
+		}
 
-	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Stopping FetchTrades...")
 			return
-		default:
+		case <-time.After(reconnectInterval):
+		}
+		if err != nil {
+			reconnectInterval = min(reconnectInterval*2, 60*time.Second)
+		}
+	}
+}
 
-			conn, _, err := websocket.DefaultDialer.Dial(okx.okxConfig.WssEndpoint, nil)
-			if err != nil {
-				log.Printf("Failed to connect to WebSocket: %v", err)
-				time.Sleep(reconnectInterval)
-				reconnectInterval *= 2
-				if reconnectInterval > 60*time.Second {
-					reconnectInterval = 60 * time.Second
-				}
-				continue
-			}
-			log.Println("Connected to WebSocket")
-			reconnectInterval = 1 * time.Second
+// receiveTrades subscribes to trades and listens for them until the connection breaks or ctx is done, it closes the connection
+func (okx *OkxService) receiveTrades(ctx context.Context, conn *websocket.Conn, producer kafka.Producer) {
+	defer conn.Close()
 
-			// Defer connection close
-			defer conn.Close()
+	if err := okx.subscribeToTrades(conn); err != nil {
+		log.Printf("Failed to subscribe: %v", err)
+		return
+	}
+	okx.setWebsocketState(true)
 
-			err = okx.subscribeToTrades(conn)
-			if err != nil {
-				log.Printf("Failed to subscribe: %v", err)
-				continue
-			}
+	// Listen for messages
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer okx.setWebsocketState(false)
+		_ = okx.listenForTrades(ctx, conn, producer)
+	}()
 
-			// Listen for messages
-			done := make(chan struct{})
-			go func() {
-				defer close(done)
-				if err := listenForTrades(ctx, conn, kafkaProducer); err != nil {
-					return
-				}
-			}()
-
-			ticker := time.NewTicker(10 * time.Second)
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-ctx.Done():
-					log.Println("Stopping WebSocket connection...")
-					_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-					return
-				case <-ticker.C:
-					// Send ping to keep connection alive
-					err := conn.WriteMessage(websocket.PingMessage, []byte{})
-					if err != nil {
-						log.Printf("Ping error: %v", err)
-						return
-					}
-				case <-done:
-					log.Println("Connection closed, attempting to reconnect...")
-					break
-				}
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Stopping WebSocket connection...")
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			// the listener blocked in reading stops when the connection is closed
+			_ = conn.Close()
+			<-done
+			return
+		case <-ticker.C:
+			// Send ping to keep connection alive
+			if err := conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+				log.Printf("Ping error: %v", err)
+				_ = conn.Close()
+				<-done
+				return
 			}
+		case <-done:
+			log.Println("Connection closed, attempting to reconnect...")
+			return
 		}
 	}
 }
@@ -316,6 +448,9 @@ func (okx *OkxService) subscribeToTrades(conn *websocket.Conn) error {
 	for _, v := range okx.okxConfig.Currencies {
 		requestArg := request.Arg{Channel: "trades", InstId: fmt.Sprintf("%s-%s", v, okx.okxConfig.BaseCurrency)}
 		requestArgs = append(requestArgs, requestArg)
+		if len(okx.tickerHandlers) > 0 {
+			requestArgs = append(requestArgs, request.Arg{Channel: "tickers", InstId: requestArg.InstId})
+		}
 	}
 
 	subscription := request.SubscriptionMessage{
@@ -333,7 +468,7 @@ func (okx *OkxService) subscribeToTrades(conn *websocket.Conn) error {
 }
 
 // listenForTrades Listen for trades in real time
-func listenForTrades(ctx context.Context, conn *websocket.Conn, kafkaProducer *kafka.KafkaAsyncProducer) error {
+func (okx *OkxService) listenForTrades(ctx context.Context, conn *websocket.Conn, producer kafka.Producer) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -350,11 +485,20 @@ func listenForTrades(ctx context.Context, conn *websocket.Conn, kafkaProducer *k
 			var trade response.TradeMessage
 			err = json.Unmarshal(message, &trade)
 			if err != nil {
+				metrics.WebsocketMessage("invalid")
 				log.Printf("JSON unmarshal error: %v", err)
 				continue
 			}
+			metrics.WebsocketMessage(trade.Arg.Channel)
+
+			if trade.Arg.Channel == "tickers" {
+				okx.handleTickers(message)
+				continue
+			}
 
-			kafkaProducer.SendMessage("trades", string(message))
+			if producer != nil {
+				producer.SendMessage(ctx, "trades", string(message))
+			}
 
 			for _, data := range trade.Data {
 				fmt.Printf("[%s] Trade ID: %s | Price: %s | Size: %s | Side: %s | Time: %s\n",
@@ -366,8 +510,100 @@ func listenForTrades(ctx context.Context, conn *websocket.Conn, kafkaProducer *k
 					data.Time,
 				)
 			}
+
+			okx.handleTrades(trade)
+		}
+	}
+}
+
+// handleTrades passes trades of the message to trade handlers
+func (okx *OkxService) handleTrades(message response.TradeMessage) {
+	if len(okx.tradeHandlers) == 0 || message.Arg.Channel != "trades" {
+		return
+	}
+
+	for _, data := range message.Data {
+		trade, err := toTrade(message.Arg.InstId, data.TradeID, data.Price, data.Size, data.Side, data.Time)
+		if err != nil {
+			log.Printf("Skipping malformed trade %s: %v", data.TradeID, err)
+			continue
 		}
+		if okx.tradeFilter != nil && !okx.tradeFilter(trade) {
+			continue
+		}
+		for _, handler := range okx.tradeHandlers {
+			handler(trade)
+		}
+	}
+}
+
+// handleTickers passes tickers of the message to ticker handlers
+func (okx *OkxService) handleTickers(message []byte) {
+	var tickers response.TickerMessage
+	if err := json.Unmarshal(message, &tickers); err != nil {
+		log.Printf("JSON unmarshal error: %v", err)
+		return
+	}
+
+	for _, data := range tickers.Data {
+		ticker, err := toTicker(data)
+		if err != nil {
+			log.Printf("Skipping malformed ticker %s: %v", data.InstId, err)
+			continue
+		}
+		for _, handler := range okx.tickerHandlers {
+			handler(ticker)
+		}
+	}
+}
+
+func toTicker(data response.TickerResponseData) (model.Ticker, error) {
+	var values [5]int64
+	for i, v := range []string{data.Last, data.Open24H, data.High24H, data.Low24H, data.Vol24H} {
+		parsed, err := price.ParsePrice(v)
+		if err != nil {
+			return model.Ticker{}, err
+		}
+		values[i] = parsed
 	}
+	timestamp, err := strconv.ParseInt(data.Timestamp, 10, 64)
+	if err != nil {
+		return model.Ticker{}, fmt.Errorf("timestamp: %w", err)
+	}
+
+	return model.Ticker{
+		Pair:      data.InstId,
+		Last:      values[0],
+		Open24h:   values[1],
+		High24h:   values[2],
+		Low24h:    values[3],
+		Volume24h: values[4],
+		Timestamp: time.UnixMilli(timestamp).In(time.UTC),
+	}, nil
+}
+
+func toTrade(pair, tradeId, px, sz, side, ts string) (model.Trade, error) {
+	tradePrice, err := price.ParsePrice(px)
+	if err != nil {
+		return model.Trade{}, fmt.Errorf("price: %w", err)
+	}
+	size, err := price.ParsePrice(sz)
+	if err != nil {
+		return model.Trade{}, fmt.Errorf("size: %w", err)
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return model.Trade{}, fmt.Errorf("timestamp: %w", err)
+	}
+
+	return model.Trade{
+		Pair:      pair,
+		TradeId:   tradeId,
+		Price:     tradePrice,
+		Size:      size,
+		Side:      side,
+		Timestamp: time.UnixMilli(timestamp).In(time.UTC),
+	}, nil
 }
 
 // createSignature create signature for okx request
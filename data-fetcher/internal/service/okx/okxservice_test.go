@@ -1,46 +1,38 @@
 package okx
 
 import (
-	"cur/internal/config/dbConfig"
+	"context"
 	"cur/internal/config/kafkaConfig"
 	"cur/internal/config/okxConfig"
-	"cur/internal/infrastructure/dbConnection"
+	"cur/internal/infrastructure/tracing"
 	"cur/internal/model"
+	"cur/internal/service/okx/okxtest"
 	"cur/internal/service/okx/response"
-	"cur/internal/store"
-	"database/sql"
+	"cur/internal/store/memory"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	db           *sql.DB
-	storage      *store.Store
-	currencyRep  *store.CurrencyRepository
+	storage      *memory.Store
+	currencyRep  *memory.CurrencyRepository
 	okxService   *OkxService
 	okxApiConfig *okxConfig.OkxApiConfig
 )
 
-func makeTestStore() *store.Store {
-	dbConfig.LoadEnv()
-	conf, err := dbConfig.GetDbConfig()
-
-	if err != nil {
-		panic(err)
-	}
-	db, _ = dbConnection.GetDbConnection(conf)
-	return store.NewStore(db)
-}
-
 func TestMain(m *testing.M) {
-	storage = makeTestStore()
+	storage = memory.NewStore()
 	currencyRep = storage.Currency()
 
 	okxApiConfig = &okxConfig.OkxApiConfig{
@@ -59,9 +51,6 @@ func TestMain(m *testing.M) {
 	// Выполнение тестов
 	exitVal := m.Run()
 
-	// Очистка ресурсов, если требуется
-	storage.CloseConnection()
-
 	// Завершение тестов с корректным кодом выхода
 	os.Exit(exitVal)
 }
@@ -204,12 +193,11 @@ func TestOkxService_UpdateCurrencies(t *testing.T) {
 		mockServer := startMockServer(testCase.response)
 		defer mockServer.Close()
 
-		okxService.SetConfig(&okxConfig.OkxApiConfig{
-			ApiUri:         mockServer.URL,
-			CurrenciesPath: "/currencies",
-		})
+		okxService.SetConfig(mockServer.Config("USDT"))
 
-		err := okxService.UpdateCurrencies()
+		stored, err := okxService.UpdateCurrencies(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, len(testCase.response.Data), stored)
 
 		currencies, err := currencyRep.FetchAll()
 
@@ -224,19 +212,15 @@ func TestOkxService_UpdateCurrencies(t *testing.T) {
 		}
 
 		if testCase.params.truncateAfterTest {
-			err := storage.TruncateTables([]string{"currencies"})
-			if err != nil {
-				fmt.Println(err)
-			}
+			currencyRep.Truncate()
 		}
 	}
 }
 
-func startMockServer(response response.CurrencyResponse) *httptest.Server {
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(response)
-	}))
+func startMockServer(response response.CurrencyResponse) *okxtest.Server {
+	server := okxtest.NewServer()
+	server.SetCurrencies(response.Data...)
+	return server
 }
 
 func TestOkxService_FetchCurrenciesFails(t *testing.T) {
@@ -248,7 +232,7 @@ func TestOkxService_FetchCurrenciesFails(t *testing.T) {
 
 	defer mockServer.Close()
 
-	_, err := fetchCurrencies(okxApiConfig)
+	_, err := fetchCurrencies(context.Background(), okxApiConfig)
 	assert.Error(t, err)
 }
 
@@ -361,7 +345,7 @@ func TestOkxService_UpdateCandles(t *testing.T) {
 				Currencies:     []string{"BTC", "ETH"},
 			})
 
-			okxService.UpdateCandles()
+			okxService.UpdateCandles(context.Background())
 
 			candles, err := storage.Candle().FetchAll()
 
@@ -369,11 +353,171 @@ func TestOkxService_UpdateCandles(t *testing.T) {
 			assert.Equal(t, testCase.expected.BdQty, len(candles))
 
 			if testCase.params.truncateAfterTest {
-				err := storage.TruncateTables([]string{"candles"})
-				if err != nil {
-					fmt.Println(err)
-				}
+				storage.Candle().Truncate()
 			}
 		})
 	}
 }
+
+func TestOkxService_CandleFilter(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		server.AddCandles("BTC-USDT", okxtest.DefaultBar, okxtest.NewCandle(start.Add(time.Duration(i)*time.Hour), "1", "2", "0.5", "1.5", "10"))
+	}
+
+	storage := memory.NewStore()
+	service := NewOkxService(storage.Currency(), storage.Candle(), server.Config("USDT", "BTC"), &kafkaConfig.KafkaConfig{}, log.New())
+	var filtered []model.Candle
+	service.SetFilters(func(candles []model.Candle) []model.Candle {
+		var kept []model.Candle
+		for _, candle := range candles {
+			if candle.Timestamp.Equal(start.Add(4 * time.Hour)) {
+				filtered = append(filtered, candle)
+				continue
+			}
+			kept = append(kept, candle)
+		}
+		return kept
+	}, nil)
+
+	done := make(chan struct{})
+	var stored int
+	go func() {
+		defer close(done)
+		stored, _ = service.UpdateCandles(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the filtered newest candle is fetched again and again")
+	}
+
+	candles, err := storage.Candle().FetchAll()
+	assert.NoError(t, err)
+	assert.Len(t, candles, 4)
+	assert.Equal(t, 4, stored)
+	assert.Len(t, filtered, 1)
+}
+
+func TestOkxService_TradeFilter(t *testing.T) {
+	service := NewOkxService(nil, nil, &okxConfig.OkxApiConfig{}, &kafkaConfig.KafkaConfig{}, log.New())
+	var received []string
+	service.OnTrade(func(trade model.Trade) {
+		received = append(received, trade.TradeId)
+	})
+	service.SetFilters(nil, func(trade model.Trade) bool {
+		return trade.TradeId != "2"
+	})
+
+	var message response.TradeMessage
+	err := json.Unmarshal([]byte(`{"arg": {"channel": "trades", "instId": "BTC-USDT"}, "data": [
+		{"tradeId": "1", "px": "100", "sz": "1", "side": "buy", "ts": "1738368000000"},
+		{"tradeId": "2", "px": "1", "sz": "1", "side": "buy", "ts": "1738368000001"},
+		{"tradeId": "3", "px": "100", "sz": "1", "side": "sell", "ts": "1738368000002"}]}`), &message)
+	assert.NoError(t, err)
+	service.handleTrades(message)
+
+	assert.Equal(t, []string{"1", "3"}, received)
+}
+
+func TestOkxService_UpdateCandlesTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install("test", 1, sdktrace.NewSimpleSpanProcessor(exporter))
+	defer provider.Shutdown(context.Background())
+
+	server := okxtest.NewServer()
+	defer server.Close()
+	server.AddCandles("BTC-USDT", okxtest.DefaultBar, okxtest.NewCandle(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), "1", "2", "0.5", "1.5", "10"))
+
+	storage := memory.NewStore()
+	service := NewOkxService(storage.Currency(), storage.Candle(), server.Config("USDT", "BTC"), &kafkaConfig.KafkaConfig{}, log.New())
+
+	ctx, parent := tracing.Start(context.Background(), "cron update_candles")
+	service.UpdateCandles(ctx)
+	parent.End()
+
+	var rest []tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.SpanKind == trace.SpanKindClient {
+			rest = append(rest, span)
+		}
+	}
+	if assert.NotEmpty(t, rest) {
+		assert.Equal(t, "GET "+server.Config("USDT", "BTC").CandlesPath, rest[0].Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), rest[0].Parent.SpanID())
+		assert.Equal(t, parent.SpanContext().TraceID(), rest[0].SpanContext.TraceID())
+	}
+}
+
+type nopProducer struct{}
+
+func (nopProducer) SendMessage(ctx context.Context, topic, message string)           {}
+func (nopProducer) SendKeyedMessage(ctx context.Context, topic, key, message string) {}
+
+func TestOkxService_FetchTradesReconnectsAndStops(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+
+	service := NewOkxService(nil, nil, server.Config("USDT", "BTC"), &kafkaConfig.KafkaConfig{}, log.New())
+	service.SetProducer(nopProducer{})
+	trades := make(chan model.Trade, 10)
+	service.OnTrade(func(trade model.Trade) { trades <- trade })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.FetchTrades(ctx)
+	}()
+
+	assert.True(t, server.WaitForSubscription("trades", "BTC-USDT", 5*time.Second))
+	assert.Equal(t, 1, server.DropConnections())
+
+	// the dropped connection is closed and a new one subscribes again
+	assert.Eventually(t, func() bool {
+		return server.PushTrades("BTC-USDT", okxtest.Trade{TradeId: "1", Price: "97000", Size: "1", Side: "buy", Time: time.Now()}) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	select {
+	case trade := <-trades:
+		assert.Equal(t, "1", trade.TradeId)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no trade after reconnect")
+	}
+	assert.Equal(t, 1, server.Connections())
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("FetchTrades didn't stop")
+	}
+	connected, _ := service.WebsocketState()
+	assert.False(t, connected)
+	assert.Eventually(t, func() bool { return server.Connections() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOkxService_UpdateHistoricalCandlesCancelled(t *testing.T) {
+	server := okxtest.NewServer()
+	defer server.Close()
+
+	start := time.Now().Add(-1000 * time.Hour).Truncate(time.Hour)
+	var candles []okxtest.Candle
+	for i := 0; i < 3*Limit; i++ {
+		candles = append(candles, okxtest.NewCandle(start.Add(time.Duration(i)*time.Hour), "1", "2", "0.5", "1.5", "10"))
+	}
+	server.AddCandles("BTC-USDT", okxtest.DefaultBar, candles...)
+
+	storage := memory.NewStore()
+	service := NewOkxService(storage.Currency(), storage.Candle(), server.Config("USDT", "BTC"), &kafkaConfig.KafkaConfig{}, log.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	storage.Candle().OnInsert(func(context.Context, []model.Candle) { cancel() })
+
+	service.UpdateHistoricalCandles(ctx)
+
+	stored, err := storage.Candle().FetchAll()
+	assert.NoError(t, err)
+	assert.Len(t, stored, Limit)
+}
@@ -0,0 +1,382 @@
+// Package okxtest provides a stateful fake of the OKX REST and websocket API
+// for integration tests of the okx service.
+package okxtest
+
+import (
+	"cur/internal/config/okxConfig"
+	"cur/internal/service/okx/response"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CurrenciesPath     = "/api/v5/asset/currencies"
+	CandlesPath        = "/api/v5/market/candles"
+	HistoryCandlesPath = "/api/v5/market/history-candles"
+	TickersPath        = "/api/v5/market/tickers"
+	InstrumentsPath    = "/api/v5/public/instruments"
+	WsPath             = "/ws/v5/public"
+
+	DefaultBar = "1m"
+
+	candlesMaxLimit        = 300
+	historyCandlesMaxLimit = 100
+)
+
+// Failure is an error response served instead of the regular one
+type Failure struct {
+	Status int
+	Code   string
+	Msg    string
+	// Body replaces the whole response body when set, e.g. for malformed JSON
+	Body string
+}
+
+var (
+	RateLimited   = Failure{Status: http.StatusTooManyRequests, Code: "50011", Msg: "Too Many Requests"}
+	InternalError = Failure{Status: http.StatusInternalServerError, Code: "50000", Msg: "Internal Server Error"}
+	MalformedBody = Failure{Status: http.StatusOK, Body: `{"code":"0","data":[`}
+)
+
+// RecordedRequest is a request received by the fake server
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Candle is a raw OKX candle row: ts, o, h, l, c, vol, volCcy, volCcyQuote, confirm
+type Candle []string
+
+// NewCandle makes a confirmed candle row
+func NewCandle(ts time.Time, open, high, low, closePrice, volume string) Candle {
+	return Candle{strconv.FormatInt(ts.UnixMilli(), 10), open, high, low, closePrice, volume, volume, volume, "1"}
+}
+
+func (c Candle) ts() int64 {
+	ts, _ := strconv.ParseInt(c[0], 10, 64)
+	return ts
+}
+
+type Server struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	requireAuth bool
+	currencies  []response.CurrencyResponseData
+	candles     map[string][]Candle // key is instId/bar, rows are sorted newest first
+	tickers     map[string]response.TickerResponseData
+	instruments []response.InstrumentResponseData
+	failures    map[string][]Failure
+	requests    []RecordedRequest
+
+	ws *wsHub
+}
+
+// NewServer starts a fake OKX server. Close it after the test.
+func NewServer() *Server {
+	s := &Server{
+		candles:  make(map[string][]Candle),
+		tickers:  make(map[string]response.TickerResponseData),
+		failures: make(map[string][]Failure),
+	}
+	s.ws = newWsHub()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(CurrenciesPath, s.handleCurrencies)
+	mux.HandleFunc(CandlesPath, s.handleCandles(candlesMaxLimit))
+	mux.HandleFunc(HistoryCandlesPath, s.handleCandles(historyCandlesMaxLimit))
+	mux.HandleFunc(TickersPath, s.handleTickers)
+	mux.HandleFunc(InstrumentsPath, s.handleInstruments)
+	mux.HandleFunc(WsPath, s.ws.handle)
+
+	s.server = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *Server) Close() {
+	s.ws.closeAll()
+	s.server.Close()
+}
+
+// URL base url of the REST api
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// WssEndpoint url of the public websocket endpoint
+func (s *Server) WssEndpoint() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http") + WsPath
+}
+
+// Config makes okx api config pointing at the fake server
+func (s *Server) Config(baseCurrency string, currencies ...string) *okxConfig.OkxApiConfig {
+	return &okxConfig.OkxApiConfig{
+		ApiKey:         "test-key",
+		Secret:         "test-secret",
+		PassPhrase:     "test-passphrase",
+		ApiUri:         s.URL(),
+		CandlesPath:    HistoryCandlesPath,
+		TickersPath:    TickersPath + "?instType=SPOT",
+		CurrenciesPath: CurrenciesPath,
+		BaseCurrency:   baseCurrency,
+		Currencies:     currencies,
+		CandlesBar:     DefaultBar,
+		WssEndpoint:    s.WssEndpoint(),
+	}
+}
+
+// RequireAuth makes private endpoints reject requests without OK-ACCESS-* headers
+func (s *Server) RequireAuth(require bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requireAuth = require
+}
+
+func (s *Server) SetCurrencies(currencies ...response.CurrencyResponseData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currencies = currencies
+}
+
+// AddCandles stores candles for the instrument, replacing rows with the same timestamp
+func (s *Server) AddCandles(instId, bar string, candles ...Candle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := candlesKey(instId, bar)
+	byTs := make(map[int64]Candle, len(s.candles[key])+len(candles))
+	for _, c := range s.candles[key] {
+		byTs[c.ts()] = c
+	}
+	for _, c := range candles {
+		byTs[c.ts()] = c
+	}
+
+	rows := make([]Candle, 0, len(byTs))
+	for _, c := range byTs {
+		rows = append(rows, c)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ts() > rows[j].ts() })
+
+	s.candles[key] = rows
+}
+
+func (s *Server) SetTicker(ticker response.TickerResponseData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickers[ticker.InstId] = ticker
+}
+
+func (s *Server) SetInstruments(instruments ...response.InstrumentResponseData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instruments = instruments
+}
+
+// FailNext makes the next len(failures) requests to the path fail in order
+func (s *Server) FailNext(path string, failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failures...)
+}
+
+// Requests returns received REST requests for the path, all requests if the path is empty
+func (s *Server) Requests(path string) []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []RecordedRequest
+	for _, r := range s.requests {
+		if path == "" || r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// begin records the request and serves an injected failure if there is one
+func (s *Server) begin(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.requests = append(s.requests, RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+	})
+	var failure *Failure
+	if queue := s.failures[r.URL.Path]; len(queue) > 0 {
+		failure = &queue[0]
+		s.failures[r.URL.Path] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if failure == nil {
+		return true
+	}
+
+	if failure.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(failure.Status)
+		_, _ = w.Write([]byte(failure.Body))
+		return false
+	}
+	writeEnvelope(w, failure.Status, failure.Code, failure.Msg, []struct{}{})
+	return false
+}
+
+func (s *Server) handleCurrencies(w http.ResponseWriter, r *http.Request) {
+	if !s.begin(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	requireAuth := s.requireAuth
+	data := append([]response.CurrencyResponseData{}, s.currencies...)
+	s.mu.Unlock()
+
+	if requireAuth && (r.Header.Get("OK-ACCESS-KEY") == "" || r.Header.Get("OK-ACCESS-SIGN") == "" ||
+		r.Header.Get("OK-ACCESS-TIMESTAMP") == "" || r.Header.Get("OK-ACCESS-PASSPHRASE") == "") {
+		writeEnvelope(w, http.StatusUnauthorized, "50103", "Request header OK-ACCESS-KEY can not be empty.", []struct{}{})
+		return
+	}
+
+	if ccy := r.URL.Query().Get("ccy"); ccy != "" {
+		filtered := data[:0]
+		for _, c := range data {
+			if c.Ccy == ccy {
+				filtered = append(filtered, c)
+			}
+		}
+		data = filtered
+	}
+
+	writeEnvelope(w, http.StatusOK, "0", "", data)
+}
+
+// handleCandles serves candles newest first. As in OKX, `after` returns records
+// earlier than the timestamp and `before` returns records newer than the timestamp.
+func (s *Server) handleCandles(maxLimit int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.begin(w, r) {
+			return
+		}
+
+		q := r.URL.Query()
+		instId := q.Get("instId")
+		if instId == "" {
+			writeEnvelope(w, http.StatusBadRequest, "51000", "Parameter instId error", []struct{}{})
+			return
+		}
+
+		bar := q.Get("bar")
+		if bar == "" {
+			bar = DefaultBar
+		}
+
+		limit := maxLimit
+		if l := q.Get("limit"); l != "" {
+			parsed, err := strconv.Atoi(l)
+			if err != nil || parsed <= 0 {
+				writeEnvelope(w, http.StatusBadRequest, "51000", "Parameter limit error", []struct{}{})
+				return
+			}
+			limit = min(parsed, maxLimit)
+		}
+
+		before, beforeErr := parseTs(q.Get("before"))
+		after, afterErr := parseTs(q.Get("after"))
+		if beforeErr != nil || afterErr != nil {
+			writeEnvelope(w, http.StatusBadRequest, "51000", "Parameter before or after error", []struct{}{})
+			return
+		}
+
+		s.mu.Lock()
+		rows := s.candles[candlesKey(instId, bar)]
+		data := make([][]string, 0, limit)
+		for _, c := range rows {
+			if before != 0 && c.ts() <= before {
+				break
+			}
+			if after != 0 && c.ts() >= after {
+				continue
+			}
+			data = append(data, append([]string{}, c...))
+			if len(data) == limit {
+				break
+			}
+		}
+		s.mu.Unlock()
+
+		writeEnvelope(w, http.StatusOK, "0", "", data)
+	}
+}
+
+func (s *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
+	if !s.begin(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	data := make([]response.TickerResponseData, 0, len(s.tickers))
+	for _, t := range s.tickers {
+		data = append(data, t)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(data, func(i, j int) bool { return data[i].InstId < data[j].InstId })
+	writeEnvelope(w, http.StatusOK, "0", "", data)
+}
+
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+	if !s.begin(w, r) {
+		return
+	}
+
+	instType := r.URL.Query().Get("instType")
+	if instType == "" {
+		writeEnvelope(w, http.StatusBadRequest, "51000", "Parameter instType error", []struct{}{})
+		return
+	}
+
+	s.mu.Lock()
+	data := make([]response.InstrumentResponseData, 0, len(s.instruments))
+	for _, i := range s.instruments {
+		if i.InstType == instType {
+			data = append(data, i)
+		}
+	}
+	s.mu.Unlock()
+
+	writeEnvelope(w, http.StatusOK, "0", "", data)
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, code, msg string, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data any    `json:"data"`
+	}{code, msg, data})
+}
+
+func parseTs(ts string) (int64, error) {
+	if ts == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(ts, 10, 64)
+}
+
+func candlesKey(instId, bar string) string {
+	return instId + "/" + bar
+}
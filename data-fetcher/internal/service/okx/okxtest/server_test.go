@@ -0,0 +1,170 @@
+package okxtest
+
+import (
+	"cur/internal/service/okx/request"
+	"cur/internal/service/okx/response"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getJson(t *testing.T, url string, target any) int {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(target))
+	return resp.StatusCode
+}
+
+func TestServer_CandlesPagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		s.AddCandles("BTC-USDT", "1H", NewCandle(start.Add(time.Duration(i)*time.Hour), "1", "2", "0.5", "1.5", "10"))
+	}
+
+	ts := func(hours int) string {
+		return strconv.FormatInt(start.Add(time.Duration(hours)*time.Hour).UnixMilli(), 10)
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "newest first", query: "&bar=1H&limit=2", expected: []string{ts(4), ts(3)}},
+		{name: "after returns older records", query: "&bar=1H&after=" + ts(3), expected: []string{ts(2), ts(1), ts(0)}},
+		{name: "before returns newer records", query: "&bar=1H&before=" + ts(2), expected: []string{ts(4), ts(3)}},
+		{name: "before and after", query: "&bar=1H&before=" + ts(0) + "&after=" + ts(3), expected: []string{ts(2), ts(1)}},
+		{name: "other bar is empty", query: "&bar=1D", expected: []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var resp response.CandlesResponse
+			status := getJson(t, s.URL()+HistoryCandlesPath+"?instId=BTC-USDT"+testCase.query, &resp)
+
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "0", resp.Code)
+			got := make([]string, 0, len(resp.Data))
+			for _, row := range resp.Data {
+				got = append(got, row[0])
+			}
+			assert.Equal(t, testCase.expected, got)
+		})
+	}
+}
+
+func TestServer_FailNext(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetCurrencies(response.CurrencyResponseData{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: true, CanWd: true})
+	s.FailNext(CurrenciesPath, RateLimited, MalformedBody)
+
+	var resp response.CurrencyResponse
+	assert.Equal(t, http.StatusTooManyRequests, getJson(t, s.URL()+CurrenciesPath, &resp))
+	assert.Equal(t, "50011", resp.Code)
+
+	httpResp, err := http.Get(s.URL() + CurrenciesPath)
+	require.NoError(t, err)
+	assert.Error(t, json.NewDecoder(httpResp.Body).Decode(&resp))
+	_ = httpResp.Body.Close()
+
+	resp = response.CurrencyResponse{}
+	assert.Equal(t, http.StatusOK, getJson(t, s.URL()+CurrenciesPath, &resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Len(t, s.Requests(CurrenciesPath), 3)
+}
+
+func TestServer_RequireAuth(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.RequireAuth(true)
+
+	var resp response.CurrencyResponse
+	assert.Equal(t, http.StatusUnauthorized, getJson(t, s.URL()+CurrenciesPath, &resp))
+	assert.Equal(t, "50103", resp.Code)
+}
+
+func TestServer_Instruments(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetInstruments(
+		response.InstrumentResponseData{InstType: "SPOT", InstId: "ETH-BTC", BaseCcy: "ETH", QuoteCcy: "BTC", State: "live"},
+		response.InstrumentResponseData{InstType: "SWAP", InstId: "BTC-USDT-SWAP", State: "live"},
+	)
+
+	var resp response.InstrumentsResponse
+	assert.Equal(t, http.StatusOK, getJson(t, s.URL()+InstrumentsPath+"?instType=SPOT", &resp))
+	require.Len(t, resp.Data, 1)
+	assert.Equal(t, "ETH-BTC", resp.Data[0].InstId)
+}
+
+func dialAndSubscribe(t *testing.T, s *Server, instId string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(s.WssEndpoint(), nil)
+	require.NoError(t, err)
+
+	msg, _ := json.Marshal(request.SubscriptionMessage{Op: "subscribe", Args: []request.Arg{{Channel: "trades", InstId: instId}}})
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, msg))
+
+	var event eventMessage
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, "subscribe", event.Event)
+
+	return conn
+}
+
+func TestServer_WebsocketTrades(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	tradeTime := time.UnixMilli(1738857600000)
+	s.QueueTrades("BTC-USDT", Trade{TradeId: "1", Price: "97000.1", Size: "0.5", Side: "buy", Time: tradeTime})
+
+	conn := dialAndSubscribe(t, s, "BTC-USDT")
+	defer conn.Close()
+
+	var trade response.TradeMessage
+	require.NoError(t, conn.ReadJSON(&trade))
+	assert.Equal(t, "BTC-USDT", trade.Arg.InstId)
+	require.Len(t, trade.Data, 1)
+	assert.Equal(t, "97000.1", trade.Data[0].Price)
+	assert.Equal(t, "1738857600000", trade.Data[0].Time)
+
+	assert.Equal(t, 1, s.PushTrades("BTC-USDT", Trade{TradeId: "2", Price: "97001", Size: "1", Side: "sell", Time: tradeTime}))
+	assert.Equal(t, 0, s.PushTrades("ETH-USDT", Trade{TradeId: "3", Price: "3000", Size: "1", Side: "sell", Time: tradeTime}))
+	require.NoError(t, conn.ReadJSON(&trade))
+	assert.Equal(t, "2", trade.Data[0].TradeID)
+
+	assert.Equal(t, 1, s.PushRaw("{not json"))
+	_, frame, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Error(t, json.Unmarshal(frame, &trade))
+
+	assert.Equal(t, 1, s.DropConnections())
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+}
+
+func TestServer_RejectConnections(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.RejectConnections(1)
+
+	_, resp, err := websocket.DefaultDialer.Dial(s.WssEndpoint(), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	conn := dialAndSubscribe(t, s, "ETH-USDT")
+	defer conn.Close()
+	assert.True(t, s.WaitForSubscription("trades", "ETH-USDT", time.Second))
+	assert.Equal(t, 1, s.Connections())
+}
@@ -0,0 +1,295 @@
+package okxtest
+
+import (
+	"cur/internal/service/okx/request"
+	"cur/internal/service/okx/response"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Trade is a scripted trade pushed to websocket subscribers
+type Trade struct {
+	TradeId string
+	Price   string
+	Size    string
+	Side    string
+	Time    time.Time
+}
+
+type tradeData struct {
+	InstId  string `json:"instId"`
+	TradeId string `json:"tradeId"`
+	Px      string `json:"px"`
+	Sz      string `json:"sz"`
+	Side    string `json:"side"`
+	Ts      string `json:"ts"`
+	Count   string `json:"count"`
+}
+
+type pushMessage struct {
+	Arg  request.Arg `json:"arg"`
+	Data any         `json:"data"`
+}
+
+type eventMessage struct {
+	Event  string       `json:"event"`
+	Arg    *request.Arg `json:"arg,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Msg    string       `json:"msg,omitempty"`
+	ConnId string       `json:"connId,omitempty"`
+}
+
+type wsConn struct {
+	id   string
+	conn *websocket.Conn
+
+	writeMu sync.Mutex
+	subs    map[request.Arg]struct{}
+}
+
+func (c *wsConn) write(frame []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, frame)
+}
+
+type wsHub struct {
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	conns    map[*wsConn]struct{}
+	queued   map[string][]Trade
+	reject   int
+	accepted int
+}
+
+func newWsHub() *wsHub {
+	return &wsHub{
+		conns:  make(map[*wsConn]struct{}),
+		queued: make(map[string][]Trade),
+	}
+}
+
+func (h *wsHub) handle(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.reject > 0 {
+		h.reject--
+		h.mu.Unlock()
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	h.mu.Unlock()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.accepted++
+	c := &wsConn{
+		id:   strconv.Itoa(h.accepted),
+		conn: conn,
+		subs: make(map[request.Arg]struct{}),
+	}
+	h.conns[c] = struct{}{}
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.conns, c)
+		h.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if string(message) == "ping" {
+			_ = c.write([]byte("pong"))
+			continue
+		}
+
+		var req request.SubscriptionMessage
+		if err := json.Unmarshal(message, &req); err != nil {
+			h.sendEvent(c, eventMessage{Event: "error", Code: "60012", Msg: "Invalid request: " + string(message)})
+			continue
+		}
+
+		switch req.Op {
+		case "subscribe":
+			for _, arg := range req.Args {
+				if arg.Channel == "" || arg.InstId == "" {
+					h.sendEvent(c, eventMessage{Event: "error", Code: "60018", Msg: "Wrong URL or channel:" + arg.Channel + ",instId:" + arg.InstId + " doesn't exist."})
+					continue
+				}
+				h.mu.Lock()
+				c.subs[arg] = struct{}{}
+				h.mu.Unlock()
+				h.sendEvent(c, eventMessage{Event: "subscribe", Arg: &arg, ConnId: c.id})
+				if arg.Channel == "trades" {
+					h.flushQueued(c, arg.InstId)
+				}
+			}
+		case "unsubscribe":
+			for _, arg := range req.Args {
+				h.mu.Lock()
+				delete(c.subs, arg)
+				h.mu.Unlock()
+				h.sendEvent(c, eventMessage{Event: "unsubscribe", Arg: &arg, ConnId: c.id})
+			}
+		default:
+			h.sendEvent(c, eventMessage{Event: "error", Code: "60012", Msg: "Invalid request: " + string(message)})
+		}
+	}
+}
+
+func (h *wsHub) sendEvent(c *wsConn, event eventMessage) {
+	frame, _ := json.Marshal(event)
+	_ = c.write(frame)
+}
+
+func (h *wsHub) flushQueued(c *wsConn, instId string) {
+	h.mu.Lock()
+	trades := h.queued[instId]
+	delete(h.queued, instId)
+	h.mu.Unlock()
+
+	if len(trades) > 0 {
+		_ = c.write(tradesFrame(instId, trades))
+	}
+}
+
+// subscribers returns connections subscribed to the channel and instrument
+func (h *wsHub) subscribers(arg request.Arg) []*wsConn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var conns []*wsConn
+	for c := range h.conns {
+		if _, ok := c.subs[arg]; ok {
+			conns = append(conns, c)
+		}
+	}
+	return conns
+}
+
+func (h *wsHub) all() []*wsConn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns := make([]*wsConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (h *wsHub) closeAll() int {
+	conns := h.all()
+	for _, c := range conns {
+		_ = c.conn.Close()
+	}
+	return len(conns)
+}
+
+func tradesFrame(instId string, trades []Trade) []byte {
+	data := make([]tradeData, 0, len(trades))
+	for _, t := range trades {
+		data = append(data, tradeData{
+			InstId:  instId,
+			TradeId: t.TradeId,
+			Px:      t.Price,
+			Sz:      t.Size,
+			Side:    t.Side,
+			Ts:      strconv.FormatInt(t.Time.UnixMilli(), 10),
+			Count:   "1",
+		})
+	}
+	frame, _ := json.Marshal(pushMessage{Arg: request.Arg{Channel: "trades", InstId: instId}, Data: data})
+	return frame
+}
+
+// QueueTrades scripts trades which are pushed right after the next subscription to the instrument
+func (s *Server) QueueTrades(instId string, trades ...Trade) {
+	s.ws.mu.Lock()
+	defer s.ws.mu.Unlock()
+	s.ws.queued[instId] = append(s.ws.queued[instId], trades...)
+}
+
+// PushTrades sends trades to current subscribers, returns the number of connections reached
+func (s *Server) PushTrades(instId string, trades ...Trade) int {
+	frame := tradesFrame(instId, trades)
+	sent := 0
+	for _, c := range s.ws.subscribers(request.Arg{Channel: "trades", InstId: instId}) {
+		if c.write(frame) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// PushTicker sends the ticker to subscribers of the tickers channel, returns the number of connections reached
+func (s *Server) PushTicker(ticker response.TickerResponseData) int {
+	frame, _ := json.Marshal(pushMessage{
+		Arg:  request.Arg{Channel: "tickers", InstId: ticker.InstId},
+		Data: []response.TickerResponseData{ticker},
+	})
+	sent := 0
+	for _, c := range s.ws.subscribers(request.Arg{Channel: "tickers", InstId: ticker.InstId}) {
+		if c.write(frame) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// PushRaw sends an arbitrary (e.g. malformed) frame to every connection
+func (s *Server) PushRaw(frame string) int {
+	sent := 0
+	for _, c := range s.ws.all() {
+		if c.write([]byte(frame)) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// DropConnections closes every websocket connection without a close frame
+func (s *Server) DropConnections() int {
+	return s.ws.closeAll()
+}
+
+// RejectConnections makes the next n websocket handshakes fail with 503
+func (s *Server) RejectConnections(n int) {
+	s.ws.mu.Lock()
+	defer s.ws.mu.Unlock()
+	s.ws.reject += n
+}
+
+// Connections number of open websocket connections
+func (s *Server) Connections() int {
+	return len(s.ws.all())
+}
+
+// WaitForSubscription waits until some connection subscribes to the channel and instrument
+func (s *Server) WaitForSubscription(channel, instId string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if len(s.ws.subscribers(request.Arg{Channel: channel, InstId: instId})) > 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
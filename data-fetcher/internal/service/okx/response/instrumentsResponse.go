@@ -0,0 +1,18 @@
+package response
+
+type InstrumentsResponse struct {
+	Code string                   `json:"code"`
+	Msg  string                   `json:"msg"`
+	Data []InstrumentResponseData `json:"data"`
+}
+
+type InstrumentResponseData struct {
+	InstType string `json:"instType"`
+	InstId   string `json:"instId"`
+	BaseCcy  string `json:"baseCcy"`
+	QuoteCcy string `json:"quoteCcy"`
+	TickSz   string `json:"tickSz"`
+	LotSz    string `json:"lotSz"`
+	MinSz    string `json:"minSz"`
+	State    string `json:"state"`
+}
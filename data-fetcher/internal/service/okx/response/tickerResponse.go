@@ -15,3 +15,12 @@ type TickerResponseData struct {
 	Vol24H    string `json:"vol24h"`
 	Timestamp string `json:"ts"`
 }
+
+// TickerMessage websocket push of the tickers channel
+type TickerMessage struct {
+	Arg struct {
+		Channel string `json:"channel"`
+		InstId  string `json:"instId"`
+	} `json:"arg"`
+	Data []TickerResponseData `json:"data"`
+}
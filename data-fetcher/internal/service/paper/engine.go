@@ -0,0 +1,400 @@
+// Package paper simulates order execution against live trades without real money
+package paper
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/infrastructure/kafka"
+	"cur/internal/infrastructure/tracing"
+	"cur/internal/model"
+	"cur/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	FillTopic = "paper-fills"
+	// queueSize pending trades which fill orders, trades received while the queue is full are skipped
+	queueSize = 256
+	hundred   = 100 * price.PriceFactor
+)
+
+var (
+	ErrInvalidOrder  = errors.New("invalid order")
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+type Engine struct {
+	repository store.PaperStore
+	producer   kafka.Producer
+	feePercent int64
+	log        *log.Logger
+	now        func() time.Time
+
+	mu      sync.Mutex
+	open    map[string][]model.PaperOrder // open orders by pair in id order
+	stopped bool
+
+	// execMu serializes changes of balances and positions
+	execMu sync.Mutex
+
+	queue   chan model.Trade
+	pending sync.WaitGroup
+	done    chan struct{}
+}
+
+// NewEngine makes paper trading engine, fills aren't published when producer is nil
+func NewEngine(repository store.PaperStore, producer kafka.Producer, feePercent int64, log *log.Logger) (*Engine, error) {
+	if feePercent < 0 || feePercent >= hundred {
+		return nil, fmt.Errorf("fee must be in [0, 100) percent")
+	}
+
+	e := &Engine{
+		repository: repository,
+		producer:   producer,
+		feePercent: feePercent,
+		log:        log,
+		now:        time.Now,
+		open:       make(map[string][]model.PaperOrder),
+		queue:      make(chan model.Trade, queueSize),
+		done:       make(chan struct{}),
+	}
+
+	// a single worker fills orders in the order of trades and keeps the trade handler fast
+	go func() {
+		defer close(e.done)
+		for trade := range e.queue {
+			e.execute(trade)
+			e.pending.Done()
+		}
+	}()
+
+	return e, nil
+}
+
+// Load reads open orders from the repository
+func (e *Engine) Load() error {
+	orders, err := e.repository.FetchOpenPaperOrders()
+	if err != nil {
+		return err
+	}
+
+	open := make(map[string][]model.PaperOrder)
+	for _, order := range orders {
+		open[order.Pair] = append(open[order.Pair], order)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.open = open
+	return nil
+}
+
+// ValidateOrder checks parameters of a new order
+func ValidateOrder(order model.PaperOrder) error {
+	if base, quote, ok := strings.Cut(order.Pair, "-"); !ok || base == "" || quote == "" {
+		return fmt.Errorf("%w: pair must be like BTC-USDT", ErrInvalidOrder)
+	}
+	if order.Side != model.SideBuy && order.Side != model.SideSell {
+		return fmt.Errorf("%w: side must be buy or sell", ErrInvalidOrder)
+	}
+	if order.Size <= 0 {
+		return fmt.Errorf("%w: size must be positive", ErrInvalidOrder)
+	}
+
+	switch order.Type {
+	case model.OrderMarket:
+		if order.LimitPrice != 0 {
+			return fmt.Errorf("%w: market order can't have a limit price", ErrInvalidOrder)
+		}
+	case model.OrderLimit:
+		if order.LimitPrice <= 0 {
+			return fmt.Errorf("%w: limit order needs a positive limit price", ErrInvalidOrder)
+		}
+	default:
+		return fmt.Errorf("%w: type must be market or limit", ErrInvalidOrder)
+	}
+
+	return nil
+}
+
+// PlaceOrder stores a new open order, it is filled by the next matching trade of the pair
+func (e *Engine) PlaceOrder(order model.PaperOrder) (model.PaperOrder, error) {
+	if err := ValidateOrder(order); err != nil {
+		return model.PaperOrder{}, err
+	}
+	order.Status = model.OrderOpen
+	order.Reason = ""
+
+	order, err := e.repository.InsertPaperOrder(order)
+	if err != nil {
+		return model.PaperOrder{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.open[order.Pair] = append(e.open[order.Pair], order)
+	return order, nil
+}
+
+// CancelOrder cancels an open order, returns store.ErrNotFound when the order isn't open
+func (e *Engine) CancelOrder(id int64) error {
+	if err := e.repository.ClosePaperOrder(id, model.OrderCancelled, "", e.now()); err != nil {
+		return err
+	}
+	e.remove(id)
+	return nil
+}
+
+// Deposit adds the amount to the balance of the currency
+func (e *Engine) Deposit(currency string, amount int64) (model.PaperBalance, error) {
+	if amount <= 0 {
+		return model.PaperBalance{}, fmt.Errorf("%w: amount must be positive", ErrInvalidAmount)
+	}
+	if currency == "" || strings.Contains(currency, "-") {
+		return model.PaperBalance{}, fmt.Errorf("%w: invalid currency %q", ErrInvalidAmount, currency)
+	}
+
+	e.execMu.Lock()
+	defer e.execMu.Unlock()
+
+	balances, err := e.balances()
+	if err != nil {
+		return model.PaperBalance{}, err
+	}
+	balance := model.PaperBalance{Currency: currency, Amount: balances[currency] + amount}
+	if err := e.repository.SetPaperBalance(balance); err != nil {
+		return model.PaperBalance{}, err
+	}
+	return balance, nil
+}
+
+// Trade is the trade handler, orders crossed by the trade are filled in background
+func (e *Engine) Trade(trade model.Trade) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped || !slices.ContainsFunc(e.open[trade.Pair], func(o model.PaperOrder) bool { return crossed(o, trade.Price) }) {
+		return
+	}
+
+	e.pending.Add(1)
+	select {
+	case e.queue <- trade:
+	default:
+		e.pending.Done()
+		e.log.Errorf("paper trading queue is full, trade %s of %s is skipped", trade.TradeId, trade.Pair)
+	}
+}
+
+// Close waits for pending fills and stops the worker, later trades are ignored
+func (e *Engine) Close() {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return
+	}
+	e.stopped = true
+	e.mu.Unlock()
+
+	e.pending.Wait()
+	close(e.queue)
+	<-e.done
+}
+
+// crossed tells whether an order is filled by a trade at the price
+func crossed(order model.PaperOrder, tradePrice int64) bool {
+	switch {
+	case order.Type == model.OrderMarket:
+		return true
+	case order.Side == model.SideBuy:
+		return tradePrice <= order.LimitPrice
+	default:
+		return tradePrice >= order.LimitPrice
+	}
+}
+
+func (e *Engine) execute(trade model.Trade) {
+	e.mu.Lock()
+	var matched []model.PaperOrder
+	for _, order := range e.open[trade.Pair] {
+		if crossed(order, trade.Price) {
+			matched = append(matched, order)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, order := range matched {
+		if err := e.fill(order, trade); err != nil {
+			e.log.Errorf("paper order %d fill failed: %v", order.Id, err)
+		}
+	}
+}
+
+// fill executes the whole order, market orders at the trade price and limit orders at the limit price.
+// The fill is the root span of its trace which goes on in the published message.
+func (e *Engine) fill(order model.PaperOrder, trade model.Trade) (err error) {
+	ctx, span := tracing.Start(context.Background(), "paper fill",
+		attribute.Int64("order", order.Id), attribute.String("pair", order.Pair), attribute.String("trade", trade.TradeId))
+	defer func() { tracing.End(span, err) }()
+
+	e.execMu.Lock()
+	defer e.execMu.Unlock()
+
+	fillPrice := trade.Price
+	if order.Type == model.OrderLimit {
+		fillPrice = order.LimitPrice
+	}
+	notional := price.MulDiv(fillPrice, order.Size, price.PriceFactor)
+	fee := price.MulDiv(notional, e.feePercent, hundred)
+	base, quote, _ := strings.Cut(order.Pair, "-")
+
+	balances, err := e.balances()
+	if err != nil {
+		return err
+	}
+	position, err := e.position(order.Pair)
+	if err != nil {
+		return err
+	}
+
+	switch order.Side {
+	case model.SideBuy:
+		if balances[quote] < notional+fee {
+			return e.reject(order, fmt.Sprintf("insufficient %s balance: %s needed", quote, price.Price{Price: notional + fee}))
+		}
+		balances[quote] -= notional + fee
+		balances[base] += order.Size
+
+		cost := price.MulDiv(position.AvgPrice, position.Size, price.PriceFactor) + notional
+		position.Size += order.Size
+		position.AvgPrice = price.MulDiv(cost, price.PriceFactor, position.Size)
+		position.RealizedPnl -= fee
+	case model.SideSell:
+		if balances[base] < order.Size {
+			return e.reject(order, fmt.Sprintf("insufficient %s balance: %s needed", base, price.Price{Price: order.Size}))
+		}
+		balances[base] -= order.Size
+		balances[quote] += notional - fee
+
+		// deposited coins aren't a part of the position and have no P&L
+		closed := min(order.Size, position.Size)
+		position.RealizedPnl += price.MulDiv(fillPrice-position.AvgPrice, closed, price.PriceFactor) - fee
+		position.Size -= closed
+		if position.Size == 0 {
+			position.AvgPrice = 0
+		}
+	}
+	position.UpdatedAt = trade.Timestamp
+
+	fill, err := e.repository.InsertPaperFill(
+		model.PaperFill{
+			OrderId: order.Id, Pair: order.Pair, Side: order.Side, Price: fillPrice, Size: order.Size, Fee: fee,
+			TradeId: trade.TradeId, FilledAt: trade.Timestamp,
+		},
+		[]model.PaperBalance{{Currency: base, Amount: balances[base]}, {Currency: quote, Amount: balances[quote]}},
+		position,
+	)
+	if errors.Is(err, store.ErrNotFound) {
+		// cancelled meanwhile
+		e.remove(order.Id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	e.remove(order.Id)
+	e.publish(ctx, fill)
+	return nil
+}
+
+func (e *Engine) reject(order model.PaperOrder, reason string) error {
+	err := e.repository.ClosePaperOrder(order.Id, model.OrderRejected, reason, e.now())
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	e.remove(order.Id)
+	return nil
+}
+
+// remove drops the order from open orders
+func (e *Engine) remove(id int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for pair, orders := range e.open {
+		orders = slices.DeleteFunc(orders, func(o model.PaperOrder) bool { return o.Id == id })
+		if len(orders) == 0 {
+			delete(e.open, pair)
+		} else {
+			e.open[pair] = orders
+		}
+	}
+}
+
+func (e *Engine) balances() (map[string]int64, error) {
+	stored, err := e.repository.FetchPaperBalances()
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int64, len(stored))
+	for _, balance := range stored {
+		balances[balance.Currency] = balance.Amount
+	}
+	return balances, nil
+}
+
+func (e *Engine) position(pair string) (model.PaperPosition, error) {
+	positions, err := e.repository.FetchPaperPositions()
+	if err != nil {
+		return model.PaperPosition{}, err
+	}
+	for _, position := range positions {
+		if position.Pair == pair {
+			return position, nil
+		}
+	}
+	return model.PaperPosition{Pair: pair}, nil
+}
+
+type fillMessage struct {
+	Id       int64     `json:"id"`
+	OrderId  int64     `json:"orderId"`
+	Pair     string    `json:"pair"`
+	Side     string    `json:"side"`
+	Price    string    `json:"price"`
+	Size     string    `json:"size"`
+	Fee      string    `json:"fee"`
+	TradeId  string    `json:"tradeId"`
+	FilledAt time.Time `json:"filledAt"`
+}
+
+func (e *Engine) publish(ctx context.Context, fill model.PaperFill) {
+	if e.producer == nil {
+		return
+	}
+
+	body, err := json.Marshal(fillMessage{
+		Id:       fill.Id,
+		OrderId:  fill.OrderId,
+		Pair:     fill.Pair,
+		Side:     string(fill.Side),
+		Price:    price.Price{Price: fill.Price}.String(),
+		Size:     price.Price{Price: fill.Size}.String(),
+		Fee:      price.Price{Price: fill.Fee}.String(),
+		TradeId:  fill.TradeId,
+		FilledAt: fill.FilledAt.UTC(),
+	})
+	if err != nil {
+		e.log.Error(err)
+		return
+	}
+	e.producer.SendKeyedMessage(ctx, FillTopic, fill.Pair, string(body))
+}
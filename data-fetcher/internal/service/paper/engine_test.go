@@ -0,0 +1,230 @@
+package paper
+
+import (
+	"context"
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+type sentMessage struct {
+	topic, key, message string
+}
+
+type recordingProducer struct {
+	mu       sync.Mutex
+	messages []sentMessage
+}
+
+func (p *recordingProducer) SendMessage(ctx context.Context, topic, message string) {
+	p.SendKeyedMessage(ctx, topic, "", message)
+}
+
+func (p *recordingProducer) SendKeyedMessage(ctx context.Context, topic, key, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, sentMessage{topic, key, message})
+}
+
+func (p *recordingProducer) fills(t *testing.T) []fillMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var fills []fillMessage
+	for _, m := range p.messages {
+		assert.Equal(t, FillTopic, m.topic)
+		var fill fillMessage
+		require.NoError(t, json.Unmarshal([]byte(m.message), &fill))
+		assert.Equal(t, fill.Pair, m.key)
+		fills = append(fills, fill)
+	}
+	return fills
+}
+
+type testEngine struct {
+	*Engine
+	repository *memory.PaperRepository
+	producer   *recordingProducer
+	trades     int
+}
+
+// newTestEngine makes an engine with 0.1% fee and 10000 USDT
+func newTestEngine(t *testing.T) *testEngine {
+	env := &testEngine{repository: memory.NewPaperRepository(), producer: &recordingProducer{}}
+	var err error
+	env.Engine, err = NewEngine(env.repository, env.producer, 10_000_000, log.New())
+	require.NoError(t, err)
+	t.Cleanup(env.Close)
+
+	_, err = env.Deposit("USDT", pricetest.Units(10_000))
+	require.NoError(t, err)
+	return env
+}
+
+// trade sends a trade and waits for the fills
+func (env *testEngine) trade(pair string, p int64) {
+	env.trades++
+	env.Trade(model.Trade{Pair: pair, TradeId: strconv.Itoa(env.trades), Price: pricetest.Units(p), Size: 1, Timestamp: start.Add(time.Duration(env.trades) * time.Minute)})
+	env.pending.Wait()
+}
+
+func (env *testEngine) order(t *testing.T, side model.OrderSide, orderType model.OrderType, size, limit int64) model.PaperOrder {
+	order, err := env.PlaceOrder(model.PaperOrder{Pair: "BTC-USDT", Side: side, Type: orderType, Size: size, LimitPrice: limit})
+	require.NoError(t, err)
+	return order
+}
+
+func (env *testEngine) holdings(t *testing.T) map[string]int64 {
+	balances, err := env.balances()
+	require.NoError(t, err)
+	return balances
+}
+
+func TestEngine_MarketOrders(t *testing.T) {
+	env := newTestEngine(t)
+	buy := env.order(t, model.SideBuy, model.OrderMarket, 10_000_000, 0) // 0.1 BTC
+
+	env.trade("ETH-USDT", 3_000) // other pairs don't fill
+	order, err := env.repository.FetchPaperOrder(buy.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderOpen, order.Status)
+
+	env.trade("BTC-USDT", 50_000)
+	order, err = env.repository.FetchPaperOrder(buy.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderFilled, order.Status)
+	// 5000 notional and 5 fee
+	assert.Equal(t, map[string]int64{"USDT": pricetest.Units(4_995), "BTC": 10_000_000}, env.holdings(t))
+
+	env.order(t, model.SideSell, model.OrderMarket, 10_000_000, 0)
+	env.trade("BTC-USDT", 60_000)
+	// 6000 notional and 6 fee
+	assert.Equal(t, map[string]int64{"USDT": pricetest.Units(10_989), "BTC": 0}, env.holdings(t))
+
+	positions, err := env.repository.FetchPaperPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, model.PaperPosition{Pair: "BTC-USDT", RealizedPnl: pricetest.Units(989), UpdatedAt: start.Add(3 * time.Minute)}, positions[0])
+
+	fills := env.producer.fills(t)
+	require.Len(t, fills, 2)
+	assert.Equal(t, fillMessage{
+		Id: 1, OrderId: buy.Id, Pair: "BTC-USDT", Side: "buy", Price: "50000", Size: "0.1", Fee: "5",
+		TradeId: "2", FilledAt: start.Add(2 * time.Minute),
+	}, fills[0])
+	assert.Equal(t, "60000", fills[1].Price)
+}
+
+func TestEngine_LimitOrders(t *testing.T) {
+	env := newTestEngine(t)
+	buy := env.order(t, model.SideBuy, model.OrderLimit, 20_000_000, pricetest.Units(45_000))
+
+	env.trade("BTC-USDT", 46_000)
+	env.trade("BTC-USDT", 44_000) // filled at the limit price
+	fills, err := env.repository.FetchPaperFills(10)
+	require.NoError(t, err)
+	require.Len(t, fills, 1)
+	assert.Equal(t, buy.Id, fills[0].OrderId)
+	assert.Equal(t, pricetest.Units(45_000), fills[0].Price)
+	assert.Equal(t, pricetest.Units(9), fills[0].Fee)
+
+	env.order(t, model.SideBuy, model.OrderLimit, 10_000_000, pricetest.Units(40_000))
+	env.order(t, model.SideSell, model.OrderLimit, 10_000_000, pricetest.Units(50_000))
+	env.trade("BTC-USDT", 51_000)
+
+	positions, err := env.repository.FetchPaperPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, int64(10_000_000), positions[0].Size)
+	assert.Equal(t, pricetest.Units(45_000), positions[0].AvgPrice)
+	// 500 profit minus 9 and 5 of fees
+	assert.Equal(t, pricetest.Units(486), positions[0].RealizedPnl)
+
+	open, err := env.repository.FetchOpenPaperOrders()
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, pricetest.Units(40_000), open[0].LimitPrice)
+}
+
+func TestEngine_RejectsUncoveredOrders(t *testing.T) {
+	env := newTestEngine(t)
+	buy := env.order(t, model.SideBuy, model.OrderMarket, pricetest.Units(1), 0)
+	sell := env.order(t, model.SideSell, model.OrderMarket, pricetest.Units(1), 0)
+	env.trade("BTC-USDT", 50_000)
+
+	for id, reason := range map[int64]string{
+		buy.Id:  "insufficient USDT balance: 50050 needed",
+		sell.Id: "insufficient BTC balance: 1 needed",
+	} {
+		order, err := env.repository.FetchPaperOrder(id)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderRejected, order.Status)
+		assert.Equal(t, reason, order.Reason)
+	}
+	assert.Empty(t, env.producer.fills(t))
+	assert.Equal(t, pricetest.Units(10_000), env.holdings(t)["USDT"])
+}
+
+func TestEngine_CancelAndReload(t *testing.T) {
+	env := newTestEngine(t)
+	first := env.order(t, model.SideBuy, model.OrderLimit, 10_000_000, pricetest.Units(40_000))
+	second := env.order(t, model.SideBuy, model.OrderLimit, 10_000_000, pricetest.Units(41_000))
+
+	require.NoError(t, env.CancelOrder(first.Id))
+	assert.ErrorIs(t, env.CancelOrder(first.Id), store.ErrNotFound)
+
+	// a restarted engine loads open orders
+	restarted, err := NewEngine(env.repository, nil, 0, log.New())
+	require.NoError(t, err)
+	require.NoError(t, restarted.Load())
+	restarted.Trade(model.Trade{Pair: "BTC-USDT", TradeId: "1", Price: pricetest.Units(39_000), Timestamp: start})
+	restarted.Close()
+
+	order, err := env.repository.FetchPaperOrder(second.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderFilled, order.Status)
+	order, err = env.repository.FetchPaperOrder(first.Id)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderCancelled, order.Status)
+}
+
+func TestValidateOrder(t *testing.T) {
+	valid := model.PaperOrder{Pair: "BTC-USDT", Side: model.SideBuy, Type: model.OrderLimit, Size: 1, LimitPrice: 1}
+	require.NoError(t, ValidateOrder(valid))
+
+	for name, change := range map[string]func(o *model.PaperOrder){
+		"pair":   func(o *model.PaperOrder) { o.Pair = "BTCUSDT" },
+		"side":   func(o *model.PaperOrder) { o.Side = "short" },
+		"size":   func(o *model.PaperOrder) { o.Size = 0 },
+		"type":   func(o *model.PaperOrder) { o.Type = "stop" },
+		"limit":  func(o *model.PaperOrder) { o.LimitPrice = 0 },
+		"market": func(o *model.PaperOrder) { o.Type = model.OrderMarket },
+	} {
+		order := valid
+		change(&order)
+		assert.ErrorIs(t, ValidateOrder(order), ErrInvalidOrder, name)
+	}
+}
+
+func TestEngine_Deposit(t *testing.T) {
+	env := newTestEngine(t)
+	balance, err := env.Deposit("USDT", pricetest.Units(5))
+	require.NoError(t, err)
+	assert.Equal(t, model.PaperBalance{Currency: "USDT", Amount: pricetest.Units(10_005)}, balance)
+
+	_, err = env.Deposit("USDT", 0)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	_, err = env.Deposit("BTC-USDT", 1)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
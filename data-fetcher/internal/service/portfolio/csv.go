@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvTimeLayouts accepted layouts of acquired_at besides unix milliseconds
+var csvTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseCsv reads holdings from csv with a header row. Columns currency and amount are required,
+// price, acquired_at (RFC3339, date or unix milliseconds, UTC when without zone) and note are optional
+func ParseCsv(r io.Reader) ([]model.Holding, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: empty csv", ErrInvalidHolding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHolding, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"currency", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: csv has no %s column", ErrInvalidHolding, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var holdings []model.Holding
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHolding, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		h := model.Holding{Currency: field(record, "currency"), Note: field(record, "note")}
+		if h.Amount, err = price.ParsePrice(field(record, "amount")); err != nil {
+			return nil, fmt.Errorf("%w: line %d: amount must be a decimal number", ErrInvalidHolding, line)
+		}
+		if value := field(record, "price"); value != "" {
+			if h.Price, err = price.ParsePrice(value); err != nil {
+				return nil, fmt.Errorf("%w: line %d: price must be a decimal number", ErrInvalidHolding, line)
+			}
+		}
+		if value := field(record, "acquired_at"); value != "" {
+			if h.AcquiredAt, err = parseCsvTime(value); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidHolding, line, err)
+			}
+		}
+		holdings = append(holdings, h)
+	}
+
+	if len(holdings) == 0 {
+		return nil, fmt.Errorf("%w: csv has no holdings", ErrInvalidHolding)
+	}
+	return holdings, nil
+}
+
+func parseCsvTime(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid acquired_at %q", value)
+}
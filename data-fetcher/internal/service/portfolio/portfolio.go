@@ -0,0 +1,212 @@
+// Package portfolio values holdings in the base currency over time by stored candles
+package portfolio
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxHistoryPoints limits valuations of a single history request
+const MaxHistoryPoints = 1000
+
+var (
+	ErrInvalidHolding = errors.New("invalid holding")
+	ErrInvalidRange   = errors.New("invalid range")
+)
+
+// Asset valuation of a currency, Cost is the net amount paid for it in the base currency
+type Asset struct {
+	Currency string
+	Amount   int64
+	Rate     int64
+	Value    int64
+	Cost     int64
+	Pnl      int64
+	Path     []string // pairs converting the currency to the base currency, empty for the base currency itself
+}
+
+// Valuation of the portfolio at a time, currencies without a rate are listed in Unpriced and excluded from totals
+type Valuation struct {
+	Timestamp time.Time
+	Value     int64
+	Cost      int64
+	Pnl       int64
+	Assets    []Asset
+	Unpriced  []string
+}
+
+type Portfolio struct {
+	holdings store.PortfolioStore
+	rates    store.CrossRateStore
+	base     string
+	bar      string
+	now      func() time.Time
+}
+
+// New makes portfolio valued in the base currency by closes of the bar candles
+func New(holdings store.PortfolioStore, rates store.CrossRateStore, base, bar string) *Portfolio {
+	return &Portfolio{holdings: holdings, rates: rates, base: base, bar: bar, now: time.Now}
+}
+
+func (p *Portfolio) BaseCurrency() string {
+	return p.base
+}
+
+// ValidateHolding checks a holding before it's stored
+func ValidateHolding(h model.Holding) error {
+	if h.Currency == "" || strings.Contains(h.Currency, "-") {
+		return fmt.Errorf("%w: invalid currency %q", ErrInvalidHolding, h.Currency)
+	}
+	if h.Amount == 0 {
+		return fmt.Errorf("%w: amount must not be zero", ErrInvalidHolding)
+	}
+	if h.Price < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidHolding)
+	}
+	return nil
+}
+
+// Add validates and stores holdings, currencies are upper-cased and holdings without a time are acquired now
+func (p *Portfolio) Add(holdings []model.Holding) ([]model.Holding, error) {
+	if len(holdings) == 0 {
+		return nil, fmt.Errorf("%w: no holdings", ErrInvalidHolding)
+	}
+
+	now := p.now()
+	prepared := make([]model.Holding, 0, len(holdings))
+	for i, h := range holdings {
+		h.Currency = strings.ToUpper(strings.TrimSpace(h.Currency))
+		if h.AcquiredAt.IsZero() {
+			h.AcquiredAt = now
+		}
+		if err := ValidateHolding(h); err != nil {
+			if len(holdings) > 1 {
+				return nil, fmt.Errorf("holding %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		prepared = append(prepared, h)
+	}
+
+	return p.holdings.InsertHoldings(prepared)
+}
+
+// Value values the portfolio at the time
+func (p *Portfolio) Value(at time.Time) (Valuation, error) {
+	v, err := p.valuer()
+	if err != nil {
+		return Valuation{}, err
+	}
+	return v.at(at)
+}
+
+// History values the portfolio at every step from the start to the end inclusive
+func (p *Portfolio) History(from, to time.Time, step time.Duration) ([]Valuation, error) {
+	if step <= 0 || to.Before(from) {
+		return nil, fmt.Errorf("%w: step must be positive and from not later than to", ErrInvalidRange)
+	}
+	if to.Sub(from)/step >= MaxHistoryPoints {
+		return nil, fmt.Errorf("%w: more than %d points, increase the step", ErrInvalidRange, MaxHistoryPoints)
+	}
+
+	v, err := p.valuer()
+	if err != nil {
+		return nil, err
+	}
+
+	var history []Valuation
+	for ts := from; !ts.After(to); ts = ts.Add(step) {
+		valuation, err := v.at(ts)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, valuation)
+	}
+	return history, nil
+}
+
+// valuer values holdings loaded once, unit costs of holdings without a price are the rates at their acquisition
+type valuer struct {
+	*Portfolio
+	holdings []model.Holding
+	costs    []int64 // unit cost of every holding, zero when unknown
+}
+
+func (p *Portfolio) valuer() (*valuer, error) {
+	holdings, err := p.holdings.FetchHoldings()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &valuer{Portfolio: p, holdings: holdings, costs: make([]int64, len(holdings))}
+	for i, h := range holdings {
+		v.costs[i] = h.Price
+		if h.Price != 0 {
+			continue
+		}
+		rate, err := p.rates.FetchCrossRateAt(h.Currency, p.base, p.bar, h.AcquiredAt)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		v.costs[i] = rate.Rate
+	}
+	return v, nil
+}
+
+func (v *valuer) at(ts time.Time) (Valuation, error) {
+	valuation := Valuation{Timestamp: ts}
+
+	assets := make(map[string]*Asset)
+	var currencies []string
+	unknownCosts := make(map[string]int64) // amounts without a known cost, valued at the current rate
+	for i, h := range v.holdings {
+		if h.AcquiredAt.After(ts) {
+			break
+		}
+		asset, ok := assets[h.Currency]
+		if !ok {
+			asset = &Asset{Currency: h.Currency}
+			assets[h.Currency] = asset
+			currencies = append(currencies, h.Currency)
+		}
+		asset.Amount += h.Amount
+		if v.costs[i] == 0 {
+			unknownCosts[h.Currency] += h.Amount
+		} else {
+			asset.Cost += price.MulDiv(h.Amount, v.costs[i], price.PriceFactor)
+		}
+	}
+	sort.Strings(currencies)
+
+	for _, currency := range currencies {
+		asset := assets[currency]
+		if asset.Amount != 0 || unknownCosts[currency] != 0 {
+			rate, err := v.rates.FetchCrossRateAt(currency, v.base, v.bar, ts)
+			if errors.Is(err, store.ErrNotFound) {
+				valuation.Unpriced = append(valuation.Unpriced, currency)
+				continue
+			}
+			if err != nil {
+				return Valuation{}, err
+			}
+			asset.Rate = rate.Rate
+			asset.Path = rate.Path
+			asset.Value = price.MulDiv(asset.Amount, rate.Rate, price.PriceFactor)
+			asset.Cost += price.MulDiv(unknownCosts[currency], rate.Rate, price.PriceFactor)
+		}
+		asset.Pnl = asset.Value - asset.Cost
+
+		valuation.Value += asset.Value
+		valuation.Cost += asset.Cost
+		valuation.Assets = append(valuation.Assets, *asset)
+	}
+	valuation.Pnl = valuation.Value - valuation.Cost
+
+	return valuation, nil
+}
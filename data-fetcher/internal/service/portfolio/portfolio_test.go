@@ -0,0 +1,153 @@
+package portfolio
+
+import (
+	"cur/internal/helper/price/pricetest"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+func candle(pair string, ts time.Time, close int64) model.Candle {
+	return model.Candle{Pair: pair, Bar: "1H", Timestamp: ts, Open: close, High: close, Low: close, Close: close, Volume: 1}
+}
+
+// newTestPortfolio stores hourly BTC-USDT closes 50000, 60000, 40000 and TON-BTC closes 0.0001, 0.0002, 0.0001
+func newTestPortfolio(t *testing.T) (*Portfolio, *memory.Store) {
+	storage := memory.NewStore()
+	var candles []model.Candle
+	for i, close := range []int64{50_000, 60_000, 40_000} {
+		candles = append(candles, candle("BTC-USDT", start.Add(time.Duration(i)*time.Hour), pricetest.Units(close)))
+	}
+	for i, close := range []int64{10_000, 20_000, 10_000} {
+		candles = append(candles, candle("TON-BTC", start.Add(time.Duration(i)*time.Hour), close))
+	}
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	p := New(storage.Portfolio(), store.NewCrossRateRepository(storage.Candle(), store.NewTickerRepository()), "USDT", "1H")
+	p.now = func() time.Time { return start.Add(time.Hour) }
+	return p, storage
+}
+
+func TestPortfolio_Value(t *testing.T) {
+	p, _ := newTestPortfolio(t)
+	_, err := p.Add([]model.Holding{
+		{Currency: "btc", Amount: pricetest.Units(1), Price: pricetest.Units(45_000), AcquiredAt: start},
+		{Currency: "USDT", Amount: pricetest.Units(1_000), AcquiredAt: start},
+		{Currency: "TON", Amount: pricetest.Units(10_000), AcquiredAt: start.Add(30 * time.Minute)},
+	})
+	require.NoError(t, err)
+
+	valuation, err := p.Value(start.Add(90 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, valuation.Assets, 3)
+
+	assert.Equal(t, Asset{
+		Currency: "BTC", Amount: pricetest.Units(1), Rate: pricetest.Units(60_000), Value: pricetest.Units(60_000), Cost: pricetest.Units(45_000),
+		Pnl: pricetest.Units(15_000), Path: []string{"BTC-USDT"},
+	}, valuation.Assets[0])
+	// TON has no direct pair, 0.0002 BTC at 60000 is 12 USDT, the cost is the rate at its acquisition
+	assert.Equal(t, Asset{
+		Currency: "TON", Amount: pricetest.Units(10_000), Rate: pricetest.Units(12), Value: pricetest.Units(120_000), Cost: pricetest.Units(50_000),
+		Pnl: pricetest.Units(70_000), Path: []string{"TON-BTC", "BTC-USDT"},
+	}, valuation.Assets[1])
+	assert.Equal(t, Asset{Currency: "USDT", Amount: pricetest.Units(1_000), Rate: pricetest.Units(1), Value: pricetest.Units(1_000), Cost: pricetest.Units(1_000), Path: []string{}}, valuation.Assets[2])
+
+	assert.Equal(t, pricetest.Units(181_000), valuation.Value)
+	assert.Equal(t, pricetest.Units(96_000), valuation.Cost)
+	assert.Equal(t, pricetest.Units(85_000), valuation.Pnl)
+	assert.Empty(t, valuation.Unpriced)
+}
+
+func TestPortfolio_History(t *testing.T) {
+	p, _ := newTestPortfolio(t)
+	_, err := p.Add([]model.Holding{
+		{Currency: "BTC", Amount: pricetest.Units(2), AcquiredAt: start},
+		{Currency: "BTC", Amount: -pricetest.Units(1), Price: pricetest.Units(60_000), AcquiredAt: start.Add(time.Hour), Note: "sold"},
+		{Currency: "ETH", Amount: pricetest.Units(1), Price: pricetest.Units(3_000), AcquiredAt: start},
+	})
+	require.NoError(t, err)
+
+	history, err := p.History(start.Add(-time.Hour), start.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	assert.Empty(t, history[0].Assets, "nothing acquired yet")
+	assert.Equal(t, pricetest.Units(100_000), history[1].Value)
+	assert.Equal(t, int64(0), history[1].Pnl)
+	assert.Equal(t, []string{"ETH"}, history[1].Unpriced, "ETH has no candles")
+	// the sale realized 10000 of profit, 1 BTC is left
+	assert.Equal(t, pricetest.Units(60_000), history[2].Value)
+	assert.Equal(t, pricetest.Units(40_000), history[2].Cost)
+	assert.Equal(t, pricetest.Units(20_000), history[2].Pnl)
+	assert.Equal(t, pricetest.Units(40_000), history[3].Value)
+	assert.Equal(t, int64(0), history[3].Pnl)
+
+	_, err = p.History(start, start.Add(-time.Hour), time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = p.History(start, start.Add(MaxHistoryPoints*time.Minute), time.Minute)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+}
+
+func TestPortfolio_Add(t *testing.T) {
+	p, storage := newTestPortfolio(t)
+
+	holdings, err := p.Add([]model.Holding{{Currency: " eth ", Amount: pricetest.Units(1)}})
+	require.NoError(t, err)
+	require.Len(t, holdings, 1)
+	assert.Equal(t, "ETH", holdings[0].Currency)
+	assert.True(t, start.Add(time.Hour).Equal(holdings[0].AcquiredAt), "acquired now")
+
+	for name, h := range map[string]model.Holding{
+		"pair":   {Currency: "BTC-USDT", Amount: 1},
+		"empty":  {Amount: 1},
+		"amount": {Currency: "BTC"},
+		"price":  {Currency: "BTC", Amount: 1, Price: -1},
+	} {
+		_, err := p.Add([]model.Holding{{Currency: "BTC", Amount: 1}, h})
+		assert.ErrorIs(t, err, ErrInvalidHolding, name)
+	}
+	_, err = p.Add(nil)
+	assert.ErrorIs(t, err, ErrInvalidHolding)
+
+	stored, err := storage.Portfolio().FetchHoldings()
+	require.NoError(t, err)
+	assert.Len(t, stored, 1, "invalid batches are not stored")
+}
+
+func TestParseCsv(t *testing.T) {
+	holdings, err := ParseCsv(strings.NewReader("Currency,Amount,Price,Acquired_At,Note\n" +
+		"BTC,0.5,45000,2025-02-01,first buy\n" +
+		"ETH,-1.25,,2025-02-01T10:00:00+02:00,\n" +
+		"TON,100,,1738368000000,\"staked, locked\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []model.Holding{
+		{Currency: "BTC", Amount: 50_000_000, Price: pricetest.Units(45_000), AcquiredAt: start, Note: "first buy"},
+		{Currency: "ETH", Amount: -125_000_000, AcquiredAt: time.Date(2025, 2, 1, 10, 0, 0, 0, time.FixedZone("", 2*3600))},
+		{Currency: "TON", Amount: pricetest.Units(100), AcquiredAt: start, Note: "staked, locked"},
+	}, holdings)
+
+	holdings, err = ParseCsv(strings.NewReader("amount,currency\n1,BTC\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []model.Holding{{Currency: "BTC", Amount: pricetest.Units(1)}}, holdings)
+
+	for name, body := range map[string]string{
+		"empty":     "",
+		"no amount": "currency\nBTC\n",
+		"no rows":   "currency,amount\n",
+		"amount":    "currency,amount\nBTC,one\n",
+		"price":     "currency,amount,price\nBTC,1,cheap\n",
+		"time":      "currency,amount,acquired_at\nBTC,1,yesterday\n",
+		"columns":   "currency,amount\nBTC,1,2\n",
+	} {
+		_, err := ParseCsv(strings.NewReader(body))
+		assert.ErrorIs(t, err, ErrInvalidHolding, name)
+	}
+}
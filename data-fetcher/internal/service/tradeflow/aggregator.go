@@ -0,0 +1,124 @@
+// Package tradeflow aggregates live trades into per bar flows stored alongside candles:
+// VWAP, taker buy and sell volume, trade count and the largest trade
+package tradeflow
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type flowKey struct {
+	pair, bar string
+	timestamp int64
+}
+
+// Aggregator counts trades in flows of every bar and periodically merges them into the store.
+// Only trades received since the previous flush are kept in memory, so a restart in the middle of a bar adds up.
+type Aggregator struct {
+	repository store.TradeFlowStore
+	bars       []string
+	log        *log.Logger
+
+	mu      sync.Mutex
+	pending map[flowKey]*model.TradeFlow
+}
+
+func NewAggregator(repository store.TradeFlowStore, bars []string, log *log.Logger) (*Aggregator, error) {
+	if len(bars) == 0 {
+		return nil, errors.New("no bars")
+	}
+	for _, bar := range bars {
+		if _, err := model.BarDuration(bar); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Aggregator{
+		repository: repository,
+		bars:       bars,
+		log:        log,
+		pending:    make(map[flowKey]*model.TradeFlow),
+	}, nil
+}
+
+// Trade counts the trade in the flow of its bar, fits okx.TradeHandler
+func (a *Aggregator) Trade(trade model.Trade) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, bar := range a.bars {
+		start, _ := model.BarStart(bar, trade.Timestamp)
+		key := flowKey{pair: trade.Pair, bar: bar, timestamp: start.UnixMicro()}
+		flow, ok := a.pending[key]
+		if !ok {
+			flow = &model.TradeFlow{Pair: trade.Pair, Bar: bar, Timestamp: start}
+			a.pending[key] = flow
+		}
+		flow.Add(trade)
+	}
+}
+
+// Run flushes flows every interval until ctx is done, the rest is flushed on the way out
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := a.Flush(); err != nil {
+				a.log.Errorf("trade flows flush failed: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := a.Flush(); err != nil {
+				a.log.Errorf("trade flows flush failed: %v", err)
+			}
+		}
+	}
+}
+
+// Flush merges flows counted since the previous flush into the store, they are kept for the next one on error
+func (a *Aggregator) Flush() error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[flowKey]*model.TradeFlow)
+	a.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	flows := make([]model.TradeFlow, 0, len(pending))
+	for _, flow := range pending {
+		flows = append(flows, *flow)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].Pair != flows[j].Pair {
+			return flows[i].Pair < flows[j].Pair
+		}
+		if flows[i].Bar != flows[j].Bar {
+			return flows[i].Bar < flows[j].Bar
+		}
+		return flows[i].Timestamp.Before(flows[j].Timestamp)
+	})
+
+	if err := a.repository.MergeTradeFlows(flows); err != nil {
+		a.mu.Lock()
+		for key, flow := range pending {
+			if newer, ok := a.pending[key]; ok {
+				flow.Merge(*newer)
+			}
+			a.pending[key] = flow
+		}
+		a.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package tradeflow
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const factor = 100_000_000
+
+func trade(side string, price, size float64, ts time.Time) model.Trade {
+	return model.Trade{Pair: "BTC-USDT", Price: int64(price * factor), Size: int64(size * factor), Side: side, Timestamp: ts}
+}
+
+func TestAggregator_Flush(t *testing.T) {
+	repository := memory.NewTradeFlowRepository()
+	aggregator, err := NewAggregator(repository, []string{"1H", "1m"}, log.New())
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	aggregator.Trade(trade("buy", 100, 1, start.Add(10*time.Second)))
+	aggregator.Trade(trade("sell", 110, 3, start.Add(30*time.Second)))
+	require.NoError(t, aggregator.Flush())
+	aggregator.Trade(trade("buy", 90, 1, start.Add(90*time.Second)))
+	aggregator.Trade(trade("buy", 200, 1, start.Add(time.Hour)))
+	require.NoError(t, aggregator.Flush())
+	require.NoError(t, aggregator.Flush(), "nothing to flush")
+
+	flows, err := repository.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	require.Len(t, flows, 2)
+	flow := flows[0]
+	assert.Equal(t, start, flow.Timestamp)
+	assert.Equal(t, int64(2*factor), flow.BuyVolume)
+	assert.Equal(t, int64(3*factor), flow.SellVolume)
+	assert.Equal(t, int64(3), flow.TradeCount)
+	assert.Equal(t, int64(3*factor), flow.LargestTrade)
+	assert.Equal(t, int64(104*factor), flow.Vwap(), "(100 + 330 + 90) / 5")
+	assert.Equal(t, int64(200*factor), flows[1].Vwap())
+
+	flows, err = repository.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1m"})
+	require.NoError(t, err)
+	require.Len(t, flows, 3)
+	assert.Equal(t, int64(2), flows[0].TradeCount)
+	assert.Equal(t, start.Add(time.Minute), flows[1].Timestamp)
+}
+
+type failingStore struct {
+	store.TradeFlowStore
+	fail bool
+}
+
+func (s *failingStore) MergeTradeFlows(flows []model.TradeFlow) error {
+	if s.fail {
+		return errors.New("db is down")
+	}
+	return s.TradeFlowStore.MergeTradeFlows(flows)
+}
+
+func TestAggregator_FlushRetries(t *testing.T) {
+	repository := &failingStore{TradeFlowStore: memory.NewTradeFlowRepository(), fail: true}
+	aggregator, err := NewAggregator(repository, []string{"1H"}, log.New())
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	aggregator.Trade(trade("buy", 100, 1, start))
+	require.Error(t, aggregator.Flush())
+	aggregator.Trade(trade("sell", 100, 2, start.Add(time.Minute)))
+
+	repository.fail = false
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	aggregator.Run(ctx, time.Hour)
+
+	flows, err := repository.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	require.Len(t, flows, 1)
+	assert.Equal(t, int64(2), flows[0].TradeCount, "failed flows are kept")
+	assert.Equal(t, int64(factor), flows[0].BuyVolume)
+	assert.Equal(t, int64(2*factor), flows[0].SellVolume)
+}
+
+func TestNewAggregator_Validates(t *testing.T) {
+	_, err := NewAggregator(memory.NewTradeFlowRepository(), nil, log.New())
+	assert.Error(t, err)
+	_, err = NewAggregator(memory.NewTradeFlowRepository(), []string{"1M"}, log.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+package trend
+
+import (
+	"cur/internal/helper/price"
+	"cur/internal/indicators"
+	"cur/internal/model"
+	"errors"
+)
+
+const (
+	DefaultFastPeriod   = 20
+	DefaultSlowPeriod   = 50
+	DefaultAdxPeriod    = 14
+	DefaultAdxThreshold = 25 * price.PriceFactor
+	DefaultSwingLength  = 10
+)
+
+type Config struct {
+	FastPeriod   int   // fast EMA of the crossover
+	SlowPeriod   int   // slow EMA of the crossover
+	AdxPeriod    int   // ADX period
+	AdxThreshold int64 // ADX below it means there is no trend, in price scale
+	SwingLength  int   // the last two windows of this length are compared for higher highs / lower lows
+}
+
+func DefaultConfig() Config {
+	return Config{
+		FastPeriod:   DefaultFastPeriod,
+		SlowPeriod:   DefaultSlowPeriod,
+		AdxPeriod:    DefaultAdxPeriod,
+		AdxThreshold: DefaultAdxThreshold,
+		SwingLength:  DefaultSwingLength,
+	}
+}
+
+func (c Config) validate() error {
+	if c.FastPeriod <= 0 || c.SlowPeriod <= 0 || c.AdxPeriod <= 0 || c.SwingLength <= 0 {
+		return indicators.ErrInvalidPeriod
+	}
+	if c.FastPeriod >= c.SlowPeriod {
+		return errors.New("fast period must be shorter than slow period")
+	}
+	return nil
+}
+
+// MinCandles number of candles required for a classification
+func (c Config) MinCandles() int {
+	return max(c.SlowPeriod, 2*c.AdxPeriod, 2*c.SwingLength)
+}
+
+// Classify decides the regime at the last candle, candles are ordered by timestamp.
+// A trend needs ADX at least the threshold and two of three signals agreeing on the
+// direction: EMA crossover, +DI/-DI and swing structure. Otherwise it is a range.
+func Classify(candles []model.Candle, config Config) (model.TrendState, bool) {
+	if len(candles) < config.MinCandles() {
+		return model.TrendState{}, false
+	}
+
+	fast, _ := indicators.NewEmaStream(config.FastPeriod)
+	slow, _ := indicators.NewEmaStream(config.SlowPeriod)
+	adx, _ := indicators.NewAdxStream(config.AdxPeriod)
+	var fastMa, slowMa price.Price
+	var direction indicators.AdxValue
+	for _, c := range candles {
+		fastMa, _ = fast.Update(c)
+		slowMa, _ = slow.Update(c)
+		direction, _ = adx.Update(c)
+	}
+
+	last := candles[len(candles)-1]
+	state := model.TrendState{
+		Pair:      last.Pair,
+		Bar:       last.Bar,
+		Timestamp: last.Timestamp,
+		Regime:    model.RegimeRange,
+		FastMa:    fastMa.Price,
+		SlowMa:    slowMa.Price,
+		Adx:       direction.Adx.Price,
+		Structure: swingStructure(candles, config.SwingLength),
+	}
+
+	if state.Adx < config.AdxThreshold {
+		return state, true
+	}
+
+	score := sign(fastMa.Price-slowMa.Price) + sign(direction.PlusDi.Price-direction.MinusDi.Price)
+	switch state.Structure {
+	case model.StructureHigherHighs:
+		score++
+	case model.StructureLowerLows:
+		score--
+	}
+
+	switch {
+	case score >= 2:
+		state.Regime = model.RegimeUptrend
+	case score <= -2:
+		state.Regime = model.RegimeDowntrend
+	}
+
+	return state, true
+}
+
+// swingStructure compares highs and lows of the last window with the previous one
+func swingStructure(candles []model.Candle, length int) model.Structure {
+	recentHigh, recentLow := highLow(candles[len(candles)-length:])
+	previousHigh, previousLow := highLow(candles[len(candles)-2*length : len(candles)-length])
+
+	switch {
+	case recentHigh > previousHigh && recentLow > previousLow:
+		return model.StructureHigherHighs
+	case recentHigh < previousHigh && recentLow < previousLow:
+		return model.StructureLowerLows
+	default:
+		return model.StructureMixed
+	}
+}
+
+func highLow(candles []model.Candle) (int64, int64) {
+	high, low := candles[0].High, candles[0].Low
+	for _, c := range candles[1:] {
+		high, low = max(high, c.High), min(low, c.Low)
+	}
+	return high, low
+}
+
+func sign(value int64) int {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
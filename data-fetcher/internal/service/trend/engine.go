@@ -0,0 +1,177 @@
+// Package trend classifies pairs into uptrend, downtrend or range regimes as new candles are stored
+package trend
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/infrastructure/kafka"
+	"cur/internal/model"
+	"cur/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RegimeTopic = "trend-regimes"
+	// warmupFactor more candles than required smooth out the EMA seed
+	warmupFactor = 4
+)
+
+type seriesKey struct {
+	pair string
+	bar  string
+}
+
+type Engine struct {
+	candleRepository store.CandleStore
+	trendRepository  store.TrendStore
+	producer         kafka.Producer
+	config           Config
+	log              *log.Logger
+	now              func() time.Time
+
+	mu        sync.Mutex
+	current   map[seriesKey]model.TrendState // the latest stored regime
+	evaluated map[seriesKey]time.Time        // the latest classified candle
+}
+
+// NewEngine makes trend engine, regime changes aren't published when producer is nil
+func NewEngine(
+	candleRepository store.CandleStore,
+	trendRepository store.TrendStore,
+	producer kafka.Producer,
+	config Config,
+	log *log.Logger,
+) (*Engine, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		candleRepository: candleRepository,
+		trendRepository:  trendRepository,
+		producer:         producer,
+		config:           config,
+		log:              log,
+		now:              time.Now,
+		current:          make(map[seriesKey]model.TrendState),
+		evaluated:        make(map[seriesKey]time.Time),
+	}, nil
+}
+
+// OnCandles is the candle insert listener, every pair and bar of the candles is evaluated
+func (e *Engine) OnCandles(ctx context.Context, candles []model.Candle) {
+	seen := make(map[seriesKey]bool)
+	for _, c := range candles {
+		key := seriesKey{c.Pair, c.Bar}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, err := e.Evaluate(ctx, c.Pair, c.Bar); err != nil {
+			e.log.Errorf("trend evaluation of %s %s failed: %v", c.Pair, c.Bar, err)
+		}
+	}
+}
+
+// Evaluate classifies the latest closed candle of the pair and bar. A regime change is
+// stored and published with trace context of ctx, the returned state is nil when the regime is unchanged.
+func (e *Engine) Evaluate(ctx context.Context, pair, bar string) (*model.TrendState, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := seriesKey{pair, bar}
+	candles, err := store.CandlesWithContext(ctx, e.candleRepository).FetchLatest(pair, bar, warmupFactor*e.config.MinCandles()+1)
+	if err != nil {
+		return nil, err
+	}
+
+	candles = model.ClosedCandles(candles, bar, e.now())
+	if len(candles) == 0 || !candles[len(candles)-1].Timestamp.After(e.evaluated[key]) {
+		return nil, nil
+	}
+
+	state, ok := Classify(candles, e.config)
+	if !ok {
+		return nil, nil
+	}
+	e.evaluated[key] = state.Timestamp
+
+	current, err := e.currentState(key)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Regime == state.Regime {
+		return nil, nil
+	}
+
+	if err := e.trendRepository.InsertTrendState(state); err != nil {
+		return nil, err
+	}
+	e.current[key] = state
+	e.publish(ctx, state, current)
+
+	return &state, nil
+}
+
+func (e *Engine) currentState(key seriesKey) (*model.TrendState, error) {
+	if state, ok := e.current[key]; ok {
+		return &state, nil
+	}
+
+	state, err := e.trendRepository.FetchLatestTrendState(key.pair, key.bar)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current trend state: %w", err)
+	}
+
+	e.current[key] = state
+	return &state, nil
+}
+
+type regimeMessage struct {
+	Pair           string    `json:"pair"`
+	Bar            string    `json:"bar"`
+	Timestamp      time.Time `json:"timestamp"`
+	Regime         string    `json:"regime"`
+	PreviousRegime string    `json:"previousRegime,omitempty"`
+	FastMa         string    `json:"fastMa"`
+	SlowMa         string    `json:"slowMa"`
+	Adx            string    `json:"adx"`
+	Structure      string    `json:"structure"`
+}
+
+func (e *Engine) publish(ctx context.Context, state model.TrendState, previous *model.TrendState) {
+	if e.producer == nil {
+		return
+	}
+
+	message := regimeMessage{
+		Pair:      state.Pair,
+		Bar:       state.Bar,
+		Timestamp: state.Timestamp.UTC(),
+		Regime:    string(state.Regime),
+		FastMa:    price.Price{Price: state.FastMa}.String(),
+		SlowMa:    price.Price{Price: state.SlowMa}.String(),
+		Adx:       price.Price{Price: state.Adx}.String(),
+		Structure: string(state.Structure),
+	}
+	if previous != nil {
+		message.PreviousRegime = string(previous.Regime)
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		e.log.Error(err)
+		return
+	}
+	e.producer.SendKeyedMessage(ctx, RegimeTopic, state.Pair, string(body))
+}
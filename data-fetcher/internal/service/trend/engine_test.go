@@ -0,0 +1,187 @@
+package trend
+
+import (
+	"context"
+	"cur/internal/helper/price"
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"encoding/json"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sentMessage struct {
+	topic, key, message string
+}
+
+type recordingProducer struct {
+	mu       sync.Mutex
+	messages []sentMessage
+}
+
+func (p *recordingProducer) SendMessage(ctx context.Context, topic, message string) {
+	p.SendKeyedMessage(ctx, topic, "", message)
+}
+
+func (p *recordingProducer) SendKeyedMessage(ctx context.Context, topic, key, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, sentMessage{topic, key, message})
+}
+
+func (p *recordingProducer) regimes(t *testing.T) []regimeMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var regimes []regimeMessage
+	for _, m := range p.messages {
+		assert.Equal(t, RegimeTopic, m.topic)
+		var message regimeMessage
+		require.NoError(t, json.Unmarshal([]byte(m.message), &message))
+		assert.Equal(t, message.Pair, m.key)
+		regimes = append(regimes, message)
+	}
+	return regimes
+}
+
+var start = time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+// series makes hourly candles starting from the close with a constant step per candle and some noise
+func series(from int, count int, close float64, step float64) []model.Candle {
+	candles := make([]model.Candle, 0, count)
+	for i := 0; i < count; i++ {
+		noise := math.Sin(float64(from+i)) * 0.3
+		c := close + step*float64(i) + noise
+		candles = append(candles, model.Candle{
+			Pair:      "BTC-USDT",
+			Bar:       "1H",
+			Timestamp: start.Add(time.Duration(from+i) * time.Hour),
+			Open:      int64((c - step/2) * price.PriceFactor),
+			High:      int64((c + 0.5) * price.PriceFactor),
+			Low:       int64((c - 0.5) * price.PriceFactor),
+			Close:     int64(c * price.PriceFactor),
+			Volume:    price.PriceFactor,
+		})
+	}
+	return candles
+}
+
+func TestClassify(t *testing.T) {
+	config := DefaultConfig()
+
+	up, ok := Classify(series(0, 120, 100, 1), config)
+	require.True(t, ok)
+	assert.Equal(t, model.RegimeUptrend, up.Regime)
+	assert.Equal(t, model.StructureHigherHighs, up.Structure)
+	assert.Greater(t, up.FastMa, up.SlowMa)
+	assert.GreaterOrEqual(t, up.Adx, config.AdxThreshold)
+	assert.Equal(t, start.Add(119*time.Hour), up.Timestamp)
+
+	down, ok := Classify(series(0, 120, 300, -1), config)
+	require.True(t, ok)
+	assert.Equal(t, model.RegimeDowntrend, down.Regime)
+	assert.Equal(t, model.StructureLowerLows, down.Structure)
+
+	flat, ok := Classify(series(0, 120, 100, 0), config)
+	require.True(t, ok)
+	assert.Equal(t, model.RegimeRange, flat.Regime)
+	assert.Less(t, flat.Adx, config.AdxThreshold)
+
+	_, ok = Classify(series(0, config.MinCandles()-1, 100, 1), config)
+	assert.False(t, ok)
+}
+
+func TestNewEngine_InvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.FastPeriod = config.SlowPeriod
+	_, err := NewEngine(nil, nil, nil, config, log.New())
+	assert.Error(t, err)
+}
+
+func newTestEngine(t *testing.T, storage *memory.Store, producer *recordingProducer, now time.Time) *Engine {
+	engine, err := NewEngine(storage.Candle(), storage.Trend(), producer, DefaultConfig(), log.New())
+	require.NoError(t, err)
+	engine.now = func() time.Time { return now }
+	return engine
+}
+
+func TestEngine_RegimeChanges(t *testing.T) {
+	storage := memory.NewStore()
+	producer := &recordingProducer{}
+	// the last inserted candle (index 199) is still open
+	engine := newTestEngine(t, storage, producer, start.Add(199*time.Hour+30*time.Minute))
+	storage.Candle().OnInsert(engine.OnCandles)
+
+	rising := series(0, 120, 100, 1)
+	require.NoError(t, storage.Candle().InsertCandles(&rising))
+
+	states, err := storage.Trend().FetchTrendStates(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, model.RegimeUptrend, states[0].Regime)
+	assert.True(t, start.Add(119*time.Hour).Equal(states[0].Timestamp))
+
+	// the same regime isn't stored again
+	more := series(120, 5, 220, 1)
+	require.NoError(t, storage.Candle().InsertCandles(&more))
+
+	falling := series(125, 75, 224, -2)
+	require.NoError(t, storage.Candle().InsertCandles(&falling))
+
+	states, err = storage.Trend().FetchTrendStates(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, model.RegimeDowntrend, states[1].Regime)
+	assert.True(t, start.Add(198*time.Hour).Equal(states[1].Timestamp), "the open candle must be skipped")
+
+	regimes := producer.regimes(t)
+	require.Len(t, regimes, 2)
+	assert.Equal(t, "uptrend", regimes[0].Regime)
+	assert.Empty(t, regimes[0].PreviousRegime)
+	assert.Equal(t, "downtrend", regimes[1].Regime)
+	assert.Equal(t, "uptrend", regimes[1].PreviousRegime)
+	assert.Equal(t, "1H", regimes[1].Bar)
+	assert.Equal(t, string(model.StructureLowerLows), regimes[1].Structure)
+}
+
+func TestEngine_RestartKeepsRegime(t *testing.T) {
+	storage := memory.NewStore()
+	now := start.Add(200 * time.Hour)
+	candles := series(0, 120, 100, 1)
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	first := &recordingProducer{}
+	state, err := newTestEngine(t, storage, first, now).Evaluate(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, model.RegimeUptrend, state.Regime)
+
+	restarted := &recordingProducer{}
+	state, err = newTestEngine(t, storage, restarted, now).Evaluate(context.Background(), "BTC-USDT", "1H")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+	assert.Len(t, first.regimes(t), 1)
+	assert.Empty(t, restarted.regimes(t))
+}
+
+func TestEngine_NotEnoughCandles(t *testing.T) {
+	storage := memory.NewStore()
+	producer := &recordingProducer{}
+	engine := newTestEngine(t, storage, producer, start.Add(100*time.Hour))
+	storage.Candle().OnInsert(engine.OnCandles)
+
+	candles := series(0, 10, 100, 1)
+	require.NoError(t, storage.Candle().InsertCandles(&candles))
+
+	states, err := storage.Trend().FetchTrendStates(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+	require.NoError(t, err)
+	assert.Empty(t, states)
+	assert.Empty(t, producer.regimes(t))
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AdvisoryLock is a session-level Postgres advisory lock, it's held by a dedicated connection
+// and released by Postgres when the session ends, e.g. the process holding it dies
+type AdvisoryLock struct {
+	db   *sql.DB
+	name string
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewAdvisoryLock(db *sql.DB, name string) *AdvisoryLock {
+	return &AdvisoryLock{db: db, name: name}
+}
+
+// TryAcquire takes the lock unless another session holds it, it's a no-op when the lock is already held
+func (l *AdvisoryLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn != nil {
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", l.name).Scan(&acquired); err != nil {
+		discard(conn)
+		return false, fmt.Errorf("failed to try advisory lock %q: %w", l.name, err)
+	}
+	if !acquired {
+		_ = conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Check returns an error when the session holding the lock is gone, the lock is dropped then
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return errors.New("advisory lock isn't held")
+	}
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT 1"); err != nil {
+		discard(l.conn)
+		l.conn = nil
+		return fmt.Errorf("session of advisory lock %q is lost: %w", l.name, err)
+	}
+	return nil
+}
+
+// Release unlocks the lock and returns its connection to the pool
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+
+	conn := l.conn
+	l.conn = nil
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", l.name); err != nil {
+		// the lock ends with the session
+		discard(conn)
+		return fmt.Errorf("failed to unlock advisory lock %q: %w", l.name, err)
+	}
+	return conn.Close()
+}
+
+// discard closes the connection instead of returning it to the pool
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
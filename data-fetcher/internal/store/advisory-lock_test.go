@@ -0,0 +1,38 @@
+package store_test
+
+import (
+	"context"
+	"cur/internal/store"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	db := openTestDb(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	first := store.NewAdvisoryLock(db, "advisory-lock-test")
+	second := store.NewAdvisoryLock(db, "advisory-lock-test")
+
+	acquired, err := first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = first.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired, "the holder keeps the lock")
+
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.NoError(t, first.Check(ctx))
+	assert.Error(t, second.Check(ctx))
+
+	require.NoError(t, first.Release(ctx))
+	acquired, err = second.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.NoError(t, second.Release(ctx))
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AlertRepository alert rules and history of triggered alerts
+type AlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const alertRuleColumns = "name, pair, bar, kind, direction, level, percent, window_ms, multiplier, lookback, indicator, cooldown_ms, channels, enabled"
+
+// InsertAlertRule stores a new rule, returns it with the assigned id
+func (rep *AlertRepository) InsertAlertRule(rule model.AlertRule) (model.AlertRule, error) {
+	query := "INSERT INTO alert_rules (" + alertRuleColumns + ") " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at"
+
+	err := rep.db.QueryRow(query, rule.Name, rule.Pair, rule.Bar, rule.Kind, rule.Direction, rule.Level, rule.Percent,
+		rule.Window.Milliseconds(), rule.Multiplier, rule.Lookback, rule.Indicator, rule.Cooldown.Milliseconds(),
+		pq.Array(rule.Channels), rule.Enabled,
+	).Scan(&rule.Id, &rule.CreatedAt)
+	if err != nil {
+		return model.AlertRule{}, fmt.Errorf("failed to insert alert rule: %w", err)
+	}
+	rule.LastTriggeredAt = time.Time{}
+	return rule, nil
+}
+
+// FetchAlertRules returns all rules ordered by id
+func (rep *AlertRepository) FetchAlertRules() ([]model.AlertRule, error) {
+	rows, err := rep.db.Query("SELECT id, " + alertRuleColumns + ", last_triggered_at, created_at FROM alert_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rowsToAlertRules(rows)
+}
+
+// FetchAlertRule returns the rule or ErrNotFound
+func (rep *AlertRepository) FetchAlertRule(id int64) (model.AlertRule, error) {
+	rows, err := rep.db.Query("SELECT id, "+alertRuleColumns+", last_triggered_at, created_at FROM alert_rules WHERE id=$1", id)
+	if err != nil {
+		return model.AlertRule{}, err
+	}
+	defer rows.Close()
+
+	rules, err := rowsToAlertRules(rows)
+	if err != nil {
+		return model.AlertRule{}, err
+	}
+	if len(rules) == 0 {
+		return model.AlertRule{}, ErrNotFound
+	}
+	return rules[0], nil
+}
+
+// DeleteAlertRule deletes the rule with its events or returns ErrNotFound
+func (rep *AlertRepository) DeleteAlertRule(id int64) error {
+	result, err := rep.db.Exec("DELETE FROM alert_rules WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// InsertAlertEvent stores the event and the trigger time of its rule in one transaction,
+// returns the event with the assigned id or ErrNotFound when the rule was deleted
+func (rep *AlertRepository) InsertAlertEvent(event model.AlertEvent) (model.AlertEvent, error) {
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return model.AlertEvent{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	result, err := tx.Exec("UPDATE alert_rules SET last_triggered_at=$2 WHERE id=$1", event.RuleId, event.TriggeredAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return model.AlertEvent{}, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		_ = tx.Rollback()
+		if err != nil {
+			return model.AlertEvent{}, err
+		}
+		return model.AlertEvent{}, ErrNotFound
+	}
+
+	err = tx.QueryRow("INSERT INTO alert_events (rule_id, pair, kind, value, message, triggered_at) "+
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		event.RuleId, event.Pair, event.Kind, event.Value, event.Message, event.TriggeredAt,
+	).Scan(&event.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return model.AlertEvent{}, fmt.Errorf("failed to insert alert event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.AlertEvent{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
+
+// FetchAlertEvents returns the latest events of the rule (of every rule when ruleId is 0), newest first
+func (rep *AlertRepository) FetchAlertEvents(ruleId int64, limit int) ([]model.AlertEvent, error) {
+	rows, err := rep.db.Query("SELECT id, rule_id, pair, kind, value, message, triggered_at FROM alert_events "+
+		"WHERE $1::BIGINT = 0 OR rule_id = $1 ORDER BY triggered_at DESC, id DESC LIMIT $2", ruleId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.AlertEvent
+	for rows.Next() {
+		var e model.AlertEvent
+		if err := rows.Scan(&e.Id, &e.RuleId, &e.Pair, &e.Kind, &e.Value, &e.Message, &e.TriggeredAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func rowsToAlertRules(rows *sql.Rows) ([]model.AlertRule, error) {
+	var rules []model.AlertRule
+	for rows.Next() {
+		var r model.AlertRule
+		var window, cooldown int64
+		var lastTriggered sql.NullTime
+		err := rows.Scan(&r.Id, &r.Name, &r.Pair, &r.Bar, &r.Kind, &r.Direction, &r.Level, &r.Percent, &window,
+			&r.Multiplier, &r.Lookback, &r.Indicator, &cooldown, pq.Array(&r.Channels), &r.Enabled, &lastTriggered, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.Window = time.Duration(window) * time.Millisecond
+		r.Cooldown = time.Duration(cooldown) * time.Millisecond
+		if lastTriggered.Valid {
+			r.LastTriggeredAt = lastTriggered.Time
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
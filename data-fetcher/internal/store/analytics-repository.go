@@ -0,0 +1,159 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VolatilityQuery selects volatilities with From <= timestamp < To, empty fields match everything.
+// Zero From or To means the range is unbounded on that side.
+type VolatilityQuery struct {
+	Pair      string
+	Bar       string
+	Window    int
+	Estimator model.VolatilityEstimator
+	From      time.Time
+	To        time.Time
+}
+
+// AnalyticsRepository rolling volatilities of pairs and correlations across pairs
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// InsertVolatilities upserts values in one transaction
+func (rep *AnalyticsRepository) InsertVolatilities(values []model.Volatility) error {
+	query := strings.Join([]string{"INSERT INTO volatility_values (pair, bar, window_size, estimator, timestamp, value)",
+		"VALUES ($1, $2, $3, $4, $5, $6)",
+		"ON CONFLICT (pair, bar, window_size, estimator, timestamp)",
+		"DO UPDATE SET value = EXCLUDED.value",
+	}, " ")
+
+	return rep.insert(query, len(values), func(stmt *sql.Stmt, i int) error {
+		v := values[i]
+		_, err := stmt.Exec(v.Pair, v.Bar, v.Window, v.Estimator, v.Timestamp, v.Value)
+		return err
+	})
+}
+
+// InsertCorrelations upserts values in one transaction
+func (rep *AnalyticsRepository) InsertCorrelations(values []model.Correlation) error {
+	query := strings.Join([]string{"INSERT INTO correlation_values (bar, window_size, method, timestamp, pair_a, pair_b, value)",
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		"ON CONFLICT (bar, window_size, method, timestamp, pair_a, pair_b)",
+		"DO UPDATE SET value = EXCLUDED.value",
+	}, " ")
+
+	return rep.insert(query, len(values), func(stmt *sql.Stmt, i int) error {
+		v := values[i]
+		_, err := stmt.Exec(v.Bar, v.Window, v.Method, v.Timestamp, v.PairA, v.PairB, v.Value)
+		return err
+	})
+}
+
+func (rep *AnalyticsRepository) insert(query string, n int, exec func(stmt *sql.Stmt, i int) error) error {
+	if n == 0 {
+		return nil
+	}
+
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to insert/update analytics: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if err := exec(stmt, i); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert/update analytics: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// LastVolatilityTimestamp returns the timestamp of the latest volatility of the window or ErrNotFound
+func (rep *AnalyticsRepository) LastVolatilityTimestamp(pair, bar string, window int) (time.Time, error) {
+	return rep.last("SELECT MAX(timestamp) FROM volatility_values WHERE pair=$1 AND bar=$2 AND window_size=$3", pair, bar, window)
+}
+
+// LastCorrelationTimestamp returns the timestamp of the latest correlation of the pairs and window or ErrNotFound
+func (rep *AnalyticsRepository) LastCorrelationTimestamp(pairA, pairB, bar string, window int) (time.Time, error) {
+	return rep.last("SELECT MAX(timestamp) FROM correlation_values WHERE pair_a=$1 AND pair_b=$2 AND bar=$3 AND window_size=$4",
+		pairA, pairB, bar, window)
+}
+
+func (rep *AnalyticsRepository) last(query string, args ...any) (time.Time, error) {
+	var last sql.NullTime
+	if err := rep.db.QueryRow(query, args...).Scan(&last); err != nil {
+		return time.Time{}, err
+	}
+	if !last.Valid {
+		return time.Time{}, ErrNotFound
+	}
+	return last.Time, nil
+}
+
+func (rep *AnalyticsRepository) FetchVolatilities(query VolatilityQuery, limit int) ([]model.Volatility, error) {
+	rows, err := rep.db.Query("SELECT pair, bar, window_size, estimator, timestamp, value FROM volatility_values "+
+		"WHERE ($1::TEXT = '' OR pair = $1) AND ($2::TEXT = '' OR bar = $2) AND ($3 = 0 OR window_size = $3) "+
+		"AND ($4::TEXT = '' OR estimator = $4) "+
+		"AND ($5::TIMESTAMPTZ IS NULL OR timestamp >= $5) AND ($6::TIMESTAMPTZ IS NULL OR timestamp < $6) "+
+		"ORDER BY timestamp DESC, pair, window_size, estimator LIMIT $7",
+		query.Pair, query.Bar, query.Window, query.Estimator, nullTime(query.From), nullTime(query.To), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []model.Volatility
+	for rows.Next() {
+		var v model.Volatility
+		if err := rows.Scan(&v.Pair, &v.Bar, &v.Window, &v.Estimator, &v.Timestamp, &v.Value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
+
+// FetchCorrelationMatrix returns the latest correlation of every two pairs at or before at (the latest ones when zero),
+// ordered by pairs. Pairs are computed separately so their timestamps may differ.
+func (rep *AnalyticsRepository) FetchCorrelationMatrix(bar string, window int, method model.CorrelationMethod, at time.Time) ([]model.Correlation, error) {
+	rows, err := rep.db.Query("SELECT DISTINCT ON (pair_a, pair_b) bar, window_size, method, timestamp, pair_a, pair_b, value "+
+		"FROM correlation_values WHERE bar = $1 AND window_size = $2 AND method = $3 "+
+		"AND ($4::TIMESTAMPTZ IS NULL OR timestamp <= $4) ORDER BY pair_a, pair_b, timestamp DESC",
+		bar, window, method, nullTime(at))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []model.Correlation
+	for rows.Next() {
+		var v model.Correlation
+		if err := rows.Scan(&v.Bar, &v.Window, &v.Method, &v.Timestamp, &v.PairA, &v.PairB, &v.Value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
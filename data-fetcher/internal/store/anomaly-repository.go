@@ -0,0 +1,162 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AnomalyQuery selects anomalies of the pair, kind and status, empty fields match everything
+type AnomalyQuery struct {
+	Pair   string
+	Kind   model.AnomalyKind
+	Status model.AnomalyStatus
+}
+
+// AnomalyRepository quarantined candles and trades, the row is kept as JSON payload
+type AnomalyRepository struct {
+	db *sql.DB
+}
+
+func NewAnomalyRepository(db *sql.DB) *AnomalyRepository {
+	return &AnomalyRepository{db: db}
+}
+
+// InsertAnomalies stores new anomalies in one transaction, returns them with the assigned ids.
+// Anomalies of an already quarantined row are skipped.
+func (rep *AnomalyRepository) InsertAnomalies(anomalies []model.Anomaly) ([]model.Anomaly, error) {
+	if len(anomalies) == 0 {
+		return nil, nil
+	}
+
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	inserted := make([]model.Anomaly, 0, len(anomalies))
+	for _, a := range anomalies {
+		payload, tradeId, err := anomalyPayload(a)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if a.Status == "" {
+			a.Status = model.AnomalyPending
+		}
+
+		err = tx.QueryRow("INSERT INTO anomalies (kind, source, pair, bar, ts, trade_id, reason, payload, status, detected_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (source, pair, bar, ts, trade_id) DO NOTHING RETURNING id",
+			a.Kind, a.Source, a.Pair, a.Bar, a.Timestamp, tradeId, a.Reason, payload, a.Status, a.DetectedAt,
+		).Scan(&a.Id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to insert anomaly: %w", err)
+		}
+		inserted = append(inserted, a)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inserted, nil
+}
+
+// anomalyPayload returns the quarantined row as JSON (nil for feeds) and the id of a trade
+func anomalyPayload(a model.Anomaly) ([]byte, string, error) {
+	var row any
+	var tradeId string
+	switch {
+	case a.Candle != nil:
+		row = a.Candle
+	case a.Trade != nil:
+		row, tradeId = a.Trade, a.Trade.TradeId
+	default:
+		return nil, "", nil
+	}
+
+	payload, err := json.Marshal(row)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode anomaly payload: %w", err)
+	}
+	return payload, tradeId, nil
+}
+
+// FetchAnomalies returns the latest anomalies of the query, newest first
+func (rep *AnomalyRepository) FetchAnomalies(query AnomalyQuery, limit int) ([]model.Anomaly, error) {
+	rows, err := rep.db.Query("SELECT id, kind, source, pair, bar, ts, reason, payload, status, detected_at, reviewed_at "+
+		"FROM anomalies WHERE ($1::TEXT = '' OR pair = $1) AND ($2::TEXT = '' OR kind = $2) AND ($3::TEXT = '' OR status = $3) "+
+		"ORDER BY detected_at DESC, id DESC LIMIT $4",
+		query.Pair, query.Kind, query.Status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var anomalies []model.Anomaly
+	for rows.Next() {
+		a, err := scanAnomaly(rows)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, a)
+	}
+
+	return anomalies, rows.Err()
+}
+
+// FetchAnomaly returns the anomaly or ErrNotFound
+func (rep *AnomalyRepository) FetchAnomaly(id int64) (model.Anomaly, error) {
+	a, err := scanAnomaly(rep.db.QueryRow("SELECT id, kind, source, pair, bar, ts, reason, payload, status, detected_at, reviewed_at "+
+		"FROM anomalies WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Anomaly{}, ErrNotFound
+	}
+	return a, err
+}
+
+// ReviewAnomaly moves a pending anomaly to the status or returns ErrNotFound when it isn't pending
+func (rep *AnomalyRepository) ReviewAnomaly(id int64, status model.AnomalyStatus, at time.Time) error {
+	result, err := rep.db.Exec("UPDATE anomalies SET status = $2, reviewed_at = $3 WHERE id = $1 AND status = $4",
+		id, status, at, model.AnomalyPending)
+	if err != nil {
+		return fmt.Errorf("failed to review anomaly: %w", err)
+	}
+	reviewed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if reviewed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanAnomaly(row interface{ Scan(dest ...any) error }) (model.Anomaly, error) {
+	var a model.Anomaly
+	var payload []byte
+	var reviewedAt sql.NullTime
+	err := row.Scan(&a.Id, &a.Kind, &a.Source, &a.Pair, &a.Bar, &a.Timestamp, &a.Reason, &payload, &a.Status, &a.DetectedAt, &reviewedAt)
+	if err != nil {
+		return model.Anomaly{}, err
+	}
+	a.ReviewedAt = reviewedAt.Time
+
+	switch a.Source {
+	case model.AnomalySourceCandle:
+		a.Candle = &model.Candle{}
+		err = json.Unmarshal(payload, a.Candle)
+	case model.AnomalySourceTrade:
+		a.Trade = &model.Trade{}
+		err = json.Unmarshal(payload, a.Trade)
+	}
+	if err != nil {
+		return model.Anomaly{}, fmt.Errorf("failed to decode anomaly payload: %w", err)
+	}
+	return a, nil
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"cur/internal/model"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CandleQuery selects candles of the pair and bar with From <= timestamp < To.
+// Zero From or To means the range is unbounded on that side.
+type CandleQuery struct {
+	Pair string
+	Bar  string
+	From time.Time
+	To   time.Time
+}
+
+// CandlePage candles ordered by timestamp, Next is empty on the last page
+type CandlePage struct {
+	Candles []model.Candle
+	Next    string
+}
+
+// Contains checks if the timestamp is in the query range
+func (q CandleQuery) Contains(ts time.Time) bool {
+	if !q.From.IsZero() && ts.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !ts.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// NewCandleCursor makes an opaque keyset cursor pointing after the candle timestamp
+func NewCandleCursor(ts time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(ts.UnixMicro(), 10)))
+}
+
+// ParseCandleCursor returns the timestamp the cursor points after
+func ParseCandleCursor(cursor string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	micro, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return time.UnixMicro(micro).UTC(), nil
+}
@@ -1,24 +1,54 @@
 package store
 
 import (
+	"context"
+	"cur/internal/infrastructure/tracing"
 	"cur/internal/model"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CandleRepository struct {
-	db *sql.DB
+	db        *sql.DB
+	ctx       context.Context
+	listeners *candleListeners
 }
 
 func NewCandleRepository(db *sql.DB) *CandleRepository {
 	return &CandleRepository{
-		db: db,
+		db:        db,
+		listeners: &candleListeners{},
 	}
 }
 
-func (rep *CandleRepository) InsertCandles(candles *[]model.Candle) error {
+// WithContext returns the repository running queries with ctx, their spans are children of the span of ctx.
+// Listeners are shared with the repository.
+func (rep *CandleRepository) WithContext(ctx context.Context) CandleStore {
+	bound := *rep
+	bound.ctx = ctx
+	return &bound
+}
+
+// start starts the span of the repository operation
+func (rep *CandleRepository) start(operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := rep.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracing.Start(ctx, "CandleRepository."+operation, append(attributes, dbSystem)...)
+}
+
+func (rep *CandleRepository) InsertCandles(candles *[]model.Candle) (err error) {
+	ctx, span := rep.start("InsertCandles", attribute.Int("candles", len(*candles)))
+	defer func() { tracing.End(span, err) }()
+
 	query := strings.Join([]string{"INSERT INTO candles (pair, timestamp, open_price, high_price, low_price, close_price, volume, bar)",
 		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
 		"ON CONFLICT (pair, timestamp, bar)",
@@ -30,13 +60,13 @@ func (rep *CandleRepository) InsertCandles(candles *[]model.Candle) error {
 	},
 		" ")
 
-	tx, err := rep.db.Begin()
+	tx, err := rep.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	for _, candle := range *candles {
-		_, err := tx.Exec(query, candle.Pair, candle.Timestamp, candle.Open, candle.High, candle.Low, candle.Close, candle.Volume, candle.Bar)
+		_, err := tx.ExecContext(ctx, query, candle.Pair, candle.Timestamp, candle.Open, candle.High, candle.Low, candle.Close, candle.Volume, candle.Bar)
 		if err != nil {
 			if err := tx.Rollback(); err != nil {
 				return fmt.Errorf("failed to insert/update candles: %w", err)
@@ -50,13 +80,25 @@ func (rep *CandleRepository) InsertCandles(candles *[]model.Candle) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	rep.listeners.notify(ctx, *candles)
+
 	return nil
 }
 
-func (rep *CandleRepository) FetchAll() ([]model.Candle, error) {
-	query := "SELECT * FROM candles"
+// OnInsert adds listener called with candles after each successful InsertCandles
+func (rep *CandleRepository) OnInsert(listener CandlesListener) {
+	rep.listeners.add(listener)
+}
+
+const candleColumns = "pair, timestamp, open_price, high_price, low_price, close_price, volume, bar"
+
+func (rep *CandleRepository) FetchAll() (candles []model.Candle, err error) {
+	ctx, span := rep.start("FetchAll")
+	defer func() { tracing.End(span, err) }()
 
-	rows, err := rep.db.Query(query)
+	query := "SELECT " + candleColumns + " FROM candles ORDER BY pair, bar, timestamp"
+
+	rows, err := rep.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -65,51 +107,243 @@ func (rep *CandleRepository) FetchAll() ([]model.Candle, error) {
 	return rowsToCandles(rows)
 }
 
+func scanCandle(rows *sql.Rows) (model.Candle, error) {
+	var candle model.Candle
+	err := rows.Scan(&candle.Pair, &candle.Timestamp, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Volume, &candle.Bar)
+	return candle, err
+}
+
 func rowsToCandles(rows *sql.Rows) ([]model.Candle, error) {
 	var candles []model.Candle
 	for rows.Next() {
-		var candle model.Candle
-		err := rows.Scan(&candle.Pair, &candle.Timestamp, &candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Volume, &candle.Bar)
+		candle, err := scanCandle(rows)
 		if err != nil {
 			return nil, err
 		}
 		candles = append(candles, candle)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return candles, nil
 }
 
-func (rep *CandleRepository) fetchByCurrency(pair string, from, to time.Time) ([]model.Candle, error) {
-	query := "SELECT * FROM candles WHERE pair=$1 and `timestamp` BETWEEN($2,$3)"
+// rangeConditions makes WHERE conditions and args for the query, placeholders start from $1
+func rangeConditions(query CandleQuery) (string, []any) {
+	conditions := []string{"pair=$1", "bar=$2"}
+	args := []any{query.Pair, query.Bar}
 
-	rows, err := rep.db.Query(query, pair, from.Unix(), to.Unix())
+	if !query.From.IsZero() {
+		args = append(args, query.From)
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", len(args)))
+	}
+	if !query.To.IsZero() {
+		args = append(args, query.To)
+		conditions = append(conditions, fmt.Sprintf("timestamp < $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
 
+// FetchRange returns candles of the query range ordered by timestamp
+func (rep *CandleRepository) FetchRange(query CandleQuery) (candles []model.Candle, err error) {
+	ctx, span := rep.start("FetchRange", queryAttributes(query)...)
+	defer func() { tracing.End(span, err) }()
+
+	conditions, args := rangeConditions(query)
+
+	rows, err := rep.db.QueryContext(ctx, "SELECT "+candleColumns+" FROM candles WHERE "+conditions+" ORDER BY timestamp", args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close() // Ensure rows are closed after function execution
+	defer rows.Close()
 
 	return rowsToCandles(rows)
 }
 
-// GetLastTsForPair getting max timestamp in milliseconds
-func (rep *CandleRepository) GetLastTsForPair(pair string) (string, error) {
-	query := "SELECT (EXTRACT(EPOCH FROM timestamp) * 1000)::BIGINT::TEXT as ts  FROM candles WHERE pair=$1 ORDER BY timestamp DESC LIMIT 1"
+// FetchLatest returns up to n most recent candles ordered by timestamp
+func (rep *CandleRepository) FetchLatest(pair, bar string, n int) (candles []model.Candle, err error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ctx, span := rep.start("FetchLatest", attribute.String("pair", pair), attribute.String("bar", bar), attribute.Int("n", n))
+	defer func() { tracing.End(span, err) }()
+
+	query := "SELECT " + candleColumns + " FROM candles WHERE pair=$1 AND bar=$2 ORDER BY timestamp DESC LIMIT $3"
+
+	rows, err := rep.db.QueryContext(ctx, query, pair, bar, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles, err = rowsToCandles(rows)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(candles)
+
+	return candles, nil
+}
+
+// FetchAtOrBefore returns the candle with the greatest timestamp not after ts
+func (rep *CandleRepository) FetchAtOrBefore(pair, bar string, ts time.Time) (candle model.Candle, err error) {
+	ctx, span := rep.start("FetchAtOrBefore", attribute.String("pair", pair), attribute.String("bar", bar))
+	defer func() { tracing.End(span, ignoreNotFound(err)) }()
+
+	query := "SELECT " + candleColumns + " FROM candles WHERE pair=$1 AND bar=$2 AND timestamp <= $3 ORDER BY timestamp DESC LIMIT 1"
+
+	rows, err := rep.db.QueryContext(ctx, query, pair, bar, ts)
+	if err != nil {
+		return model.Candle{}, err
+	}
+	defer rows.Close()
+
+	candles, err := rowsToCandles(rows)
+	if err != nil {
+		return model.Candle{}, err
+	}
+	if len(candles) == 0 {
+		return model.Candle{}, ErrNotFound
+	}
+
+	return candles[0], nil
+}
+
+// FetchPage returns a page of the query range using keyset pagination by timestamp.
+// Empty cursor means the first page, next page cursor is returned in CandlePage.Next.
+func (rep *CandleRepository) FetchPage(query CandleQuery, cursor string, limit int) (page CandlePage, err error) {
+	if limit <= 0 {
+		return CandlePage{}, fmt.Errorf("invalid page limit %d", limit)
+	}
+
+	ctx, span := rep.start("FetchPage", append(queryAttributes(query), attribute.Int("limit", limit))...)
+	defer func() { tracing.End(span, err) }()
+
+	conditions, args := rangeConditions(query)
+	if cursor != "" {
+		after, err := ParseCandleCursor(cursor)
+		if err != nil {
+			return CandlePage{}, err
+		}
+		args = append(args, after)
+		conditions += fmt.Sprintf(" AND timestamp > $%d", len(args))
+	}
+	args = append(args, limit+1)
+
+	rows, err := rep.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT %s FROM candles WHERE %s ORDER BY timestamp LIMIT $%d", candleColumns, conditions, len(args)),
+		args...,
+	)
+	if err != nil {
+		return CandlePage{}, err
+	}
+	defer rows.Close()
+
+	candles, err := rowsToCandles(rows)
+	if err != nil {
+		return CandlePage{}, err
+	}
+
+	return makeCandlePage(candles, limit), nil
+}
+
+// makeCandlePage cuts the extra candle fetched to find out if there is a next page
+func makeCandlePage(candles []model.Candle, limit int) CandlePage {
+	if len(candles) <= limit {
+		return CandlePage{Candles: candles}
+	}
+
+	candles = candles[:limit]
+	return CandlePage{
+		Candles: candles,
+		Next:    NewCandleCursor(candles[limit-1].Timestamp),
+	}
+}
+
+// Iterate streams candles of the query range ordered by timestamp without loading them all in memory.
+// Iteration stops at the first error returned by fn.
+func (rep *CandleRepository) Iterate(ctx context.Context, query CandleQuery, fn func(model.Candle) error) (err error) {
+	ctx, span := tracing.Start(ctx, "CandleRepository.Iterate", append(queryAttributes(query), dbSystem)...)
+	defer func() { tracing.End(span, err) }()
+
+	conditions, args := rangeConditions(query)
+
+	rows, err := rep.db.QueryContext(ctx, "SELECT "+candleColumns+" FROM candles WHERE "+conditions+" ORDER BY timestamp", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		candle, err := scanCandle(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(candle); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetLastTsForPair getting max timestamp of the bar in milliseconds
+func (rep *CandleRepository) GetLastTsForPair(pair, bar string) (ts string, err error) {
+	ctx, span := rep.start("GetLastTsForPair", attribute.String("pair", pair), attribute.String("bar", bar))
+	defer func() { tracing.End(span, ignoreNotFound(err)) }()
+
+	query := "SELECT (EXTRACT(EPOCH FROM timestamp) * 1000)::BIGINT::TEXT as ts  FROM candles WHERE pair=$1 AND bar=$2 ORDER BY timestamp DESC LIMIT 1"
 	var lastTimestamp string
-	err := rep.db.QueryRow(query, pair).Scan(&lastTimestamp)
+	err = rep.db.QueryRowContext(ctx, query, pair, bar).Scan(&lastTimestamp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		return "", err
 	}
 	return lastTimestamp, nil
 }
 
-// GetFirstTsForPair getting max timestamp in milliseconds
-func (rep *CandleRepository) GetFirstTsForPair(pair string) (string, error) {
-	query := "SELECT (EXTRACT(EPOCH FROM timestamp) * 1000)::BIGINT::TEXT as ts  FROM candles WHERE pair=$1 ORDER BY timestamp ASC LIMIT 1"
+// GetFirstTsForPair getting min timestamp of the bar in milliseconds
+func (rep *CandleRepository) GetFirstTsForPair(pair, bar string) (ts string, err error) {
+	ctx, span := rep.start("GetFirstTsForPair", attribute.String("pair", pair), attribute.String("bar", bar))
+	defer func() { tracing.End(span, ignoreNotFound(err)) }()
+
+	query := "SELECT (EXTRACT(EPOCH FROM timestamp) * 1000)::BIGINT::TEXT as ts  FROM candles WHERE pair=$1 AND bar=$2 ORDER BY timestamp ASC LIMIT 1"
 	var lastTimestamp string
-	err := rep.db.QueryRow(query, pair).Scan(&lastTimestamp)
+	err = rep.db.QueryRowContext(ctx, query, pair, bar).Scan(&lastTimestamp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		return "", err
 	}
 	return lastTimestamp, nil
 }
+
+func (rep *CandleRepository) FetchPairStats() (stats []PairStats, err error) {
+	ctx, span := rep.start("FetchPairStats")
+	defer func() { tracing.End(span, err) }()
+
+	query := "SELECT pair, bar, MIN(timestamp), MAX(timestamp), COUNT(*) FROM candles GROUP BY pair, bar ORDER BY pair, bar"
+
+	rows, err := rep.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s PairStats
+		if err := rows.Scan(&s.Pair, &s.Bar, &s.First, &s.Last, &s.Count); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/service/conversion"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CrossRate price of a unit of From in To, Path lists the pairs used and Timestamp is of the oldest price
+type CrossRate struct {
+	From      string
+	To        string
+	Rate      int64
+	Path      []string
+	Timestamp time.Time
+}
+
+// CrossCandlePage synthetic candles with the pairs they are made of
+type CrossCandlePage struct {
+	CandlePage
+	Path []string
+}
+
+// CrossRateRepository converts currencies through stored candles and latest tickers.
+// The graphs of candle pairs are cached until candles of a new pair or bar are inserted.
+type CrossRateRepository struct {
+	candles CandleStore
+	tickers TickerStore
+
+	mu     sync.Mutex
+	loaded bool
+	known  map[string]bool              // pair and bar of stored candles
+	bars   map[string][]string          // bars of stored candles by pair
+	graphs map[string]*conversion.Graph // candle pairs by bar, of every bar by ""
+}
+
+func NewCrossRateRepository(candles CandleStore, tickers TickerStore) *CrossRateRepository {
+	rep := &CrossRateRepository{candles: candles, tickers: tickers}
+	candles.OnInsert(rep.candlesInserted)
+	return rep
+}
+
+// candlesInserted drops the cached graphs when candles of a new pair or bar appear
+func (rep *CrossRateRepository) candlesInserted(_ context.Context, candles []model.Candle) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if !rep.loaded {
+		return
+	}
+	for _, c := range candles {
+		if !rep.known[c.Pair+"/"+c.Bar] {
+			rep.loaded = false
+			return
+		}
+	}
+}
+
+// graph returns the graph of pairs with candles of the bar, of every bar when the bar is empty.
+// Pairs with more candles are preferred among equally short paths.
+func (rep *CrossRateRepository) graph(bar string) (*conversion.Graph, error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if !rep.loaded {
+		stats, err := rep.candles.FetchPairStats()
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(stats, func(i, j int) bool { return stats[i].Count > stats[j].Count })
+
+		rep.known = make(map[string]bool)
+		rep.bars = make(map[string][]string)
+		rep.graphs = map[string]*conversion.Graph{"": conversion.NewGraph(nil)}
+		for _, s := range stats {
+			rep.known[s.Pair+"/"+s.Bar] = true
+			rep.bars[s.Pair] = append(rep.bars[s.Pair], s.Bar)
+			if rep.graphs[s.Bar] == nil {
+				rep.graphs[s.Bar] = conversion.NewGraph(nil)
+			}
+			rep.graphs[s.Bar].Add(s.Pair)
+			rep.graphs[""].Add(s.Pair)
+		}
+		rep.loaded = true
+	}
+
+	if g, ok := rep.graphs[bar]; ok {
+		return g, nil
+	}
+	return conversion.NewGraph(nil), nil
+}
+
+// path finds the path in the graph, ErrNotFound when the currencies aren't connected
+func path(g *conversion.Graph, from, to string) (conversion.Path, error) {
+	p, err := g.Path(from, to)
+	if errors.Is(err, conversion.ErrNoPath) {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return p, err
+}
+
+// FetchCrossCandlePage pages candles of the first pair of the path and matches candles of the other pairs to them,
+// so a page may have fewer candles than the limit when some pairs miss candles
+func (rep *CrossRateRepository) FetchCrossCandlePage(query CandleQuery, cursor string, limit int) (CrossCandlePage, error) {
+	from, to, ok := strings.Cut(query.Pair, "-")
+	if !ok || from == "" || to == "" || from == to {
+		return CrossCandlePage{}, fmt.Errorf("invalid pair %q", query.Pair)
+	}
+
+	g, err := rep.graph(query.Bar)
+	if err != nil {
+		return CrossCandlePage{}, err
+	}
+	p, err := path(g, from, to)
+	if err != nil {
+		return CrossCandlePage{}, err
+	}
+
+	first := query
+	first.Pair = p[0].Pair
+	page, err := rep.candles.FetchPage(first, cursor, limit)
+	if err != nil {
+		return CrossCandlePage{}, err
+	}
+	result := CrossCandlePage{CandlePage: CandlePage{Next: page.Next}, Path: p.Pairs()}
+	if len(page.Candles) == 0 {
+		return result, nil
+	}
+
+	legs := [][]model.Candle{page.Candles}
+	for _, e := range p[1:] {
+		leg, err := rep.candles.FetchRange(CandleQuery{
+			Pair: e.Pair,
+			Bar:  query.Bar,
+			From: page.Candles[0].Timestamp,
+			To:   page.Candles[len(page.Candles)-1].Timestamp.Add(time.Microsecond),
+		})
+		if err != nil {
+			return CrossCandlePage{}, err
+		}
+		legs = append(legs, leg)
+	}
+
+	result.Candles, err = p.Candles(query.Pair, query.Bar, legs)
+	if err != nil {
+		return CrossCandlePage{}, err
+	}
+	return result, nil
+}
+
+// FetchCrossRate converts through pairs with a ticker or stored candles
+func (rep *CrossRateRepository) FetchCrossRate(from, to string) (CrossRate, error) {
+	candleGraph, err := rep.graph("")
+	if err != nil {
+		return CrossRate{}, err
+	}
+
+	// tickers are live prices, their pairs are preferred
+	tickers := make(map[string]model.Ticker)
+	g := conversion.NewGraph(nil)
+	for _, ticker := range rep.tickers.All() {
+		tickers[ticker.Pair] = ticker
+		g.Add(ticker.Pair)
+	}
+	for _, pair := range candleGraph.Pairs() {
+		g.Add(pair)
+	}
+
+	return rep.rate(g, from, to, func(pair string) (int64, time.Time, error) {
+		if ticker, ok := tickers[pair]; ok {
+			return ticker.Last, ticker.Timestamp, nil
+		}
+		return rep.latestClose(pair)
+	})
+}
+
+// latestClose returns the close of the newest candle of the pair among its bars
+func (rep *CrossRateRepository) latestClose(pair string) (int64, time.Time, error) {
+	rep.mu.Lock()
+	bars := rep.bars[pair]
+	rep.mu.Unlock()
+
+	var latest model.Candle
+	for _, bar := range bars {
+		candles, err := rep.candles.FetchLatest(pair, bar, 1)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		if len(candles) > 0 && candles[0].Timestamp.After(latest.Timestamp) {
+			latest = candles[0]
+		}
+	}
+	if latest.Timestamp.IsZero() {
+		return 0, time.Time{}, fmt.Errorf("%w: no %s prices", ErrNotFound, pair)
+	}
+	return latest.Close, latest.Timestamp, nil
+}
+
+func (rep *CrossRateRepository) FetchCrossRateAt(from, to, bar string, at time.Time) (CrossRate, error) {
+	g, err := rep.graph(bar)
+	if err != nil {
+		return CrossRate{}, err
+	}
+
+	return rep.rate(g, from, to, func(pair string) (int64, time.Time, error) {
+		candle, err := rep.candles.FetchAtOrBefore(pair, bar, at)
+		if errors.Is(err, ErrNotFound) {
+			return 0, time.Time{}, fmt.Errorf("%w: no %s %s candles at %s", ErrNotFound, pair, bar, at.UTC().Format(time.RFC3339))
+		}
+		return candle.Close, candle.Timestamp, err
+	})
+}
+
+// rate converts by prices of the path pairs
+func (rep *CrossRateRepository) rate(g *conversion.Graph, from, to string, pairPrice func(pair string) (int64, time.Time, error)) (CrossRate, error) {
+	p, err := path(g, from, to)
+	if err != nil {
+		return CrossRate{}, err
+	}
+
+	result := CrossRate{From: from, To: to, Path: p.Pairs()}
+	prices := make([]int64, 0, len(p))
+	for _, e := range p {
+		value, ts, err := pairPrice(e.Pair)
+		if err != nil {
+			return CrossRate{}, err
+		}
+		prices = append(prices, value)
+		if result.Timestamp.IsZero() || ts.Before(result.Timestamp) {
+			result.Timestamp = ts
+		}
+	}
+
+	result.Rate, err = p.Rate(prices)
+	if errors.Is(err, conversion.ErrZeroPrice) {
+		return CrossRate{}, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if err != nil {
+		return CrossRate{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package store_test
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"cur/internal/store/memory"
+	"cur/internal/store/storetest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCrossRateRepository(t *testing.T) (*store.CrossRateRepository, *memory.CandleRepository, *store.TickerRepository) {
+	candles := memory.NewCandleRepository()
+	tickers := store.NewTickerRepository()
+	return store.NewCrossRateRepository(candles, tickers), candles, tickers
+}
+
+func TestCrossRateRepository_FetchCrossCandlePage(t *testing.T) {
+	rep, candles, _ := newCrossRateRepository(t)
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	var stored []model.Candle
+	for i := range 3 {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		stored = append(stored, storetest.Candle("ETH-USDT", "1H", ts, 30+int64(i)), storetest.Candle("BTC-USDT", "1H", ts, 500))
+	}
+	stored = append(stored, storetest.Candle("SOL-USDT", "1D", start, 2))
+	require.NoError(t, candles.InsertCandles(&stored))
+
+	page, err := rep.FetchCrossCandlePage(store.CandleQuery{Pair: "ETH-BTC", Bar: "1H"}, "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ETH-USDT", "BTC-USDT"}, page.Path)
+	require.Len(t, page.Candles, 2)
+	assert.NotEmpty(t, page.Next)
+	// 3000 / 50000 at the open and 3010 / 50010 at the close
+	assert.Equal(t, int64(6_000_000), page.Candles[0].Open)
+	assert.Equal(t, int64(6_018_796), page.Candles[0].Close)
+	assert.Equal(t, "ETH-BTC", page.Candles[0].Pair)
+
+	page, err = rep.FetchCrossCandlePage(store.CandleQuery{Pair: "ETH-BTC", Bar: "1H"}, page.Next, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Candles, 1)
+	assert.True(t, start.Add(2*time.Hour).Equal(page.Candles[0].Timestamp))
+	assert.Empty(t, page.Next)
+
+	_, err = rep.FetchCrossCandlePage(store.CandleQuery{Pair: "SOL-BTC", Bar: "1H"}, "", 2)
+	assert.ErrorIs(t, err, store.ErrNotFound, "SOL has 1D candles only")
+
+	// the cached graph is rebuilt when candles of a new bar appear
+	more := []model.Candle{storetest.Candle("BTC-USDT", "1D", start, 500)}
+	require.NoError(t, candles.InsertCandles(&more))
+	page, err = rep.FetchCrossCandlePage(store.CandleQuery{Pair: "SOL-BTC", Bar: "1D"}, "", 2)
+	require.NoError(t, err)
+	assert.Len(t, page.Candles, 1)
+
+	_, err = rep.FetchCrossCandlePage(store.CandleQuery{Pair: "BTC", Bar: "1H"}, "", 2)
+	assert.Error(t, err)
+}
+
+func TestCrossRateRepository_FetchCrossRate(t *testing.T) {
+	rep, candles, tickers := newCrossRateRepository(t)
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	stored := []model.Candle{
+		{Pair: "TON-BTC", Bar: "1H", Timestamp: start, Close: 10_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, Close: 50_000 * 100_000_000},
+		{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Hour), Close: 60_000 * 100_000_000},
+	}
+	require.NoError(t, candles.InsertCandles(&stored))
+
+	rate, err := rep.FetchCrossRate("TON", "USDT")
+	require.NoError(t, err)
+	assert.Equal(t, store.CrossRate{From: "TON", To: "USDT", Rate: 6 * 100_000_000, Path: []string{"TON-BTC", "BTC-USDT"}, Timestamp: start}, rate)
+
+	// tickers are preferred to candles
+	tickers.Set(model.Ticker{Pair: "BTC-USDT", Last: 70_000 * 100_000_000, Timestamp: start.Add(2 * time.Hour)})
+	rate, err = rep.FetchCrossRate("USDT", "BTC")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_429), rate.Rate)
+	assert.True(t, start.Add(2*time.Hour).Equal(rate.Timestamp))
+
+	rate, err = rep.FetchCrossRateAt("TON", "USDT", "1H", start.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5*100_000_000), rate.Rate)
+
+	_, err = rep.FetchCrossRateAt("TON", "USDT", "1H", start.Add(-time.Minute))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = rep.FetchCrossRate("TON", "EUR")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -1,15 +1,21 @@
 package store
 
 import (
+	"context"
+	"cur/internal/infrastructure/tracing"
 	"cur/internal/model"
 	"cur/internal/service/okx/response"
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CurrencyRepository struct {
-	db *sql.DB
+	db  *sql.DB
+	ctx context.Context
 }
 
 func NewCurrencyRepository(db *sql.DB) *CurrencyRepository {
@@ -18,18 +24,35 @@ func NewCurrencyRepository(db *sql.DB) *CurrencyRepository {
 	}
 }
 
-func (rep *CurrencyRepository) InsertOrUpdateCurrencies(currencies *[]response.CurrencyResponseData) error {
+// WithContext returns the repository running queries with ctx, their spans are children of the span of ctx
+func (rep *CurrencyRepository) WithContext(ctx context.Context) CurrencyStore {
+	return &CurrencyRepository{db: rep.db, ctx: ctx}
+}
+
+// start starts the span of the repository operation
+func (rep *CurrencyRepository) start(operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := rep.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracing.Start(ctx, "CurrencyRepository."+operation, append(attributes, dbSystem)...)
+}
+
+func (rep *CurrencyRepository) InsertOrUpdateCurrencies(currencies *[]response.CurrencyResponseData) (err error) {
+	ctx, span := rep.start("InsertOrUpdateCurrencies", attribute.Int("currencies", len(*currencies)))
+	defer func() { tracing.End(span, err) }()
+
 	query := strings.Join([]string{"INSERT INTO currencies (code, chain, can_deposit, can_withdraw)	VALUES ($1, $2, $3, $4)",
 		"ON CONFLICT (code, chain)",
 		"DO UPDATE SET can_deposit = $3, can_withdraw = $4;"}, " ")
 
-	tx, err := rep.db.Begin()
+	tx, err := rep.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	for _, currency := range *currencies {
-		_, err := tx.Exec(query, currency.Ccy, currency.Chain, currency.CanDep, currency.CanWd)
+		_, err := tx.ExecContext(ctx, query, currency.Ccy, currency.Chain, currency.CanDep, currency.CanWd)
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("failed to insert/update currency: %w", err)
@@ -44,16 +67,18 @@ func (rep *CurrencyRepository) InsertOrUpdateCurrencies(currencies *[]response.C
 	return nil
 }
 
-func (rep *CurrencyRepository) FetchAll() ([]model.Currency, error) {
-	query := "SELECT * FROM currencies"
+func (rep *CurrencyRepository) FetchAll() (currencies []model.Currency, err error) {
+	ctx, span := rep.start("FetchAll")
+	defer func() { tracing.End(span, err) }()
+
+	query := "SELECT id, code, chain, can_deposit, can_withdraw FROM currencies ORDER BY id"
 
-	rows, err := rep.db.Query(query)
+	rows, err := rep.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close() // Ensure rows are closed after function execution
 
-	var currencies []model.Currency
 	for rows.Next() {
 		var currency model.Currency
 		err := rows.Scan(&currency.Id, &currency.Code, &currency.Chain, &currency.CanDeposit, &currency.CanWithdraw)
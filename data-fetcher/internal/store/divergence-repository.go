@@ -0,0 +1,116 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DivergenceQuery selects divergences of the pair and source (of every one when empty) with From <= observed_at < To.
+// Zero From or To means the range is unbounded on that side.
+type DivergenceQuery struct {
+	Pair   string
+	Source string
+	From   time.Time
+	To     time.Time
+}
+
+// DivergenceRepository spreads between OKX and reference venues and events of persistent spreads
+type DivergenceRepository struct {
+	db *sql.DB
+}
+
+func NewDivergenceRepository(db *sql.DB) *DivergenceRepository {
+	return &DivergenceRepository{db: db}
+}
+
+// InsertDivergences stores all divergences or none of them
+func (rep *DivergenceRepository) InsertDivergences(divergences []model.Divergence) error {
+	if len(divergences) == 0 {
+		return nil
+	}
+
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, d := range divergences {
+		_, err := tx.Exec("INSERT INTO price_divergences (pair, source, okx_price, reference_price, spread, net_spread, observed_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			d.Pair, d.Source, d.OkxPrice, d.ReferencePrice, d.Spread, d.NetSpread, d.ObservedAt)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert divergence: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (rep *DivergenceRepository) FetchDivergences(query DivergenceQuery, limit int) ([]model.Divergence, error) {
+	rows, err := rep.db.Query("SELECT id, pair, source, okx_price, reference_price, spread, net_spread, observed_at "+
+		"FROM price_divergences WHERE ($1::TEXT = '' OR pair = $1) AND ($2::TEXT = '' OR source = $2) "+
+		"AND ($3::TIMESTAMPTZ IS NULL OR observed_at >= $3) AND ($4::TIMESTAMPTZ IS NULL OR observed_at < $4) "+
+		"ORDER BY observed_at DESC, id DESC LIMIT $5",
+		query.Pair, query.Source, nullTime(query.From), nullTime(query.To), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var divergences []model.Divergence
+	for rows.Next() {
+		var d model.Divergence
+		if err := rows.Scan(&d.Id, &d.Pair, &d.Source, &d.OkxPrice, &d.ReferencePrice, &d.Spread, &d.NetSpread, &d.ObservedAt); err != nil {
+			return nil, err
+		}
+		divergences = append(divergences, d)
+	}
+
+	return divergences, rows.Err()
+}
+
+// InsertDivergenceEvent stores the event, returns it with the assigned id
+func (rep *DivergenceRepository) InsertDivergenceEvent(event model.DivergenceEvent) (model.DivergenceEvent, error) {
+	err := rep.db.QueryRow("INSERT INTO divergence_events "+
+		"(pair, source, okx_price, reference_price, spread, net_spread, threshold, started_at, triggered_at) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+		event.Pair, event.Source, event.OkxPrice, event.ReferencePrice, event.Spread, event.NetSpread, event.Threshold,
+		event.StartedAt, event.TriggeredAt,
+	).Scan(&event.Id)
+	if err != nil {
+		return model.DivergenceEvent{}, fmt.Errorf("failed to insert divergence event: %w", err)
+	}
+	return event, nil
+}
+
+func (rep *DivergenceRepository) FetchDivergenceEvents(pair string, limit int) ([]model.DivergenceEvent, error) {
+	rows, err := rep.db.Query("SELECT id, pair, source, okx_price, reference_price, spread, net_spread, threshold, "+
+		"started_at, triggered_at FROM divergence_events WHERE $1::TEXT = '' OR pair = $1 "+
+		"ORDER BY triggered_at DESC, id DESC LIMIT $2", pair, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.DivergenceEvent
+	for rows.Next() {
+		var e model.DivergenceEvent
+		err := rows.Scan(&e.Id, &e.Pair, &e.Source, &e.OkxPrice, &e.ReferencePrice, &e.Spread, &e.NetSpread, &e.Threshold,
+			&e.StartedAt, &e.TriggeredAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// nullTime maps zero time to NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
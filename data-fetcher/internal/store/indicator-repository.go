@@ -0,0 +1,132 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IndicatorPage candles of the page with their indicator values
+type IndicatorPage struct {
+	Rows []model.CandleIndicators
+	Next string
+}
+
+// IndicatorRepository precomputed indicator values aligned with stored candles
+type IndicatorRepository struct {
+	db      *sql.DB
+	candles *CandleRepository
+}
+
+func NewIndicatorRepository(db *sql.DB) *IndicatorRepository {
+	return &IndicatorRepository{db: db, candles: NewCandleRepository(db)}
+}
+
+// InsertIndicatorValues upserts values in one transaction
+func (rep *IndicatorRepository) InsertIndicatorValues(values []model.IndicatorValue) error {
+	query := strings.Join([]string{"INSERT INTO indicator_values (pair, bar, indicator, component, timestamp, value)",
+		"VALUES ($1, $2, $3, $4, $5, $6)",
+		"ON CONFLICT (pair, bar, indicator, component, timestamp)",
+		"DO UPDATE SET value = EXCLUDED.value",
+	}, " ")
+
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to insert/update indicator values: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, v := range values {
+		if _, err := stmt.Exec(v.Pair, v.Bar, v.Indicator, v.Component, v.Timestamp, v.Value); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert/update indicator values: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// LastIndicatorTimestamp returns the timestamp of the latest stored value of the indicator or ErrNotFound
+func (rep *IndicatorRepository) LastIndicatorTimestamp(pair, bar, indicator string) (time.Time, error) {
+	var last sql.NullTime
+	err := rep.db.QueryRow("SELECT MAX(timestamp) FROM indicator_values WHERE pair=$1 AND bar=$2 AND indicator=$3", pair, bar, indicator).Scan(&last)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !last.Valid {
+		return time.Time{}, ErrNotFound
+	}
+	return last.Time, nil
+}
+
+// FetchIndicatorRange returns candles of the query range with values of the indicators, ordered by timestamp
+func (rep *IndicatorRepository) FetchIndicatorRange(query CandleQuery, indicators []string) ([]model.CandleIndicators, error) {
+	candles, err := rep.candles.FetchRange(query)
+	if err != nil {
+		return nil, err
+	}
+	return rep.attach(candles, indicators)
+}
+
+// FetchIndicatorPage is FetchIndicatorRange with keyset pagination of CandleRepository.FetchPage
+func (rep *IndicatorRepository) FetchIndicatorPage(query CandleQuery, indicators []string, cursor string, limit int) (IndicatorPage, error) {
+	page, err := rep.candles.FetchPage(query, cursor, limit)
+	if err != nil {
+		return IndicatorPage{}, err
+	}
+
+	rows, err := rep.attach(page.Candles, indicators)
+	if err != nil {
+		return IndicatorPage{}, err
+	}
+	return IndicatorPage{Rows: rows, Next: page.Next}, nil
+}
+
+// attach fetches values of the indicators within the candles time span
+func (rep *IndicatorRepository) attach(candles []model.Candle, indicators []string) ([]model.CandleIndicators, error) {
+	result := make([]model.CandleIndicators, 0, len(candles))
+	index := make(map[int64]int, len(candles))
+	for _, c := range candles {
+		index[c.Timestamp.UnixMicro()] = len(result)
+		result = append(result, model.CandleIndicators{Candle: c, Values: map[string]int64{}})
+	}
+	if len(candles) == 0 || len(indicators) == 0 {
+		return result, nil
+	}
+
+	first, last := candles[0], candles[len(candles)-1]
+	rows, err := rep.db.Query(
+		"SELECT timestamp, indicator, component, value FROM indicator_values "+
+			"WHERE pair=$1 AND bar=$2 AND timestamp >= $3 AND timestamp <= $4 AND indicator = ANY($5)",
+		first.Pair, first.Bar, first.Timestamp, last.Timestamp, pq.Array(indicators),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v model.IndicatorValue
+		if err := rows.Scan(&v.Timestamp, &v.Indicator, &v.Component, &v.Value); err != nil {
+			return nil, err
+		}
+		if i, ok := index[v.Timestamp.UnixMicro()]; ok {
+			result[i].Values[model.IndicatorKey(v.Indicator, v.Component)] = v.Value
+		}
+	}
+
+	return result, rows.Err()
+}
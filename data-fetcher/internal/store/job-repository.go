@@ -0,0 +1,128 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobRepository runs of scheduled jobs and paused jobs
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+const jobRunColumns = "id, job, trigger, status, started_at, finished_at, error, items"
+
+// StartJobRun stores the run, returns it with the assigned id
+func (rep *JobRepository) StartJobRun(run model.JobRun) (model.JobRun, error) {
+	err := rep.db.QueryRow("INSERT INTO job_runs (job, trigger, status, started_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		run.Job, run.Trigger, run.Status, run.StartedAt).Scan(&run.Id)
+	if err != nil {
+		return model.JobRun{}, fmt.Errorf("failed to insert job run: %w", err)
+	}
+	return run, nil
+}
+
+// FinishJobRun sets the status, finish time, error and items of the run or returns ErrNotFound
+func (rep *JobRepository) FinishJobRun(run model.JobRun) error {
+	result, err := rep.db.Exec("UPDATE job_runs SET status = $2, finished_at = $3, error = $4, items = $5 WHERE id = $1",
+		run.Id, run.Status, run.FinishedAt, run.Error, run.Items)
+	if err != nil {
+		return fmt.Errorf("failed to finish job run: %w", err)
+	}
+	finished, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if finished == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// InterruptJobRuns marks runs left running as interrupted at the time, returns how many there were
+func (rep *JobRepository) InterruptJobRuns(at time.Time) (int, error) {
+	result, err := rep.db.Exec("UPDATE job_runs SET status = $1, finished_at = $2 WHERE status = $3",
+		model.JobInterrupted, at, model.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to interrupt job runs: %w", err)
+	}
+	interrupted, err := result.RowsAffected()
+	return int(interrupted), err
+}
+
+// LastJobRun returns the latest started run of the job or ErrNotFound
+func (rep *JobRepository) LastJobRun(job string) (model.JobRun, error) {
+	run, err := scanJobRun(rep.db.QueryRow("SELECT "+jobRunColumns+" FROM job_runs WHERE job = $1 ORDER BY started_at DESC, id DESC LIMIT 1", job))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.JobRun{}, ErrNotFound
+	}
+	return run, err
+}
+
+// FetchJobRuns returns the latest runs of the job (of every job when empty), newest first
+func (rep *JobRepository) FetchJobRuns(job string, limit int) ([]model.JobRun, error) {
+	rows, err := rep.db.Query("SELECT "+jobRunColumns+" FROM job_runs WHERE ($1::TEXT = '' OR job = $1) "+
+		"ORDER BY started_at DESC, id DESC LIMIT $2", job, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []model.JobRun
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func scanJobRun(row interface{ Scan(dest ...any) error }) (model.JobRun, error) {
+	var run model.JobRun
+	var finishedAt sql.NullTime
+	if err := row.Scan(&run.Id, &run.Job, &run.Trigger, &run.Status, &run.StartedAt, &finishedAt, &run.Error, &run.Items); err != nil {
+		return model.JobRun{}, err
+	}
+	run.FinishedAt = finishedAt.Time
+	return run, nil
+}
+
+// SetJobPaused pauses or resumes scheduled runs of the job
+func (rep *JobRepository) SetJobPaused(job string, paused bool, at time.Time) error {
+	_, err := rep.db.Exec("INSERT INTO jobs (name, paused, updated_at) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (name) DO UPDATE SET paused = EXCLUDED.paused, updated_at = EXCLUDED.updated_at", job, paused, at)
+	if err != nil {
+		return fmt.Errorf("failed to pause job: %w", err)
+	}
+	return nil
+}
+
+// PausedJobs returns names of paused jobs ordered by name
+func (rep *JobRepository) PausedJobs() ([]string, error) {
+	rows, err := rep.db.Query("SELECT name FROM jobs WHERE paused ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []string
+	for rows.Next() {
+		var job string
+		if err := rows.Scan(&job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
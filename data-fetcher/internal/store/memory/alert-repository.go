@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AlertRepository in-memory counterpart of store.AlertRepository
+type AlertRepository struct {
+	mu          sync.RWMutex
+	rules       map[int64]model.AlertRule
+	events      []model.AlertEvent
+	nextRuleId  int64
+	nextEventId int64
+}
+
+var _ store.AlertStore = (*AlertRepository)(nil)
+
+func NewAlertRepository() *AlertRepository {
+	return &AlertRepository{rules: make(map[int64]model.AlertRule)}
+}
+
+func (rep *AlertRepository) InsertAlertRule(rule model.AlertRule) (model.AlertRule, error) {
+	for _, column := range []struct {
+		name, value string
+		max         int
+	}{
+		{"name", rule.Name, 100},
+		{"pair", rule.Pair, 10},
+		{"bar", rule.Bar, 5},
+		{"kind", string(rule.Kind), 16},
+		{"direction", string(rule.Direction), 8},
+		{"indicator", rule.Indicator, 48},
+	} {
+		if err := checkLength(column.name, column.value, column.max); err != nil {
+			return model.AlertRule{}, fmt.Errorf("failed to insert alert rule: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.nextRuleId++
+	rule.Id = rep.nextRuleId
+	rule.Window = rule.Window.Truncate(time.Millisecond)
+	rule.Cooldown = rule.Cooldown.Truncate(time.Millisecond)
+	rule.Channels = slices.Clone(rule.Channels)
+	if rule.Channels == nil {
+		rule.Channels = []string{}
+	}
+	rule.LastTriggeredAt = time.Time{}
+	rule.CreatedAt = normalizeTime(time.Now())
+	rep.rules[rule.Id] = rule
+
+	return rule, nil
+}
+
+func (rep *AlertRepository) FetchAlertRules() ([]model.AlertRule, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	rules := make([]model.AlertRule, 0, len(rep.rules))
+	for _, rule := range rep.rules {
+		rule.Channels = slices.Clone(rule.Channels)
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Id < rules[j].Id })
+
+	return rules, nil
+}
+
+func (rep *AlertRepository) FetchAlertRule(id int64) (model.AlertRule, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	rule, ok := rep.rules[id]
+	if !ok {
+		return model.AlertRule{}, store.ErrNotFound
+	}
+	rule.Channels = slices.Clone(rule.Channels)
+	return rule, nil
+}
+
+func (rep *AlertRepository) DeleteAlertRule(id int64) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if _, ok := rep.rules[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(rep.rules, id)
+	rep.events = slices.DeleteFunc(rep.events, func(e model.AlertEvent) bool { return e.RuleId == id })
+
+	return nil
+}
+
+func (rep *AlertRepository) InsertAlertEvent(event model.AlertEvent) (model.AlertEvent, error) {
+	if err := checkLength("pair", event.Pair, 10); err != nil {
+		return model.AlertEvent{}, fmt.Errorf("failed to insert alert event: %w", err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rule, ok := rep.rules[event.RuleId]
+	if !ok {
+		return model.AlertEvent{}, store.ErrNotFound
+	}
+
+	event.TriggeredAt = normalizeTime(event.TriggeredAt)
+	rule.LastTriggeredAt = event.TriggeredAt
+	rep.rules[rule.Id] = rule
+
+	rep.nextEventId++
+	event.Id = rep.nextEventId
+	rep.events = append(rep.events, event)
+
+	return event, nil
+}
+
+func (rep *AlertRepository) FetchAlertEvents(ruleId int64, limit int) ([]model.AlertEvent, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var events []model.AlertEvent
+	for _, e := range rep.events {
+		if ruleId == 0 || e.RuleId == ruleId {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].TriggeredAt.Equal(events[j].TriggeredAt) {
+			return events[i].TriggeredAt.After(events[j].TriggeredAt)
+		}
+		return events[i].Id > events[j].Id
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+func (rep *AlertRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.rules = make(map[int64]model.AlertRule)
+	rep.events = nil
+}
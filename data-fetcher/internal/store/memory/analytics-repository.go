@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type volatilityKey struct {
+	pair      string
+	bar       string
+	window    int
+	estimator model.VolatilityEstimator
+	timestamp int64
+}
+
+type correlationKey struct {
+	bar       string
+	window    int
+	method    model.CorrelationMethod
+	timestamp int64
+	pairA     string
+	pairB     string
+}
+
+// AnalyticsRepository in-memory counterpart of store.AnalyticsRepository
+type AnalyticsRepository struct {
+	mu           sync.RWMutex
+	volatilities map[volatilityKey]model.Volatility
+	correlations map[correlationKey]model.Correlation
+}
+
+var _ store.AnalyticsStore = (*AnalyticsRepository)(nil)
+
+func NewAnalyticsRepository() *AnalyticsRepository {
+	return &AnalyticsRepository{
+		volatilities: make(map[volatilityKey]model.Volatility),
+		correlations: make(map[correlationKey]model.Correlation),
+	}
+}
+
+// InsertVolatilities upserts values, either all of them or none like the transaction in postgres
+func (rep *AnalyticsRepository) InsertVolatilities(values []model.Volatility) error {
+	for _, v := range values {
+		if err := checkSeries(v.Pair, v.Bar); err != nil {
+			return fmt.Errorf("failed to insert/update analytics: %w", err)
+		}
+		if err := checkLength("estimator", string(v.Estimator), 16); err != nil {
+			return fmt.Errorf("failed to insert/update analytics: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, v := range values {
+		v.Timestamp = normalizeTime(v.Timestamp)
+		rep.volatilities[volatilityKey{v.Pair, v.Bar, v.Window, v.Estimator, v.Timestamp.UnixMicro()}] = v
+	}
+	return nil
+}
+
+// InsertCorrelations upserts values, either all of them or none like the transaction in postgres
+func (rep *AnalyticsRepository) InsertCorrelations(values []model.Correlation) error {
+	for _, v := range values {
+		for _, err := range []error{
+			checkSeries(v.PairA, v.Bar),
+			checkLength("pair_b", v.PairB, 10),
+			checkLength("method", string(v.Method), 16),
+		} {
+			if err != nil {
+				return fmt.Errorf("failed to insert/update analytics: %w", err)
+			}
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, v := range values {
+		v.Timestamp = normalizeTime(v.Timestamp)
+		rep.correlations[correlationKey{v.Bar, v.Window, v.Method, v.Timestamp.UnixMicro(), v.PairA, v.PairB}] = v
+	}
+	return nil
+}
+
+// checkSeries mimics the pair and bar columns
+func checkSeries(pair, bar string) error {
+	if err := checkLength("pair", pair, 10); err != nil {
+		return err
+	}
+	return checkLength("bar", bar, 5)
+}
+
+func (rep *AnalyticsRepository) LastVolatilityTimestamp(pair, bar string, window int) (time.Time, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var last time.Time
+	for _, v := range rep.volatilities {
+		if v.Pair == pair && v.Bar == bar && v.Window == window && v.Timestamp.After(last) {
+			last = v.Timestamp
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, store.ErrNotFound
+	}
+	return last, nil
+}
+
+func (rep *AnalyticsRepository) LastCorrelationTimestamp(pairA, pairB, bar string, window int) (time.Time, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var last time.Time
+	for _, v := range rep.correlations {
+		if v.PairA == pairA && v.PairB == pairB && v.Bar == bar && v.Window == window && v.Timestamp.After(last) {
+			last = v.Timestamp
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, store.ErrNotFound
+	}
+	return last, nil
+}
+
+func (rep *AnalyticsRepository) FetchVolatilities(query store.VolatilityQuery, limit int) ([]model.Volatility, error) {
+	rep.mu.RLock()
+	var values []model.Volatility
+	for _, v := range rep.volatilities {
+		if (query.Pair == "" || v.Pair == query.Pair) && (query.Bar == "" || v.Bar == query.Bar) &&
+			(query.Window == 0 || v.Window == query.Window) && (query.Estimator == "" || v.Estimator == query.Estimator) &&
+			(query.From.IsZero() || !v.Timestamp.Before(query.From)) && (query.To.IsZero() || v.Timestamp.Before(query.To)) {
+			values = append(values, v)
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(values, func(i, j int) bool {
+		a, b := values[i], values[j]
+		switch {
+		case !a.Timestamp.Equal(b.Timestamp):
+			return a.Timestamp.After(b.Timestamp)
+		case a.Pair != b.Pair:
+			return a.Pair < b.Pair
+		case a.Window != b.Window:
+			return a.Window < b.Window
+		}
+		return a.Estimator < b.Estimator
+	})
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	return values, nil
+}
+
+func (rep *AnalyticsRepository) FetchCorrelationMatrix(bar string, window int, method model.CorrelationMethod, at time.Time) ([]model.Correlation, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	latest := make(map[[2]string]model.Correlation)
+	for _, v := range rep.correlations {
+		if v.Bar != bar || v.Window != window || v.Method != method || (!at.IsZero() && v.Timestamp.After(at)) {
+			continue
+		}
+		key := [2]string{v.PairA, v.PairB}
+		if last, ok := latest[key]; !ok || v.Timestamp.After(last.Timestamp) {
+			latest[key] = v
+		}
+	}
+
+	values := make([]model.Correlation, 0, len(latest))
+	for _, v := range latest {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].PairA != values[j].PairA {
+			return values[i].PairA < values[j].PairA
+		}
+		return values[i].PairB < values[j].PairB
+	})
+	return values, nil
+}
+
+func (rep *AnalyticsRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.volatilities = make(map[volatilityKey]model.Volatility)
+	rep.correlations = make(map[correlationKey]model.Correlation)
+}
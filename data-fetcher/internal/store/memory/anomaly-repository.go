@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnomalyRepository in-memory counterpart of store.AnomalyRepository
+type AnomalyRepository struct {
+	mu        sync.RWMutex
+	anomalies []model.Anomaly
+	nextId    int64
+}
+
+var _ store.AnomalyStore = (*AnomalyRepository)(nil)
+
+func NewAnomalyRepository() *AnomalyRepository {
+	return &AnomalyRepository{}
+}
+
+func (rep *AnomalyRepository) InsertAnomalies(anomalies []model.Anomaly) ([]model.Anomaly, error) {
+	for _, a := range anomalies {
+		if err := checkLength("pair", a.Pair, 10); err != nil {
+			return nil, fmt.Errorf("failed to insert anomaly: %w", err)
+		}
+		if err := checkLength("bar", a.Bar, 5); err != nil {
+			return nil, fmt.Errorf("failed to insert anomaly: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	var inserted []model.Anomaly
+	for _, a := range anomalies {
+		a.Timestamp = normalizeTime(a.Timestamp)
+		if rep.quarantined(a) {
+			continue
+		}
+		rep.nextId++
+		a.Id = rep.nextId
+		a.DetectedAt = normalizeTime(a.DetectedAt)
+		if a.Status == "" {
+			a.Status = model.AnomalyPending
+		}
+		a.Candle, a.Trade = copyCandle(a.Candle), copyTrade(a.Trade)
+		rep.anomalies = append(rep.anomalies, a)
+		inserted = append(inserted, a)
+	}
+	return inserted, nil
+}
+
+// quarantined mimics the unique constraint of the anomalies table
+func (rep *AnomalyRepository) quarantined(a model.Anomaly) bool {
+	for _, stored := range rep.anomalies {
+		if stored.Source == a.Source && stored.Pair == a.Pair && stored.Bar == a.Bar &&
+			stored.Timestamp.Equal(a.Timestamp) && tradeId(stored) == tradeId(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func tradeId(a model.Anomaly) string {
+	if a.Trade == nil {
+		return ""
+	}
+	return a.Trade.TradeId
+}
+
+// copyCandle and copyTrade keep stored payloads apart from callers, as JSON columns do
+func copyCandle(candle *model.Candle) *model.Candle {
+	if candle == nil {
+		return nil
+	}
+	c := *candle
+	c.Timestamp = normalizeTime(c.Timestamp)
+	return &c
+}
+
+func copyTrade(trade *model.Trade) *model.Trade {
+	if trade == nil {
+		return nil
+	}
+	t := *trade
+	t.Timestamp = normalizeTime(t.Timestamp)
+	return &t
+}
+
+func (rep *AnomalyRepository) FetchAnomalies(query store.AnomalyQuery, limit int) ([]model.Anomaly, error) {
+	rep.mu.RLock()
+	var anomalies []model.Anomaly
+	for _, a := range rep.anomalies {
+		if (query.Pair == "" || a.Pair == query.Pair) && (query.Kind == "" || a.Kind == query.Kind) &&
+			(query.Status == "" || a.Status == query.Status) {
+			anomalies = append(anomalies, withPayloadCopy(a))
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if !anomalies[i].DetectedAt.Equal(anomalies[j].DetectedAt) {
+			return anomalies[i].DetectedAt.After(anomalies[j].DetectedAt)
+		}
+		return anomalies[i].Id > anomalies[j].Id
+	})
+	if len(anomalies) > limit {
+		anomalies = anomalies[:limit]
+	}
+	return anomalies, nil
+}
+
+func (rep *AnomalyRepository) FetchAnomaly(id int64) (model.Anomaly, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	for _, a := range rep.anomalies {
+		if a.Id == id {
+			return withPayloadCopy(a), nil
+		}
+	}
+	return model.Anomaly{}, store.ErrNotFound
+}
+
+func (rep *AnomalyRepository) ReviewAnomaly(id int64, status model.AnomalyStatus, at time.Time) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for i := range rep.anomalies {
+		if rep.anomalies[i].Id == id && rep.anomalies[i].Status == model.AnomalyPending {
+			rep.anomalies[i].Status = status
+			rep.anomalies[i].ReviewedAt = normalizeTime(at)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func withPayloadCopy(a model.Anomaly) model.Anomaly {
+	a.Candle, a.Trade = copyCandle(a.Candle), copyTrade(a.Trade)
+	return a
+}
+
+func (rep *AnomalyRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.anomalies = nil
+}
@@ -0,0 +1,240 @@
+package memory
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type candleKey struct {
+	pair      string
+	timestamp int64
+	bar       string
+}
+
+// CandleRepository in-memory counterpart of store.CandleRepository
+type CandleRepository struct {
+	mu        sync.RWMutex
+	rows      map[candleKey]model.Candle
+	listeners []store.CandlesListener
+}
+
+var _ store.CandleStore = (*CandleRepository)(nil)
+
+func NewCandleRepository() *CandleRepository {
+	return &CandleRepository{
+		rows: make(map[candleKey]model.Candle),
+	}
+}
+
+// InsertCandles upserts candles, either all of them or none like the transaction in postgres
+func (rep *CandleRepository) InsertCandles(candles *[]model.Candle) error {
+	for _, candle := range *candles {
+		if err := checkLength("pair", candle.Pair, 10); err != nil {
+			return fmt.Errorf("failed to insert/update candles: %w", err)
+		}
+		if err := checkLength("bar", candle.Bar, 5); err != nil {
+			return fmt.Errorf("failed to insert/update candles: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	for _, candle := range *candles {
+		candle.Timestamp = normalizeTime(candle.Timestamp)
+		rep.rows[keyOf(candle)] = candle
+	}
+	listeners := rep.listeners
+	rep.mu.Unlock()
+
+	if len(*candles) > 0 {
+		for _, listener := range listeners {
+			listener(context.Background(), *candles)
+		}
+	}
+
+	return nil
+}
+
+func (rep *CandleRepository) OnInsert(listener store.CandlesListener) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.listeners = append(rep.listeners, listener)
+}
+
+// FetchAll returns candles ordered by pair, bar and timestamp
+func (rep *CandleRepository) FetchAll() ([]model.Candle, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	return rep.filter(func(model.Candle) bool { return true }), nil
+}
+
+// GetLastTsForPair getting max timestamp of the bar in milliseconds
+func (rep *CandleRepository) GetLastTsForPair(pair, bar string) (string, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var last *time.Time
+	for key, candle := range rep.rows {
+		if key.pair == pair && key.bar == bar && (last == nil || candle.Timestamp.After(*last)) {
+			last = &candle.Timestamp
+		}
+	}
+	if last == nil {
+		return "", store.ErrNotFound
+	}
+	return strconv.FormatInt(last.Round(time.Millisecond).UnixMilli(), 10), nil
+}
+
+// GetFirstTsForPair getting min timestamp of the bar in milliseconds
+func (rep *CandleRepository) GetFirstTsForPair(pair, bar string) (string, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var first *time.Time
+	for key, candle := range rep.rows {
+		if key.pair == pair && key.bar == bar && (first == nil || candle.Timestamp.Before(*first)) {
+			first = &candle.Timestamp
+		}
+	}
+	if first == nil {
+		return "", store.ErrNotFound
+	}
+	return strconv.FormatInt(first.Round(time.Millisecond).UnixMilli(), 10), nil
+}
+
+func (rep *CandleRepository) FetchRange(query store.CandleQuery) ([]model.Candle, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	return rep.filter(matchQuery(query)), nil
+}
+
+func (rep *CandleRepository) FetchLatest(pair, bar string, n int) ([]model.Candle, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	candles, _ := rep.FetchRange(store.CandleQuery{Pair: pair, Bar: bar})
+	if len(candles) > n {
+		candles = candles[len(candles)-n:]
+	}
+	return candles, nil
+}
+
+func (rep *CandleRepository) FetchAtOrBefore(pair, bar string, ts time.Time) (model.Candle, error) {
+	candles, _ := rep.FetchRange(store.CandleQuery{Pair: pair, Bar: bar, To: normalizeTime(ts).Add(time.Microsecond)})
+	if len(candles) == 0 {
+		return model.Candle{}, store.ErrNotFound
+	}
+	return candles[len(candles)-1], nil
+}
+
+func (rep *CandleRepository) FetchPage(query store.CandleQuery, cursor string, limit int) (store.CandlePage, error) {
+	if limit <= 0 {
+		return store.CandlePage{}, fmt.Errorf("invalid page limit %d", limit)
+	}
+
+	match := matchQuery(query)
+	if cursor != "" {
+		after, err := store.ParseCandleCursor(cursor)
+		if err != nil {
+			return store.CandlePage{}, err
+		}
+		inRange := match
+		match = func(candle model.Candle) bool { return inRange(candle) && candle.Timestamp.After(after) }
+	}
+
+	rep.mu.RLock()
+	candles := rep.filter(match)
+	rep.mu.RUnlock()
+
+	if len(candles) <= limit {
+		return store.CandlePage{Candles: candles}, nil
+	}
+	candles = candles[:limit]
+	return store.CandlePage{Candles: candles, Next: store.NewCandleCursor(candles[limit-1].Timestamp)}, nil
+}
+
+func (rep *CandleRepository) Iterate(ctx context.Context, query store.CandleQuery, fn func(model.Candle) error) error {
+	candles, _ := rep.FetchRange(query)
+	for _, candle := range candles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(candle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rep *CandleRepository) FetchPairStats() ([]store.PairStats, error) {
+	candles, _ := rep.FetchAll()
+
+	var stats []store.PairStats
+	for _, candle := range candles {
+		last := len(stats) - 1
+		if last < 0 || stats[last].Pair != candle.Pair || stats[last].Bar != candle.Bar {
+			stats = append(stats, store.PairStats{Pair: candle.Pair, Bar: candle.Bar, First: candle.Timestamp})
+			last++
+		}
+		stats[last].Last = candle.Timestamp
+		stats[last].Count++
+	}
+
+	return stats, nil
+}
+
+// Truncate removes all candles
+func (rep *CandleRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.rows = make(map[candleKey]model.Candle)
+}
+
+// filter returns matching candles ordered by pair, bar and timestamp, must be called under lock
+func (rep *CandleRepository) filter(match func(model.Candle) bool) []model.Candle {
+	var candles []model.Candle
+	for _, candle := range rep.rows {
+		if match(candle) {
+			candles = append(candles, candle)
+		}
+	}
+
+	sort.Slice(candles, func(i, j int) bool {
+		a, b := candles[i], candles[j]
+		if a.Pair != b.Pair {
+			return a.Pair < b.Pair
+		}
+		if a.Bar != b.Bar {
+			return a.Bar < b.Bar
+		}
+		return a.Timestamp.Before(b.Timestamp)
+	})
+
+	return candles
+}
+
+// matchQuery matches candles of the query, bounds are rounded like postgres does with TIMESTAMPTZ params
+func matchQuery(query store.CandleQuery) func(model.Candle) bool {
+	if !query.From.IsZero() {
+		query.From = normalizeTime(query.From)
+	}
+	if !query.To.IsZero() {
+		query.To = normalizeTime(query.To)
+	}
+	return func(candle model.Candle) bool {
+		return candle.Pair == query.Pair && candle.Bar == query.Bar && query.Contains(candle.Timestamp)
+	}
+}
+
+func keyOf(candle model.Candle) candleKey {
+	return candleKey{candle.Pair, candle.Timestamp.UnixMicro(), candle.Bar}
+}
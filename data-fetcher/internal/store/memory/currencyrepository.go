@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/service/okx/response"
+	"cur/internal/store"
+	"fmt"
+	"sync"
+)
+
+type currencyKey struct {
+	code  string
+	chain string
+}
+
+// CurrencyRepository in-memory counterpart of store.CurrencyRepository
+type CurrencyRepository struct {
+	mu     sync.RWMutex
+	lastId int64
+	rows   []model.Currency // ordered by id
+	index  map[currencyKey]int
+}
+
+var _ store.CurrencyStore = (*CurrencyRepository)(nil)
+
+func NewCurrencyRepository() *CurrencyRepository {
+	return &CurrencyRepository{
+		index: make(map[currencyKey]int),
+	}
+}
+
+// InsertOrUpdateCurrencies upserts currencies, either all of them or none like the transaction in postgres
+func (rep *CurrencyRepository) InsertOrUpdateCurrencies(currencies *[]response.CurrencyResponseData) error {
+	for _, currency := range *currencies {
+		if err := checkLength("code", currency.Ccy, 10); err != nil {
+			return fmt.Errorf("failed to insert/update currency: %w", err)
+		}
+		if err := checkLength("chain", currency.Chain, 100); err != nil {
+			return fmt.Errorf("failed to insert/update currency: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for _, currency := range *currencies {
+		// postgres takes the serial value even if the row is updated on conflict
+		rep.lastId++
+		key := currencyKey{currency.Ccy, currency.Chain}
+		if i, ok := rep.index[key]; ok {
+			rep.rows[i].CanDeposit = currency.CanDep
+			rep.rows[i].CanWithdraw = currency.CanWd
+			continue
+		}
+
+		rep.index[key] = len(rep.rows)
+		rep.rows = append(rep.rows, model.Currency{
+			Id:          rep.lastId,
+			Code:        currency.Ccy,
+			Chain:       currency.Chain,
+			CanDeposit:  currency.CanDep,
+			CanWithdraw: currency.CanWd,
+		})
+	}
+
+	return nil
+}
+
+func (rep *CurrencyRepository) FetchAll() ([]model.Currency, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	if len(rep.rows) == 0 {
+		return nil, nil
+	}
+	return append([]model.Currency{}, rep.rows...), nil
+}
+
+// Truncate removes all currencies, the id sequence isn't reset as with TRUNCATE in postgres
+func (rep *CurrencyRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.rows = nil
+	rep.index = make(map[currencyKey]int)
+}
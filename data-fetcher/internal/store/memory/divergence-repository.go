@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DivergenceRepository in-memory counterpart of store.DivergenceRepository
+type DivergenceRepository struct {
+	mu          sync.RWMutex
+	divergences []model.Divergence
+	events      []model.DivergenceEvent
+	nextId      int64
+	nextEventId int64
+}
+
+var _ store.DivergenceStore = (*DivergenceRepository)(nil)
+
+func NewDivergenceRepository() *DivergenceRepository {
+	return &DivergenceRepository{}
+}
+
+func (rep *DivergenceRepository) InsertDivergences(divergences []model.Divergence) error {
+	for _, d := range divergences {
+		if err := checkVenue(d.Pair, d.Source); err != nil {
+			return fmt.Errorf("failed to insert divergence: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, d := range divergences {
+		rep.nextId++
+		d.Id = rep.nextId
+		d.ObservedAt = normalizeTime(d.ObservedAt)
+		rep.divergences = append(rep.divergences, d)
+	}
+	return nil
+}
+
+// checkVenue mimics the pair and source columns of divergence tables
+func checkVenue(pair, source string) error {
+	if err := checkLength("pair", pair, 10); err != nil {
+		return err
+	}
+	return checkLength("source", source, 32)
+}
+
+func (rep *DivergenceRepository) FetchDivergences(query store.DivergenceQuery, limit int) ([]model.Divergence, error) {
+	rep.mu.RLock()
+	var divergences []model.Divergence
+	for _, d := range rep.divergences {
+		if (query.Pair == "" || d.Pair == query.Pair) && (query.Source == "" || d.Source == query.Source) &&
+			(query.From.IsZero() || !d.ObservedAt.Before(query.From)) && (query.To.IsZero() || d.ObservedAt.Before(query.To)) {
+			divergences = append(divergences, d)
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(divergences, func(i, j int) bool {
+		if !divergences[i].ObservedAt.Equal(divergences[j].ObservedAt) {
+			return divergences[i].ObservedAt.After(divergences[j].ObservedAt)
+		}
+		return divergences[i].Id > divergences[j].Id
+	})
+	if len(divergences) > limit {
+		divergences = divergences[:limit]
+	}
+	return divergences, nil
+}
+
+func (rep *DivergenceRepository) InsertDivergenceEvent(event model.DivergenceEvent) (model.DivergenceEvent, error) {
+	if err := checkVenue(event.Pair, event.Source); err != nil {
+		return model.DivergenceEvent{}, fmt.Errorf("failed to insert divergence event: %w", err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.nextEventId++
+	event.Id = rep.nextEventId
+	event.StartedAt = normalizeTime(event.StartedAt)
+	event.TriggeredAt = normalizeTime(event.TriggeredAt)
+	rep.events = append(rep.events, event)
+	return event, nil
+}
+
+func (rep *DivergenceRepository) FetchDivergenceEvents(pair string, limit int) ([]model.DivergenceEvent, error) {
+	rep.mu.RLock()
+	var events []model.DivergenceEvent
+	for _, e := range rep.events {
+		if pair == "" || e.Pair == pair {
+			events = append(events, e)
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].TriggeredAt.Equal(events[j].TriggeredAt) {
+			return events[i].TriggeredAt.After(events[j].TriggeredAt)
+		}
+		return events[i].Id > events[j].Id
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (rep *DivergenceRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.divergences = nil
+	rep.events = nil
+}
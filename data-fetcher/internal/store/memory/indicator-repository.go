@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type indicatorKey struct {
+	pair      string
+	bar       string
+	indicator string
+	component string
+	timestamp int64
+}
+
+// IndicatorRepository in-memory counterpart of store.IndicatorRepository
+type IndicatorRepository struct {
+	candles *CandleRepository
+
+	mu   sync.RWMutex
+	rows map[indicatorKey]model.IndicatorValue
+}
+
+var _ store.IndicatorStore = (*IndicatorRepository)(nil)
+
+func NewIndicatorRepository(candles *CandleRepository) *IndicatorRepository {
+	return &IndicatorRepository{candles: candles, rows: make(map[indicatorKey]model.IndicatorValue)}
+}
+
+func keyOfIndicator(v model.IndicatorValue) indicatorKey {
+	return indicatorKey{v.Pair, v.Bar, v.Indicator, v.Component, v.Timestamp.UnixMicro()}
+}
+
+// InsertIndicatorValues upserts values, either all of them or none like the transaction in postgres
+func (rep *IndicatorRepository) InsertIndicatorValues(values []model.IndicatorValue) error {
+	for _, v := range values {
+		for _, column := range []struct {
+			name, value string
+			max         int
+		}{
+			{"pair", v.Pair, 10},
+			{"bar", v.Bar, 5},
+			{"indicator", v.Indicator, 32},
+			{"component", v.Component, 16},
+		} {
+			if err := checkLength(column.name, column.value, column.max); err != nil {
+				return fmt.Errorf("failed to insert/update indicator values: %w", err)
+			}
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, v := range values {
+		v.Timestamp = normalizeTime(v.Timestamp)
+		rep.rows[keyOfIndicator(v)] = v
+	}
+
+	return nil
+}
+
+func (rep *IndicatorRepository) LastIndicatorTimestamp(pair, bar, indicator string) (time.Time, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var last time.Time
+	for _, v := range rep.rows {
+		if v.Pair == pair && v.Bar == bar && v.Indicator == indicator && v.Timestamp.After(last) {
+			last = v.Timestamp
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, store.ErrNotFound
+	}
+	return last, nil
+}
+
+func (rep *IndicatorRepository) FetchIndicatorRange(query store.CandleQuery, indicators []string) ([]model.CandleIndicators, error) {
+	candles, err := rep.candles.FetchRange(query)
+	if err != nil {
+		return nil, err
+	}
+	return rep.attach(candles, indicators), nil
+}
+
+func (rep *IndicatorRepository) FetchIndicatorPage(query store.CandleQuery, indicators []string, cursor string, limit int) (store.IndicatorPage, error) {
+	page, err := rep.candles.FetchPage(query, cursor, limit)
+	if err != nil {
+		return store.IndicatorPage{}, err
+	}
+	return store.IndicatorPage{Rows: rep.attach(page.Candles, indicators), Next: page.Next}, nil
+}
+
+func (rep *IndicatorRepository) attach(candles []model.Candle, indicators []string) []model.CandleIndicators {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	result := make([]model.CandleIndicators, 0, len(candles))
+	for _, c := range candles {
+		row := model.CandleIndicators{Candle: c, Values: map[string]int64{}}
+		for _, v := range rep.rows {
+			if v.Pair == c.Pair && v.Bar == c.Bar && v.Timestamp.Equal(c.Timestamp) && slices.Contains(indicators, v.Indicator) {
+				row.Values[model.IndicatorKey(v.Indicator, v.Component)] = v.Value
+			}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+func (rep *IndicatorRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.rows = make(map[indicatorKey]model.IndicatorValue)
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobRepository in-memory counterpart of store.JobRepository
+type JobRepository struct {
+	mu     sync.RWMutex
+	runs   []model.JobRun
+	paused map[string]bool
+	nextId int64
+}
+
+var _ store.JobStore = (*JobRepository)(nil)
+
+func NewJobRepository() *JobRepository {
+	return &JobRepository{paused: map[string]bool{}}
+}
+
+func (rep *JobRepository) StartJobRun(run model.JobRun) (model.JobRun, error) {
+	if err := checkLength("job", run.Job, 64); err != nil {
+		return model.JobRun{}, fmt.Errorf("failed to insert job run: %w", err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.nextId++
+	run.Id = rep.nextId
+	run.StartedAt = normalizeTime(run.StartedAt)
+	run.FinishedAt, run.Error, run.Items = time.Time{}, "", 0
+	rep.runs = append(rep.runs, run)
+	return run, nil
+}
+
+func (rep *JobRepository) FinishJobRun(run model.JobRun) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for i := range rep.runs {
+		if rep.runs[i].Id == run.Id {
+			rep.runs[i].Status = run.Status
+			rep.runs[i].FinishedAt = normalizeTime(run.FinishedAt)
+			rep.runs[i].Error = run.Error
+			rep.runs[i].Items = run.Items
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (rep *JobRepository) InterruptJobRuns(at time.Time) (int, error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	interrupted := 0
+	for i := range rep.runs {
+		if rep.runs[i].Status == model.JobRunning {
+			rep.runs[i].Status = model.JobInterrupted
+			rep.runs[i].FinishedAt = normalizeTime(at)
+			interrupted++
+		}
+	}
+	return interrupted, nil
+}
+
+func (rep *JobRepository) LastJobRun(job string) (model.JobRun, error) {
+	runs, _ := rep.FetchJobRuns(job, 1)
+	if len(runs) == 0 || job == "" {
+		return model.JobRun{}, store.ErrNotFound
+	}
+	return runs[0], nil
+}
+
+func (rep *JobRepository) FetchJobRuns(job string, limit int) ([]model.JobRun, error) {
+	rep.mu.RLock()
+	var runs []model.JobRun
+	for _, run := range rep.runs {
+		if job == "" || run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].Id > runs[j].Id
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+func (rep *JobRepository) SetJobPaused(job string, paused bool, at time.Time) error {
+	if err := checkLength("name", job, 64); err != nil {
+		return fmt.Errorf("failed to pause job: %w", err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.paused[job] = paused
+	return nil
+}
+
+func (rep *JobRepository) PausedJobs() ([]string, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var jobs []string
+	for job, paused := range rep.paused {
+		if paused {
+			jobs = append(jobs, job)
+		}
+	}
+	slices.Sort(jobs)
+	return jobs, nil
+}
+
+func (rep *JobRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.runs = nil
+	rep.paused = map[string]bool{}
+}
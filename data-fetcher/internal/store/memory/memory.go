@@ -0,0 +1,104 @@
+// Package memory provides in-memory repositories with the same semantics as
+// the postgres ones in the store package. Intended for unit tests.
+package memory
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// Store in-memory counterpart of store.Store
+type Store struct {
+	currencyRep   *CurrencyRepository
+	candleRep     *CandleRepository
+	trendRep      *TrendRepository
+	indicatorRep  *IndicatorRepository
+	alertRep      *AlertRepository
+	paperRep      *PaperRepository
+	portfolioRep  *PortfolioRepository
+	divergenceRep *DivergenceRepository
+	anomalyRep    *AnomalyRepository
+	analyticsRep  *AnalyticsRepository
+	tradeFlowRep  *TradeFlowRepository
+	jobRep        *JobRepository
+}
+
+func NewStore() *Store {
+	candleRep := NewCandleRepository()
+	return &Store{
+		currencyRep:   NewCurrencyRepository(),
+		candleRep:     candleRep,
+		trendRep:      NewTrendRepository(),
+		indicatorRep:  NewIndicatorRepository(candleRep),
+		alertRep:      NewAlertRepository(),
+		paperRep:      NewPaperRepository(),
+		portfolioRep:  NewPortfolioRepository(),
+		divergenceRep: NewDivergenceRepository(),
+		anomalyRep:    NewAnomalyRepository(),
+		analyticsRep:  NewAnalyticsRepository(),
+		tradeFlowRep:  NewTradeFlowRepository(),
+		jobRep:        NewJobRepository(),
+	}
+}
+
+func (s *Store) Currency() *CurrencyRepository {
+	return s.currencyRep
+}
+
+func (s *Store) Candle() *CandleRepository {
+	return s.candleRep
+}
+
+func (s *Store) Trend() *TrendRepository {
+	return s.trendRep
+}
+
+func (s *Store) Indicator() *IndicatorRepository {
+	return s.indicatorRep
+}
+
+func (s *Store) Alert() *AlertRepository {
+	return s.alertRep
+}
+
+func (s *Store) Paper() *PaperRepository {
+	return s.paperRep
+}
+
+func (s *Store) Portfolio() *PortfolioRepository {
+	return s.portfolioRep
+}
+
+func (s *Store) Divergence() *DivergenceRepository {
+	return s.divergenceRep
+}
+
+func (s *Store) Anomaly() *AnomalyRepository {
+	return s.anomalyRep
+}
+
+func (s *Store) Analytics() *AnalyticsRepository {
+	return s.analyticsRep
+}
+
+func (s *Store) TradeFlow() *TradeFlowRepository {
+	return s.tradeFlowRep
+}
+
+func (s *Store) Job() *JobRepository {
+	return s.jobRep
+}
+
+// checkLength mimics VARCHAR(n) constraint of the postgres schema
+func checkLength(column, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("value too long for type character varying(%d) in column %s", max, column)
+	}
+	return nil
+}
+
+// normalizeTime mimics TIMESTAMPTZ which keeps microseconds
+func normalizeTime(t time.Time) time.Time {
+	return t.Round(time.Microsecond).UTC()
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"cur/internal/store/storetest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Repositories {
+		s := NewStore()
+		return storetest.Repositories{Currency: s.Currency(), Candle: s.Candle(), Trend: s.Trend(), Indicator: s.Indicator(), Alert: s.Alert(), Paper: s.Paper(), Portfolio: s.Portfolio(), Divergence: s.Divergence(), Anomaly: s.Anomaly(), Analytics: s.Analytics(), TradeFlow: s.TradeFlow(), Job: s.Job()}
+	})
+}
@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PaperRepository in-memory counterpart of store.PaperRepository
+type PaperRepository struct {
+	mu          sync.RWMutex
+	orders      map[int64]model.PaperOrder
+	fills       []model.PaperFill
+	balances    map[string]model.PaperBalance
+	positions   map[string]model.PaperPosition
+	nextOrderId int64
+	nextFillId  int64
+}
+
+var _ store.PaperStore = (*PaperRepository)(nil)
+
+func NewPaperRepository() *PaperRepository {
+	rep := &PaperRepository{}
+	rep.Truncate()
+	return rep
+}
+
+func (rep *PaperRepository) InsertPaperOrder(order model.PaperOrder) (model.PaperOrder, error) {
+	for _, column := range []struct {
+		name, value string
+		max         int
+	}{
+		{"pair", order.Pair, 10},
+		{"side", string(order.Side), 4},
+		{"type", string(order.Type), 8},
+		{"status", string(order.Status), 10},
+		{"reason", order.Reason, 200},
+	} {
+		if err := checkLength(column.name, column.value, column.max); err != nil {
+			return model.PaperOrder{}, fmt.Errorf("failed to insert paper order: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	rep.nextOrderId++
+	order.Id = rep.nextOrderId
+	order.CreatedAt = normalizeTime(time.Now())
+	order.UpdatedAt = order.CreatedAt
+	rep.orders[order.Id] = order
+
+	return order, nil
+}
+
+func (rep *PaperRepository) FetchPaperOrder(id int64) (model.PaperOrder, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	order, ok := rep.orders[id]
+	if !ok {
+		return model.PaperOrder{}, store.ErrNotFound
+	}
+	return order, nil
+}
+
+func (rep *PaperRepository) FetchPaperOrders(status model.OrderStatus, limit int) ([]model.PaperOrder, error) {
+	orders := rep.filterOrders(func(o model.PaperOrder) bool { return status == "" || o.Status == status })
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id > orders[j].Id })
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (rep *PaperRepository) FetchOpenPaperOrders() ([]model.PaperOrder, error) {
+	orders := rep.filterOrders(func(o model.PaperOrder) bool { return o.Status == model.OrderOpen })
+	sort.Slice(orders, func(i, j int) bool { return orders[i].Id < orders[j].Id })
+	return orders, nil
+}
+
+func (rep *PaperRepository) filterOrders(match func(o model.PaperOrder) bool) []model.PaperOrder {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var orders []model.PaperOrder
+	for _, o := range rep.orders {
+		if match(o) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+func (rep *PaperRepository) ClosePaperOrder(id int64, status model.OrderStatus, reason string, at time.Time) error {
+	if err := checkLength("reason", reason, 200); err != nil {
+		return fmt.Errorf("failed to update paper order: %w", err)
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	order, ok := rep.orders[id]
+	if !ok || order.Status != model.OrderOpen {
+		return store.ErrNotFound
+	}
+	order.Status = status
+	order.Reason = reason
+	order.UpdatedAt = normalizeTime(at)
+	rep.orders[id] = order
+
+	return nil
+}
+
+func (rep *PaperRepository) InsertPaperFill(fill model.PaperFill, balances []model.PaperBalance, position model.PaperPosition) (model.PaperFill, error) {
+	if err := checkLength("trade_id", fill.TradeId, 32); err != nil {
+		return model.PaperFill{}, fmt.Errorf("failed to insert paper fill: %w", err)
+	}
+	for _, balance := range balances {
+		if err := checkBalance(balance); err != nil {
+			return model.PaperFill{}, err
+		}
+	}
+	if position.Size < 0 {
+		return model.PaperFill{}, fmt.Errorf("failed to update paper position: new row violates check constraint on size")
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	order, ok := rep.orders[fill.OrderId]
+	if !ok || order.Status != model.OrderOpen {
+		return model.PaperFill{}, store.ErrNotFound
+	}
+	fill.FilledAt = normalizeTime(fill.FilledAt)
+	order.Status = model.OrderFilled
+	order.UpdatedAt = fill.FilledAt
+	rep.orders[order.Id] = order
+
+	rep.nextFillId++
+	fill.Id = rep.nextFillId
+	rep.fills = append(rep.fills, fill)
+
+	for _, balance := range balances {
+		rep.balances[balance.Currency] = balance
+	}
+	position.UpdatedAt = normalizeTime(position.UpdatedAt)
+	rep.positions[position.Pair] = position
+
+	return fill, nil
+}
+
+func (rep *PaperRepository) FetchPaperFills(limit int) ([]model.PaperFill, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	fills := append([]model.PaperFill(nil), rep.fills...)
+	sort.Slice(fills, func(i, j int) bool {
+		if !fills[i].FilledAt.Equal(fills[j].FilledAt) {
+			return fills[i].FilledAt.After(fills[j].FilledAt)
+		}
+		return fills[i].Id > fills[j].Id
+	})
+	if len(fills) > limit {
+		fills = fills[:limit]
+	}
+
+	return fills, nil
+}
+
+func (rep *PaperRepository) SetPaperBalance(balance model.PaperBalance) error {
+	if err := checkBalance(balance); err != nil {
+		return err
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.balances[balance.Currency] = balance
+
+	return nil
+}
+
+// checkBalance mimics the column constraints of paper_balances
+func checkBalance(balance model.PaperBalance) error {
+	if err := checkLength("currency", balance.Currency, 10); err != nil {
+		return fmt.Errorf("failed to set paper balance: %w", err)
+	}
+	if balance.Amount < 0 {
+		return fmt.Errorf("failed to set paper balance: new row violates check constraint on amount")
+	}
+	return nil
+}
+
+func (rep *PaperRepository) FetchPaperBalances() ([]model.PaperBalance, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	balances := make([]model.PaperBalance, 0, len(rep.balances))
+	for _, balance := range rep.balances {
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+
+	return balances, nil
+}
+
+func (rep *PaperRepository) FetchPaperPositions() ([]model.PaperPosition, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	positions := make([]model.PaperPosition, 0, len(rep.positions))
+	for _, position := range rep.positions {
+		positions = append(positions, position)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Pair < positions[j].Pair })
+
+	return positions, nil
+}
+
+func (rep *PaperRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.orders = make(map[int64]model.PaperOrder)
+	rep.fills = nil
+	rep.balances = make(map[string]model.PaperBalance)
+	rep.positions = make(map[string]model.PaperPosition)
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PortfolioRepository in-memory counterpart of store.PortfolioRepository
+type PortfolioRepository struct {
+	mu       sync.RWMutex
+	holdings map[int64]model.Holding
+	nextId   int64
+}
+
+var _ store.PortfolioStore = (*PortfolioRepository)(nil)
+
+func NewPortfolioRepository() *PortfolioRepository {
+	rep := &PortfolioRepository{}
+	rep.Truncate()
+	return rep
+}
+
+func (rep *PortfolioRepository) InsertHoldings(holdings []model.Holding) ([]model.Holding, error) {
+	for _, h := range holdings {
+		if err := checkHolding(h); err != nil {
+			return nil, fmt.Errorf("failed to insert holding: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	inserted := make([]model.Holding, 0, len(holdings))
+	createdAt := normalizeTime(time.Now())
+	for _, h := range holdings {
+		rep.nextId++
+		h.Id = rep.nextId
+		h.AcquiredAt = normalizeTime(h.AcquiredAt)
+		h.CreatedAt = createdAt
+		rep.holdings[h.Id] = h
+		inserted = append(inserted, h)
+	}
+
+	return inserted, nil
+}
+
+// checkHolding mimics the column constraints of portfolio_holdings
+func checkHolding(h model.Holding) error {
+	if err := checkLength("currency", h.Currency, 10); err != nil {
+		return err
+	}
+	if err := checkLength("note", h.Note, 200); err != nil {
+		return err
+	}
+	if h.Amount == 0 {
+		return fmt.Errorf("new row violates check constraint on amount")
+	}
+	if h.Price < 0 {
+		return fmt.Errorf("new row violates check constraint on price")
+	}
+	return nil
+}
+
+func (rep *PortfolioRepository) FetchHoldings() ([]model.Holding, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	holdings := make([]model.Holding, 0, len(rep.holdings))
+	for _, h := range rep.holdings {
+		holdings = append(holdings, h)
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		if !holdings[i].AcquiredAt.Equal(holdings[j].AcquiredAt) {
+			return holdings[i].AcquiredAt.Before(holdings[j].AcquiredAt)
+		}
+		return holdings[i].Id < holdings[j].Id
+	})
+
+	return holdings, nil
+}
+
+func (rep *PortfolioRepository) DeleteHolding(id int64) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if _, ok := rep.holdings[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(rep.holdings, id)
+	return nil
+}
+
+func (rep *PortfolioRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.holdings = make(map[int64]model.Holding)
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TradeFlowRepository in-memory counterpart of store.TradeFlowRepository
+type TradeFlowRepository struct {
+	mu   sync.RWMutex
+	rows map[candleKey]model.TradeFlow
+}
+
+var _ store.TradeFlowStore = (*TradeFlowRepository)(nil)
+
+func NewTradeFlowRepository() *TradeFlowRepository {
+	return &TradeFlowRepository{
+		rows: make(map[candleKey]model.TradeFlow),
+	}
+}
+
+// MergeTradeFlows adds flows to the stored ones, either all of them or none like the transaction in postgres
+func (rep *TradeFlowRepository) MergeTradeFlows(flows []model.TradeFlow) error {
+	for _, f := range flows {
+		if err := checkLength("pair", f.Pair, 10); err != nil {
+			return fmt.Errorf("failed to merge trade flows: %w", err)
+		}
+		if err := checkLength("bar", f.Bar, 5); err != nil {
+			return fmt.Errorf("failed to merge trade flows: %w", err)
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, f := range flows {
+		f.Timestamp = normalizeTime(f.Timestamp)
+		key := candleKey{pair: f.Pair, timestamp: f.Timestamp.UnixMicro(), bar: f.Bar}
+		if stored, ok := rep.rows[key]; ok {
+			stored.Merge(f)
+			f = stored
+		}
+		rep.rows[key] = f
+	}
+	return nil
+}
+
+func (rep *TradeFlowRepository) FetchTradeFlows(query store.CandleQuery) ([]model.TradeFlow, error) {
+	rep.mu.RLock()
+	var flows []model.TradeFlow
+	for _, f := range rep.rows {
+		if f.Pair == query.Pair && f.Bar == query.Bar && query.Contains(f.Timestamp) {
+			flows = append(flows, f)
+		}
+	}
+	rep.mu.RUnlock()
+
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Timestamp.Before(flows[j].Timestamp)
+	})
+	return flows, nil
+}
+
+func (rep *TradeFlowRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.rows = make(map[candleKey]model.TradeFlow)
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"cur/internal/model"
+	"cur/internal/store"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type trendKey struct {
+	pair      string
+	bar       string
+	timestamp int64
+}
+
+// TrendRepository in-memory counterpart of store.TrendRepository
+type TrendRepository struct {
+	mu   sync.RWMutex
+	rows map[trendKey]model.TrendState
+}
+
+var _ store.TrendStore = (*TrendRepository)(nil)
+
+func NewTrendRepository() *TrendRepository {
+	return &TrendRepository{rows: make(map[trendKey]model.TrendState)}
+}
+
+func (rep *TrendRepository) InsertTrendState(state model.TrendState) error {
+	for _, column := range []struct {
+		name, value string
+		max         int
+	}{
+		{"pair", state.Pair, 10},
+		{"bar", state.Bar, 5},
+		{"regime", string(state.Regime), 10},
+		{"structure", string(state.Structure), 12},
+	} {
+		if err := checkLength(column.name, column.value, column.max); err != nil {
+			return fmt.Errorf("failed to insert trend state: %w", err)
+		}
+	}
+
+	state.Timestamp = normalizeTime(state.Timestamp)
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.rows[trendKey{state.Pair, state.Bar, state.Timestamp.UnixMicro()}] = state
+
+	return nil
+}
+
+func (rep *TrendRepository) FetchLatestTrendState(pair, bar string) (model.TrendState, error) {
+	states, _ := rep.FetchTrendStates(store.CandleQuery{Pair: pair, Bar: bar})
+	if len(states) == 0 {
+		return model.TrendState{}, store.ErrNotFound
+	}
+	return states[len(states)-1], nil
+}
+
+func (rep *TrendRepository) FetchTrendStates(query store.CandleQuery) ([]model.TrendState, error) {
+	if !query.From.IsZero() {
+		query.From = normalizeTime(query.From)
+	}
+	if !query.To.IsZero() {
+		query.To = normalizeTime(query.To)
+	}
+
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	var states []model.TrendState
+	for _, state := range rep.rows {
+		if state.Pair == query.Pair && state.Bar == query.Bar && query.Contains(state.Timestamp) {
+			states = append(states, state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Timestamp.Before(states[j].Timestamp) })
+
+	return states, nil
+}
+
+func (rep *TrendRepository) Truncate() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.rows = make(map[trendKey]model.TrendState)
+}
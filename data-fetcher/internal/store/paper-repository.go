@@ -0,0 +1,215 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PaperRepository orders, fills, balances and positions of paper trading
+type PaperRepository struct {
+	db *sql.DB
+}
+
+func NewPaperRepository(db *sql.DB) *PaperRepository {
+	return &PaperRepository{db: db}
+}
+
+const paperOrderColumns = "id, pair, side, type, size, limit_price, status, reason, created_at, updated_at"
+
+// InsertPaperOrder stores a new order, returns it with the assigned id
+func (rep *PaperRepository) InsertPaperOrder(order model.PaperOrder) (model.PaperOrder, error) {
+	err := rep.db.QueryRow("INSERT INTO paper_orders (pair, side, type, size, limit_price, status, reason) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at",
+		order.Pair, order.Side, order.Type, order.Size, order.LimitPrice, order.Status, order.Reason,
+	).Scan(&order.Id, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return model.PaperOrder{}, fmt.Errorf("failed to insert paper order: %w", err)
+	}
+	return order, nil
+}
+
+// FetchPaperOrder returns the order or ErrNotFound
+func (rep *PaperRepository) FetchPaperOrder(id int64) (model.PaperOrder, error) {
+	orders, err := rep.queryOrders("SELECT "+paperOrderColumns+" FROM paper_orders WHERE id=$1", id)
+	if err != nil {
+		return model.PaperOrder{}, err
+	}
+	if len(orders) == 0 {
+		return model.PaperOrder{}, ErrNotFound
+	}
+	return orders[0], nil
+}
+
+func (rep *PaperRepository) FetchPaperOrders(status model.OrderStatus, limit int) ([]model.PaperOrder, error) {
+	return rep.queryOrders("SELECT "+paperOrderColumns+" FROM paper_orders "+
+		"WHERE $1::TEXT = '' OR status = $1 ORDER BY id DESC LIMIT $2", status, limit)
+}
+
+func (rep *PaperRepository) FetchOpenPaperOrders() ([]model.PaperOrder, error) {
+	return rep.queryOrders("SELECT "+paperOrderColumns+" FROM paper_orders WHERE status = $1 ORDER BY id", model.OrderOpen)
+}
+
+func (rep *PaperRepository) ClosePaperOrder(id int64, status model.OrderStatus, reason string, at time.Time) error {
+	result, err := rep.db.Exec("UPDATE paper_orders SET status=$2, reason=$3, updated_at=$4 WHERE id=$1 AND status=$5",
+		id, status, reason, at, model.OrderOpen)
+	if err != nil {
+		return fmt.Errorf("failed to update paper order: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (rep *PaperRepository) InsertPaperFill(fill model.PaperFill, balances []model.PaperBalance, position model.PaperPosition) (model.PaperFill, error) {
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return model.PaperFill{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	result, err := tx.Exec("UPDATE paper_orders SET status=$2, updated_at=$3 WHERE id=$1 AND status=$4",
+		fill.OrderId, model.OrderFilled, fill.FilledAt, model.OrderOpen)
+	if err != nil {
+		_ = tx.Rollback()
+		return model.PaperFill{}, fmt.Errorf("failed to update paper order: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		_ = tx.Rollback()
+		if err != nil {
+			return model.PaperFill{}, err
+		}
+		return model.PaperFill{}, ErrNotFound
+	}
+
+	err = tx.QueryRow("INSERT INTO paper_fills (order_id, pair, side, price, size, fee, trade_id, filled_at) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		fill.OrderId, fill.Pair, fill.Side, fill.Price, fill.Size, fill.Fee, fill.TradeId, fill.FilledAt,
+	).Scan(&fill.Id)
+	if err != nil {
+		_ = tx.Rollback()
+		return model.PaperFill{}, fmt.Errorf("failed to insert paper fill: %w", err)
+	}
+
+	for _, balance := range balances {
+		if err := setPaperBalance(tx, balance); err != nil {
+			_ = tx.Rollback()
+			return model.PaperFill{}, err
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO paper_positions (pair, size, avg_price, realized_pnl, updated_at) VALUES ($1, $2, $3, $4, $5) "+
+		"ON CONFLICT (pair) DO UPDATE SET size=EXCLUDED.size, avg_price=EXCLUDED.avg_price, "+
+		"realized_pnl=EXCLUDED.realized_pnl, updated_at=EXCLUDED.updated_at",
+		position.Pair, position.Size, position.AvgPrice, position.RealizedPnl, position.UpdatedAt)
+	if err != nil {
+		_ = tx.Rollback()
+		return model.PaperFill{}, fmt.Errorf("failed to update paper position: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return model.PaperFill{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return fill, nil
+}
+
+func (rep *PaperRepository) FetchPaperFills(limit int) ([]model.PaperFill, error) {
+	rows, err := rep.db.Query("SELECT id, order_id, pair, side, price, size, fee, trade_id, filled_at FROM paper_fills "+
+		"ORDER BY filled_at DESC, id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fills []model.PaperFill
+	for rows.Next() {
+		var f model.PaperFill
+		if err := rows.Scan(&f.Id, &f.OrderId, &f.Pair, &f.Side, &f.Price, &f.Size, &f.Fee, &f.TradeId, &f.FilledAt); err != nil {
+			return nil, err
+		}
+		fills = append(fills, f)
+	}
+
+	return fills, rows.Err()
+}
+
+func (rep *PaperRepository) SetPaperBalance(balance model.PaperBalance) error {
+	return setPaperBalance(rep.db, balance)
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func setPaperBalance(db execer, balance model.PaperBalance) error {
+	_, err := db.Exec("INSERT INTO paper_balances (currency, amount) VALUES ($1, $2) "+
+		"ON CONFLICT (currency) DO UPDATE SET amount=EXCLUDED.amount", balance.Currency, balance.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to set paper balance: %w", err)
+	}
+	return nil
+}
+
+func (rep *PaperRepository) FetchPaperBalances() ([]model.PaperBalance, error) {
+	rows, err := rep.db.Query("SELECT currency, amount FROM paper_balances ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []model.PaperBalance
+	for rows.Next() {
+		var b model.PaperBalance
+		if err := rows.Scan(&b.Currency, &b.Amount); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+
+	return balances, rows.Err()
+}
+
+func (rep *PaperRepository) FetchPaperPositions() ([]model.PaperPosition, error) {
+	rows, err := rep.db.Query("SELECT pair, size, avg_price, realized_pnl, updated_at FROM paper_positions ORDER BY pair")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []model.PaperPosition
+	for rows.Next() {
+		var p model.PaperPosition
+		if err := rows.Scan(&p.Pair, &p.Size, &p.AvgPrice, &p.RealizedPnl, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+
+	return positions, rows.Err()
+}
+
+func (rep *PaperRepository) queryOrders(query string, args ...any) ([]model.PaperOrder, error) {
+	rows, err := rep.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []model.PaperOrder
+	for rows.Next() {
+		var o model.PaperOrder
+		err := rows.Scan(&o.Id, &o.Pair, &o.Side, &o.Type, &o.Size, &o.LimitPrice, &o.Status, &o.Reason, &o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// PortfolioRepository holdings of the portfolio
+type PortfolioRepository struct {
+	db *sql.DB
+}
+
+func NewPortfolioRepository(db *sql.DB) *PortfolioRepository {
+	return &PortfolioRepository{db: db}
+}
+
+// InsertHoldings stores all holdings or none of them, returns them with the assigned ids
+func (rep *PortfolioRepository) InsertHoldings(holdings []model.Holding) ([]model.Holding, error) {
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	inserted := make([]model.Holding, 0, len(holdings))
+	for _, h := range holdings {
+		err := tx.QueryRow("INSERT INTO portfolio_holdings (currency, amount, price, acquired_at, note) "+
+			"VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+			h.Currency, h.Amount, h.Price, h.AcquiredAt, h.Note,
+		).Scan(&h.Id, &h.CreatedAt)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to insert holding: %w", err)
+		}
+		inserted = append(inserted, h)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return inserted, nil
+}
+
+func (rep *PortfolioRepository) FetchHoldings() ([]model.Holding, error) {
+	rows, err := rep.db.Query("SELECT id, currency, amount, price, acquired_at, note, created_at FROM portfolio_holdings " +
+		"ORDER BY acquired_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []model.Holding
+	for rows.Next() {
+		var h model.Holding
+		if err := rows.Scan(&h.Id, &h.Currency, &h.Amount, &h.Price, &h.AcquiredAt, &h.Note, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, h)
+	}
+
+	return holdings, rows.Err()
+}
+
+func (rep *PortfolioRepository) DeleteHolding(id int64) error {
+	result, err := rep.db.Exec("DELETE FROM portfolio_holdings WHERE id=$1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete holding: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/service/okx/response"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ErrNotFound returned by repositories when a single requested row doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// CurrencyStore currencies storage, upserts by (code, chain)
+type CurrencyStore interface {
+	InsertOrUpdateCurrencies(currencies *[]response.CurrencyResponseData) error
+	// FetchAll returns currencies in insertion order
+	FetchAll() ([]model.Currency, error)
+}
+
+// CandleStore candles storage, upserts by (pair, timestamp, bar)
+type CandleStore interface {
+	InsertCandles(candles *[]model.Candle) error
+	// FetchAll returns candles ordered by pair, bar and timestamp
+	FetchAll() ([]model.Candle, error)
+	GetLastTsForPair(pair, bar string) (string, error)
+	GetFirstTsForPair(pair, bar string) (string, error)
+	FetchRange(query CandleQuery) ([]model.Candle, error)
+	FetchLatest(pair, bar string, n int) ([]model.Candle, error)
+	FetchAtOrBefore(pair, bar string, ts time.Time) (model.Candle, error)
+	FetchPage(query CandleQuery, cursor string, limit int) (CandlePage, error)
+	Iterate(ctx context.Context, query CandleQuery, fn func(model.Candle) error) error
+	// FetchPairStats returns stats of stored candles ordered by pair and bar
+	FetchPairStats() ([]PairStats, error)
+	OnInsert(listener CandlesListener)
+}
+
+// CandlesWithContext returns candles running queries with ctx if the store supports it, the store itself otherwise
+func CandlesWithContext(ctx context.Context, candles CandleStore) CandleStore {
+	if bindable, ok := candles.(interface {
+		WithContext(ctx context.Context) CandleStore
+	}); ok {
+		return bindable.WithContext(ctx)
+	}
+	return candles
+}
+
+// CurrenciesWithContext returns currencies running queries with ctx if the store supports it, the store itself otherwise
+func CurrenciesWithContext(ctx context.Context, currencies CurrencyStore) CurrencyStore {
+	if bindable, ok := currencies.(interface {
+		WithContext(ctx context.Context) CurrencyStore
+	}); ok {
+		return bindable.WithContext(ctx)
+	}
+	return currencies
+}
+
+// dbSystem is the attribute of repository spans
+var dbSystem = semconv.DBSystemPostgreSQL
+
+func queryAttributes(query CandleQuery) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("pair", query.Pair), attribute.String("bar", query.Bar)}
+}
+
+// ignoreNotFound doesn't mark spans failed when a row is missing, callers expect it
+func ignoreNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// CandlesListener is called synchronously with inserted (or updated) candles, it must not block.
+// ctx carries the span of the insert.
+type CandlesListener func(ctx context.Context, candles []model.Candle)
+
+// candleListeners is shared by candle repositories to notify listeners after inserts
+type candleListeners struct {
+	mu        sync.RWMutex
+	listeners []CandlesListener
+}
+
+func (l *candleListeners) add(listener CandlesListener) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, listener)
+}
+
+func (l *candleListeners) notify(ctx context.Context, candles []model.Candle) {
+	if len(candles) == 0 {
+		return
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, listener := range l.listeners {
+		listener(ctx, candles)
+	}
+}
+
+// TradeStore latest trades storage
+type TradeStore interface {
+	Add(trade model.Trade)
+	Latest(pair string, n int) []model.Trade
+	Pairs() []string
+}
+
+// TickerStore latest tickers storage
+type TickerStore interface {
+	Set(ticker model.Ticker)
+	Get(pair string) (model.Ticker, error)
+	All() []model.Ticker
+}
+
+// TrendStore regime history of pairs
+type TrendStore interface {
+	InsertTrendState(state model.TrendState) error
+	FetchLatestTrendState(pair, bar string) (model.TrendState, error)
+	FetchTrendStates(query CandleQuery) ([]model.TrendState, error)
+}
+
+// IndicatorStore precomputed indicator values
+type IndicatorStore interface {
+	InsertIndicatorValues(values []model.IndicatorValue) error
+	LastIndicatorTimestamp(pair, bar, indicator string) (time.Time, error)
+	FetchIndicatorRange(query CandleQuery, indicators []string) ([]model.CandleIndicators, error)
+	FetchIndicatorPage(query CandleQuery, indicators []string, cursor string, limit int) (IndicatorPage, error)
+}
+
+// AlertStore alert rules and triggered alerts
+type AlertStore interface {
+	InsertAlertRule(rule model.AlertRule) (model.AlertRule, error)
+	// FetchAlertRules returns rules ordered by id
+	FetchAlertRules() ([]model.AlertRule, error)
+	FetchAlertRule(id int64) (model.AlertRule, error)
+	DeleteAlertRule(id int64) error
+	InsertAlertEvent(event model.AlertEvent) (model.AlertEvent, error)
+	FetchAlertEvents(ruleId int64, limit int) ([]model.AlertEvent, error)
+}
+
+// PaperStore orders, fills, balances and positions of paper trading
+type PaperStore interface {
+	InsertPaperOrder(order model.PaperOrder) (model.PaperOrder, error)
+	FetchPaperOrder(id int64) (model.PaperOrder, error)
+	// FetchPaperOrders returns the latest orders with the status (of every status when empty), newest first
+	FetchPaperOrders(status model.OrderStatus, limit int) ([]model.PaperOrder, error)
+	// FetchOpenPaperOrders returns open orders ordered by id
+	FetchOpenPaperOrders() ([]model.PaperOrder, error)
+	// ClosePaperOrder moves an open order to the status or returns ErrNotFound when it isn't open
+	ClosePaperOrder(id int64, status model.OrderStatus, reason string, at time.Time) error
+	// InsertPaperFill fills the open order and sets the balances and the position in one transaction,
+	// returns the fill with the assigned id or ErrNotFound when the order isn't open
+	InsertPaperFill(fill model.PaperFill, balances []model.PaperBalance, position model.PaperPosition) (model.PaperFill, error)
+	// FetchPaperFills returns the latest fills, newest first
+	FetchPaperFills(limit int) ([]model.PaperFill, error)
+	SetPaperBalance(balance model.PaperBalance) error
+	// FetchPaperBalances returns balances ordered by currency
+	FetchPaperBalances() ([]model.PaperBalance, error)
+	// FetchPaperPositions returns positions ordered by pair
+	FetchPaperPositions() ([]model.PaperPosition, error)
+}
+
+// PortfolioStore holdings of the portfolio
+type PortfolioStore interface {
+	// InsertHoldings stores all holdings or none of them, returns them with the assigned ids
+	InsertHoldings(holdings []model.Holding) ([]model.Holding, error)
+	// FetchHoldings returns holdings ordered by acquisition time and id
+	FetchHoldings() ([]model.Holding, error)
+	// DeleteHolding deletes the holding or returns ErrNotFound
+	DeleteHolding(id int64) error
+}
+
+// DivergenceStore spreads between OKX and reference venues
+type DivergenceStore interface {
+	// InsertDivergences stores all divergences or none of them
+	InsertDivergences(divergences []model.Divergence) error
+	// FetchDivergences returns the latest divergences of the query, newest first
+	FetchDivergences(query DivergenceQuery, limit int) ([]model.Divergence, error)
+	InsertDivergenceEvent(event model.DivergenceEvent) (model.DivergenceEvent, error)
+	// FetchDivergenceEvents returns the latest events of the pair (of every pair when empty), newest first
+	FetchDivergenceEvents(pair string, limit int) ([]model.DivergenceEvent, error)
+}
+
+// AnomalyStore suspect rows quarantined out of candles and trades, unique by source, pair, bar, timestamp and trade id
+type AnomalyStore interface {
+	// InsertAnomalies stores new anomalies, returns them with the assigned ids, already quarantined rows are skipped
+	InsertAnomalies(anomalies []model.Anomaly) ([]model.Anomaly, error)
+	// FetchAnomalies returns the latest anomalies of the query, newest first
+	FetchAnomalies(query AnomalyQuery, limit int) ([]model.Anomaly, error)
+	FetchAnomaly(id int64) (model.Anomaly, error)
+	// ReviewAnomaly moves a pending anomaly to the status or returns ErrNotFound when it isn't pending
+	ReviewAnomaly(id int64, status model.AnomalyStatus, at time.Time) error
+}
+
+// AnalyticsStore rolling volatilities of pairs and correlation matrices across pairs, upserted by their keys
+type AnalyticsStore interface {
+	InsertVolatilities(values []model.Volatility) error
+	LastVolatilityTimestamp(pair, bar string, window int) (time.Time, error)
+	// FetchVolatilities returns the latest volatilities of the query, newest first
+	FetchVolatilities(query VolatilityQuery, limit int) ([]model.Volatility, error)
+	InsertCorrelations(values []model.Correlation) error
+	// LastCorrelationTimestamp returns the timestamp of the latest correlation of the pairs, ordered as stored, and the window
+	LastCorrelationTimestamp(pairA, pairB, bar string, window int) (time.Time, error)
+	// FetchCorrelationMatrix returns the latest correlation of every two pairs at or before at (the latest ones when zero),
+	// ordered by pairs
+	FetchCorrelationMatrix(bar string, window int, method model.CorrelationMethod, at time.Time) ([]model.Correlation, error)
+}
+
+// TradeFlowStore trade flows of candles, flows of the same pair, bar and timestamp add up
+type TradeFlowStore interface {
+	MergeTradeFlows(flows []model.TradeFlow) error
+	// FetchTradeFlows returns flows of the query range ordered by timestamp
+	FetchTradeFlows(query CandleQuery) ([]model.TradeFlow, error)
+}
+
+// JobStore runs of scheduled jobs and paused jobs
+type JobStore interface {
+	// StartJobRun stores the run, returns it with the assigned id
+	StartJobRun(run model.JobRun) (model.JobRun, error)
+	// FinishJobRun sets the status, finish time, error and items of the run or returns ErrNotFound
+	FinishJobRun(run model.JobRun) error
+	// InterruptJobRuns marks runs left running, e.g. by a killed app, as interrupted, returns how many there were
+	InterruptJobRuns(at time.Time) (int, error)
+	// LastJobRun returns the latest started run of the job or ErrNotFound
+	LastJobRun(job string) (model.JobRun, error)
+	// FetchJobRuns returns the latest runs of the job (of every job when empty), newest first
+	FetchJobRuns(job string, limit int) ([]model.JobRun, error)
+	SetJobPaused(job string, paused bool, at time.Time) error
+	// PausedJobs returns names of paused jobs ordered by name
+	PausedJobs() ([]string, error)
+}
+
+// CrossRateStore prices of any two currencies converted through available pairs,
+// ErrNotFound is returned when the currencies aren't connected or a pair has no prices
+type CrossRateStore interface {
+	// FetchCrossCandlePage returns a page of synthetic candles of the query pair, see CandleStore.FetchPage
+	FetchCrossCandlePage(query CandleQuery, cursor string, limit int) (CrossCandlePage, error)
+	// FetchCrossRate returns the latest rate by tickers, by the latest candles for pairs without a ticker
+	FetchCrossRate(from, to string) (CrossRate, error)
+	// FetchCrossRateAt returns the rate by closes of the latest bar candles opened at or before the time
+	FetchCrossRateAt(from, to, bar string, at time.Time) (CrossRate, error)
+}
+
+// PairStats stored candles of the pair and bar
+type PairStats struct {
+	Pair  string
+	Bar   string
+	First time.Time
+	Last  time.Time
+	Count int64
+}
+
+var (
+	_ CurrencyStore   = (*CurrencyRepository)(nil)
+	_ CandleStore     = (*CandleRepository)(nil)
+	_ TradeStore      = (*TradeRepository)(nil)
+	_ TickerStore     = (*TickerRepository)(nil)
+	_ TrendStore      = (*TrendRepository)(nil)
+	_ IndicatorStore  = (*IndicatorRepository)(nil)
+	_ AlertStore      = (*AlertRepository)(nil)
+	_ PaperStore      = (*PaperRepository)(nil)
+	_ PortfolioStore  = (*PortfolioRepository)(nil)
+	_ CrossRateStore  = (*CrossRateRepository)(nil)
+	_ DivergenceStore = (*DivergenceRepository)(nil)
+	_ AnomalyStore    = (*AnomalyRepository)(nil)
+	_ AnalyticsStore  = (*AnalyticsRepository)(nil)
+	_ TradeFlowStore  = (*TradeFlowRepository)(nil)
+	_ JobStore        = (*JobRepository)(nil)
+)
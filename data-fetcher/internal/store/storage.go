@@ -1,14 +1,28 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"strings"
 )
 
 type Store struct {
-	db          *sql.DB
-	currencyRep *CurrencyRepository
-	candleRep   *CandleRepository
+	db            *sql.DB
+	currencyRep   *CurrencyRepository
+	candleRep     *CandleRepository
+	tradeRep      *TradeRepository
+	tickerRep     *TickerRepository
+	trendRep      *TrendRepository
+	indicatorRep  *IndicatorRepository
+	alertRep      *AlertRepository
+	paperRep      *PaperRepository
+	portfolioRep  *PortfolioRepository
+	crossRateRep  *CrossRateRepository
+	divergenceRep *DivergenceRepository
+	anomalyRep    *AnomalyRepository
+	analyticsRep  *AnalyticsRepository
+	tradeFlowRep  *TradeFlowRepository
+	jobRep        *JobRepository
 }
 
 func NewStore(db *sql.DB) *Store {
@@ -31,6 +45,110 @@ func (s *Store) Candle() *CandleRepository {
 	return s.candleRep
 }
 
+func (s *Store) Trade() *TradeRepository {
+	if s.tradeRep == nil {
+		s.tradeRep = NewTradeRepository(DefaultTradesPerPair)
+	}
+
+	return s.tradeRep
+}
+
+func (s *Store) Ticker() *TickerRepository {
+	if s.tickerRep == nil {
+		s.tickerRep = NewTickerRepository()
+	}
+
+	return s.tickerRep
+}
+
+func (s *Store) Trend() *TrendRepository {
+	if s.trendRep == nil {
+		s.trendRep = NewTrendRepository(s.db)
+	}
+
+	return s.trendRep
+}
+
+func (s *Store) Indicator() *IndicatorRepository {
+	if s.indicatorRep == nil {
+		s.indicatorRep = NewIndicatorRepository(s.db)
+	}
+
+	return s.indicatorRep
+}
+
+func (s *Store) Alert() *AlertRepository {
+	if s.alertRep == nil {
+		s.alertRep = NewAlertRepository(s.db)
+	}
+
+	return s.alertRep
+}
+
+func (s *Store) Paper() *PaperRepository {
+	if s.paperRep == nil {
+		s.paperRep = NewPaperRepository(s.db)
+	}
+
+	return s.paperRep
+}
+
+func (s *Store) Portfolio() *PortfolioRepository {
+	if s.portfolioRep == nil {
+		s.portfolioRep = NewPortfolioRepository(s.db)
+	}
+
+	return s.portfolioRep
+}
+
+func (s *Store) CrossRate() *CrossRateRepository {
+	if s.crossRateRep == nil {
+		s.crossRateRep = NewCrossRateRepository(s.Candle(), s.Ticker())
+	}
+
+	return s.crossRateRep
+}
+
+func (s *Store) Divergence() *DivergenceRepository {
+	if s.divergenceRep == nil {
+		s.divergenceRep = NewDivergenceRepository(s.db)
+	}
+
+	return s.divergenceRep
+}
+
+func (s *Store) Anomaly() *AnomalyRepository {
+	if s.anomalyRep == nil {
+		s.anomalyRep = NewAnomalyRepository(s.db)
+	}
+
+	return s.anomalyRep
+}
+
+func (s *Store) Analytics() *AnalyticsRepository {
+	if s.analyticsRep == nil {
+		s.analyticsRep = NewAnalyticsRepository(s.db)
+	}
+
+	return s.analyticsRep
+}
+
+func (s *Store) TradeFlow() *TradeFlowRepository {
+	if s.tradeFlowRep == nil {
+		s.tradeFlowRep = NewTradeFlowRepository(s.db)
+	}
+
+	return s.tradeFlowRep
+}
+
+func (s *Store) Job() *JobRepository {
+	if s.jobRep == nil {
+		s.jobRep = NewJobRepository(s.db)
+	}
+
+	return s.jobRep
+}
+
 func (s *Store) TruncateTables(tables []string) error {
 	if len(tables) > 0 {
 		_, err := s.db.Exec("TRUNCATE " + strings.Join(tables, ",") + " CASCADE")
@@ -39,11 +157,20 @@ func (s *Store) TruncateTables(tables []string) error {
 	return nil
 }
 
-func (s *Store) CloseConnection() {
+// AdvisoryLock makes the Postgres advisory lock of the name
+func (s *Store) AdvisoryLock(name string) *AdvisoryLock {
+	return NewAdvisoryLock(s.db, name)
+}
+
+// Ping checks the database is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// CloseConnection closes the database, it waits for running queries
+func (s *Store) CloseConnection() error {
 	if s.db != nil {
-		err := s.db.Close()
-		if err != nil {
-			return
-		}
+		return s.db.Close()
 	}
+	return nil
 }
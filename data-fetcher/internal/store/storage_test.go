@@ -0,0 +1,47 @@
+package store_test
+
+import (
+	"cur/internal/config"
+	"cur/internal/store"
+	"cur/internal/store/storetest"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDb connects to the db configured by DB_* environment variables (see `make test`)
+func openTestDb(t *testing.T) *sql.DB {
+	cfg, _ := config.Load("")
+	if cfg == nil {
+		t.Skip("postgres is not configured")
+	}
+	conf := cfg.DbConfig()
+	if err := conf.Validate(); err != nil {
+		t.Skipf("postgres is not configured: %v", err)
+	}
+
+	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		conf.Host, conf.Port, conf.User, conf.Password, conf.DbName))
+	require.NoError(t, err)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		t.Skipf("postgres is not available: %v", err)
+	}
+
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	db := openTestDb(t)
+	s := store.NewStore(db)
+	defer s.CloseConnection()
+
+	storetest.Run(t, func(t *testing.T) storetest.Repositories {
+		require.NoError(t, s.TruncateTables([]string{"currencies", "candles", "trend_states", "indicator_values", "alert_rules", "alert_events", "paper_orders", "paper_fills", "paper_balances", "paper_positions", "portfolio_holdings", "price_divergences", "divergence_events", "anomalies", "volatility_values", "correlation_values", "trade_flows", "job_runs", "jobs"}))
+		return storetest.Repositories{Currency: store.NewCurrencyRepository(db), Candle: store.NewCandleRepository(db), Trend: store.NewTrendRepository(db), Indicator: store.NewIndicatorRepository(db), Alert: store.NewAlertRepository(db), Paper: store.NewPaperRepository(db), Portfolio: store.NewPortfolioRepository(db), Divergence: store.NewDivergenceRepository(db), Anomaly: store.NewAnomalyRepository(db), Analytics: store.NewAnalyticsRepository(db), TradeFlow: store.NewTradeFlowRepository(db), Job: store.NewJobRepository(db)}
+	})
+}
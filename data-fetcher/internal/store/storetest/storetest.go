@@ -0,0 +1,1273 @@
+// Package storetest is a conformance suite for store repositories. Every
+// implementation (postgres, memory) must pass it to keep identical semantics.
+package storetest
+
+import (
+	"context"
+	"cur/internal/model"
+	"cur/internal/service/okx/response"
+	"cur/internal/store"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Repositories struct {
+	Currency   store.CurrencyStore
+	Candle     store.CandleStore
+	Trend      store.TrendStore
+	Indicator  store.IndicatorStore
+	Alert      store.AlertStore
+	Paper      store.PaperStore
+	Portfolio  store.PortfolioStore
+	Divergence store.DivergenceStore
+	Anomaly    store.AnomalyStore
+	Analytics  store.AnalyticsStore
+	TradeFlow  store.TradeFlowStore
+	Job        store.JobStore
+}
+
+// Factory must return repositories with empty storage
+type Factory func(t *testing.T) Repositories
+
+// Run runs the whole conformance suite
+func Run(t *testing.T, newRepositories Factory) {
+	t.Run("Currency", func(t *testing.T) { RunCurrencyTests(t, newRepositories) })
+	t.Run("Candle", func(t *testing.T) { RunCandleTests(t, newRepositories) })
+	t.Run("CandleQuery", func(t *testing.T) { RunCandleQueryTests(t, newRepositories) })
+	t.Run("Trend", func(t *testing.T) { RunTrendTests(t, newRepositories) })
+	t.Run("Indicator", func(t *testing.T) { RunIndicatorTests(t, newRepositories) })
+	t.Run("Alert", func(t *testing.T) { RunAlertTests(t, newRepositories) })
+	t.Run("Paper", func(t *testing.T) { RunPaperTests(t, newRepositories) })
+	t.Run("Portfolio", func(t *testing.T) { RunPortfolioTests(t, newRepositories) })
+	t.Run("Divergence", func(t *testing.T) { RunDivergenceTests(t, newRepositories) })
+	t.Run("Anomaly", func(t *testing.T) { RunAnomalyTests(t, newRepositories) })
+	t.Run("Analytics", func(t *testing.T) { RunAnalyticsTests(t, newRepositories) })
+	t.Run("TradeFlow", func(t *testing.T) { RunTradeFlowTests(t, newRepositories) })
+	t.Run("Job", func(t *testing.T) { RunJobTests(t, newRepositories) })
+}
+
+func RunCurrencyTests(t *testing.T, newRepositories Factory) {
+	t.Run("empty", func(t *testing.T) {
+		rep := newRepositories(t).Currency
+
+		currencies, err := rep.FetchAll()
+		require.NoError(t, err)
+		assert.Empty(t, currencies)
+	})
+
+	t.Run("insert keeps order", func(t *testing.T) {
+		rep := newRepositories(t).Currency
+
+		require.NoError(t, rep.InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+			{Ccy: "USDT", Chain: "USDT-TRC20", CanDep: true, CanWd: false},
+			{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: false, CanWd: true},
+		}))
+		require.NoError(t, rep.InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+			{Ccy: "ETH", Chain: "ETH-ERC20", CanDep: true, CanWd: true},
+		}))
+
+		currencies, err := rep.FetchAll()
+		require.NoError(t, err)
+		require.Len(t, currencies, 3)
+		assertCurrency(t, model.Currency{Code: "USDT", Chain: "USDT-TRC20", CanDeposit: true, CanWithdraw: false}, currencies[0])
+		assertCurrency(t, model.Currency{Code: "BTC", Chain: "BTC-Bitcoin", CanDeposit: false, CanWithdraw: true}, currencies[1])
+		assertCurrency(t, model.Currency{Code: "ETH", Chain: "ETH-ERC20", CanDeposit: true, CanWithdraw: true}, currencies[2])
+		assert.Less(t, currencies[0].Id, currencies[1].Id)
+		assert.Less(t, currencies[1].Id, currencies[2].Id)
+	})
+
+	t.Run("upsert on conflict", func(t *testing.T) {
+		rep := newRepositories(t).Currency
+
+		require.NoError(t, rep.InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+			{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: true, CanWd: true},
+			{Ccy: "USDT", Chain: "USDT-TRC20", CanDep: true, CanWd: true},
+		}))
+		before, err := rep.FetchAll()
+		require.NoError(t, err)
+
+		require.NoError(t, rep.InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+			{Ccy: "USDT", Chain: "USDT-ERC20", CanDep: true, CanWd: true},
+			{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: false, CanWd: true},
+			{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: false, CanWd: false},
+		}))
+
+		currencies, err := rep.FetchAll()
+		require.NoError(t, err)
+		require.Len(t, currencies, 3)
+		assert.Equal(t, before[0].Id, currencies[0].Id)
+		assertCurrency(t, model.Currency{Code: "BTC", Chain: "BTC-Bitcoin", CanDeposit: false, CanWithdraw: false}, currencies[0])
+		assertCurrency(t, model.Currency{Code: "USDT", Chain: "USDT-TRC20", CanDeposit: true, CanWithdraw: true}, currencies[1])
+		assertCurrency(t, model.Currency{Code: "USDT", Chain: "USDT-ERC20", CanDeposit: true, CanWithdraw: true}, currencies[2])
+	})
+
+	t.Run("failed batch inserts nothing", func(t *testing.T) {
+		rep := newRepositories(t).Currency
+
+		err := rep.InsertOrUpdateCurrencies(&[]response.CurrencyResponseData{
+			{Ccy: "BTC", Chain: "BTC-Bitcoin", CanDep: true, CanWd: true},
+			{Ccy: strings.Repeat("X", 11), Chain: "X", CanDep: true, CanWd: true},
+		})
+		assert.Error(t, err)
+
+		currencies, err := rep.FetchAll()
+		require.NoError(t, err)
+		assert.Empty(t, currencies)
+	})
+}
+
+func RunCandleTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("empty", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		candles, err := rep.FetchAll()
+		require.NoError(t, err)
+		assert.Empty(t, candles)
+
+		_, err = rep.GetLastTsForPair("BTC-USDT", "1H")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = rep.GetFirstTsForPair("BTC-USDT", "1H")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("fetch all ordered by pair, bar and timestamp", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{
+			Candle("ETH-USDT", "1H", start.Add(time.Hour), 3),
+			Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+			Candle("BTC-USDT", "1D", start, 4),
+			Candle("BTC-USDT", "1H", start, 1),
+		}))
+
+		candles, err := rep.FetchAll()
+		require.NoError(t, err)
+		require.Len(t, candles, 4)
+		assertCandle(t, Candle("BTC-USDT", "1D", start, 4), candles[0])
+		assertCandle(t, Candle("BTC-USDT", "1H", start, 1), candles[1])
+		assertCandle(t, Candle("BTC-USDT", "1H", start.Add(time.Hour), 2), candles[2])
+		assertCandle(t, Candle("ETH-USDT", "1H", start.Add(time.Hour), 3), candles[3])
+	})
+
+	t.Run("upsert on conflict", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{
+			Candle("BTC-USDT", "1H", start, 1),
+			Candle("BTC-USDT", "1D", start, 1),
+		}))
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{
+			Candle("BTC-USDT", "1H", start, 5),
+		}))
+
+		candles, err := rep.FetchAll()
+		require.NoError(t, err)
+		require.Len(t, candles, 2)
+		assertCandle(t, Candle("BTC-USDT", "1D", start, 1), candles[0])
+		assertCandle(t, Candle("BTC-USDT", "1H", start, 5), candles[1])
+	})
+
+	t.Run("failed batch inserts nothing", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		err := rep.InsertCandles(&[]model.Candle{
+			Candle("BTC-USDT", "1H", start, 1),
+			Candle("TOO-LONG-PAIR", "1H", start, 1),
+		})
+		assert.Error(t, err)
+
+		candles, err := rep.FetchAll()
+		require.NoError(t, err)
+		assert.Empty(t, candles)
+	})
+
+	t.Run("min and max timestamp for pair and bar", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{
+			Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 1),
+			Candle("BTC-USDT", "1D", start.Add(-24*time.Hour), 1),
+			Candle("BTC-USDT", "1H", start.Add(time.Hour), 1),
+			Candle("ETH-USDT", "1H", start.Add(5*time.Hour), 1),
+		}))
+
+		last, err := rep.GetLastTsForPair("BTC-USDT", "1H")
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(start.Add(2*time.Hour).UnixMilli(), 10), last)
+
+		first, err := rep.GetFirstTsForPair("BTC-USDT", "1H")
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(start.Add(time.Hour).UnixMilli(), 10), first, "candles of other bars are ignored")
+
+		last, err = rep.GetLastTsForPair("BTC-USDT", "1D")
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(start.Add(-24*time.Hour).UnixMilli(), 10), last)
+		first, err = rep.GetFirstTsForPair("BTC-USDT", "1D")
+		require.NoError(t, err)
+		assert.Equal(t, last, first)
+
+		_, err = rep.GetLastTsForPair("SOL-USDT", "1H")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = rep.GetFirstTsForPair("ETH-USDT", "1D")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("insert listeners", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		var notified [][]model.Candle
+		rep.OnInsert(func(_ context.Context, candles []model.Candle) {
+			notified = append(notified, candles)
+		})
+
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{Candle("BTC-USDT", "1H", start, 1)}))
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{}))
+		assert.Error(t, rep.InsertCandles(&[]model.Candle{Candle("TOO-LONG-PAIR", "1H", start, 1)}))
+
+		require.Len(t, notified, 1)
+		require.Len(t, notified[0], 1)
+		assert.Equal(t, "BTC-USDT", notified[0][0].Pair)
+	})
+
+	t.Run("pair stats", func(t *testing.T) {
+		rep := newRepositories(t).Candle
+
+		stats, err := rep.FetchPairStats()
+		require.NoError(t, err)
+		assert.Empty(t, stats)
+
+		require.NoError(t, rep.InsertCandles(&[]model.Candle{
+			Candle("ETH-USDT", "1H", start, 1),
+			Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 1),
+			Candle("BTC-USDT", "1H", start, 1),
+			Candle("BTC-USDT", "1D", start, 1),
+		}))
+
+		stats, err = rep.FetchPairStats()
+		require.NoError(t, err)
+		require.Len(t, stats, 3)
+		assert.Equal(t, []string{"BTC-USDT/1D", "BTC-USDT/1H", "ETH-USDT/1H"},
+			[]string{stats[0].Pair + "/" + stats[0].Bar, stats[1].Pair + "/" + stats[1].Bar, stats[2].Pair + "/" + stats[2].Bar})
+		assert.True(t, start.Equal(stats[1].First))
+		assert.True(t, start.Add(2*time.Hour).Equal(stats[1].Last))
+		assert.Equal(t, int64(2), stats[1].Count)
+	})
+}
+
+func RunCandleQueryTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+
+	// 10 hourly BTC-USDT 1H candles plus noise of other pairs and bars
+	seed := func(t *testing.T) store.CandleStore {
+		rep := newRepositories(t).Candle
+		candles := []model.Candle{
+			Candle("ETH-USDT", "1H", at(3), 99),
+			Candle("BTC-USDT", "1D", at(0), 98),
+			Candle("BTC-USDT", "1D", at(24), 97),
+		}
+		for i := 0; i < 10; i++ {
+			candles = append(candles, Candle("BTC-USDT", "1H", at(i), int64(i+1)))
+		}
+		require.NoError(t, rep.InsertCandles(&candles))
+		return rep
+	}
+
+	values := func(candles []model.Candle) []int64 {
+		result := make([]int64, 0, len(candles))
+		for _, c := range candles {
+			result = append(result, c.Open/100)
+		}
+		return result
+	}
+
+	t.Run("range is half-open and bar aware", func(t *testing.T) {
+		rep := seed(t)
+
+		candles, err := rep.FetchRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: at(2), To: at(5)})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 4, 5}, values(candles))
+
+		candles, err = rep.FetchRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: at(8)})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{9, 10}, values(candles))
+
+		candles, err = rep.FetchRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1D", To: at(24)})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{98}, values(candles))
+
+		candles, err = rep.FetchRange(store.CandleQuery{Pair: "SOL-USDT", Bar: "1H"})
+		require.NoError(t, err)
+		assert.Empty(t, candles)
+	})
+
+	t.Run("latest n", func(t *testing.T) {
+		rep := seed(t)
+
+		candles, err := rep.FetchLatest("BTC-USDT", "1H", 3)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{8, 9, 10}, values(candles))
+
+		candles, err = rep.FetchLatest("BTC-USDT", "1D", 5)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{98, 97}, values(candles))
+
+		candles, err = rep.FetchLatest("BTC-USDT", "1H", 0)
+		require.NoError(t, err)
+		assert.Empty(t, candles)
+	})
+
+	t.Run("at or before", func(t *testing.T) {
+		rep := seed(t)
+
+		candle, err := rep.FetchAtOrBefore("BTC-USDT", "1H", at(4))
+		require.NoError(t, err)
+		assertCandle(t, Candle("BTC-USDT", "1H", at(4), 5), candle)
+
+		candle, err = rep.FetchAtOrBefore("BTC-USDT", "1H", at(4).Add(30*time.Minute))
+		require.NoError(t, err)
+		assertCandle(t, Candle("BTC-USDT", "1H", at(4), 5), candle)
+
+		_, err = rep.FetchAtOrBefore("BTC-USDT", "1H", at(0).Add(-time.Second))
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("keyset pagination", func(t *testing.T) {
+		rep := seed(t)
+		query := store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: at(1)}
+
+		var pages [][]int64
+		cursor := ""
+		for {
+			page, err := rep.FetchPage(query, cursor, 4)
+			require.NoError(t, err)
+			pages = append(pages, values(page.Candles))
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		assert.Equal(t, [][]int64{{2, 3, 4, 5}, {6, 7, 8, 9}, {10}}, pages)
+
+		page, err := rep.FetchPage(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", To: at(4)}, "", 4)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 2, 3, 4}, values(page.Candles))
+		assert.Empty(t, page.Next)
+
+		_, err = rep.FetchPage(query, "not a cursor", 4)
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+
+		_, err = rep.FetchPage(query, "", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		rep := seed(t)
+		query := store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: at(5)}
+
+		var visited []model.Candle
+		require.NoError(t, rep.Iterate(context.Background(), query, func(candle model.Candle) error {
+			visited = append(visited, candle)
+			return nil
+		}))
+		assert.Equal(t, []int64{6, 7, 8, 9, 10}, values(visited))
+
+		stop := errors.New("stop")
+		visited = nil
+		err := rep.Iterate(context.Background(), query, func(candle model.Candle) error {
+			visited = append(visited, candle)
+			if len(visited) == 2 {
+				return stop
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Len(t, visited, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, rep.Iterate(ctx, query, func(model.Candle) error { return nil }))
+	})
+}
+
+// Candle makes a candle with all prices derived from the value
+func RunTrendTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	state := func(ts time.Time, regime model.Regime) model.TrendState {
+		return model.TrendState{
+			Pair: "BTC-USDT", Bar: "1H", Timestamp: ts, Regime: regime,
+			FastMa: 2, SlowMa: 1, Adx: 30 * 100_000_000, Structure: model.StructureHigherHighs,
+		}
+	}
+
+	t.Run("latest", func(t *testing.T) {
+		rep := newRepositories(t).Trend
+
+		_, err := rep.FetchLatestTrendState("BTC-USDT", "1H")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		require.NoError(t, rep.InsertTrendState(state(start.Add(time.Hour), model.RegimeDowntrend)))
+		require.NoError(t, rep.InsertTrendState(state(start, model.RegimeUptrend)))
+		other := state(start.Add(2*time.Hour), model.RegimeRange)
+		other.Bar = "1D"
+		require.NoError(t, rep.InsertTrendState(other))
+
+		latest, err := rep.FetchLatestTrendState("BTC-USDT", "1H")
+		require.NoError(t, err)
+		assert.Equal(t, model.RegimeDowntrend, latest.Regime)
+		assert.True(t, start.Add(time.Hour).Equal(latest.Timestamp))
+		assert.Equal(t, int64(30*100_000_000), latest.Adx)
+		assert.Equal(t, model.StructureHigherHighs, latest.Structure)
+	})
+
+	t.Run("history range and upsert", func(t *testing.T) {
+		rep := newRepositories(t).Trend
+
+		for i, regime := range []model.Regime{model.RegimeUptrend, model.RegimeRange, model.RegimeDowntrend} {
+			require.NoError(t, rep.InsertTrendState(state(start.Add(time.Duration(i)*time.Hour), regime)))
+		}
+		require.NoError(t, rep.InsertTrendState(state(start.Add(time.Hour), model.RegimeUptrend)))
+
+		states, err := rep.FetchTrendStates(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: start.Add(time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, states, 2)
+		assert.Equal(t, model.RegimeUptrend, states[0].Regime)
+		assert.Equal(t, model.RegimeDowntrend, states[1].Regime)
+
+		states, err = rep.FetchTrendStates(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", To: start.Add(time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, states, 1)
+	})
+
+	t.Run("too long pair", func(t *testing.T) {
+		rep := newRepositories(t).Trend
+
+		s := state(start, model.RegimeUptrend)
+		s.Pair = "VERY-LONG-PAIR"
+		assert.Error(t, rep.InsertTrendState(s))
+	})
+}
+
+// RunIndicatorTests needs Candle and Indicator repositories sharing the storage
+func RunIndicatorTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	value := func(i int, indicator, component string, v int64) model.IndicatorValue {
+		return model.IndicatorValue{
+			Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Indicator: indicator, Component: component, Value: v,
+		}
+	}
+	prepare := func(t *testing.T) Repositories {
+		repos := newRepositories(t)
+		candles := []model.Candle{
+			Candle("BTC-USDT", "1H", start, 1),
+			Candle("BTC-USDT", "1H", start.Add(time.Hour), 2),
+			Candle("BTC-USDT", "1H", start.Add(2*time.Hour), 3),
+			Candle("BTC-USDT", "1D", start, 4),
+		}
+		require.NoError(t, repos.Candle.InsertCandles(&candles))
+		require.NoError(t, repos.Indicator.InsertIndicatorValues([]model.IndicatorValue{
+			value(1, "sma_2", "value", 150),
+			value(2, "sma_2", "value", 250),
+			value(2, "macd_1_2_1", "signal", -5),
+			value(2, "rsi_2", "value", 7),
+		}))
+		return repos
+	}
+
+	t.Run("last timestamp", func(t *testing.T) {
+		repos := prepare(t)
+
+		last, err := repos.Indicator.LastIndicatorTimestamp("BTC-USDT", "1H", "sma_2")
+		require.NoError(t, err)
+		assert.True(t, start.Add(2*time.Hour).Equal(last))
+
+		_, err = repos.Indicator.LastIndicatorTimestamp("BTC-USDT", "1D", "sma_2")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("aligned range", func(t *testing.T) {
+		repos := prepare(t)
+		require.NoError(t, repos.Indicator.InsertIndicatorValues([]model.IndicatorValue{value(2, "sma_2", "value", 251)}))
+
+		rows, err := repos.Indicator.FetchIndicatorRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"}, []string{"sma_2", "macd_1_2_1"})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assertCandle(t, Candle("BTC-USDT", "1H", start, 1), rows[0].Candle)
+		assert.Empty(t, rows[0].Values)
+		assert.Equal(t, map[string]int64{"sma_2": 150}, rows[1].Values)
+		assert.Equal(t, map[string]int64{"sma_2": 251, "macd_1_2_1.signal": -5}, rows[2].Values)
+
+		rows, err = repos.Indicator.FetchIndicatorRange(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: start.Add(2 * time.Hour)}, nil)
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.Empty(t, rows[0].Values)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		repos := prepare(t)
+
+		page, err := repos.Indicator.FetchIndicatorPage(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"}, []string{"rsi_2"}, "", 2)
+		require.NoError(t, err)
+		require.Len(t, page.Rows, 2)
+		require.NotEmpty(t, page.Next)
+
+		page, err = repos.Indicator.FetchIndicatorPage(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"}, []string{"rsi_2"}, page.Next, 2)
+		require.NoError(t, err)
+		require.Len(t, page.Rows, 1)
+		assert.Equal(t, map[string]int64{"rsi_2": 7}, page.Rows[0].Values)
+		assert.Empty(t, page.Next)
+
+		_, err = repos.Indicator.FetchIndicatorPage(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"}, nil, "???", 2)
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+
+	t.Run("too long indicator", func(t *testing.T) {
+		repos := newRepositories(t)
+		assert.Error(t, repos.Indicator.InsertIndicatorValues([]model.IndicatorValue{
+			value(0, "sma_2", "value", 1),
+			value(0, strings.Repeat("x", 33), "value", 1),
+		}))
+		_, err := repos.Indicator.LastIndicatorTimestamp("BTC-USDT", "1H", "sma_2")
+		assert.ErrorIs(t, err, store.ErrNotFound, "the batch must be rejected as a whole")
+	})
+}
+
+func RunAlertTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	threshold := model.AlertRule{
+		Name: "BTC above 100k", Pair: "BTC-USDT", Kind: model.AlertThreshold, Direction: model.DirectionAbove,
+		Level: 100_000 * 100_000_000, Cooldown: 15 * time.Minute, Channels: []string{model.ChannelWebhook, model.ChannelEmail}, Enabled: true,
+	}
+	change := model.AlertRule{
+		Name: "BTC moves 5%", Pair: "BTC-USDT", Kind: model.AlertPercentChange, Direction: model.DirectionAny,
+		Percent: 5 * 100_000_000, Window: time.Hour, Enabled: false,
+	}
+
+	t.Run("rules", func(t *testing.T) {
+		rep := newRepositories(t).Alert
+
+		rules, err := rep.FetchAlertRules()
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+
+		first, err := rep.InsertAlertRule(threshold)
+		require.NoError(t, err)
+		second, err := rep.InsertAlertRule(change)
+		require.NoError(t, err)
+		assert.Less(t, first.Id, second.Id)
+		assert.False(t, first.CreatedAt.IsZero())
+
+		rules, err = rep.FetchAlertRules()
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		assert.Equal(t, first.Id, rules[0].Id)
+		assert.Equal(t, threshold.Level, rules[0].Level)
+		assert.Equal(t, 15*time.Minute, rules[0].Cooldown)
+		assert.Equal(t, []string{model.ChannelWebhook, model.ChannelEmail}, rules[0].Channels)
+		assert.True(t, rules[0].Enabled)
+		assert.True(t, rules[0].LastTriggeredAt.IsZero())
+		assert.Equal(t, model.AlertPercentChange, rules[1].Kind)
+		assert.Equal(t, time.Hour, rules[1].Window)
+		assert.Empty(t, rules[1].Channels)
+		assert.False(t, rules[1].Enabled)
+
+		rule, err := rep.FetchAlertRule(second.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "BTC moves 5%", rule.Name)
+
+		require.NoError(t, rep.DeleteAlertRule(first.Id))
+		assert.ErrorIs(t, rep.DeleteAlertRule(first.Id), store.ErrNotFound)
+		_, err = rep.FetchAlertRule(first.Id)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		rep := newRepositories(t).Alert
+		first, err := rep.InsertAlertRule(threshold)
+		require.NoError(t, err)
+		second, err := rep.InsertAlertRule(change)
+		require.NoError(t, err)
+
+		for i, ruleId := range []int64{first.Id, second.Id, first.Id} {
+			event, err := rep.InsertAlertEvent(model.AlertEvent{
+				RuleId: ruleId, Pair: "BTC-USDT", Kind: model.AlertThreshold, Value: int64(i),
+				Message: "crossed", TriggeredAt: start.Add(time.Duration(i) * time.Minute),
+			})
+			require.NoError(t, err)
+			assert.NotZero(t, event.Id)
+		}
+
+		rule, err := rep.FetchAlertRule(first.Id)
+		require.NoError(t, err)
+		assert.True(t, start.Add(2*time.Minute).Equal(rule.LastTriggeredAt))
+
+		events, err := rep.FetchAlertEvents(first.Id, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, int64(2), events[0].Value, "newest first")
+		assert.Equal(t, "crossed", events[0].Message)
+
+		events, err = rep.FetchAlertEvents(0, 2)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, second.Id, events[1].RuleId)
+
+		_, err = rep.InsertAlertEvent(model.AlertEvent{RuleId: second.Id + 100, Pair: "BTC-USDT", Kind: model.AlertThreshold, TriggeredAt: start})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		require.NoError(t, rep.DeleteAlertRule(first.Id))
+		events, err = rep.FetchAlertEvents(0, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1, "events are deleted with the rule")
+	})
+
+	t.Run("too long pair", func(t *testing.T) {
+		rep := newRepositories(t).Alert
+
+		rule := threshold
+		rule.Pair = "VERY-LONG-PAIR"
+		_, err := rep.InsertAlertRule(rule)
+		assert.Error(t, err)
+	})
+}
+
+func RunPaperTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	market := model.PaperOrder{Pair: "BTC-USDT", Side: model.SideBuy, Type: model.OrderMarket, Size: 50_000_000, Status: model.OrderOpen}
+	limit := model.PaperOrder{
+		Pair: "BTC-USDT", Side: model.SideSell, Type: model.OrderLimit, Size: 50_000_000,
+		LimitPrice: 100_000 * 100_000_000, Status: model.OrderOpen,
+	}
+
+	t.Run("orders", func(t *testing.T) {
+		rep := newRepositories(t).Paper
+
+		orders, err := rep.FetchOpenPaperOrders()
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+
+		first, err := rep.InsertPaperOrder(market)
+		require.NoError(t, err)
+		second, err := rep.InsertPaperOrder(limit)
+		require.NoError(t, err)
+		assert.Less(t, first.Id, second.Id)
+		assert.False(t, first.CreatedAt.IsZero())
+
+		order, err := rep.FetchPaperOrder(second.Id)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderLimit, order.Type)
+		assert.Equal(t, limit.LimitPrice, order.LimitPrice)
+		_, err = rep.FetchPaperOrder(second.Id + 100)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		require.NoError(t, rep.ClosePaperOrder(first.Id, model.OrderCancelled, "", start))
+		assert.ErrorIs(t, rep.ClosePaperOrder(first.Id, model.OrderRejected, "late", start), store.ErrNotFound, "only open orders are closed")
+
+		orders, err = rep.FetchOpenPaperOrders()
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, second.Id, orders[0].Id)
+
+		orders, err = rep.FetchPaperOrders("", 10)
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		assert.Equal(t, second.Id, orders[0].Id, "newest first")
+		assert.Equal(t, model.OrderCancelled, orders[1].Status)
+		assert.True(t, start.Equal(orders[1].UpdatedAt))
+
+		orders, err = rep.FetchPaperOrders(model.OrderCancelled, 10)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, first.Id, orders[0].Id)
+	})
+
+	t.Run("fills", func(t *testing.T) {
+		rep := newRepositories(t).Paper
+		require.NoError(t, rep.SetPaperBalance(model.PaperBalance{Currency: "USDT", Amount: 100_000 * 100_000_000}))
+		order, err := rep.InsertPaperOrder(market)
+		require.NoError(t, err)
+
+		fill, err := rep.InsertPaperFill(
+			model.PaperFill{
+				OrderId: order.Id, Pair: "BTC-USDT", Side: model.SideBuy, Price: 97_000 * 100_000_000, Size: 50_000_000,
+				Fee: 48_500_000_000, TradeId: "42", FilledAt: start,
+			},
+			[]model.PaperBalance{{Currency: "USDT", Amount: 51_015 * 100_000_000}, {Currency: "BTC", Amount: 50_000_000}},
+			model.PaperPosition{Pair: "BTC-USDT", Size: 50_000_000, AvgPrice: 97_000 * 100_000_000, RealizedPnl: -48_500_000_000, UpdatedAt: start},
+		)
+		require.NoError(t, err)
+		assert.NotZero(t, fill.Id)
+
+		_, err = rep.InsertPaperFill(model.PaperFill{OrderId: order.Id, Pair: "BTC-USDT", Side: model.SideBuy, FilledAt: start}, nil,
+			model.PaperPosition{Pair: "BTC-USDT", UpdatedAt: start})
+		assert.ErrorIs(t, err, store.ErrNotFound, "an order is filled once")
+
+		order, err = rep.FetchPaperOrder(order.Id)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderFilled, order.Status)
+
+		fills, err := rep.FetchPaperFills(10)
+		require.NoError(t, err)
+		require.Len(t, fills, 1)
+		assert.Equal(t, "42", fills[0].TradeId)
+		assert.True(t, start.Equal(fills[0].FilledAt))
+
+		balances, err := rep.FetchPaperBalances()
+		require.NoError(t, err)
+		assert.Equal(t, []model.PaperBalance{{Currency: "BTC", Amount: 50_000_000}, {Currency: "USDT", Amount: 51_015 * 100_000_000}}, balances)
+
+		positions, err := rep.FetchPaperPositions()
+		require.NoError(t, err)
+		require.Len(t, positions, 1)
+		assert.Equal(t, int64(50_000_000), positions[0].Size)
+		assert.Equal(t, int64(-48_500_000_000), positions[0].RealizedPnl)
+		assert.True(t, start.Equal(positions[0].UpdatedAt))
+	})
+
+	t.Run("negative balance", func(t *testing.T) {
+		rep := newRepositories(t).Paper
+		assert.Error(t, rep.SetPaperBalance(model.PaperBalance{Currency: "USDT", Amount: -1}))
+	})
+}
+
+func RunPortfolioTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("holdings", func(t *testing.T) {
+		rep := newRepositories(t).Portfolio
+
+		holdings, err := rep.FetchHoldings()
+		require.NoError(t, err)
+		assert.Empty(t, holdings)
+
+		inserted, err := rep.InsertHoldings([]model.Holding{
+			{Currency: "ETH", Amount: 2 * 100_000_000, Price: 3_000 * 100_000_000, AcquiredAt: start.Add(time.Hour), Note: "bought"},
+			{Currency: "BTC", Amount: 50_000_000, AcquiredAt: start},
+			{Currency: "ETH", Amount: -100_000_000, AcquiredAt: start.Add(time.Hour)},
+		})
+		require.NoError(t, err)
+		require.Len(t, inserted, 3)
+		assert.Less(t, inserted[0].Id, inserted[1].Id)
+		assert.False(t, inserted[0].CreatedAt.IsZero())
+
+		holdings, err = rep.FetchHoldings()
+		require.NoError(t, err)
+		require.Len(t, holdings, 3)
+		assert.Equal(t, inserted[1].Id, holdings[0].Id, "ordered by acquisition time")
+		assert.Equal(t, inserted[0].Id, holdings[1].Id, "then by id")
+		assert.Equal(t, "bought", holdings[1].Note)
+		assert.Equal(t, int64(3_000*100_000_000), holdings[1].Price)
+		assert.Equal(t, int64(-100_000_000), holdings[2].Amount)
+		assert.True(t, start.Equal(holdings[0].AcquiredAt))
+
+		require.NoError(t, rep.DeleteHolding(inserted[0].Id))
+		assert.ErrorIs(t, rep.DeleteHolding(inserted[0].Id), store.ErrNotFound)
+		holdings, err = rep.FetchHoldings()
+		require.NoError(t, err)
+		assert.Len(t, holdings, 2)
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		rep := newRepositories(t).Portfolio
+
+		_, err := rep.InsertHoldings([]model.Holding{
+			{Currency: "BTC", Amount: 1, AcquiredAt: start},
+			{Currency: "BTC", Amount: 0, AcquiredAt: start},
+		})
+		assert.Error(t, err)
+		_, err = rep.InsertHoldings([]model.Holding{{Currency: "VERY-LONG-COIN", Amount: 1, AcquiredAt: start}})
+		assert.Error(t, err)
+
+		holdings, err := rep.FetchHoldings()
+		require.NoError(t, err)
+		assert.Empty(t, holdings)
+	})
+}
+
+func RunDivergenceTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("divergences", func(t *testing.T) {
+		rep := newRepositories(t).Divergence
+
+		divergences, err := rep.FetchDivergences(store.DivergenceQuery{}, 10)
+		require.NoError(t, err)
+		assert.Empty(t, divergences)
+
+		require.NoError(t, rep.InsertDivergences(nil))
+		require.NoError(t, rep.InsertDivergences([]model.Divergence{
+			{Pair: "BTC-USDT", Source: "binance", OkxPrice: 100, ReferencePrice: 101, Spread: 100_000_000, NetSpread: 80_000_000, ObservedAt: start},
+			{Pair: "ETH-USDT", Source: "binance", OkxPrice: 100, ReferencePrice: 99, Spread: -100_000_000, NetSpread: 80_000_000, ObservedAt: start},
+			{Pair: "BTC-USDT", Source: "kraken", OkxPrice: 100, ReferencePrice: 100, ObservedAt: start.Add(time.Minute)},
+			{Pair: "BTC-USDT", Source: "binance", OkxPrice: 100, ReferencePrice: 102, Spread: 200_000_000, NetSpread: 180_000_000, ObservedAt: start.Add(2 * time.Minute)},
+		}))
+
+		divergences, err = rep.FetchDivergences(store.DivergenceQuery{Pair: "BTC-USDT"}, 10)
+		require.NoError(t, err)
+		require.Len(t, divergences, 3)
+		assert.NotZero(t, divergences[0].Id)
+		assert.Equal(t, int64(102), divergences[0].ReferencePrice, "newest first")
+		assert.Equal(t, int64(180_000_000), divergences[0].NetSpread)
+		assert.True(t, start.Add(2*time.Minute).Equal(divergences[0].ObservedAt))
+
+		divergences, err = rep.FetchDivergences(store.DivergenceQuery{Pair: "BTC-USDT", Source: "binance", From: start, To: start.Add(2 * time.Minute)}, 10)
+		require.NoError(t, err)
+		require.Len(t, divergences, 1)
+		assert.Equal(t, int64(100_000_000), divergences[0].Spread)
+
+		divergences, err = rep.FetchDivergences(store.DivergenceQuery{}, 2)
+		require.NoError(t, err)
+		assert.Len(t, divergences, 2)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		rep := newRepositories(t).Divergence
+
+		for i, pair := range []string{"BTC-USDT", "ETH-USDT", "BTC-USDT"} {
+			event, err := rep.InsertDivergenceEvent(model.DivergenceEvent{
+				Pair: pair, Source: "binance", OkxPrice: 100, ReferencePrice: 102, Spread: 200_000_000, NetSpread: 180_000_000,
+				Threshold: 50_000_000, StartedAt: start, TriggeredAt: start.Add(time.Duration(i+1) * time.Minute),
+			})
+			require.NoError(t, err)
+			assert.NotZero(t, event.Id)
+		}
+
+		events, err := rep.FetchDivergenceEvents("BTC-USDT", 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.True(t, start.Add(3*time.Minute).Equal(events[0].TriggeredAt), "newest first")
+		assert.True(t, start.Equal(events[0].StartedAt))
+		assert.Equal(t, int64(50_000_000), events[0].Threshold)
+
+		events, err = rep.FetchDivergenceEvents("", 10)
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+	})
+
+	t.Run("too long source", func(t *testing.T) {
+		rep := newRepositories(t).Divergence
+		err := rep.InsertDivergences([]model.Divergence{{Pair: "BTC-USDT", Source: strings.Repeat("x", 33), ObservedAt: start}})
+		assert.Error(t, err)
+	})
+}
+
+func RunAnomalyTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	candle := Candle("BTC-USDT", "1H", start, 1)
+	candle.High = 0
+	trade := model.Trade{Pair: "BTC-USDT", TradeId: "42", Price: 1_000_000, Size: 5, Side: "buy", Timestamp: start.Add(time.Second)}
+
+	t.Run("insert and fetch", func(t *testing.T) {
+		rep := newRepositories(t).Anomaly
+
+		anomalies, err := rep.FetchAnomalies(store.AnomalyQuery{}, 10)
+		require.NoError(t, err)
+		assert.Empty(t, anomalies)
+
+		inserted, err := rep.InsertAnomalies([]model.Anomaly{
+			{Kind: model.AnomalyInvariant, Source: model.AnomalySourceCandle, Pair: "BTC-USDT", Bar: "1H", Timestamp: start,
+				Reason: "high below low", Candle: &candle, DetectedAt: start.Add(time.Hour)},
+			{Kind: model.AnomalyOutlier, Source: model.AnomalySourceTrade, Pair: "BTC-USDT", Timestamp: trade.Timestamp,
+				Reason: "far from median", Trade: &trade, DetectedAt: start.Add(2 * time.Hour)},
+			{Kind: model.AnomalyStale, Source: model.AnomalySourceFeed, Pair: "ETH-USDT", Timestamp: start,
+				Reason: "no trades", DetectedAt: start.Add(3 * time.Hour)},
+		})
+		require.NoError(t, err)
+		require.Len(t, inserted, 3)
+		assert.NotZero(t, inserted[0].Id)
+		assert.Equal(t, model.AnomalyPending, inserted[0].Status)
+
+		again, err := rep.InsertAnomalies([]model.Anomaly{
+			{Kind: model.AnomalyOutlier, Source: model.AnomalySourceCandle, Pair: "BTC-USDT", Bar: "1H", Timestamp: start,
+				Reason: "far from median", Candle: &candle, DetectedAt: start.Add(4 * time.Hour)},
+			{Kind: model.AnomalyOutlier, Source: model.AnomalySourceCandle, Pair: "BTC-USDT", Bar: "1D", Timestamp: start,
+				Reason: "far from median", Candle: &candle, DetectedAt: start.Add(4 * time.Hour)},
+		})
+		require.NoError(t, err)
+		require.Len(t, again, 1, "the 1H candle is quarantined already")
+		assert.Equal(t, "1D", again[0].Bar)
+
+		anomalies, err = rep.FetchAnomalies(store.AnomalyQuery{Pair: "BTC-USDT"}, 10)
+		require.NoError(t, err)
+		require.Len(t, anomalies, 3)
+		assert.Equal(t, "1D", anomalies[0].Bar, "newest first")
+		assert.True(t, start.Add(4*time.Hour).Equal(anomalies[0].DetectedAt))
+		assert.True(t, anomalies[0].ReviewedAt.IsZero())
+
+		require.NotNil(t, anomalies[1].Trade)
+		assert.Nil(t, anomalies[1].Candle)
+		assert.Equal(t, trade.TradeId, anomalies[1].Trade.TradeId)
+		assert.Equal(t, trade.Price, anomalies[1].Trade.Price)
+		assert.True(t, trade.Timestamp.Equal(anomalies[1].Trade.Timestamp))
+
+		require.NotNil(t, anomalies[2].Candle)
+		assert.Equal(t, candle.Close, anomalies[2].Candle.Close)
+		assert.Equal(t, int64(0), anomalies[2].Candle.High)
+
+		anomalies, err = rep.FetchAnomalies(store.AnomalyQuery{Kind: model.AnomalyStale}, 10)
+		require.NoError(t, err)
+		require.Len(t, anomalies, 1)
+		assert.Nil(t, anomalies[0].Candle)
+		assert.Nil(t, anomalies[0].Trade)
+
+		anomalies, err = rep.FetchAnomalies(store.AnomalyQuery{}, 2)
+		require.NoError(t, err)
+		assert.Len(t, anomalies, 2)
+	})
+
+	t.Run("review", func(t *testing.T) {
+		rep := newRepositories(t).Anomaly
+
+		inserted, err := rep.InsertAnomalies([]model.Anomaly{{Kind: model.AnomalyOutlier, Source: model.AnomalySourceTrade,
+			Pair: "BTC-USDT", Timestamp: trade.Timestamp, Reason: "far from median", Trade: &trade, DetectedAt: start}})
+		require.NoError(t, err)
+		require.Len(t, inserted, 1)
+		id := inserted[0].Id
+
+		require.NoError(t, rep.ReviewAnomaly(id, model.AnomalyReleased, start.Add(time.Hour)))
+		assert.ErrorIs(t, rep.ReviewAnomaly(id, model.AnomalyDiscarded, start.Add(time.Hour)), store.ErrNotFound, "reviewed already")
+		assert.ErrorIs(t, rep.ReviewAnomaly(id+1, model.AnomalyDiscarded, start), store.ErrNotFound)
+
+		anomaly, err := rep.FetchAnomaly(id)
+		require.NoError(t, err)
+		assert.Equal(t, model.AnomalyReleased, anomaly.Status)
+		assert.True(t, start.Add(time.Hour).Equal(anomaly.ReviewedAt))
+
+		anomalies, err := rep.FetchAnomalies(store.AnomalyQuery{Status: model.AnomalyPending}, 10)
+		require.NoError(t, err)
+		assert.Empty(t, anomalies)
+
+		_, err = rep.FetchAnomaly(id + 1)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("too long pair", func(t *testing.T) {
+		rep := newRepositories(t).Anomaly
+		_, err := rep.InsertAnomalies([]model.Anomaly{{Kind: model.AnomalyStale, Source: model.AnomalySourceFeed,
+			Pair: "TOO-LONG-PAIR", Timestamp: start, Reason: "no trades", DetectedAt: start}})
+		assert.Error(t, err)
+	})
+}
+
+func RunAnalyticsTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("volatilities", func(t *testing.T) {
+		rep := newRepositories(t).Analytics
+
+		_, err := rep.LastVolatilityTimestamp("BTC-USDT", "1H", 20)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		require.NoError(t, rep.InsertVolatilities(nil))
+		var values []model.Volatility
+		for i := 0; i < 3; i++ {
+			for _, estimator := range []model.VolatilityEstimator{model.VolatilityCloseToClose, model.VolatilityParkinson} {
+				values = append(values, model.Volatility{Pair: "BTC-USDT", Bar: "1H", Window: 20, Estimator: estimator,
+					Timestamp: start.Add(time.Duration(i) * time.Hour), Value: int64(i)})
+			}
+		}
+		values = append(values, model.Volatility{Pair: "ETH-USDT", Bar: "1H", Window: 60, Estimator: model.VolatilityGarmanKlass,
+			Timestamp: start.Add(5 * time.Hour), Value: 7})
+		require.NoError(t, rep.InsertVolatilities(values))
+
+		// upsert
+		require.NoError(t, rep.InsertVolatilities([]model.Volatility{{Pair: "BTC-USDT", Bar: "1H", Window: 20,
+			Estimator: model.VolatilityParkinson, Timestamp: start.Add(2 * time.Hour), Value: 42}}))
+
+		last, err := rep.LastVolatilityTimestamp("BTC-USDT", "1H", 20)
+		require.NoError(t, err)
+		assert.True(t, start.Add(2*time.Hour).Equal(last))
+		_, err = rep.LastVolatilityTimestamp("BTC-USDT", "1H", 60)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		fetched, err := rep.FetchVolatilities(store.VolatilityQuery{Pair: "BTC-USDT", Bar: "1H", Window: 20, Estimator: model.VolatilityParkinson}, 10)
+		require.NoError(t, err)
+		require.Len(t, fetched, 3)
+		assert.Equal(t, int64(42), fetched[0].Value, "newest first")
+
+		fetched, err = rep.FetchVolatilities(store.VolatilityQuery{Pair: "BTC-USDT", From: start.Add(time.Hour), To: start.Add(2 * time.Hour)}, 10)
+		require.NoError(t, err)
+		require.Len(t, fetched, 2)
+		assert.Equal(t, model.VolatilityCloseToClose, fetched[0].Estimator)
+
+		fetched, err = rep.FetchVolatilities(store.VolatilityQuery{}, 2)
+		require.NoError(t, err)
+		require.Len(t, fetched, 2)
+		assert.Equal(t, "ETH-USDT", fetched[0].Pair)
+		assert.Equal(t, 60, fetched[0].Window)
+	})
+
+	t.Run("correlations", func(t *testing.T) {
+		rep := newRepositories(t).Analytics
+
+		matrix, err := rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, matrix)
+		_, err = rep.LastCorrelationTimestamp("BTC-USDT", "ETH-USDT", "1H", 20)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		var values []model.Correlation
+		for i := 0; i < 2; i++ {
+			for _, pairs := range [][2]string{{"ETH-USDT", "SOL-USDT"}, {"BTC-USDT", "ETH-USDT"}, {"BTC-USDT", "SOL-USDT"}} {
+				values = append(values, model.Correlation{Bar: "1H", Window: 20, Method: model.CorrelationPearson,
+					Timestamp: start.Add(time.Duration(i) * time.Hour), PairA: pairs[0], PairB: pairs[1], Value: int64(i)})
+			}
+		}
+		values = append(values, model.Correlation{Bar: "1H", Window: 20, Method: model.CorrelationSpearman,
+			Timestamp: start.Add(3 * time.Hour), PairA: "BTC-USDT", PairB: "ETH-USDT", Value: -5})
+		require.NoError(t, rep.InsertCorrelations(values))
+
+		last, err := rep.LastCorrelationTimestamp("BTC-USDT", "ETH-USDT", "1H", 20)
+		require.NoError(t, err)
+		assert.True(t, start.Add(3*time.Hour).Equal(last))
+		last, err = rep.LastCorrelationTimestamp("ETH-USDT", "SOL-USDT", "1H", 20)
+		require.NoError(t, err)
+		assert.True(t, start.Add(time.Hour).Equal(last), "tracked by pairs")
+		_, err = rep.LastCorrelationTimestamp("ETH-USDT", "BTC-USDT", "1H", 20)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = rep.LastCorrelationTimestamp("BTC-USDT", "ETH-USDT", "1H", 60)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		matrix, err = rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, matrix, 3)
+		assert.Equal(t, "BTC-USDT", matrix[0].PairA, "ordered by pairs")
+		assert.Equal(t, "ETH-USDT", matrix[0].PairB)
+		assert.Equal(t, "SOL-USDT", matrix[1].PairB)
+		assert.Equal(t, "ETH-USDT", matrix[2].PairA)
+		assert.True(t, start.Add(time.Hour).Equal(matrix[0].Timestamp))
+		assert.Equal(t, int64(1), matrix[0].Value)
+
+		matrix, err = rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, start.Add(59*time.Minute))
+		require.NoError(t, err)
+		require.Len(t, matrix, 3)
+		assert.Equal(t, int64(0), matrix[0].Value)
+
+		matrix, err = rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, start.Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, matrix)
+	})
+
+	t.Run("correlations with diverging timestamps", func(t *testing.T) {
+		rep := newRepositories(t).Analytics
+
+		correlation := func(pairA, pairB string, hour int) model.Correlation {
+			return model.Correlation{Bar: "1H", Window: 20, Method: model.CorrelationPearson,
+				Timestamp: start.Add(time.Duration(hour) * time.Hour), PairA: pairA, PairB: pairB, Value: int64(hour)}
+		}
+		require.NoError(t, rep.InsertCorrelations([]model.Correlation{
+			correlation("BTC-USDT", "ETH-USDT", 0),
+			correlation("BTC-USDT", "ETH-USDT", 1),
+			correlation("BTC-USDT", "ETH-USDT", 2),
+			correlation("BTC-USDT", "SOL-USDT", 0),
+			correlation("ETH-USDT", "SOL-USDT", 1),
+		}))
+
+		matrix, err := rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, matrix, 3, "pairs computed earlier are kept")
+		assert.Equal(t, []int64{2, 0, 1}, []int64{matrix[0].Value, matrix[1].Value, matrix[2].Value})
+		assert.True(t, start.Add(2*time.Hour).Equal(matrix[0].Timestamp))
+		assert.True(t, start.Equal(matrix[1].Timestamp))
+
+		matrix, err = rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, start.Add(90*time.Minute))
+		require.NoError(t, err)
+		require.Len(t, matrix, 3)
+		assert.Equal(t, []int64{1, 0, 1}, []int64{matrix[0].Value, matrix[1].Value, matrix[2].Value})
+
+		matrix, err = rep.FetchCorrelationMatrix("1H", 20, model.CorrelationPearson, start.Add(30*time.Minute))
+		require.NoError(t, err)
+		require.Len(t, matrix, 2)
+		assert.Equal(t, "BTC-USDT", matrix[0].PairA)
+		assert.Equal(t, "SOL-USDT", matrix[1].PairB)
+	})
+
+	t.Run("too long estimator", func(t *testing.T) {
+		rep := newRepositories(t).Analytics
+		err := rep.InsertVolatilities([]model.Volatility{{Pair: "BTC-USDT", Bar: "1H", Window: 20,
+			Estimator: model.VolatilityEstimator(strings.Repeat("x", 17)), Timestamp: start}})
+		assert.Error(t, err)
+	})
+}
+
+func Candle(pair, bar string, ts time.Time, value int64) model.Candle {
+	return model.Candle{
+		Pair:      pair,
+		Timestamp: ts,
+		Open:      value * 100,
+		High:      value*100 + 50,
+		Low:       value*100 - 50,
+		Close:     value*100 + 10,
+		Volume:    value * 1000,
+		Bar:       bar,
+	}
+}
+
+func assertCurrency(t *testing.T, expected, actual model.Currency) {
+	t.Helper()
+	expected.Id = actual.Id
+	assert.Equal(t, expected, actual)
+}
+
+func assertCandle(t *testing.T, expected, actual model.Candle) {
+	t.Helper()
+	assert.True(t, expected.Timestamp.Equal(actual.Timestamp), "timestamp: expected %v, actual %v", expected.Timestamp, actual.Timestamp)
+	expected.Timestamp = actual.Timestamp
+	assert.Equal(t, expected, actual)
+}
+
+func RunTradeFlowTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("merge", func(t *testing.T) {
+		rep := newRepositories(t).TradeFlow
+
+		require.NoError(t, rep.MergeTradeFlows(nil))
+		require.NoError(t, rep.MergeTradeFlows([]model.TradeFlow{
+			{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, BuyVolume: 100, SellVolume: 50, Notional: 1500, TradeCount: 3, LargestTrade: 70},
+			{Pair: "BTC-USDT", Bar: "1H", Timestamp: start.Add(time.Hour), BuyVolume: 10, Notional: 100, TradeCount: 1, LargestTrade: 10},
+			{Pair: "BTC-USDT", Bar: "1m", Timestamp: start, BuyVolume: 10, Notional: 100, TradeCount: 1, LargestTrade: 10},
+			{Pair: "ETH-USDT", Bar: "1H", Timestamp: start, SellVolume: 10, Notional: 100, TradeCount: 1, LargestTrade: 10},
+		}))
+		require.NoError(t, rep.MergeTradeFlows([]model.TradeFlow{
+			{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, BuyVolume: 20, SellVolume: 30, Notional: 500, TradeCount: 2, LargestTrade: 30},
+		}))
+
+		flows, err := rep.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+		require.NoError(t, err)
+		require.Len(t, flows, 2)
+		assert.True(t, start.Equal(flows[0].Timestamp))
+		assert.Equal(t, int64(120), flows[0].BuyVolume)
+		assert.Equal(t, int64(80), flows[0].SellVolume)
+		assert.Equal(t, int64(2000), flows[0].Notional)
+		assert.Equal(t, int64(5), flows[0].TradeCount)
+		assert.Equal(t, int64(70), flows[0].LargestTrade, "the largest trade is kept")
+
+		flows, err = rep.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", From: start.Add(time.Hour)})
+		require.NoError(t, err)
+		require.Len(t, flows, 1)
+		assert.Equal(t, int64(10), flows[0].BuyVolume)
+
+		flows, err = rep.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H", To: start})
+		require.NoError(t, err)
+		assert.Empty(t, flows)
+	})
+
+	t.Run("too long pair", func(t *testing.T) {
+		rep := newRepositories(t).TradeFlow
+		err := rep.MergeTradeFlows([]model.TradeFlow{{Pair: "BTC-USDT", Bar: "1H", Timestamp: start, TradeCount: 1},
+			{Pair: "TOO-LONG-PAIR", Bar: "1H", Timestamp: start, TradeCount: 1}})
+		assert.Error(t, err)
+
+		flows, err := rep.FetchTradeFlows(store.CandleQuery{Pair: "BTC-USDT", Bar: "1H"})
+		require.NoError(t, err)
+		assert.Empty(t, flows, "nothing is merged")
+	})
+}
+
+func RunJobTests(t *testing.T, newRepositories Factory) {
+	start := time.Date(2025, 2, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("runs", func(t *testing.T) {
+		rep := newRepositories(t).Job
+
+		_, err := rep.LastJobRun("update_candles")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		first, err := rep.StartJobRun(model.JobRun{Job: "update_candles", Trigger: model.JobTriggerSchedule, Status: model.JobRunning, StartedAt: start})
+		require.NoError(t, err)
+		assert.NotZero(t, first.Id)
+		first.Status, first.FinishedAt, first.Items = model.JobSucceeded, start.Add(time.Minute), 42
+		require.NoError(t, rep.FinishJobRun(first))
+
+		second, err := rep.StartJobRun(model.JobRun{Job: "update_candles", Trigger: model.JobTriggerManual, Status: model.JobRunning, StartedAt: start.Add(time.Hour)})
+		require.NoError(t, err)
+		second.Status, second.FinishedAt, second.Error = model.JobFailed, start.Add(time.Hour+time.Second), "okx is down"
+		require.NoError(t, rep.FinishJobRun(second))
+
+		_, err = rep.StartJobRun(model.JobRun{Job: "update_currencies", Trigger: model.JobTriggerCatchUp, Status: model.JobRunning, StartedAt: start.Add(30 * time.Minute)})
+		require.NoError(t, err)
+
+		last, err := rep.LastJobRun("update_candles")
+		require.NoError(t, err)
+		assert.Equal(t, second.Id, last.Id)
+		assert.Equal(t, model.JobTriggerManual, last.Trigger)
+		assert.Equal(t, model.JobFailed, last.Status)
+		assert.Equal(t, "okx is down", last.Error)
+		assert.True(t, start.Add(time.Hour).Equal(last.StartedAt))
+		assert.True(t, start.Add(time.Hour+time.Second).Equal(last.FinishedAt))
+
+		runs, err := rep.FetchJobRuns("update_candles", 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Equal(t, second.Id, runs[0].Id)
+		assert.Equal(t, model.JobSucceeded, runs[1].Status)
+		assert.Equal(t, 42, runs[1].Items)
+
+		runs, err = rep.FetchJobRuns("", 2)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		assert.Equal(t, "update_candles", runs[0].Job)
+		assert.Equal(t, "update_currencies", runs[1].Job)
+		assert.Equal(t, model.JobRunning, runs[1].Status)
+		assert.True(t, runs[1].FinishedAt.IsZero())
+
+		assert.ErrorIs(t, rep.FinishJobRun(model.JobRun{Id: second.Id + 100, Status: model.JobSucceeded, FinishedAt: start}), store.ErrNotFound)
+	})
+
+	t.Run("interrupt", func(t *testing.T) {
+		rep := newRepositories(t).Job
+
+		running, err := rep.StartJobRun(model.JobRun{Job: "update_candles", Trigger: model.JobTriggerSchedule, Status: model.JobRunning, StartedAt: start})
+		require.NoError(t, err)
+		finished, err := rep.StartJobRun(model.JobRun{Job: "update_currencies", Trigger: model.JobTriggerSchedule, Status: model.JobRunning, StartedAt: start})
+		require.NoError(t, err)
+		finished.Status, finished.FinishedAt = model.JobSucceeded, start.Add(time.Second)
+		require.NoError(t, rep.FinishJobRun(finished))
+
+		interrupted, err := rep.InterruptJobRuns(start.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 1, interrupted)
+
+		last, err := rep.LastJobRun("update_candles")
+		require.NoError(t, err)
+		assert.Equal(t, running.Id, last.Id)
+		assert.Equal(t, model.JobInterrupted, last.Status)
+		assert.True(t, start.Add(time.Hour).Equal(last.FinishedAt))
+
+		last, err = rep.LastJobRun("update_currencies")
+		require.NoError(t, err)
+		assert.Equal(t, model.JobSucceeded, last.Status)
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		rep := newRepositories(t).Job
+
+		paused, err := rep.PausedJobs()
+		require.NoError(t, err)
+		assert.Empty(t, paused)
+
+		require.NoError(t, rep.SetJobPaused("update_currencies", true, start))
+		require.NoError(t, rep.SetJobPaused("update_candles", true, start))
+		require.NoError(t, rep.SetJobPaused("update_currencies", false, start.Add(time.Minute)))
+
+		paused, err = rep.PausedJobs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"update_candles"}, paused)
+	})
+
+	t.Run("too long job", func(t *testing.T) {
+		rep := newRepositories(t).Job
+		_, err := rep.StartJobRun(model.JobRun{Job: strings.Repeat("j", 65), Trigger: model.JobTriggerManual, Status: model.JobRunning, StartedAt: start})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"cur/internal/model"
+	"sort"
+	"sync"
+)
+
+// TickerRepository keeps the latest ticker of each pair in memory
+type TickerRepository struct {
+	mu      sync.RWMutex
+	tickers map[string]model.Ticker
+}
+
+func NewTickerRepository() *TickerRepository {
+	return &TickerRepository{tickers: make(map[string]model.Ticker)}
+}
+
+// Set replaces the ticker of the pair unless a newer one is stored already
+func (rep *TickerRepository) Set(ticker model.Ticker) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	if current, ok := rep.tickers[ticker.Pair]; ok && current.Timestamp.After(ticker.Timestamp) {
+		return
+	}
+	rep.tickers[ticker.Pair] = ticker
+}
+
+// Get returns the latest ticker of the pair or ErrNotFound
+func (rep *TickerRepository) Get(pair string) (model.Ticker, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	ticker, ok := rep.tickers[pair]
+	if !ok {
+		return model.Ticker{}, ErrNotFound
+	}
+	return ticker, nil
+}
+
+// All returns the latest tickers ordered by pair
+func (rep *TickerRepository) All() []model.Ticker {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	tickers := make([]model.Ticker, 0, len(rep.tickers))
+	for _, ticker := range rep.tickers {
+		tickers = append(tickers, ticker)
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Pair < tickers[j].Pair })
+	return tickers
+}
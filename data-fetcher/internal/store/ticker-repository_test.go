@@ -0,0 +1,34 @@
+package store
+
+import (
+	"cur/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickerRepository_Get(t *testing.T) {
+	rep := NewTickerRepository()
+	ts := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := rep.Get("BTC-USDT")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	rep.Set(model.Ticker{Pair: "BTC-USDT", Last: 2, Timestamp: ts.Add(time.Second)})
+	rep.Set(model.Ticker{Pair: "BTC-USDT", Last: 1, Timestamp: ts})
+
+	ticker, err := rep.Get("BTC-USDT")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ticker.Last)
+}
+
+func TestTickerRepository_All(t *testing.T) {
+	rep := NewTickerRepository()
+	assert.Empty(t, rep.All())
+
+	rep.Set(model.Ticker{Pair: "ETH-USDT", Last: 2})
+	rep.Set(model.Ticker{Pair: "BTC-USDT", Last: 1})
+	assert.Equal(t, []model.Ticker{{Pair: "BTC-USDT", Last: 1}, {Pair: "ETH-USDT", Last: 2}}, rep.All())
+}
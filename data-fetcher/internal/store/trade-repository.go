@@ -0,0 +1,73 @@
+package store
+
+import (
+	"cur/internal/model"
+	"sort"
+	"sync"
+)
+
+const DefaultTradesPerPair = 1000
+
+// TradeRepository keeps the latest trades of each pair in memory, trades aren't persisted
+type TradeRepository struct {
+	mu       sync.RWMutex
+	capacity int
+	trades   map[string][]model.Trade // ring buffer per pair
+	next     map[string]int
+}
+
+func NewTradeRepository(capacity int) *TradeRepository {
+	if capacity <= 0 {
+		capacity = DefaultTradesPerPair
+	}
+	return &TradeRepository{
+		capacity: capacity,
+		trades:   make(map[string][]model.Trade),
+		next:     make(map[string]int),
+	}
+}
+
+func (rep *TradeRepository) Add(trade model.Trade) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	ring := rep.trades[trade.Pair]
+	if len(ring) < rep.capacity {
+		rep.trades[trade.Pair] = append(ring, trade)
+		return
+	}
+
+	i := rep.next[trade.Pair]
+	ring[i] = trade
+	rep.next[trade.Pair] = (i + 1) % rep.capacity
+}
+
+// Latest returns up to n latest trades of the pair, newest first
+func (rep *TradeRepository) Latest(pair string, n int) []model.Trade {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	ring := rep.trades[pair]
+	n = min(n, len(ring))
+	trades := make([]model.Trade, 0, n)
+	newest := (rep.next[pair] - 1 + len(ring)) % max(len(ring), 1)
+	for i := 0; i < n; i++ {
+		trades = append(trades, ring[(newest-i+len(ring))%len(ring)])
+	}
+
+	return trades
+}
+
+// Pairs returns pairs having trades in alphabetical order
+func (rep *TradeRepository) Pairs() []string {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+
+	pairs := make([]string, 0, len(rep.trades))
+	for pair := range rep.trades {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	return pairs
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"cur/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTradeRepository_Latest(t *testing.T) {
+	rep := NewTradeRepository(3)
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		rep.Add(model.Trade{Pair: "BTC-USDT", TradeId: id})
+	}
+	rep.Add(model.Trade{Pair: "ETH-USDT", TradeId: "6"})
+
+	ids := func(trades []model.Trade) []string {
+		result := make([]string, 0, len(trades))
+		for _, t := range trades {
+			result = append(result, t.TradeId)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"5", "4", "3"}, ids(rep.Latest("BTC-USDT", 10)))
+	assert.Equal(t, []string{"5"}, ids(rep.Latest("BTC-USDT", 1)))
+	assert.Equal(t, []string{"6"}, ids(rep.Latest("ETH-USDT", 10)))
+	assert.Empty(t, rep.Latest("SOL-USDT", 10))
+	assert.Equal(t, []string{"BTC-USDT", "ETH-USDT"}, rep.Pairs())
+}
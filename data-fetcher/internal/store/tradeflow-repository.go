@@ -0,0 +1,73 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TradeFlowRepository trade flows of candles, keyed like candles
+type TradeFlowRepository struct {
+	db *sql.DB
+}
+
+func NewTradeFlowRepository(db *sql.DB) *TradeFlowRepository {
+	return &TradeFlowRepository{db: db}
+}
+
+// MergeTradeFlows adds flows to the stored ones of the same bars in one transaction
+func (rep *TradeFlowRepository) MergeTradeFlows(flows []model.TradeFlow) error {
+	if len(flows) == 0 {
+		return nil
+	}
+
+	query := strings.Join([]string{"INSERT INTO trade_flows AS f (pair, timestamp, bar, buy_volume, sell_volume, notional, trade_count, largest_trade)",
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		"ON CONFLICT (pair, timestamp, bar)",
+		"DO UPDATE SET buy_volume = f.buy_volume + EXCLUDED.buy_volume,",
+		"sell_volume = f.sell_volume + EXCLUDED.sell_volume,",
+		"notional = f.notional + EXCLUDED.notional,",
+		"trade_count = f.trade_count + EXCLUDED.trade_count,",
+		"largest_trade = GREATEST(f.largest_trade, EXCLUDED.largest_trade)",
+	}, " ")
+
+	tx, err := rep.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	for _, f := range flows {
+		_, err := tx.Exec(query, f.Pair, f.Timestamp, f.Bar, f.BuyVolume, f.SellVolume, f.Notional, f.TradeCount, f.LargestTrade)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to merge trade flows: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// FetchTradeFlows returns flows of the query range ordered by timestamp
+func (rep *TradeFlowRepository) FetchTradeFlows(query CandleQuery) ([]model.TradeFlow, error) {
+	conditions, args := rangeConditions(query)
+
+	rows, err := rep.db.Query("SELECT pair, timestamp, bar, buy_volume, sell_volume, notional, trade_count, largest_trade "+
+		"FROM trade_flows WHERE "+conditions+" ORDER BY timestamp", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flows []model.TradeFlow
+	for rows.Next() {
+		var f model.TradeFlow
+		if err := rows.Scan(&f.Pair, &f.Timestamp, &f.Bar, &f.BuyVolume, &f.SellVolume, &f.Notional, &f.TradeCount, &f.LargestTrade); err != nil {
+			return nil, err
+		}
+		flows = append(flows, f)
+	}
+
+	return flows, rows.Err()
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"cur/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// TrendRepository regime history, a row is stored when the regime of a pair and bar changes
+type TrendRepository struct {
+	db *sql.DB
+}
+
+func NewTrendRepository(db *sql.DB) *TrendRepository {
+	return &TrendRepository{db: db}
+}
+
+const trendColumns = "pair, bar, timestamp, regime, fast_ma, slow_ma, adx, structure"
+
+func (rep *TrendRepository) InsertTrendState(state model.TrendState) error {
+	query := "INSERT INTO trend_states (" + trendColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"ON CONFLICT (pair, bar, timestamp) DO UPDATE SET regime = EXCLUDED.regime, fast_ma = EXCLUDED.fast_ma, " +
+		"slow_ma = EXCLUDED.slow_ma, adx = EXCLUDED.adx, structure = EXCLUDED.structure"
+
+	_, err := rep.db.Exec(query, state.Pair, state.Bar, state.Timestamp, state.Regime, state.FastMa, state.SlowMa, state.Adx, state.Structure)
+	if err != nil {
+		return fmt.Errorf("failed to insert trend state: %w", err)
+	}
+	return nil
+}
+
+// FetchLatestTrendState returns the current regime of the pair and bar or ErrNotFound
+func (rep *TrendRepository) FetchLatestTrendState(pair, bar string) (model.TrendState, error) {
+	rows, err := rep.db.Query("SELECT "+trendColumns+" FROM trend_states WHERE pair=$1 AND bar=$2 ORDER BY timestamp DESC LIMIT 1", pair, bar)
+	if err != nil {
+		return model.TrendState{}, err
+	}
+	defer rows.Close()
+
+	states, err := rowsToTrendStates(rows)
+	if err != nil {
+		return model.TrendState{}, err
+	}
+	if len(states) == 0 {
+		return model.TrendState{}, ErrNotFound
+	}
+	return states[0], nil
+}
+
+// FetchTrendStates returns regime changes of the query range ordered by timestamp
+func (rep *TrendRepository) FetchTrendStates(query CandleQuery) ([]model.TrendState, error) {
+	conditions, args := rangeConditions(query)
+
+	rows, err := rep.db.Query("SELECT "+trendColumns+" FROM trend_states WHERE "+conditions+" ORDER BY timestamp", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rowsToTrendStates(rows)
+}
+
+func rowsToTrendStates(rows *sql.Rows) ([]model.TrendState, error) {
+	var states []model.TrendState
+	for rows.Next() {
+		var s model.TrendState
+		if err := rows.Scan(&s.Pair, &s.Bar, &s.Timestamp, &s.Regime, &s.FastMa, &s.SlowMa, &s.Adx, &s.Structure); err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+
+	return states, rows.Err()
+}
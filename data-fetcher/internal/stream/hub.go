@@ -0,0 +1,190 @@
+// Package stream fans out live market events to many subscribers. Every client
+// has a bounded buffer, a client which doesn't keep up is disconnected instead
+// of slowing down the publisher.
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+const (
+	ChannelTrades  = "trades"
+	ChannelCandles = "candles"
+	ChannelTickers = "tickers"
+
+	DefaultBufferSize  = 256
+	DefaultHistorySize = 1024
+)
+
+var Channels = []string{ChannelTrades, ChannelCandles, ChannelTickers}
+
+type Event struct {
+	Id      uint64
+	Channel string
+	Pair    string
+	Data    json.RawMessage
+}
+
+// Subscription selects events by channel and pair, empty set matches everything
+type Subscription struct {
+	Channels map[string]bool
+	Pairs    map[string]bool
+}
+
+func (s Subscription) Matches(event Event) bool {
+	return (len(s.Channels) == 0 || s.Channels[event.Channel]) &&
+		(len(s.Pairs) == 0 || s.Pairs[event.Pair])
+}
+
+type Client struct {
+	hub          *Hub
+	subscription Subscription
+	events       chan Event
+	done         chan struct{}
+	closeOnce    sync.Once
+	slow         bool
+}
+
+// Events returns buffered events of the client
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Done is closed when the client is unsubscribed either by Close or by the hub
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Slow reports if the client was disconnected because its buffer overflowed
+func (c *Client) Slow() bool {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	return c.slow
+}
+
+func (c *Client) Close() {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.hub.remove(c)
+}
+
+type Hub struct {
+	bufferSize  int
+	historySize int
+
+	mu      sync.RWMutex
+	lastId  uint64
+	history []Event // ring buffer of the latest events for resuming
+	next    int
+	clients map[*Client]struct{}
+}
+
+func NewHub(bufferSize, historySize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	if historySize < 0 {
+		historySize = 0
+	}
+	return &Hub{
+		bufferSize:  bufferSize,
+		historySize: historySize,
+		clients:     make(map[*Client]struct{}),
+	}
+}
+
+// Publish assigns the next id to the event and delivers it to matching clients without blocking
+func (h *Hub) Publish(channel, pair string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastId++
+	event := Event{Id: h.lastId, Channel: channel, Pair: pair, Data: raw}
+
+	if h.historySize > 0 {
+		if len(h.history) < h.historySize {
+			h.history = append(h.history, event)
+		} else {
+			h.history[h.next] = event
+			h.next = (h.next + 1) % h.historySize
+		}
+	}
+
+	for c := range h.clients {
+		if !c.subscription.Matches(event) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			c.slow = true
+			h.remove(c)
+		}
+	}
+
+	return event, nil
+}
+
+// Subscribe registers a client. Events published after lastEventId which are still in
+// the history are delivered first, lastEventId 0 means no replay.
+func (h *Hub) Subscribe(subscription Subscription, lastEventId uint64) *Client {
+	c := &Client{
+		hub:          h,
+		subscription: subscription,
+		events:       make(chan Event, h.bufferSize),
+		done:         make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventId > 0 {
+		var missed []Event
+		for i := 0; i < len(h.history); i++ {
+			event := h.history[(h.next+i)%len(h.history)]
+			if event.Id > lastEventId && subscription.Matches(event) {
+				missed = append(missed, event)
+			}
+		}
+		// the client can't catch up if it missed more than its buffer holds
+		if len(missed) > h.bufferSize {
+			missed = missed[len(missed)-h.bufferSize:]
+		}
+		for _, event := range missed {
+			c.events <- event
+		}
+	}
+
+	h.clients[c] = struct{}{}
+
+	return c
+}
+
+// Clients number of subscribed clients
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// LastEventId id of the latest published event
+func (h *Hub) LastEventId() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastId
+}
+
+// remove unsubscribes the client, must be called under lock
+func (h *Hub) remove(c *Client) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	c.closeOnce.Do(func() { close(c.done) })
+}
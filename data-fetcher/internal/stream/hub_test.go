@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func drain(c *Client) []uint64 {
+	var ids []uint64
+	for {
+		select {
+		case event := <-c.Events():
+			ids = append(ids, event.Id)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestHub_PublishMatchesSubscription(t *testing.T) {
+	hub := NewHub(10, 10)
+	all := hub.Subscribe(Subscription{}, 0)
+	btcTrades := hub.Subscribe(Subscription{
+		Channels: map[string]bool{ChannelTrades: true},
+		Pairs:    map[string]bool{"BTC-USDT": true},
+	}, 0)
+
+	_, err := hub.Publish(ChannelTrades, "BTC-USDT", map[string]string{"price": "1"})
+	require.NoError(t, err)
+	_, _ = hub.Publish(ChannelTrades, "ETH-USDT", nil)
+	_, _ = hub.Publish(ChannelTickers, "BTC-USDT", nil)
+
+	assert.Equal(t, []uint64{1, 2, 3}, drain(all))
+	assert.Equal(t, []uint64{1}, drain(btcTrades))
+	assert.Equal(t, uint64(3), hub.LastEventId())
+}
+
+func TestHub_SlowConsumerIsDisconnected(t *testing.T) {
+	hub := NewHub(2, 10)
+	slow := hub.Subscribe(Subscription{}, 0)
+	fast := hub.Subscribe(Subscription{}, 0)
+
+	for i := 0; i < 2; i++ {
+		_, _ = hub.Publish(ChannelTrades, "BTC-USDT", i)
+		drain(fast)
+	}
+	assert.False(t, slow.Slow())
+
+	_, _ = hub.Publish(ChannelTrades, "BTC-USDT", 3)
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow client must be disconnected")
+	}
+	assert.True(t, slow.Slow())
+	assert.Equal(t, 1, hub.Clients())
+	assert.Equal(t, []uint64{3}, drain(fast))
+
+	fast.Close()
+	fast.Close()
+	assert.Equal(t, 0, hub.Clients())
+	assert.False(t, fast.Slow())
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := NewHub(3, 5)
+	for i := 0; i < 7; i++ {
+		pair := "BTC-USDT"
+		if i%2 == 1 {
+			pair = "ETH-USDT"
+		}
+		_, _ = hub.Publish(ChannelTrades, pair, i)
+	}
+	// history keeps events 3..7
+
+	resumed := hub.Subscribe(Subscription{}, 4)
+	assert.Equal(t, []uint64{5, 6, 7}, drain(resumed))
+
+	btc := hub.Subscribe(Subscription{Pairs: map[string]bool{"BTC-USDT": true}}, 1)
+	assert.Equal(t, []uint64{3, 5, 7}, drain(btc))
+
+	// only the latest events fitting the buffer are replayed
+	truncated := hub.Subscribe(Subscription{}, 1)
+	assert.Equal(t, []uint64{5, 6, 7}, drain(truncated))
+
+	fresh := hub.Subscribe(Subscription{}, 0)
+	assert.Empty(t, drain(fresh))
+}
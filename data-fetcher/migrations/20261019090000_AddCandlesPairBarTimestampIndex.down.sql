@@ -0,0 +1 @@
+DROP INDEX idx_candles_pair_bar_timestamp;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX idx_candles_pair_bar_timestamp ON candles (pair, bar, timestamp);
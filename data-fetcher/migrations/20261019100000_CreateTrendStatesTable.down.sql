@@ -0,0 +1 @@
+DROP TABLE trend_states;
@@ -0,0 +1,12 @@
+CREATE TABLE trend_states
+(
+    pair      VARCHAR(10) NOT NULL,
+    bar       VARCHAR(5)  NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    regime    VARCHAR(10) NOT NULL,
+    fast_ma   BIGINT      NOT NULL,
+    slow_ma   BIGINT      NOT NULL,
+    adx       BIGINT      NOT NULL,
+    structure VARCHAR(12) NOT NULL,
+    PRIMARY KEY (pair, bar, timestamp)
+);
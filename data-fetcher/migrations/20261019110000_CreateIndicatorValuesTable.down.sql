@@ -0,0 +1 @@
+DROP TABLE indicator_values;
@@ -0,0 +1,12 @@
+CREATE TABLE indicator_values
+(
+    pair      VARCHAR(10) NOT NULL,
+    bar       VARCHAR(5)  NOT NULL,
+    indicator VARCHAR(32) NOT NULL,
+    component VARCHAR(16) NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    value     BIGINT      NOT NULL,
+    PRIMARY KEY (pair, bar, indicator, component, timestamp)
+);
+
+CREATE INDEX idx_indicator_values_pair_bar_timestamp ON indicator_values (pair, bar, timestamp);
@@ -0,0 +1,33 @@
+CREATE TABLE alert_rules
+(
+    id                BIGSERIAL PRIMARY KEY,
+    name              VARCHAR(100) NOT NULL,
+    pair              VARCHAR(10)  NOT NULL,
+    bar               VARCHAR(5)   NOT NULL DEFAULT '',
+    kind              VARCHAR(16)  NOT NULL,
+    direction         VARCHAR(8)   NOT NULL,
+    level             BIGINT       NOT NULL DEFAULT 0,
+    percent           BIGINT       NOT NULL DEFAULT 0,
+    window_ms         BIGINT       NOT NULL DEFAULT 0,
+    multiplier        BIGINT       NOT NULL DEFAULT 0,
+    lookback          INTEGER      NOT NULL DEFAULT 0,
+    indicator         VARCHAR(48)  NOT NULL DEFAULT '',
+    cooldown_ms       BIGINT       NOT NULL DEFAULT 0,
+    channels          TEXT[]       NOT NULL DEFAULT '{}',
+    enabled           BOOLEAN      NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE alert_events
+(
+    id           BIGSERIAL PRIMARY KEY,
+    rule_id      BIGINT      NOT NULL REFERENCES alert_rules (id) ON DELETE CASCADE,
+    pair         VARCHAR(10) NOT NULL,
+    kind         VARCHAR(16) NOT NULL,
+    value        BIGINT      NOT NULL,
+    message      TEXT        NOT NULL,
+    triggered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_alert_events_rule_id ON alert_events (rule_id, triggered_at);
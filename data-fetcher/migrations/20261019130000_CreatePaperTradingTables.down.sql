@@ -0,0 +1,4 @@
+DROP TABLE paper_positions;
+DROP TABLE paper_balances;
+DROP TABLE paper_fills;
+DROP TABLE paper_orders;
@@ -0,0 +1,45 @@
+CREATE TABLE paper_orders
+(
+    id          BIGSERIAL PRIMARY KEY,
+    pair        VARCHAR(10)  NOT NULL,
+    side        VARCHAR(4)   NOT NULL,
+    type        VARCHAR(8)   NOT NULL,
+    size        BIGINT       NOT NULL,
+    limit_price BIGINT       NOT NULL DEFAULT 0,
+    status      VARCHAR(10)  NOT NULL,
+    reason      VARCHAR(200) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_paper_orders_status ON paper_orders (status, id);
+
+CREATE TABLE paper_fills
+(
+    id        BIGSERIAL PRIMARY KEY,
+    order_id  BIGINT      NOT NULL REFERENCES paper_orders (id),
+    pair      VARCHAR(10) NOT NULL,
+    side      VARCHAR(4)  NOT NULL,
+    price     BIGINT      NOT NULL,
+    size      BIGINT      NOT NULL,
+    fee       BIGINT      NOT NULL,
+    trade_id  VARCHAR(32) NOT NULL DEFAULT '',
+    filled_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_paper_fills_order_id ON paper_fills (order_id);
+
+CREATE TABLE paper_balances
+(
+    currency VARCHAR(10) PRIMARY KEY,
+    amount   BIGINT      NOT NULL CHECK (amount >= 0)
+);
+
+CREATE TABLE paper_positions
+(
+    pair         VARCHAR(10) PRIMARY KEY,
+    size         BIGINT      NOT NULL CHECK (size >= 0),
+    avg_price    BIGINT      NOT NULL,
+    realized_pnl BIGINT      NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,12 @@
+CREATE TABLE portfolio_holdings
+(
+    id          BIGSERIAL PRIMARY KEY,
+    currency    VARCHAR(10)  NOT NULL,
+    amount      BIGINT       NOT NULL CHECK (amount <> 0),
+    price       BIGINT       NOT NULL DEFAULT 0 CHECK (price >= 0),
+    acquired_at TIMESTAMPTZ  NOT NULL,
+    note        VARCHAR(200) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_portfolio_holdings_acquired_at ON portfolio_holdings (acquired_at, id);
@@ -0,0 +1,2 @@
+DROP TABLE divergence_events;
+DROP TABLE price_divergences;
@@ -0,0 +1,29 @@
+CREATE TABLE price_divergences
+(
+    id              BIGSERIAL PRIMARY KEY,
+    pair            VARCHAR(10) NOT NULL,
+    source          VARCHAR(32) NOT NULL,
+    okx_price       BIGINT      NOT NULL,
+    reference_price BIGINT      NOT NULL,
+    spread          BIGINT      NOT NULL,
+    net_spread      BIGINT      NOT NULL,
+    observed_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_price_divergences_pair_observed_at ON price_divergences (pair, observed_at);
+
+CREATE TABLE divergence_events
+(
+    id              BIGSERIAL PRIMARY KEY,
+    pair            VARCHAR(10) NOT NULL,
+    source          VARCHAR(32) NOT NULL,
+    okx_price       BIGINT      NOT NULL,
+    reference_price BIGINT      NOT NULL,
+    spread          BIGINT      NOT NULL,
+    net_spread      BIGINT      NOT NULL,
+    threshold       BIGINT      NOT NULL,
+    started_at      TIMESTAMPTZ NOT NULL,
+    triggered_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_divergence_events_triggered_at ON divergence_events (triggered_at);
@@ -0,0 +1,18 @@
+CREATE TABLE anomalies
+(
+    id          BIGSERIAL PRIMARY KEY,
+    kind        VARCHAR(16) NOT NULL,
+    source      VARCHAR(16) NOT NULL,
+    pair        VARCHAR(10) NOT NULL,
+    bar         VARCHAR(5)  NOT NULL DEFAULT '',
+    ts          TIMESTAMPTZ NOT NULL,
+    trade_id    VARCHAR(32) NOT NULL DEFAULT '',
+    reason      TEXT        NOT NULL,
+    payload     JSONB,
+    status      VARCHAR(16) NOT NULL DEFAULT 'pending',
+    detected_at TIMESTAMPTZ NOT NULL,
+    reviewed_at TIMESTAMPTZ,
+    UNIQUE (source, pair, bar, ts, trade_id)
+);
+
+CREATE INDEX idx_anomalies_status_detected_at ON anomalies (status, detected_at);
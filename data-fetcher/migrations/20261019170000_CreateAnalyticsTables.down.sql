@@ -0,0 +1,2 @@
+DROP TABLE correlation_values;
+DROP TABLE volatility_values;
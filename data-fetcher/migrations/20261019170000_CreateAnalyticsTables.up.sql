@@ -0,0 +1,22 @@
+CREATE TABLE volatility_values
+(
+    pair        VARCHAR(10) NOT NULL,
+    bar         VARCHAR(5)  NOT NULL,
+    window_size INTEGER     NOT NULL,
+    estimator   VARCHAR(16) NOT NULL,
+    timestamp   TIMESTAMPTZ NOT NULL,
+    value       BIGINT      NOT NULL,
+    PRIMARY KEY (pair, bar, window_size, estimator, timestamp)
+);
+
+CREATE TABLE correlation_values
+(
+    bar         VARCHAR(5)  NOT NULL,
+    window_size INTEGER     NOT NULL,
+    method      VARCHAR(16) NOT NULL,
+    timestamp   TIMESTAMPTZ NOT NULL,
+    pair_a      VARCHAR(10) NOT NULL,
+    pair_b      VARCHAR(10) NOT NULL,
+    value       BIGINT      NOT NULL,
+    PRIMARY KEY (bar, window_size, method, timestamp, pair_a, pair_b)
+);
@@ -0,0 +1 @@
+DROP TABLE trade_flows;
@@ -0,0 +1,12 @@
+CREATE TABLE trade_flows
+(
+    pair          VARCHAR(10) NOT NULL,
+    timestamp     TIMESTAMPTZ NOT NULL,
+    bar           VARCHAR(5)  NOT NULL,
+    buy_volume    BIGINT      NOT NULL,
+    sell_volume   BIGINT      NOT NULL,
+    notional      BIGINT      NOT NULL,
+    trade_count   BIGINT      NOT NULL,
+    largest_trade BIGINT      NOT NULL,
+    PRIMARY KEY (pair, timestamp, bar)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE job_runs
+(
+    id          BIGSERIAL PRIMARY KEY,
+    job         VARCHAR(64) NOT NULL,
+    trigger     VARCHAR(16) NOT NULL,
+    status      VARCHAR(16) NOT NULL,
+    started_at  TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    error       TEXT        NOT NULL DEFAULT '',
+    items       INTEGER     NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_job_runs_job_started_at ON job_runs (job, started_at);
+CREATE INDEX idx_job_runs_status ON job_runs (status);
+
+CREATE TABLE jobs
+(
+    name       VARCHAR(64) PRIMARY KEY,
+    paused     BOOLEAN     NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL
+);
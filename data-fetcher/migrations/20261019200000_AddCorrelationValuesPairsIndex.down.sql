@@ -0,0 +1 @@
+DROP INDEX idx_correlation_values_pairs;
@@ -0,0 +1 @@
+CREATE INDEX idx_correlation_values_pairs ON correlation_values (pair_a, pair_b, bar, window_size, timestamp);
@@ -0,0 +1,36 @@
+package marketdatav1
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client is a market data service client owning its connection
+type Client struct {
+	MarketDataServiceClient
+	conn *grpc.ClientConn
+}
+
+// NewClient connects to the market data service at target (e.g. "localhost:9090").
+// Without options the connection is made without transport security.
+func NewClient(target string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{MarketDataServiceClient: NewMarketDataServiceClient(conn), conn: conn}, nil
+}
+
+// Connection returns the underlying connection, e.g. for health or reflection clients
+func (c *Client) Connection() *grpc.ClientConn {
+	return c.conn
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}